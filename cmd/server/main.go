@@ -4,10 +4,12 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
 	"github.com/dmitrijfomin/menu-fodifood/backend/internal/handlers"
 	"github.com/dmitrijfomin/menu-fodifood/backend/internal/middleware"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/rs/cors"
@@ -29,10 +31,21 @@ func main() {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	// Себестоимость продуктов, заведённых до её расчёта по рецептуре
+	if err := services.NewCostService().BackfillProductCosts(); err != nil {
+		log.Printf("⚠️ Failed to backfill product costs: %v", err)
+	}
+
 	// Инициализация WebSocket Hub для real-time уведомлений
 	handlers.InitWebSocketHub()
 	log.Println("✅ WebSocket Hub initialized")
 
+	// Фоновые задачи
+	services.NewPriceService().StartScheduler(time.Minute)
+	services.NewRecommendationService().StartRefresher(time.Hour)
+	handlers.StartLowStockAlerts(5 * time.Minute)
+	services.NewProductionService().StartExpiryWriteOff(5 * time.Minute)
+
 	// Инициализация роутера
 	router := mux.NewRouter()
 
@@ -70,12 +83,21 @@ func main() {
 	protected.HandleFunc("/user/profile", handlers.UpdateProfile).Methods("PUT", "OPTIONS")
 	protected.HandleFunc("/user/orders", handlers.GetUserOrders).Methods("GET", "OPTIONS")
 
+	// Отзывы (только по доставленным заказам)
+	protected.HandleFunc("/products/{id}/reviews", handlers.CreateProductReview).Methods("POST", "OPTIONS")
+
 	// Orders (публичный endpoint для создания заказа)
 	api.HandleFunc("/orders", handlers.CreateOrder).Methods("POST", "OPTIONS")
 
 	// Products (публичные - только видимые продукты)
 	api.HandleFunc("/products", handlers.GetPublicProducts).Methods("GET", "OPTIONS")
+	api.HandleFunc("/products/search", handlers.SearchProducts).Methods("GET", "OPTIONS")
 	api.HandleFunc("/products/{id}", handlers.GetProduct).Methods("GET", "OPTIONS")
+	api.HandleFunc("/products/{id}/reviews", handlers.GetProductReviews).Methods("GET", "OPTIONS")
+	api.HandleFunc("/products/{id}/recommendations", handlers.GetProductRecommendations).Methods("GET", "OPTIONS")
+
+	// Предпросмотр черновика меню по подписанной ссылке
+	api.HandleFunc("/menu/preview/{id}", handlers.PreviewMenuDraft).Methods("GET", "OPTIONS")
 
 	// Admin routes
 	admin := protected.PathPrefix("/admin").Subrouter()
@@ -91,6 +113,7 @@ func main() {
 	admin.HandleFunc("/orders", handlers.GetAllOrders).Methods("GET", "OPTIONS")
 	admin.HandleFunc("/orders/recent", handlers.GetRecentOrders).Methods("GET", "OPTIONS")
 	admin.HandleFunc("/orders/{id}/status", handlers.UpdateOrderStatus).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/orders/{id}/costing", handlers.GetOrderCosting).Methods("GET", "OPTIONS")
 
 	// Stats
 	admin.HandleFunc("/stats", handlers.GetAdminStats).Methods("GET", "OPTIONS")
@@ -98,9 +121,68 @@ func main() {
 	// Ingredients
 	admin.HandleFunc("/ingredients", handlers.GetAllIngredients).Methods("GET", "OPTIONS")
 	admin.HandleFunc("/ingredients", handlers.CreateIngredient).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/ingredients/low-stock", handlers.GetLowStockIngredients).Methods("GET", "OPTIONS")
 	admin.HandleFunc("/ingredients/{id}", handlers.UpdateIngredient).Methods("PUT", "OPTIONS")
 	admin.HandleFunc("/ingredients/{id}", handlers.DeleteIngredient).Methods("DELETE", "OPTIONS")
 	admin.HandleFunc("/ingredients/{id}/movements", handlers.GetStockMovements).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/ingredients/{id}/batches", handlers.GetIngredientBatches).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/stock/batches/expiring", handlers.GetExpiringBatches).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/stock/movements", handlers.GetStockJournal).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/ingredients/{id}/recalculate-costs", handlers.RecalculateIngredientCosts).Methods("POST", "OPTIONS")
+
+	// Units (единицы измерения и коэффициенты перевода ингредиентов)
+	admin.HandleFunc("/units", handlers.GetUnits).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/ingredients/{id}/conversions", handlers.GetUnitConversions).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/ingredients/{id}/conversions", handlers.SetUnitConversion).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/ingredients/{id}/conversions/{conversionId}", handlers.DeleteUnitConversion).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/ingredients/{id}/convert", handlers.ConvertIngredientUnits).Methods("GET", "OPTIONS")
+
+	// Goods receipts (приходные накладные)
+	admin.HandleFunc("/goods-receipts", handlers.GetGoodsReceipts).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/goods-receipts", handlers.CreateGoodsReceipt).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/goods-receipts/{id}", handlers.GetGoodsReceipt).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/goods-receipts/{id}", handlers.UpdateGoodsReceipt).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/goods-receipts/{id}", handlers.DeleteGoodsReceipt).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/goods-receipts/{id}/post", handlers.PostGoodsReceipt).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/goods-receipts/{id}/reverse", handlers.ReverseGoodsReceipt).Methods("POST", "OPTIONS")
+
+	// Suppliers (поставщики и прайс-листы)
+	admin.HandleFunc("/suppliers", handlers.GetSuppliers).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/suppliers", handlers.CreateSupplier).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/suppliers/{id}", handlers.GetSupplier).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/suppliers/{id}", handlers.UpdateSupplier).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/suppliers/{id}", handlers.DeleteSupplier).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/suppliers/{id}/prices", handlers.SetSupplierPrice).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/suppliers/{id}/prices/{priceId}", handlers.DeleteSupplierPrice).Methods("DELETE", "OPTIONS")
+
+	// Purchase orders (заказы поставщикам)
+	admin.HandleFunc("/purchase-orders", handlers.GetPurchaseOrders).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/purchase-orders", handlers.CreatePurchaseOrder).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/purchase-orders/generate", handlers.GeneratePurchaseOrders).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/purchase-orders/{id}", handlers.GetPurchaseOrder).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/purchase-orders/{id}", handlers.UpdatePurchaseOrder).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/purchase-orders/{id}", handlers.DeletePurchaseOrder).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/purchase-orders/{id}/send", handlers.SendPurchaseOrder).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/purchase-orders/{id}/cancel", handlers.CancelPurchaseOrder).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/purchase-orders/{id}/receive", handlers.ReceivePurchaseOrder).Methods("POST", "OPTIONS")
+
+	// Inventory counts (инвентаризация склада)
+	admin.HandleFunc("/inventory-counts", handlers.GetInventoryCounts).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/inventory-counts", handlers.StartInventoryCount).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/inventory-counts/{id}", handlers.GetInventoryCount).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/inventory-counts/{id}/entries", handlers.GetInventoryCountEntries).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/inventory-counts/{id}/entries", handlers.AddInventoryCountEntries).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/inventory-counts/{id}/approve", handlers.ApproveInventoryCount).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/inventory-counts/{id}/cancel", handlers.CancelInventoryCount).Methods("POST", "OPTIONS")
+
+	// Write-offs (списания и потери)
+	admin.HandleFunc("/write-offs", handlers.GetWriteOffs).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/write-offs", handlers.CreateWriteOff).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/write-offs/reasons", handlers.GetWriteOffReasons).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/reports/waste", handlers.GetWasteReport).Methods("GET", "OPTIONS")
+
+	// Cost changes (журнал изменений себестоимости)
+	admin.HandleFunc("/cost-changes", handlers.GetCostChanges).Methods("GET", "OPTIONS")
 
 	// Semi-Finished Products (Полуфабрикаты)
 	admin.HandleFunc("/semi-finished", handlers.GetSemiFinished).Methods("GET", "OPTIONS")
@@ -108,13 +190,67 @@ func main() {
 	admin.HandleFunc("/semi-finished/{id}", handlers.GetSemiFinishedByID).Methods("GET", "OPTIONS")
 	admin.HandleFunc("/semi-finished/{id}", handlers.UpdateSemiFinished).Methods("PUT", "OPTIONS")
 	admin.HandleFunc("/semi-finished/{id}", handlers.DeleteSemiFinished).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/semi-finished/{id}/cost-breakdown", handlers.GetSemiFinishedCostBreakdown).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/semi-finished/{id}/tree", handlers.GetSemiFinishedTree).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/semi-finished/{id}/produce", handlers.ProduceSemiFinished).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/semi-finished/{id}/batches", handlers.GetProductionBatches).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/semi-finished/{id}/tech-card", handlers.GetSemiFinishedTechCard).Methods("GET", "OPTIONS")
+
+	// Recipe versions (версии рецептур продуктов и полуфабрикатов)
+	admin.HandleFunc("/recipes/{entityType}/{id}/versions", handlers.GetRecipeVersions).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/recipes/{entityType}/{id}/versions/diff", handlers.DiffRecipeVersions).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/recipes/{entityType}/{id}/versions/{version}", handlers.GetRecipeVersion).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/recipes/{entityType}/{id}/versions/{version}/restore", handlers.RestoreRecipeVersion).Methods("POST", "OPTIONS")
 
 	// Products
 	admin.HandleFunc("/products", handlers.GetAllProducts).Methods("GET", "OPTIONS")
 	admin.HandleFunc("/products", handlers.CreateProduct).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/products/import", handlers.ImportProducts).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/products/export", handlers.ExportProducts).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/products/deleted", handlers.GetDeletedProducts).Methods("GET", "OPTIONS")
 	admin.HandleFunc("/products/{id}", handlers.GetProduct).Methods("GET", "OPTIONS")
 	admin.HandleFunc("/products/{id}", handlers.UpdateProduct).Methods("PUT", "OPTIONS")
 	admin.HandleFunc("/products/{id}", handlers.DeleteProduct).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/products/{id}/restore", handlers.RestoreProduct).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/products/{id}/permanent", handlers.PurgeProduct).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/products/{id}/stop", handlers.StopProduct).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/products/{id}/stop", handlers.UnstopProduct).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/products/{id}/price-history", handlers.GetProductPriceHistory).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/products/{id}/cost-breakdown", handlers.GetProductCostBreakdown).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/products/{id}/tech-card", handlers.GetProductTechCard).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/products/{id}/scheduled-prices", handlers.GetScheduledPrices).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/products/{id}/scheduled-prices", handlers.ScheduleProductPrice).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/scheduled-prices/{id}", handlers.CancelScheduledPrice).Methods("DELETE", "OPTIONS")
+
+	// Tech cards (технологические карты для санитарной проверки)
+	admin.HandleFunc("/tech-cards", handlers.GetMenuTechCards).Methods("GET", "OPTIONS")
+
+	// Pricing rules (happy hour)
+	admin.HandleFunc("/pricing-rules", handlers.GetPricingRules).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/pricing-rules", handlers.CreatePricingRule).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/pricing-rules/{id}", handlers.UpdatePricingRule).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/pricing-rules/{id}", handlers.DeletePricingRule).Methods("DELETE", "OPTIONS")
+
+	// Menu drafts & versions (черновики и версии меню)
+	admin.HandleFunc("/menu/drafts", handlers.GetMenuDrafts).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/menu/drafts", handlers.CreateMenuDraft).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/menu/drafts/{id}", handlers.GetMenuDraft).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/menu/drafts/{id}", handlers.DiscardMenuDraft).Methods("DELETE", "OPTIONS")
+	admin.HandleFunc("/menu/drafts/{id}/items/{productId}", handlers.UpdateMenuDraftItem).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/menu/drafts/{id}/rename-category", handlers.RenameMenuDraftCategory).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/menu/drafts/{id}/preview-link", handlers.CreateMenuPreviewLink).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/menu/drafts/{id}/publish", handlers.PublishMenuDraft).Methods("POST", "OPTIONS")
+	admin.HandleFunc("/menu/versions", handlers.GetMenuVersions).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/menu/versions/{id}", handlers.GetMenuVersion).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/menu/versions/{id}/rollback", handlers.RollbackMenuVersion).Methods("POST", "OPTIONS")
+
+	// Reviews moderation (модерация отзывов)
+	admin.HandleFunc("/reviews", handlers.GetReviewsForModeration).Methods("GET", "OPTIONS")
+	admin.HandleFunc("/reviews/{id}/moderate", handlers.ModerateReview).Methods("PUT", "OPTIONS")
+	admin.HandleFunc("/reviews/{id}", handlers.DeleteReview).Methods("DELETE", "OPTIONS")
+
+	// Stop-list (стоп-лист)
+	admin.HandleFunc("/stop-list", handlers.GetStopList).Methods("GET", "OPTIONS")
 
 	// WebSocket для real-time уведомлений (вне всех middleware, проверка токена внутри хэндлера)
 	router.HandleFunc("/api/admin/ws", handlers.HandleWebSocket)
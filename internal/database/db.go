@@ -50,10 +50,25 @@ func GetDB() *gorm.DB {
 	return DB
 }
 
+// partialIndexes индексы с условиями и выражениями (см. migrations/)
+var partialIndexes = []string{
+	// Накладная поставщика регистрируется один раз (сторнированные не считаются)
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_goods_receipt_invoice
+		ON goods_receipts (LOWER(supplier), invoice_number) WHERE status <> 'reversed'`,
+	// Открытой может быть только одна инвентаризация
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_inventory_count_open
+		ON inventory_counts ((status)) WHERE status = 'open'`,
+}
+
 // AutoMigrate выполняет автоматическую миграцию схемы базы данных
 func AutoMigrate() error {
 	log.Println("🔄 Starting database schema migration...")
 
+	// Расширение для нечёткого поиска (см. migrations/013_add_product_search.sql)
+	if err := DB.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		log.Printf("⚠️ Failed to enable pg_trgm extension: %v", err)
+	}
+
 	// Выполняем миграцию для всех моделей
 	err := DB.AutoMigrate(
 		&models.User{},
@@ -63,12 +78,40 @@ func AutoMigrate() error {
 		&models.Product{},
 		&models.ProductIngredient{},
 		&models.ProductSemiFinished{},
+		&models.BundleItem{},
+		&models.BundleSlot{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.OrderItemComponent{},
 		&models.Business{},
 		&models.BusinessToken{},
 		&models.BusinessSubscription{},
 		&models.Transaction{},
+		&models.CostChangeLog{},
+		&models.ProductPriceHistory{},
+		&models.ScheduledPriceChange{},
+		&models.PricingRule{},
+		&models.MenuDraft{},
+		&models.MenuVersion{},
+		&models.ProductReview{},
+		&models.ProductRecommendation{},
+		&models.GoodsReceipt{},
+		&models.GoodsReceiptLine{},
+		&models.StockBatch{},
+		&models.StockBatchConsumption{},
+		&models.InventoryCount{},
+		&models.InventoryCountLine{},
+		&models.InventoryCountEntry{},
+		&models.WriteOff{},
+		&models.Supplier{},
+		&models.SupplierPrice{},
+		&models.PurchaseOrder{},
+		&models.PurchaseOrderLine{},
+		&models.IngredientUnitConversion{},
+		&models.ProductionBatch{},
+		&models.SemiFinishedMovement{},
+		&models.SemiFinishedComponent{},
+		&models.RecipeVersion{},
 	)
 
 	if err != nil {
@@ -76,6 +119,13 @@ func AutoMigrate() error {
 		return err
 	}
 
+	// Частичные и функциональные индексы, которые не описываются тегами GORM
+	for _, sql := range partialIndexes {
+		if err := DB.Exec(sql).Error; err != nil {
+			log.Printf("⚠️ Failed to create index (check existing data): %v", err)
+		}
+	}
+
 	log.Println("✅ Database schema migration completed successfully")
 	return nil
 }
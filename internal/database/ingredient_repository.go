@@ -2,6 +2,7 @@ package database
 
 import (
 	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"gorm.io/gorm"
 )
 
 // IngredientRepository репозиторий для работы с ингредиентами
@@ -32,23 +33,21 @@ func (r *IngredientRepository) FindByID(id string) (*models.StockItem, error) {
 
 // CreateIngredient создает новый ингредиент и складской остаток
 func (r *IngredientRepository) CreateIngredient(ingredient *models.Ingredient, stockItem *models.StockItem) error {
-	// Создаем в транзакции
-	tx := DB.Begin()
+	return DB.Transaction(func(tx *gorm.DB) error {
+		return r.CreateIngredientTx(tx, ingredient, stockItem)
+	})
+}
 
+// CreateIngredientTx создает ингредиент и складской остаток внутри транзакции вызывающего
+func (r *IngredientRepository) CreateIngredientTx(tx *gorm.DB, ingredient *models.Ingredient, stockItem *models.StockItem) error {
 	// Создаем ингредиент
 	if err := tx.Create(ingredient).Error; err != nil {
-		tx.Rollback()
 		return err
 	}
 
 	// Создаем складской остаток
 	stockItem.IngredientID = ingredient.ID
-	if err := tx.Create(stockItem).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	return tx.Commit().Error
+	return tx.Create(stockItem).Error
 }
 
 // UpdateStockItem обновляет складской остаток
@@ -270,3 +270,12 @@ func UpdateUserRole(w http.ResponseWriter, r *http.Request) {
 		"updated_by": claims.UserID,
 	})
 }
+
+// currentUserID возвращает ID текущего пользователя из JWT (nil для неавторизованных запросов)
+func currentUserID(r *http.Request) *string {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok || claims.UserID == "" {
+		return nil
+	}
+	return &claims.UserID
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+var costService = services.NewCostService()
+
+// RecalculateIngredientCosts принудительно пересчитывает себестоимость по текущей цене ингредиента
+// POST /api/admin/ingredients/{id}/recalculate-costs
+func RecalculateIngredientCosts(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	stockItem, err := ingredientRepo.FindByID(id)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Ingredient not found")
+		return
+	}
+
+	price := 0.0
+	if stockItem.PricePerUnit != nil {
+		price = *stockItem.PricePerUnit
+	}
+
+	result, err := costService.RecalculateForIngredient(stockItem.IngredientID, price, price)
+	if err != nil {
+		log.Printf("[COST] ❌ Error recalculating costs: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to recalculate costs")
+		return
+	}
+
+	notifyMarginDrops(result)
+
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
+
+// GetProductCostBreakdown разбивка себестоимости продукта: брутто, нетто и потери по строкам
+// GET /api/admin/products/{id}/cost-breakdown
+func GetProductCostBreakdown(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	breakdown, err := costService.ProductBreakdown(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, breakdown)
+}
+
+// GetSemiFinishedCostBreakdown разбивка себестоимости полуфабриката
+// GET /api/admin/semi-finished/{id}/cost-breakdown
+func GetSemiFinishedCostBreakdown(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	breakdown, err := costService.SemiFinishedBreakdown(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Semi-finished not found")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, breakdown)
+}
+
+// GetCostChanges журнал изменений себестоимости
+// GET /api/admin/cost-changes?entityType=product&entityId=...&limit=50
+func GetCostChanges(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	changes, err := costService.GetChanges(
+		query.Get("entityType"),
+		query.Get("entityId"),
+		parseLimit(query.Get("limit"), 50),
+	)
+	if err != nil {
+		log.Printf("[COST] ❌ Error fetching cost changes: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch cost changes")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, changes)
+}
+
+// notifyMarginDrops уведомляет админов по WebSocket о падении маржи продуктов.
+// Уведомления включаются переменной MARGIN_ALERT_THRESHOLD (порог маржи в %).
+func notifyMarginDrops(result *models.CostRecalculationResult) {
+	thresholdStr := os.Getenv("MARGIN_ALERT_THRESHOLD")
+	if thresholdStr == "" || result == nil {
+		return
+	}
+	threshold, err := strconv.ParseFloat(thresholdStr, 64)
+	if err != nil {
+		log.Printf("⚠️ Invalid MARGIN_ALERT_THRESHOLD: %s", thresholdStr)
+		return
+	}
+
+	for _, p := range result.Products {
+		if p.NewMargin >= p.OldMargin || p.NewMargin >= threshold {
+			continue
+		}
+		BroadcastOrderNotification("margin_dropped", map[string]interface{}{
+			"productId":    p.ID,
+			"productName":  p.Name,
+			"price":        p.Price,
+			"oldCost":      p.OldCost,
+			"newCost":      p.NewCost,
+			"oldMargin":    p.OldMargin,
+			"newMargin":    p.NewMargin,
+			"ingredientId": result.IngredientID,
+		})
+	}
+}
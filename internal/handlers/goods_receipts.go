@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+var goodsReceiptService = services.NewGoodsReceiptService()
+
+// GetGoodsReceipts список приходных накладных
+// GET /api/admin/goods-receipts?status=posted&supplier=...
+func GetGoodsReceipts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := parseLimit(query.Get("limit"), 100)
+
+	receipts, err := goodsReceiptService.GetAll(query.Get("status"), query.Get("supplier"), limit)
+	if err != nil {
+		log.Printf("[RECEIPT] ❌ Error fetching receipts: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch goods receipts")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, receipts)
+}
+
+// GetGoodsReceipt накладная со строками
+// GET /api/admin/goods-receipts/{id}
+func GetGoodsReceipt(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receipt, err := goodsReceiptService.GetByID(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Goods receipt not found")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, receipt)
+}
+
+// CreateGoodsReceipt создание черновика накладной
+// POST /api/admin/goods-receipts
+func CreateGoodsReceipt(w http.ResponseWriter, r *http.Request) {
+	var req models.GoodsReceiptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	receipt, err := goodsReceiptService.Create(req, currentUserID(r))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, receipt)
+}
+
+// UpdateGoodsReceipt изменение черновика накладной
+// PUT /api/admin/goods-receipts/{id}
+func UpdateGoodsReceipt(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.GoodsReceiptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	receipt, err := goodsReceiptService.Update(vars["id"], req)
+	if err != nil {
+		respondReceiptError(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, receipt)
+}
+
+// DeleteGoodsReceipt удаление черновика накладной
+// DELETE /api/admin/goods-receipts/{id}
+func DeleteGoodsReceipt(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := goodsReceiptService.Delete(vars["id"]); err != nil {
+		respondReceiptError(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Goods receipt deleted"})
+}
+
+// PostGoodsReceipt проведение накладной: приход на склад
+// POST /api/admin/goods-receipts/{id}/post
+func PostGoodsReceipt(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receipt, priceChanges, err := goodsReceiptService.Post(vars["id"], currentUserID(r))
+	if err != nil {
+		log.Printf("[RECEIPT] ❌ Error posting receipt: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	notifyReceiptPosted(receipt, priceChanges)
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"receipt":      receipt,
+		"priceChanges": priceChanges,
+	})
+}
+
+// ReverseGoodsReceipt сторнирование проведённой накладной
+// POST /api/admin/goods-receipts/{id}/reverse
+func ReverseGoodsReceipt(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.ReverseReceiptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	receipt, err := goodsReceiptService.Reverse(vars["id"], req.Reason, currentUserID(r))
+	if err != nil {
+		log.Printf("[RECEIPT] ❌ Error reversing receipt: %v", err)
+		utils.RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, receipt)
+}
+
+// notifyReceiptPosted уведомляет админов о проведении накладной и о падении маржи
+// после пересчёта себестоимости по новым закупочным ценам
+func notifyReceiptPosted(receipt *models.GoodsReceipt, priceChanges []models.IngredientPriceChange) {
+	for _, change := range priceChanges {
+		notifyMarginDrops(change.Recalculation)
+	}
+
+	BroadcastOrderNotification("goods_receipt_posted", map[string]interface{}{
+		"receiptId":       receipt.ID,
+		"supplier":        receipt.Supplier,
+		"invoiceNumber":   receipt.InvoiceNumber,
+		"total":           receipt.Total,
+		"purchaseOrderId": receipt.PurchaseOrderID,
+	})
+}
+
+// respondReceiptError отвечает 409 на попытку изменить проведённую накладную
+func respondReceiptError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrReceiptNotEditable) {
+		utils.RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+}
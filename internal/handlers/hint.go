@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 
@@ -59,11 +60,34 @@ func HintHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Дополнения: что обычно заказывают вместе с найденным
+	addons := []models.Product{}
+	if len(products) > 0 {
+		ids := make([]string, 0, len(products))
+		for _, p := range products {
+			ids = append(ids, p.ID)
+		}
+		recommended, err := recommendationService.ForProducts(ids, 6)
+		if err != nil {
+			log.Printf("[HINT] ⚠️ Failed to fetch recommendations: %v", err)
+		} else {
+			addons = availableRecommendations(recommended, 3)
+		}
+	}
+	if len(addons) > 0 {
+		names := make([]string, 0, len(addons))
+		for _, p := range addons {
+			names = append(names, p.Name)
+		}
+		hint += "\nС этим часто заказывают: " + strings.Join(names, ", ")
+	}
+
 	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"status": "ok",
 		"data": map[string]interface{}{
 			"hint":              hint,
 			"suggested_products": products,
+			"suggested_addons":   addons,
 		},
 	})
 }
@@ -4,18 +4,26 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
 	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
 	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
-var ingredientRepo = &database.IngredientRepository{}
+var (
+	ingredientRepo   = &database.IngredientRepository{}
+	nutritionService = services.NewNutritionService()
+	stockService     = services.NewStockService()
+)
 
 // GetAllIngredients получение всех ингредиентов со складскими остатками
 func GetAllIngredients(w http.ResponseWriter, r *http.Request) {
@@ -64,6 +72,11 @@ func CreateIngredient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := normalizeAllergens(&req.NutritionInput); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// 🔍 Автоматическое определение единицы измерения по названию ингредиента
 	if autoUnit := detectDefaultUnit(req.Name); autoUnit != "" {
 		req.Unit = autoUnit
@@ -73,12 +86,13 @@ func CreateIngredient(w http.ResponseWriter, r *http.Request) {
 	ingredient := &models.Ingredient{
 		ID:        uuid.New().String(),
 		Name:      req.Name,
-		Unit:      req.Unit,
+		Unit:      units.Normalize(req.Unit),
 		CreatedAt: time.Now(),
 	}
+	req.NutritionInput.ApplyTo(ingredient)
 
 	// Генерируем уникальный номер партии
-	batchNumber := generateBatchNumber(req.Name)
+	batchNumber := services.GenerateBatchNumber(req.Name, time.Now())
 
 	// Создаём складскую запись
 	stockItem := &models.StockItem{
@@ -126,45 +140,59 @@ func CreateIngredient(w http.ResponseWriter, r *http.Request) {
 		val := req.PricePerUnit
 		stockItem.PricePerUnit = &val
 	}
+	applyReorderLevels(stockItem, req.MinStock, req.ReorderQuantity)
 
 	log.Printf("📦 StockItem before save (Batch: %s): %+v\n", batchNumber, stockItem)
 
-	if err := ingredientRepo.CreateIngredient(ingredient, stockItem); err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create ingredient")
-		return
-	}
-
-	// Создаем запись о начальном поступлении
-	// Используем bruttoWeight или nettoWeight как количество для движения
-	movementQuantity := req.Quantity
-	if movementQuantity == 0 && req.BruttoWeight > 0 {
-		movementQuantity = req.BruttoWeight
-	}
-	if movementQuantity == 0 && req.NettoWeight > 0 {
-		movementQuantity = req.NettoWeight
-	}
+	// Ингредиент, складская запись, движение и партия начального остатка — одной транзакцией:
+	// остаток не может появиться без партии
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ingredientRepo.CreateIngredientTx(tx, ingredient, stockItem); err != nil {
+			return fmt.Errorf("failed to create ingredient: %w", err)
+		}
 
-	if movementQuantity > 0 {
-		movement := &models.StockMovement{
-			ID:          uuid.New().String(),
-			StockItemID: stockItem.ID,
-			Type:        "addition",
-			Quantity:    movementQuantity,
-			PriceBrutto: stockItem.PriceBrutto,
-			PriceNetto:  stockItem.PriceNetto,
-			CreatedAt:   time.Now(),
+		// Создаем запись о начальном поступлении
+		// Используем bruttoWeight или nettoWeight как количество для движения
+		movementQuantity := req.Quantity
+		if movementQuantity == 0 && req.BruttoWeight > 0 {
+			movementQuantity = req.BruttoWeight
+		}
+		if movementQuantity == 0 && req.NettoWeight > 0 {
+			movementQuantity = req.NettoWeight
 		}
-		note := "Начальное поступление"
-		movement.Note = &note
 
-		if err := database.DB.Create(movement).Error; err != nil {
-			log.Printf("⚠️ Failed to create stock movement: %v", err)
-		} else {
+		if movementQuantity > 0 {
+			movement := &models.StockMovement{
+				ID:          uuid.New().String(),
+				StockItemID: stockItem.ID,
+				Type:        "addition",
+				Quantity:    movementQuantity,
+				PriceBrutto: stockItem.PriceBrutto,
+				PriceNetto:  stockItem.PriceNetto,
+				CreatedAt:   time.Now(),
+				UserID:      currentUserID(r),
+			}
+			note := "Начальное поступление"
+			movement.Note = &note
+
+			if err := tx.Create(movement).Error; err != nil {
+				return fmt.Errorf("failed to create stock movement: %w", err)
+			}
 			log.Printf("✅ Created stock movement: %s for %.2f units", movement.ID, movementQuantity)
 		}
-	}
 
-	stockItem.Ingredient = ingredient
+		// Начальный остаток становится первой партией со сроком годности
+		stockItem.Ingredient = ingredient
+		if err := stockService.AddOpeningBatch(tx, stockItem); err != nil {
+			return fmt.Errorf("failed to create opening batch: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("❌ CreateIngredient: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create ingredient")
+		return
+	}
 
 	utils.RespondWithJSON(w, http.StatusCreated, stockItem)
 }
@@ -187,12 +215,25 @@ func UpdateIngredient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Запоминаем старую цену и потери при зачистке для каскадного пересчёта себестоимости
+	oldPrice := 0.0
+	if stockItem.PricePerUnit != nil {
+		oldPrice = *stockItem.PricePerUnit
+	}
+	oldTrimLoss := services.TrimLossOf(stockItem)
+
+	if err := normalizeAllergens(&req.NutritionInput); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Обновляем ингредиент
+	req.NutritionInput.ApplyTo(stockItem.Ingredient)
 	if req.Name != "" {
 		stockItem.Ingredient.Name = req.Name
 	}
 	if req.Unit != "" {
-		stockItem.Ingredient.Unit = req.Unit
+		stockItem.Ingredient.Unit = units.Normalize(req.Unit)
 	} else if req.Name != "" {
 		// 🔍 Автоопределение единицы измерения при изменении названия
 		if autoUnit := detectDefaultUnit(req.Name); autoUnit != "" {
@@ -200,8 +241,14 @@ func UpdateIngredient(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Остаток меняется только через приёмку, списание и инвентаризацию,
+	// иначе он разойдётся с журналом движений и партиями
+	if req.Quantity != nil && math.Abs(*req.Quantity-stockItem.Quantity) > 1e-9 {
+		utils.RespondWithError(w, http.StatusBadRequest, "Stock quantity can only be changed by receipts, write-offs or inventory counts")
+		return
+	}
+
 	// Обновляем складские данные
-	stockItem.Quantity = req.Quantity
 	if req.BruttoWeight > 0 {
 		val := req.BruttoWeight
 		stockItem.BruttoWeight = &val
@@ -210,8 +257,12 @@ func UpdateIngredient(w http.ResponseWriter, r *http.Request) {
 		val := req.NettoWeight
 		stockItem.NettoWeight = &val
 	}
-	if req.WastePercentage >= 0 {
-		val := req.WastePercentage
+	if req.WastePercentage != nil {
+		if *req.WastePercentage < 0 || *req.WastePercentage >= 100 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Waste percentage must be between 0 and 100")
+			return
+		}
+		val := *req.WastePercentage
 		stockItem.WastePercentage = &val
 	}
 	if req.ExpiryDays > 0 {
@@ -238,18 +289,40 @@ func UpdateIngredient(w http.ResponseWriter, r *http.Request) {
 		val := req.PricePerUnit
 		stockItem.PricePerUnit = &val
 	}
+	applyReorderLevels(stockItem, req.MinStock, req.ReorderQuantity)
 	stockItem.UpdatedAt = time.Now()
 
-	// Сохраняем в БД
-	if err := ingredientRepo.UpdateIngredient(stockItem.Ingredient); err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update ingredient")
-		return
-	}
+	// Сохраняем ингредиент, остаток и каскадный пересчёт себестоимости в одной транзакции
+	var costs *models.CostRecalculationResult
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(stockItem.Ingredient).Error; err != nil {
+			return fmt.Errorf("failed to update ingredient: %w", err)
+		}
+		// Количество не сохраняем: его могли изменить движения после чтения карточки
+		if err := tx.Omit("quantity").Save(stockItem).Error; err != nil {
+			return fmt.Errorf("failed to update stock: %w", err)
+		}
 
-	if err := ingredientRepo.UpdateStockItem(stockItem); err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update stock")
+		// 💰 Цена изменилась — пересчитываем себестоимость полуфабрикатов и продуктов
+		// (пересчёт по цене учитывает и новые нормы отходов)
+		var err error
+		if req.PricePerUnit > 0 && req.PricePerUnit != oldPrice {
+			costs, err = costService.RecalculateForIngredientTx(tx, stockItem.IngredientID, oldPrice, req.PricePerUnit)
+			return err
+		}
+		// Изменились отходы или брутто/нетто — меняется брутто строк, заданных в нетто
+		if services.TrimLossOf(stockItem) != oldTrimLoss {
+			costs, err = costService.RecalculateIngredientUnits(tx, stockItem.IngredientID, "trim loss changed")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[STOCK] ❌ Error updating ingredient %s: %v", id, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update ingredient")
 		return
 	}
+	notifyMarginDrops(costs)
 
 	utils.RespondWithJSON(w, http.StatusOK, stockItem)
 }
@@ -267,84 +340,72 @@ func DeleteIngredient(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Ingredient deleted successfully"})
 }
 
-// GetStockMovements получение истории движений товара
+// GetStockMovements получение истории движений товара с остатком после каждого движения (?page=&limit=)
 func GetStockMovements(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	stockItemID := vars["id"]
+	query := r.URL.Query()
 
-	var movements []models.StockMovement
-	result := database.DB.
-		Where("\"stockItemId\" = ?", stockItemID).
-		Order("\"createdAt\" DESC").
-		Limit(20).
-		Find(&movements)
+	filter, err := parseStockJournalFilter(query)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.IngredientID = vars["id"]
 
-	if result.Error != nil {
+	page, err := stockJournalService.GetPage(filter, parseLimit(query.Get("page"), 1), parseLimit(query.Get("limit"), 20))
+	if err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch stock movements")
 		return
 	}
 
-	utils.RespondWithJSON(w, http.StatusOK, movements)
+	utils.RespondWithJSON(w, http.StatusOK, page.Entries)
 }
 
-// detectDefaultUnit возвращает дефолтную единицу измерения по названию ингредиента
-func detectDefaultUnit(name string) string {
-	nameLower := strings.ToLower(name)
-
-	defaultUnits := map[string]string{
-		"мук":    "kg",
-		"сахар":  "kg",
-		"рис":    "kg",
-		"круп":   "kg",
-		"соль":   "kg",
-		"вода":   "l",
-		"масло":  "ml",
-		"молок":  "l",
-		"яйц":    "pcs",
-		"лосос":  "kg",
-		"сёмг":   "kg",
-		"тунец":  "kg",
-		"креве":  "kg",
-		"угор":   "kg",
-		"сыр":    "kg",
-		"соус":   "ml",
-		"уксус":  "ml",
-		"нори":   "pcs",
-		"васаби": "kg",
-		"имбир":  "kg",
-		"авока":  "pcs",
-		"огуре":  "pcs",
-	}
-
-	for key, unit := range defaultUnits {
-		if strings.Contains(nameLower, key) {
-			log.Printf("⚙️ Автоматически установлена единица '%s' для ингредиента '%s'", unit, name)
-			return unit
+// normalizeAllergens приводит коды аллергенов к нижнему регистру и проверяет их допустимость
+func normalizeAllergens(input *models.NutritionInput) error {
+	if input.Allergens == nil {
+		return nil
+	}
+	normalized := []string{}
+	for _, a := range input.Allergens {
+		code := strings.ToLower(strings.TrimSpace(a))
+		if code == "" {
+			continue
+		}
+		if !models.KnownAllergens[code] {
+			return fmt.Errorf("Unknown allergen: %s", a)
+		}
+		if !models.StringList(normalized).Contains(code) {
+			normalized = append(normalized, code)
 		}
 	}
-
-	return "" // если не найдено — не меняем
+	input.Allergens = normalized
+	return nil
 }
 
-// generateBatchNumber генерирует уникальный номер партии
-func generateBatchNumber(ingredientName string) string {
-	now := time.Now()
-
-	// Берём первые 3 руны (символа) для поддержки UTF-8
-	runes := []rune(ingredientName)
-	prefix := ""
-	if len(runes) >= 3 {
-		prefix = string(runes[:3])
-	} else {
-		prefix = string(runes)
+// detectDefaultUnit возвращает дефолтную единицу измерения по названию ингредиента
+func detectDefaultUnit(name string) string {
+	unit := units.DetectDefault(name)
+	if unit != "" {
+		log.Printf("⚙️ Автоматически установлена единица '%s' для ингредиента '%s'", unit, name)
 	}
+	return unit // если не найдено — не меняем
+}
 
-	// Преобразуем в заглавные и убираем пробелы
-	prefix = strings.ToUpper(strings.ReplaceAll(prefix, " ", ""))
-
-	// Формат: КРЕ-20251006-020417 (3 буквы-дата-время)
-	return fmt.Sprintf("%s-%s-%s",
-		prefix,
-		now.Format("20060102"),
-		now.Format("150405"))
+// applyReorderLevels задаёт точку заказа и объём заказа; 0 отключает значение, nil оставляет как есть
+func applyReorderLevels(stockItem *models.StockItem, minStock, reorderQuantity *float64) {
+	if minStock != nil {
+		stockItem.MinStock = nil
+		if *minStock > 0 {
+			val := *minStock
+			stockItem.MinStock = &val
+		}
+	}
+	if reorderQuantity != nil {
+		stockItem.ReorderQuantity = nil
+		if *reorderQuantity > 0 {
+			val := *reorderQuantity
+			stockItem.ReorderQuantity = &val
+		}
+	}
 }
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+var inventoryCountService = services.NewInventoryCountService()
+
+// GetInventoryCounts список инвентаризаций
+// GET /api/admin/inventory-counts?status=open
+func GetInventoryCounts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := parseLimit(query.Get("limit"), 50)
+
+	counts, err := inventoryCountService.GetAll(query.Get("status"), limit)
+	if err != nil {
+		log.Printf("[INVENTORY] ❌ Error fetching inventory counts: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch inventory counts")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, counts)
+}
+
+// GetInventoryCount инвентаризация со строками и расхождениями в единицах и деньгах
+// GET /api/admin/inventory-counts/{id}
+func GetInventoryCount(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	count, err := inventoryCountService.GetByID(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Inventory count not found")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, count)
+}
+
+// GetInventoryCountEntries журнал вводов подсчёта по устройствам
+// GET /api/admin/inventory-counts/{id}/entries
+func GetInventoryCountEntries(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	entries, err := inventoryCountService.GetEntries(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch count entries")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, entries)
+}
+
+// StartInventoryCount начало инвентаризации: фиксация учётных остатков
+// POST /api/admin/inventory-counts
+func StartInventoryCount(w http.ResponseWriter, r *http.Request) {
+	var req models.StartInventoryCountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	count, err := inventoryCountService.Start(req, currentUserID(r))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, count)
+}
+
+// AddInventoryCountEntries ввод подсчитанных количеств (с любого устройства)
+// POST /api/admin/inventory-counts/{id}/entries
+func AddInventoryCountEntries(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.InventoryCountEntriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	count, err := inventoryCountService.AddEntries(vars["id"], req, currentUserID(r))
+	if err != nil {
+		respondInventoryCountError(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, count)
+}
+
+// ApproveInventoryCount утверждение инвентаризации с корректировкой остатков
+// POST /api/admin/inventory-counts/{id}/approve
+func ApproveInventoryCount(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.ApproveInventoryCountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	count, err := inventoryCountService.Approve(vars["id"], req.Reason, currentUserID(r))
+	if err != nil {
+		log.Printf("[INVENTORY] ❌ Error approving inventory count: %v", err)
+		respondInventoryCountError(w, err)
+		return
+	}
+
+	BroadcastOrderNotification("inventory_count_approved", map[string]interface{}{
+		"countId":      count.ID,
+		"name":         count.Name,
+		"shortageCost": count.ShortageCost,
+		"surplusCost":  count.SurplusCost,
+	})
+
+	utils.RespondWithJSON(w, http.StatusOK, count)
+}
+
+// CancelInventoryCount отмена инвентаризации без корректировок
+// POST /api/admin/inventory-counts/{id}/cancel
+func CancelInventoryCount(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := inventoryCountService.Cancel(vars["id"]); err != nil {
+		respondInventoryCountError(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Inventory count cancelled"})
+}
+
+// respondInventoryCountError отвечает 409 на попытку изменить закрытую инвентаризацию
+func respondInventoryCountError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrInventoryCountClosed) {
+		utils.RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+}
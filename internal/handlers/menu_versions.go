@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+var menuService = services.NewMenuService()
+
+// GetMenuDrafts список черновиков меню
+// GET /api/admin/menu/drafts
+func GetMenuDrafts(w http.ResponseWriter, r *http.Request) {
+	drafts, err := menuService.GetDrafts()
+	if err != nil {
+		log.Printf("[MENU] ❌ Error fetching drafts: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch menu drafts")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, drafts)
+}
+
+// CreateMenuDraft создание черновика из текущего меню
+// POST /api/admin/menu/drafts
+func CreateMenuDraft(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateMenuDraftRequest
+	// Тело запроса необязательно
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	draft, err := menuService.CreateDraft(req.Name, currentUserID(r))
+	if err != nil {
+		log.Printf("[MENU] ❌ Error creating draft: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create menu draft")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, draft)
+}
+
+// GetMenuDraft черновик меню с содержимым
+// GET /api/admin/menu/drafts/{id}
+func GetMenuDraft(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	draft, err := menuService.GetDraft(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Menu draft not found")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, draft)
+}
+
+// DiscardMenuDraft отмена черновика
+// DELETE /api/admin/menu/drafts/{id}
+func DiscardMenuDraft(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := menuService.Discard(vars["id"]); err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Menu draft discarded"})
+}
+
+// UpdateMenuDraftItem изменение продукта в черновике
+// PUT /api/admin/menu/drafts/{id}/items/{productId}
+func UpdateMenuDraftItem(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.UpdateMenuDraftItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	draft, err := menuService.UpdateItem(vars["id"], vars["productId"], req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrMenuDuplicateName) {
+			status = http.StatusConflict
+		}
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, draft)
+}
+
+// RenameMenuDraftCategory переименование категории в черновике
+// POST /api/admin/menu/drafts/{id}/rename-category
+func RenameMenuDraftCategory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.RenameMenuCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	draft, renamed, err := menuService.RenameCategory(vars["id"], req.From, req.To)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrMenuDuplicateName) {
+			status = http.StatusConflict
+		}
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"renamed": renamed,
+		"draft":   draft,
+	})
+}
+
+// CreateMenuPreviewLink подписанная ссылка на предпросмотр черновика
+// POST /api/admin/menu/drafts/{id}/preview-link
+func CreateMenuPreviewLink(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	link, err := menuService.PreviewLink(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, link)
+}
+
+// PreviewMenuDraft публичный предпросмотр черновика по подписанной ссылке
+// GET /api/menu/preview/{id}?expires=...&signature=...
+func PreviewMenuDraft(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	draftID := vars["id"]
+
+	query := r.URL.Query()
+	if err := menuService.VerifyPreview(draftID, query.Get("expires"), query.Get("signature")); err != nil {
+		utils.RespondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	products, err := menuService.PreviewProducts(draftID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	// Доступность, КБЖУ и цены — как в публичном меню
+	if err := availabilityService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to check product availability: %v", err)
+	}
+	if err := nutritionService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to calculate nutrition: %v", err)
+	}
+	if err := pricingService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to apply pricing rules: %v", err)
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, products)
+}
+
+// PublishMenuDraft атомарная публикация черновика
+// POST /api/admin/menu/drafts/{id}/publish
+func PublishMenuDraft(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	version, err := menuService.Publish(vars["id"], currentUserID(r))
+	if err != nil {
+		log.Printf("[MENU] ❌ Error publishing draft: %v", err)
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrMenuDraftOutdated) || errors.Is(err, services.ErrMenuDuplicateName) {
+			status = http.StatusConflict
+		}
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	BroadcastOrderNotification("menu_published", map[string]interface{}{
+		"versionId": version.ID,
+		"version":   version.Version,
+		"note":      version.Note,
+	})
+
+	utils.RespondWithJSON(w, http.StatusOK, version)
+}
+
+// GetMenuVersions список опубликованных версий меню
+// GET /api/admin/menu/versions
+func GetMenuVersions(w http.ResponseWriter, r *http.Request) {
+	versions, err := menuService.GetVersions()
+	if err != nil {
+		log.Printf("[MENU] ❌ Error fetching versions: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch menu versions")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, versions)
+}
+
+// GetMenuVersion версия меню с содержимым
+// GET /api/admin/menu/versions/{id}
+func GetMenuVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	version, err := menuService.GetVersion(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Menu version not found")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, version)
+}
+
+// RollbackMenuVersion откат меню к опубликованной версии
+// POST /api/admin/menu/versions/{id}/rollback
+func RollbackMenuVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	version, err := menuService.Rollback(vars["id"], currentUserID(r))
+	if err != nil {
+		log.Printf("[MENU] ❌ Error rolling back menu: %v", err)
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrMenuDuplicateName) {
+			status = http.StatusConflict
+		}
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	BroadcastOrderNotification("menu_published", map[string]interface{}{
+		"versionId": version.ID,
+		"version":   version.Version,
+		"note":      version.Note,
+	})
+
+	utils.RespondWithJSON(w, http.StatusOK, version)
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"math"
 	"net/http"
@@ -13,6 +15,7 @@ import (
 	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 // CreateOrderRequest структура запроса для создания заказа
@@ -22,9 +25,10 @@ type CreateOrderRequest struct {
 	Address string `json:"address"`
 	Comment string `json:"comment"`
 	Items   []struct {
-		ProductID string  `json:"productId"`
-		Quantity  int     `json:"quantity"`
-		Price     float64 `json:"price"`
+		ProductID  string                        `json:"productId"`
+		Quantity   int                           `json:"quantity"`
+		Price      float64                       `json:"price"`                // Игнорируется: цена рассчитывается на сервере
+		Selections []models.BundleSelectionInput `json:"selections,omitempty"` // Выбор в слотах сета
 	} `json:"items"`
 }
 
@@ -48,6 +52,71 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusBadRequest, "Order must contain at least one item")
 		return
 	}
+	for _, item := range req.Items {
+		if item.Quantity <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Item quantity must be positive")
+			return
+		}
+	}
+
+	// Проверяем доступность продуктов (стоп-лист и остатки)
+	productIDs := make([]string, 0, len(req.Items))
+	for _, item := range req.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	// Раскрываем состав сетов и проверяем выбор клиента в слотах
+	var bundles []models.Product
+	if err := database.DB.
+		Preload("BundleItems").
+		Preload("BundleSlots").
+		Where("id IN ? AND type = ?", productIDs, models.ProductTypeBundle).
+		Find(&bundles).Error; err != nil {
+		log.Printf("[ORDER] ❌ Error fetching bundles: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create order")
+		return
+	}
+	bundlesByID := make(map[string]*models.Product, len(bundles))
+	for i := range bundles {
+		bundlesByID[bundles[i].ID] = &bundles[i]
+	}
+	itemComponents := make([][]models.OrderItemComponent, len(req.Items))
+	for i, item := range req.Items {
+		bundle, ok := bundlesByID[item.ProductID]
+		if !ok {
+			continue
+		}
+		components, err := bundleService.ResolveComponents(bundle, item.Selections)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		itemComponents[i] = components
+		for _, sel := range item.Selections {
+			productIDs = append(productIDs, sel.ProductID)
+		}
+	}
+	availability, err := availabilityService.Check(productIDs)
+	if err != nil {
+		log.Printf("[ORDER] ❌ Error checking availability: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check product availability")
+		return
+	}
+	var unavailable []models.ProductAvailability
+	reported := map[string]bool{}
+	for _, id := range productIDs {
+		if a := availability[id]; !a.Available && !reported[id] {
+			reported[id] = true
+			unavailable = append(unavailable, a)
+		}
+	}
+	if len(unavailable) > 0 {
+		utils.RespondWithJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":       "Some products are unavailable",
+			"unavailable": unavailable,
+		})
+		return
+	}
 
 	// Получаем ID пользователя из контекста (если авторизован)
 	var userID *string
@@ -56,10 +125,41 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 	// Если userID == nil, это гостевой заказ
 
+	// Рассчитываем цены на сервере с учётом действующих ценовых правил
+	var products []models.Product
+	if err := database.DB.Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+		log.Printf("[ORDER] ❌ Error fetching products: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create order")
+		return
+	}
+	if err := pricingService.Apply(products); err != nil {
+		log.Printf("[ORDER] ❌ Error applying pricing rules: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to calculate prices")
+		return
+	}
+	productsByID := make(map[string]*models.Product, len(products))
+	for i := range products {
+		productsByID[products[i].ID] = &products[i]
+	}
+
+	// Расход ингредиентов для списания со склада (включая фиксированные компоненты сетов)
+	stockProductIDs := append([]string{}, productIDs...)
+	for _, components := range itemComponents {
+		for _, component := range components {
+			stockProductIDs = append(stockProductIDs, component.ProductID)
+		}
+	}
+	requirements, err := stockService.Requirements(stockProductIDs)
+	if err != nil {
+		log.Printf("[ORDER] ❌ Error calculating ingredient requirements: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create order")
+		return
+	}
+
 	// Рассчитываем общую сумму с округлением
 	var total float64
 	for _, item := range req.Items {
-		total += item.Price * float64(item.Quantity)
+		total += productsByID[item.ProductID].EffectivePrice * float64(item.Quantity)
 	}
 	// Округляем до 2 знаков после запятой
 	total = math.Round(total*100) / 100
@@ -95,21 +195,69 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Сохраняем позиции заказа
-	for _, item := range req.Items {
+	items := make([]models.OrderItem, 0, len(req.Items))
+	for i, item := range req.Items {
+		product := productsByID[item.ProductID]
 		orderItem := models.OrderItem{
-			ID:        uuid.New().String(),
-			OrderID:   orderID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     item.Price,
+			ID:            uuid.New().String(),
+			OrderID:       orderID,
+			ProductID:     item.ProductID,
+			Quantity:      item.Quantity,
+			Price:         product.EffectivePrice,
+			OriginalPrice: product.Price,
+		}
+		if product.AppliedRule != nil {
+			orderItem.PricingRuleID = &product.AppliedRule.ID
 		}
 
+		// Плановая себестоимость по действующим версиям рецептур продукта и его полуфабрикатов
+		// (у сетов рецептуры нет — по текущей себестоимости сета)
+		orderItem.RecipeCost = math.Round(product.Cost*float64(item.Quantity)*100) / 100
+		if product.Type != models.ProductTypeBundle {
+			version, sfVersionIDs, err := recipeVersionService.OrderItemVersions(tx, item.ProductID)
+			if err != nil {
+				tx.Rollback()
+				log.Printf("[ORDER] ❌ Error fetching recipe version: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create order")
+				return
+			}
+			orderItem.RecipeVersionID = &version.ID
+			orderItem.SemiFinishedVersionIDs = sfVersionIDs
+			orderItem.RecipeCost = math.Round(version.Cost*float64(item.Quantity)*100) / 100
+		}
+
+		// Списываем ингредиенты по партиям (FIFO/FEFO) и фиксируем фактическую себестоимость
+		needs := stockService.OrderItemNeeds(requirements, item.ProductID, itemComponents[i], item.Quantity)
+		cost, err := stockService.Consume(tx, needs, fmt.Sprintf("Заказ %s: %s × %d", orderID, product.Name, item.Quantity),
+			models.DocumentOrder, orderID, userID)
+		if err != nil {
+			tx.Rollback()
+			log.Printf("[ORDER] ❌ Error consuming stock: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update stock")
+			return
+		}
+		orderItem.Cost = cost
+
 		if err := tx.Create(&orderItem).Error; err != nil {
 			tx.Rollback()
 			log.Printf("[ORDER] ❌ Error creating order item: %v", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create order item")
 			return
 		}
+
+		// Фактический состав сета
+		for _, component := range itemComponents[i] {
+			component.ID = uuid.New().String()
+			component.OrderItemID = orderItem.ID
+			component.Quantity *= item.Quantity
+			if err := tx.Create(&component).Error; err != nil {
+				tx.Rollback()
+				log.Printf("[ORDER] ❌ Error creating bundle component: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create order item")
+				return
+			}
+		}
+		items = append(items, orderItem)
 	}
 
 	// Коммитим транзакцию
@@ -141,6 +289,7 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 		"orderId": orderID,
 		"total":   total,
 		"status":  order.Status,
+		"items":   items,
 	}
 
 	// Если пользователь авторизован, добавляем redirectTo
@@ -369,20 +518,21 @@ func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Обновляем статус и updatedAt
+	// Обновляем статус; отмена недоставленного заказа возвращает ингредиенты
+	// в те же партии в той же транзакции
 	now := time.Now()
-	if err := database.DB.Table("Order").
-		Where("id = ?", orderID).
-		Updates(map[string]interface{}{
-			"status":     req.Status,
-			"updated_at": now,
-		}).Error; err != nil {
+	previous, _, err := stockService.ChangeOrderStatus(orderID, req.Status, currentUserID(r))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondWithError(w, http.StatusNotFound, "Order not found")
+		return
+	}
+	if err != nil {
 		log.Printf("[ORDER] ❌ Error updating order status: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update order status")
 		return
 	}
 
-	log.Printf("[ORDER] 🟢 Updated status: ID=%s, Status=%s", orderID, req.Status)
+	log.Printf("[ORDER] 🟢 Updated status: ID=%s, Status=%s (was %s)", orderID, req.Status, previous)
 
 	// Отправляем WebSocket уведомление об обновлении статуса
 	BroadcastOrderNotification("order_updated", map[string]interface{}{
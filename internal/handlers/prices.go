@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+var priceService = services.NewPriceService()
+
+// GetProductPriceHistory история цен продукта со статистикой продаж по периодам
+// GET /api/admin/products/{id}/price-history
+func GetProductPriceHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	productID := vars["id"]
+
+	periods, err := priceService.GetHistory(productID)
+	if err != nil {
+		log.Printf("[PRICE] ❌ Error fetching price history: %v", err)
+		utils.RespondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+
+	scheduled, err := priceService.GetScheduled(productID)
+	if err != nil {
+		log.Printf("[PRICE] ❌ Error fetching scheduled prices: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch scheduled prices")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"productId": productID,
+		"periods":   periods,
+		"scheduled": scheduled,
+	})
+}
+
+// ScheduleProductPrice планирование новой цены на будущую дату
+// POST /api/admin/products/{id}/scheduled-prices
+func ScheduleProductPrice(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	productID := vars["id"]
+
+	var req models.SchedulePriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	change, err := priceService.Schedule(productID, req.Price, req.EffectiveAt, currentUserID(r))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, change)
+}
+
+// GetScheduledPrices список запланированных цен продукта
+// GET /api/admin/products/{id}/scheduled-prices
+func GetScheduledPrices(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	productID := vars["id"]
+
+	changes, err := priceService.GetScheduled(productID)
+	if err != nil {
+		log.Printf("[PRICE] ❌ Error fetching scheduled prices: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch scheduled prices")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, changes)
+}
+
+// CancelScheduledPrice отмена запланированной цены
+// DELETE /api/admin/scheduled-prices/{id}
+func CancelScheduledPrice(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if err := priceService.Cancel(id); err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Scheduled price cancelled"})
+}
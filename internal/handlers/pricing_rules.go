@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+// GetPricingRules список ценовых правил
+// GET /api/admin/pricing-rules
+func GetPricingRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := pricingService.GetAll()
+	if err != nil {
+		log.Printf("[PRICING] ❌ Error fetching rules: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch pricing rules")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, rules)
+}
+
+// CreatePricingRule создание ценового правила
+// POST /api/admin/pricing-rules
+func CreatePricingRule(w http.ResponseWriter, r *http.Request) {
+	var req models.PricingRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	rule, err := pricingService.Create(req)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, rule)
+}
+
+// UpdatePricingRule обновление ценового правила
+// PUT /api/admin/pricing-rules/{id}
+func UpdatePricingRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ruleID := vars["id"]
+
+	var req models.PricingRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	rule, err := pricingService.Update(ruleID, req)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, rule)
+}
+
+// DeletePricingRule удаление ценового правила
+// DELETE /api/admin/pricing-rules/{id}
+func DeletePricingRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ruleID := vars["id"]
+
+	if err := pricingService.Delete(ruleID); err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Pricing rule deleted"})
+}
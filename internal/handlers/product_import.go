@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+)
+
+// maxImportSize максимальный размер файла импорта (10 МБ)
+const maxImportSize = 10 << 20
+
+var productImportService = services.NewProductImportService()
+
+// ImportProducts массовый импорт продуктов с составом из CSV или JSON
+// POST /api/admin/products/import?format=csv|json&dryRun=true
+// Тело — файл целиком или multipart-форма с полем "file".
+func ImportProducts(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var body io.Reader = r.Body
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	contentType := r.Header.Get("Content-Type")
+
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "File is required")
+			return
+		}
+		defer file.Close()
+		body = file
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+		}
+	}
+	if format == "" {
+		format = "json"
+		if strings.Contains(contentType, "csv") {
+			format = "csv"
+		}
+	}
+
+	dryRun := r.URL.Query().Get("dryRun") == "true" || r.URL.Query().Get("dryRun") == "1"
+
+	var rows []models.ProductImportRow
+	var rowErrors map[int][]string
+	switch format {
+	case "csv":
+		var err error
+		rows, rowErrors, err = productImportService.ParseCSV(body)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	case "json":
+		if err := json.NewDecoder(body).Decode(&rows); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON: expected an array of products")
+			return
+		}
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Unsupported format (must be 'csv' or 'json')")
+		return
+	}
+
+	if len(rows) == 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "No products to import")
+		return
+	}
+
+	result, err := productImportService.Import(rows, rowErrors, dryRun, currentUserID(r))
+	if err != nil {
+		log.Printf("[IMPORT] ❌ Error importing products: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to import products")
+		return
+	}
+
+	// При ошибках в строках ничего не записывается
+	status := http.StatusOK
+	if !dryRun && result.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	utils.RespondWithJSON(w, status, result)
+}
+
+// ExportProducts экспорт продуктов с составом в CSV или JSON (формат совместим с импортом)
+// GET /api/admin/products/export?format=csv|json
+func ExportProducts(w http.ResponseWriter, r *http.Request) {
+	rows, err := productImportService.Export()
+	if err != nil {
+		log.Printf("[IMPORT] ❌ Error exporting products: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to export products")
+		return
+	}
+
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="products.csv"`)
+		if err := productImportService.WriteCSV(w, rows); err != nil {
+			log.Printf("[IMPORT] ❌ Error writing CSV: %v", err)
+		}
+	case "", "json":
+		utils.RespondWithJSON(w, http.StatusOK, rows)
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Unsupported format (must be 'csv' or 'json')")
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+var productionService = services.NewProductionService()
+
+// ProduceSemiFinished выпуск партии полуфабриката со списанием сырья
+// POST /api/admin/semi-finished/{id}/produce
+func ProduceSemiFinished(w http.ResponseWriter, r *http.Request) {
+	var req models.ProductionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	batch, err := productionService.Produce(mux.Vars(r)["id"], req, currentUserID(r))
+	if err != nil {
+		log.Printf("[PRODUCTION] ❌ Error producing semi-finished: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, batch)
+}
+
+// GetProductionBatches партии выпуска полуфабриката
+// GET /api/admin/semi-finished/{id}/batches?active=true&limit=50
+func GetProductionBatches(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	batches, err := productionService.GetBatches(mux.Vars(r)["id"], query.Get("active") == "true", parseLimit(query.Get("limit"), 50))
+	if err != nil {
+		log.Printf("[PRODUCTION] ❌ Error fetching production batches: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch production batches")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, batches)
+}
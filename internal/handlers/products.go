@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"math"
 	"net/http"
@@ -9,8 +10,15 @@ import (
 
 	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
 	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+var (
+	bundleService  = services.NewBundleService()
+	pricingService = services.NewPricingService()
 )
 
 // GetAllProducts получить все продукты (для админки)
@@ -22,6 +30,13 @@ func GetAllProducts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := availabilityService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to check product availability: %v", err)
+	}
+	if err := pricingService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to apply pricing rules: %v", err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(products)
 }
@@ -36,6 +51,31 @@ func GetPublicProducts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Помечаем продукты из стоп-листа и закончившиеся
+	if err := availabilityService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to check product availability: %v", err)
+	}
+
+	// КБЖУ и аллергены по составу
+	if err := nutritionService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to calculate nutrition: %v", err)
+	}
+
+	// Действующая цена по ценовым правилам (happy hour)
+	if err := pricingService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to apply pricing rules: %v", err)
+	}
+
+	// Средняя оценка по отзывам
+	if err := reviewService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to aggregate ratings: %v", err)
+	}
+
+	// Фильтры: ?excludeAllergens=gluten,sesame&dietary=vegan
+	products = filterProductsByDiet(products,
+		splitQueryList(r.URL.Query().Get("excludeAllergens")),
+		splitQueryList(r.URL.Query().Get("dietary")))
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(products)
 }
@@ -49,12 +89,29 @@ func GetProduct(w http.ResponseWriter, r *http.Request) {
 	if err := database.DB.
 		Preload("Ingredients").
 		Preload("SemiFinished").
+		Preload("BundleItems").
+		Preload("BundleSlots").
 		Where("id = ?", productID).
 		First(&product).Error; err != nil {
 		http.Error(w, "Product not found", http.StatusNotFound)
 		return
 	}
 
+	products := []models.Product{product}
+	if err := availabilityService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to check product availability: %v", err)
+	}
+	if err := nutritionService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to calculate nutrition: %v", err)
+	}
+	if err := pricingService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to apply pricing rules: %v", err)
+	}
+	if err := reviewService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to aggregate ratings: %v", err)
+	}
+	product = products[0]
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(product)
 }
@@ -81,6 +138,12 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	productType, ok := normalizeProductType(req.Type)
+	if !ok {
+		http.Error(w, "Invalid product type (must be 'single' or 'bundle')", http.StatusBadRequest)
+		return
+	}
+
 	// Нормализация цены
 	req.Price = normalizeProductFloat(req.Price, 2)
 
@@ -116,8 +179,30 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 		ImageURL:    req.ImageURL,
 		Weight:      req.Weight,
 		Category:    req.Category,
+		Type:        productType,
 		IsVisible:   req.IsVisible,
 	}
+	if req.CookingInstructions != nil {
+		product.CookingInstructions = optionalText(*req.CookingInstructions)
+	}
+	if req.StorageConditions != nil {
+		product.StorageConditions = optionalText(*req.StorageConditions)
+	}
+
+	// Единицы строк рецептуры и их стоимость
+	if err := unitService.PriceProductLines(req.Ingredients, req.SemiFinished); err != nil {
+		http.Error(w, "Invalid recipe: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Себестоимость по рецептуре
+	for _, ing := range req.Ingredients {
+		product.Cost += ing.TotalPrice
+	}
+	for _, sf := range req.SemiFinished {
+		product.Cost += sf.TotalCost
+	}
+	product.Cost = normalizeProductFloat(product.Cost, 2)
 
 	// Начинаем транзакцию
 	tx := database.DB.Begin()
@@ -151,6 +236,8 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 			Unit:           ing.Unit,
 			PricePerUnit:   normalizeProductFloat(ing.PricePerUnit, 2),
 			TotalPrice:     normalizeProductFloat(ing.TotalPrice, 2),
+			QuantityType:   ing.QuantityType,
+			CookingLoss:    ing.CookingLoss,
 		}
 		if err := tx.Create(&ingredient).Error; err != nil {
 			tx.Rollback()
@@ -180,6 +267,29 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Начальная цена в истории цен
+	if err := priceService.RecordChange(tx, productID, 0, product.Price, models.PriceSourceInitial, currentUserID(r)); err != nil {
+		tx.Rollback()
+		log.Printf("Error recording price history: %v", err)
+		http.Error(w, "Failed to create product", http.StatusInternalServerError)
+		return
+	}
+
+	// Состав сета
+	if productType == models.ProductTypeBundle {
+		if err := bundleService.SaveComposition(tx, productID, req.BundleItems, req.BundleSlots); err != nil {
+			tx.Rollback()
+			log.Printf("Error saving bundle composition: %v", err)
+			http.Error(w, "Invalid bundle composition: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if _, err := recipeVersionService.Record(tx, models.CostEntityProduct, productID, "Создание", currentUserID(r)); err != nil {
+		tx.Rollback()
+		log.Printf("Error saving recipe version: %v", err)
+		http.Error(w, "Failed to save recipe version", http.StatusInternalServerError)
+		return
+	}
+
 	// Коммитим транзакцию
 	if err := tx.Commit().Error; err != nil {
 		log.Printf("Error committing transaction: %v", err)
@@ -187,6 +297,11 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Перечитываем сет, чтобы вернуть состав и рассчитанную себестоимость
+	if productType == models.ProductTypeBundle {
+		database.DB.Preload("BundleItems").Preload("BundleSlots").First(&product, "id = ?", productID)
+	}
+
 	log.Printf("✅ Product created: %s (%.2f ₽, %s) with %d ingredients and %d semi-finished",
 		product.Name, product.Price, product.Category, len(req.Ingredients), len(req.SemiFinished))
 
@@ -230,6 +345,8 @@ func UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	oldPrice := product.Price
+
 	// Обновление полей
 	product.Name = req.Name
 	product.Description = req.Description
@@ -238,24 +355,106 @@ func UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	product.Weight = req.Weight
 	product.Category = req.Category
 
+	// Технологическая карта обновляется, только если поля переданы
+	if req.CookingInstructions != nil {
+		product.CookingInstructions = optionalText(*req.CookingInstructions)
+	}
+	if req.StorageConditions != nil {
+		product.StorageConditions = optionalText(*req.StorageConditions)
+	}
+
 	// Обновление isVisible, если передано
 	if req.IsVisible != nil {
 		product.IsVisible = *req.IsVisible
 	}
 
-	if err := database.DB.Save(&product).Error; err != nil {
+	// Смена типа продукта, если передан
+	oldType := product.Type
+	if req.Type != "" {
+		productType, ok := normalizeProductType(req.Type)
+		if !ok {
+			http.Error(w, "Invalid product type (must be 'single' or 'bundle')", http.StatusBadRequest)
+			return
+		}
+		product.Type = productType
+	}
+
+	// Продукт становится сетом: нужен состав, и сам он не должен входить в другие сеты
+	if product.Type == models.ProductTypeBundle && oldType != models.ProductTypeBundle {
+		if len(req.BundleItems) == 0 && len(req.BundleSlots) == 0 {
+			http.Error(w, "Bundle must contain at least one item or slot", http.StatusBadRequest)
+			return
+		}
+		if err := bundleService.EnsureNotComponent(database.DB, productID); err != nil {
+			http.Error(w, "Invalid bundle composition: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		log.Printf("Error starting transaction: %v", tx.Error)
+		http.Error(w, "Failed to update product", http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Save(&product).Error; err != nil {
+		tx.Rollback()
 		log.Printf("❌ Failed to update product: %v", err)
 		http.Error(w, "Failed to update product", http.StatusInternalServerError)
 		return
 	}
 
+	// Записываем изменение цены в историю
+	if product.Price != oldPrice {
+		if err := priceService.RecordChange(tx, productID, oldPrice, product.Price, models.PriceSourceManual, currentUserID(r)); err != nil {
+			tx.Rollback()
+			log.Printf("Error recording price history: %v", err)
+			http.Error(w, "Failed to update product", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if product.Type == models.ProductTypeBundle && (req.BundleItems != nil || req.BundleSlots != nil) {
+		// Новый состав сета
+		if err := bundleService.SaveComposition(tx, productID, req.BundleItems, req.BundleSlots); err != nil {
+			tx.Rollback()
+			log.Printf("Error saving bundle composition: %v", err)
+			http.Error(w, "Invalid bundle composition: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if product.Type != models.ProductTypeBundle {
+		// Продукт больше не сет — удаляем состав
+		if err := tx.Where("bundle_id = ?", productID).Delete(&models.BundleItem{}).Error; err != nil {
+			tx.Rollback()
+			http.Error(w, "Failed to update product", http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Where("bundle_id = ?", productID).Delete(&models.BundleSlot{}).Error; err != nil {
+			tx.Rollback()
+			http.Error(w, "Failed to update product", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		http.Error(w, "Failed to save product", http.StatusInternalServerError)
+		return
+	}
+
 	log.Printf("✅ Product updated: %s (%.2f ₽, %s)", product.Name, product.Price, product.Category)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(product)
 }
 
-// DeleteProduct удалить продукт
+// DeleteProduct мягкое удаление продукта (история заказов сохраняется, продукт можно восстановить)
 func DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	productID := vars["id"]
@@ -267,16 +466,225 @@ func DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Удаление
+	// Нельзя удалить продукт, входящий в состав сетов
+	var usedInBundles int64
+	database.DB.Model(&models.BundleItem{}).Where("product_id = ?", productID).Count(&usedInBundles)
+	if usedInBundles > 0 {
+		http.Error(w, "Product is used in bundles", http.StatusConflict)
+		return
+	}
+
+	// Мягкое удаление (заполняет deletedAt)
 	if err := database.DB.Delete(&product).Error; err != nil {
 		http.Error(w, "Failed to delete product", http.StatusInternalServerError)
 		return
 	}
 
+	log.Printf("🗑️ Product soft-deleted: %s (%s)", product.Name, productID)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Product deleted successfully"})
 }
 
+// GetDeletedProducts список удалённых продуктов (корзина)
+// GET /api/admin/products/deleted
+func GetDeletedProducts(w http.ResponseWriter, r *http.Request) {
+	var products []models.Product
+
+	if err := database.DB.Unscoped().
+		Where(`"deletedAt" IS NOT NULL`).
+		Order(`"deletedAt" DESC`).
+		Find(&products).Error; err != nil {
+		http.Error(w, "Failed to fetch deleted products", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(products)
+}
+
+// RestoreProduct восстановление удалённого продукта
+// POST /api/admin/products/{id}/restore
+func RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	productID := vars["id"]
+
+	var product models.Product
+	if err := database.DB.Unscoped().
+		Where(`id = ? AND "deletedAt" IS NOT NULL`, productID).
+		First(&product).Error; err != nil {
+		http.Error(w, "Deleted product not found", http.StatusNotFound)
+		return
+	}
+
+	// Пока продукт был удалён, могли создать другой с тем же названием и категорией
+	var exists int64
+	database.DB.Model(&models.Product{}).
+		Where("LOWER(name) = LOWER(?) AND LOWER(category) = LOWER(?)", product.Name, product.Category).
+		Count(&exists)
+	if exists > 0 {
+		http.Error(w, "Product with this name already exists in this category", http.StatusConflict)
+		return
+	}
+
+	if err := database.DB.Unscoped().Model(&product).Update("deletedAt", nil).Error; err != nil {
+		log.Printf("❌ Failed to restore product: %v", err)
+		http.Error(w, "Failed to restore product", http.StatusInternalServerError)
+		return
+	}
+	product.DeletedAt = gorm.DeletedAt{}
+
+	log.Printf("♻️ Product restored: %s (%s)", product.Name, productID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+// PurgeProduct окончательное удаление продукта из корзины.
+// Запрещено, пока на продукт ссылаются позиции заказов или состав сетов.
+// DELETE /api/admin/products/{id}/permanent
+func PurgeProduct(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	productID := vars["id"]
+
+	var product models.Product
+	if err := database.DB.Unscoped().Where("id = ?", productID).First(&product).Error; err != nil {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	if !product.DeletedAt.Valid {
+		http.Error(w, "Product must be deleted before permanent removal", http.StatusConflict)
+		return
+	}
+
+	// Ссылки из истории заказов (в том числе из состава сетов)
+	var orderItems, components int64
+	database.DB.Model(&models.OrderItem{}).Where("product_id = ?", productID).Count(&orderItems)
+	database.DB.Model(&models.OrderItemComponent{}).Where("product_id = ?", productID).Count(&components)
+	if orderItems+components > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":      "Product is referenced by orders",
+			"orderItems": orderItems + components,
+		})
+		return
+	}
+
+	// Продукт входит в состав сетов (фиксированным компонентом или вариантом слота)
+	var bundleItems, bundleSlots int64
+	database.DB.Model(&models.BundleItem{}).Where("product_id = ?", productID).Count(&bundleItems)
+	database.DB.Model(&models.BundleSlot{}).Where("product_ids @> ?::jsonb", fmt.Sprintf("[%q]", productID)).Count(&bundleSlots)
+	if bundleItems+bundleSlots > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":       "Product is a component of bundles",
+			"bundleItems": bundleItems,
+			"bundleSlots": bundleSlots,
+		})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{
+			&models.ProductIngredient{},
+			&models.ProductSemiFinished{},
+			&models.ProductPriceHistory{},
+			&models.ScheduledPriceChange{},
+			&models.ProductReview{},
+		} {
+			if err := tx.Where("product_id = ?", productID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("bundle_id = ?", productID).Delete(&models.BundleItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("bundle_id = ?", productID).Delete(&models.BundleSlot{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ? OR recommended_id = ?", productID, productID).Delete(&models.ProductRecommendation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("entity_type = ? AND entity_id = ?", models.CostEntityProduct, productID).Delete(&models.RecipeVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&product).Error
+	})
+	if err != nil {
+		log.Printf("❌ Failed to purge product: %v", err)
+		http.Error(w, "Failed to permanently delete product", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("✅ Product permanently deleted: %s (%s)", product.Name, productID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Product permanently deleted",
+		"id":      productID,
+	})
+}
+
+// filterProductsByDiet оставляет продукты без указанных аллергенов и со всеми указанными диетическими метками
+func filterProductsByDiet(products []models.Product, excludeAllergens, dietary []string) []models.Product {
+	if len(excludeAllergens) == 0 && len(dietary) == 0 {
+		return products
+	}
+
+	filtered := make([]models.Product, 0, len(products))
+	for _, p := range products {
+		if p.Nutrition == nil {
+			continue
+		}
+		allergens := models.StringList(p.Nutrition.Allergens)
+		tags := models.StringList(p.Nutrition.DietaryTags)
+
+		matches := true
+		for _, a := range excludeAllergens {
+			// Без полных данных об аллергенах не можем гарантировать их отсутствие
+			if allergens.Contains(a) || !p.Nutrition.AllergensKnown {
+				matches = false
+				break
+			}
+		}
+		for _, t := range dietary {
+			if !tags.Contains(t) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// splitQueryList разбирает список значений через запятую из query-параметра
+func splitQueryList(value string) []string {
+	var list []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.ToLower(strings.TrimSpace(part)); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// normalizeProductType проверяет тип продукта ("single" по умолчанию)
+func normalizeProductType(productType string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(productType)) {
+	case "", models.ProductTypeSingle:
+		return models.ProductTypeSingle, true
+	case models.ProductTypeBundle:
+		return models.ProductTypeBundle, true
+	default:
+		return "", false
+	}
+}
+
 // normalizeProductFloat округляет число до указанного количества знаков
 func normalizeProductFloat(value float64, decimals int) float64 {
 	mult := math.Pow(10, float64(decimals))
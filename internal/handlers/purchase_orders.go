@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+var purchaseOrderService = services.NewPurchaseOrderService()
+
+// GetPurchaseOrders список заказов поставщикам
+// GET /api/admin/purchase-orders?status=sent&supplierId=...
+func GetPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	orders, err := purchaseOrderService.GetAll(query.Get("status"), query.Get("supplierId"), parseLimit(query.Get("limit"), 100))
+	if err != nil {
+		log.Printf("[PURCHASE] ❌ Error fetching purchase orders: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch purchase orders")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, orders)
+}
+
+// GetPurchaseOrder заказ поставщику со строками и оприходованными количествами
+// GET /api/admin/purchase-orders/{id}
+func GetPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	order, err := purchaseOrderService.GetByID(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Purchase order not found")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, order)
+}
+
+// CreatePurchaseOrder создание черновика заказа поставщику
+// POST /api/admin/purchase-orders
+func CreatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	var req models.PurchaseOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	order, err := purchaseOrderService.Create(req, currentUserID(r))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, order)
+}
+
+// GeneratePurchaseOrders формирование черновиков заказов по низким остаткам
+// POST /api/admin/purchase-orders/generate
+func GeneratePurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	var req models.GeneratePurchaseOrdersRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+	}
+
+	result, err := purchaseOrderService.GenerateFromLowStock(req, currentUserID(r))
+	if err != nil {
+		log.Printf("[PURCHASE] ❌ Error generating purchase orders: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
+
+// UpdatePurchaseOrder изменение черновика заказа
+// PUT /api/admin/purchase-orders/{id}
+func UpdatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.PurchaseOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	order, err := purchaseOrderService.Update(vars["id"], req)
+	if err != nil {
+		respondPurchaseOrderError(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, order)
+}
+
+// DeletePurchaseOrder удаление черновика заказа
+// DELETE /api/admin/purchase-orders/{id}
+func DeletePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := purchaseOrderService.Delete(vars["id"]); err != nil {
+		respondPurchaseOrderError(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Purchase order deleted"})
+}
+
+// SendPurchaseOrder отправка заказа поставщику
+// POST /api/admin/purchase-orders/{id}/send
+func SendPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	order, err := purchaseOrderService.Send(vars["id"])
+	if err != nil {
+		respondPurchaseOrderError(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, order)
+}
+
+// CancelPurchaseOrder отмена заказа, по которому ничего не оприходовано
+// POST /api/admin/purchase-orders/{id}/cancel
+func CancelPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := purchaseOrderService.Cancel(vars["id"]); err != nil {
+		utils.RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Purchase order cancelled"})
+}
+
+// ReceivePurchaseOrder приёмка поставки по заказу: создаёт и проводит приходную накладную
+// POST /api/admin/purchase-orders/{id}/receive
+func ReceivePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.ReceivePurchaseOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	receipt, priceChanges, err := purchaseOrderService.Receive(vars["id"], req, currentUserID(r))
+	if err != nil {
+		log.Printf("[PURCHASE] ❌ Error receiving purchase order: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if receipt.Status == models.ReceiptPosted {
+		notifyReceiptPosted(receipt, priceChanges)
+	}
+
+	order, err := purchaseOrderService.GetByID(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch purchase order")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"purchaseOrder": order,
+		"receipt":       receipt,
+		"priceChanges":  priceChanges,
+	})
+}
+
+// respondPurchaseOrderError отвечает 409 на попытку изменить отправленный заказ
+func respondPurchaseOrderError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrPurchaseOrderNotEditable) {
+		utils.RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// GetRecipeVersions версии рецептуры продукта или полуфабриката
+// GET /api/admin/recipes/{entityType}/{id}/versions (entityType: product | semi_finished)
+func GetRecipeVersions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	versions, err := recipeVersionService.GetVersions(vars["entityType"], vars["id"])
+	if err != nil {
+		log.Printf("[RECIPE] ❌ Error fetching recipe versions: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, versions)
+}
+
+// GetRecipeVersion версия рецептуры с составом
+// GET /api/admin/recipes/{entityType}/{id}/versions/{version}
+func GetRecipeVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	number, err := strconv.Atoi(vars["version"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid version number")
+		return
+	}
+
+	version, err := recipeVersionService.GetVersion(vars["entityType"], vars["id"], number)
+	if err != nil {
+		respondRecipeVersionError(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, version)
+}
+
+// DiffRecipeVersions различия двух версий рецептуры
+// GET /api/admin/recipes/{entityType}/{id}/versions/diff?from=1&to=3
+func DiffRecipeVersions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+	from, errFrom := strconv.Atoi(query.Get("from"))
+	to, errTo := strconv.Atoi(query.Get("to"))
+	if errFrom != nil || errTo != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Parameters 'from' and 'to' must be version numbers")
+		return
+	}
+
+	diff, err := recipeVersionService.Diff(vars["entityType"], vars["id"], from, to)
+	if err != nil {
+		respondRecipeVersionError(w, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, diff)
+}
+
+// RestoreRecipeVersion восстановление рецептуры из версии (сохраняется как новая версия)
+// POST /api/admin/recipes/{entityType}/{id}/versions/{version}/restore
+func RestoreRecipeVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	number, err := strconv.Atoi(vars["version"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid version number")
+		return
+	}
+
+	version, costs, err := recipeVersionService.Restore(vars["entityType"], vars["id"], number, currentUserID(r))
+	if err != nil {
+		if errors.Is(err, services.ErrRecipeCycle) {
+			utils.RespondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		respondRecipeVersionError(w, err)
+		return
+	}
+	notifyMarginDrops(costs)
+
+	utils.RespondWithJSON(w, http.StatusOK, version)
+}
+
+// GetOrderCosting себестоимость заказа: план по версиям рецептур на момент заказа и факт по партиям
+// GET /api/admin/orders/{id}/costing
+func GetOrderCosting(w http.ResponseWriter, r *http.Request) {
+	costing, err := recipeVersionService.OrderCosting(mux.Vars(r)["id"])
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Order not found")
+			return
+		}
+		log.Printf("[RECIPE] ❌ Error calculating order costing: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to calculate order costing")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, costing)
+}
+
+// respondRecipeVersionError 404 для отсутствующей версии, 400 для остальных ошибок
+func respondRecipeVersionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	log.Printf("[RECIPE] ❌ Recipe version error: %v", err)
+	utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+var recommendationService = services.NewRecommendationService()
+
+// GetProductRecommendations "хорошо сочетается с": продукты, которые часто заказывают вместе
+// GET /api/products/{id}/recommendations?limit=5
+func GetProductRecommendations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	limit := parseLimit(r.URL.Query().Get("limit"), 5)
+
+	// Берём с запасом: недоступные продукты отфильтруются
+	products, err := recommendationService.ForProduct(vars["id"], limit*2)
+	if err != nil {
+		log.Printf("[RECOMMEND] ❌ Error fetching recommendations: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch recommendations")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, availableRecommendations(products, limit))
+}
+
+// availableRecommendations оставляет доступные к заказу продукты с действующими ценами
+func availableRecommendations(products []models.Product, limit int) []models.Product {
+	if err := availabilityService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to check product availability: %v", err)
+	}
+	if err := pricingService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to apply pricing rules: %v", err)
+	}
+
+	available := make([]models.Product, 0, limit)
+	for _, p := range products {
+		if !p.IsAvailable {
+			continue
+		}
+		available = append(available, p)
+		if len(available) == limit {
+			break
+		}
+	}
+	return available
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+var reviewService = services.NewReviewService()
+
+// GetProductReviews одобренные отзывы о продукте
+// GET /api/products/{id}/reviews
+func GetProductReviews(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	limit := parseLimit(r.URL.Query().Get("limit"), 50)
+
+	reviews, err := reviewService.GetApproved(vars["id"], limit)
+	if err != nil {
+		log.Printf("[REVIEW] ❌ Error fetching reviews: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch reviews")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, reviews)
+}
+
+// CreateProductReview отзыв пользователя о продукте из доставленного заказа
+// POST /api/products/{id}/reviews
+func CreateProductReview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	userID := currentUserID(r)
+	if userID == nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.CreateReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	review, err := reviewService.Create(*userID, vars["id"], req)
+	if err != nil {
+		if errors.Is(err, services.ErrReviewNotAllowed) {
+			utils.RespondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	BroadcastOrderNotification("review_created", map[string]interface{}{
+		"reviewId":  review.ID,
+		"productId": review.ProductID,
+		"rating":    review.Rating,
+	})
+
+	utils.RespondWithJSON(w, http.StatusCreated, review)
+}
+
+// GetReviewsForModeration отзывы для модерации
+// GET /api/admin/reviews?status=pending
+func GetReviewsForModeration(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	limit := parseLimit(r.URL.Query().Get("limit"), 100)
+
+	reviews, err := reviewService.GetForModeration(status, limit)
+	if err != nil {
+		log.Printf("[REVIEW] ❌ Error fetching reviews: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch reviews")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, reviews)
+}
+
+// ModerateReview одобрение или отклонение отзыва
+// PUT /api/admin/reviews/{id}/moderate
+func ModerateReview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.ModerateReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	review, err := reviewService.Moderate(vars["id"], req, currentUserID(r))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, review)
+}
+
+// DeleteReview удаление отзыва
+// DELETE /api/admin/reviews/{id}
+func DeleteReview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := reviewService.Delete(vars["id"]); err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Review deleted"})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+)
+
+var searchService = services.NewSearchService()
+
+// SearchProducts полнотекстовый и нечёткий поиск по видимым продуктам
+// GET /api/products/search?q=филадельфия&limit=20
+func SearchProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	limit := parseLimit(r.URL.Query().Get("limit"), 20)
+
+	results, err := searchService.SearchProducts(query, limit)
+	if err != nil {
+		log.Printf("[SEARCH] ❌ Error searching products: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to search products")
+		return
+	}
+
+	// Доступность и действующие цены, как в публичном списке
+	products := make([]models.Product, len(results))
+	for i := range results {
+		products[i] = results[i].Product
+	}
+	if err := availabilityService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to check product availability: %v", err)
+	}
+	if err := pricingService.Apply(products); err != nil {
+		log.Printf("⚠️ Failed to apply pricing rules: %v", err)
+	}
+	for i := range results {
+		results[i].Product = products[i]
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, results)
+}
@@ -2,46 +2,42 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"math"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
 	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
 	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
+var semiFinishedService = services.NewSemiFinishedService()
+var recipeVersionService = services.NewRecipeVersionService()
+
 // normalizeFloat округляет число до указанного количества знаков после запятой
 func normalizeFloat(value float64, decimals int) float64 {
 	mult := math.Pow(10, float64(decimals))
 	return math.Round(value*mult) / mult
 }
 
-// convertToBaseUnit конвертирует значение в базовую единицу измерения
-// Граммы → кг, Миллилитры → литры
-func convertToBaseUnit(value float64, unit string) float64 {
-	switch strings.ToLower(unit) {
-	case "g":
-		return value / 1000
-	case "ml":
-		return value / 1000
-	default:
-		return value
-	}
-}
-
-// calculateCostPerUnit рассчитывает себестоимость за единицу полуфабриката
-// Учитывает конвертацию единиц измерения и защищает от деления на ноль
-func calculateCostPerUnit(ingredients []models.SemiFinishedIngredientInput, outputQty float64) float64 {
+// calculateCostPerUnit рассчитывает себестоимость за единицу полуфабриката по стоимости строк
+// и вложенных полуфабрикатов (см. UnitService.PriceSemiFinishedLines, SemiFinishedService.PriceComponents)
+// и защищает от деления на ноль
+func calculateCostPerUnit(ingredients []models.SemiFinishedIngredientInput, components []models.SemiFinishedComponentInput, outputQty float64) float64 {
 	var totalCost float64
 	for _, ing := range ingredients {
-		qty := convertToBaseUnit(ing.Quantity, ing.Unit)
-		totalCost += qty * ing.PricePerUnit
+		totalCost += ing.TotalPrice
+	}
+	for _, c := range components {
+		totalCost += c.TotalCost
 	}
 	if outputQty == 0 {
 		return 0
@@ -103,8 +99,11 @@ func CreateSemiFinished(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusBadRequest, "Output unit is required")
 		return
 	}
-	if len(req.Ingredients) == 0 {
-		utils.RespondWithError(w, http.StatusBadRequest, "At least one ingredient is required")
+	if req.ShelfLifeHours != nil && *req.ShelfLifeHours <= 0 {
+		req.ShelfLifeHours = nil
+	}
+	if len(req.Ingredients) == 0 && len(req.Components) == 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "At least one ingredient or semi-finished component is required")
 		return
 	}
 
@@ -150,12 +149,25 @@ func CreateSemiFinished(w http.ResponseWriter, r *http.Request) {
 	for i := range req.Ingredients {
 		req.Ingredients[i].Quantity = normalizeFloat(req.Ingredients[i].Quantity, 3)
 		req.Ingredients[i].PricePerUnit = normalizeFloat(req.Ingredients[i].PricePerUnit, 2)
-		req.Ingredients[i].TotalPrice = normalizeFloat(req.Ingredients[i].TotalPrice, 2)
 	}
 	req.OutputQuantity = normalizeFloat(req.OutputQuantity, 3)
+	req.OutputUnit = units.Normalize(req.OutputUnit)
+
+	// Единицы строк должны переводиться в единицу склада ингредиента
+	if err := unitService.PriceSemiFinishedLines(req.Ingredients); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for i := range req.Components {
+		req.Components[i].Quantity = normalizeFloat(req.Components[i].Quantity, 3)
+	}
+	if err := semiFinishedService.PriceComponents("", req.Components); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Рассчитываем себестоимость за единицу с учётом конвертации единиц
-	costPerUnit := calculateCostPerUnit(req.Ingredients, req.OutputQuantity)
+	costPerUnit := calculateCostPerUnit(req.Ingredients, req.Components, req.OutputQuantity)
 	totalCost := normalizeFloat(costPerUnit*req.OutputQuantity, 2)
 
 	// Создаём полуфабрикат
@@ -176,11 +188,14 @@ func CreateSemiFinished(w http.ResponseWriter, r *http.Request) {
 		CostPerUnit:    costPerUnit,
 		TotalCost:      totalCost,
 		Category:       req.Category,
+		ShelfLifeHours: req.ShelfLifeHours,
 		IsVisible:      true,
 		IsArchived:     false,
 		CreatedAt:      now,
 		UpdatedAt:      now,
 	}
+	sf.CookingInstructions = optionalText(req.CookingInstructions)
+	sf.StorageConditions = optionalText(req.StorageConditions)
 
 	// Начинаем транзакцию
 	tx := database.DB.Begin()
@@ -217,6 +232,8 @@ func CreateSemiFinished(w http.ResponseWriter, r *http.Request) {
 			Unit:           ing.Unit,
 			PricePerUnit:   ing.PricePerUnit,
 			TotalPrice:     ing.TotalPrice,
+			QuantityType:   ing.QuantityType,
+			CookingLoss:    ing.CookingLoss,
 		}
 		if err := tx.Create(&ingredient).Error; err != nil {
 			tx.Rollback()
@@ -226,6 +243,22 @@ func CreateSemiFinished(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Добавляем вложенные полуфабрикаты
+	if err := createSemiFinishedComponents(tx, id, req.Components); err != nil {
+		tx.Rollback()
+		log.Printf("Error adding components to semi-finished: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to add components")
+		return
+	}
+
+	// Первая версия рецептуры
+	if _, err := recipeVersionService.Record(tx, models.CostEntitySemiFinished, id, "Создание", currentUserID(r)); err != nil {
+		tx.Rollback()
+		log.Printf("Error saving recipe version: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save recipe version")
+		return
+	}
+
 	// Коммитим транзакцию
 	if err := tx.Commit().Error; err != nil {
 		log.Printf("Error committing transaction: %v", err)
@@ -278,8 +311,30 @@ func UpdateSemiFinished(w http.ResponseWriter, r *http.Request) {
 		sf.Category = *req.Category
 	}
 
+	if req.CookingInstructions != nil {
+		sf.CookingInstructions = optionalText(*req.CookingInstructions)
+	}
+
+	if req.StorageConditions != nil {
+		sf.StorageConditions = optionalText(*req.StorageConditions)
+	}
+
 	if req.OutputUnit != nil {
-		sf.OutputUnit = *req.OutputUnit
+		outputUnit := units.Normalize(*req.OutputUnit)
+		// Остаток и партии выпуска учитываются в единице выхода
+		if outputUnit != sf.OutputUnit && sf.StockQuantity > 0 {
+			utils.RespondWithError(w, http.StatusConflict, "Cannot change output unit while semi-finished is in stock")
+			return
+		}
+		sf.OutputUnit = outputUnit
+	}
+
+	if req.ShelfLifeHours != nil {
+		if *req.ShelfLifeHours > 0 {
+			sf.ShelfLifeHours = req.ShelfLifeHours
+		} else {
+			sf.ShelfLifeHours = nil
+		}
 	}
 
 	// Обработка ингредиентов и расчёт себестоимости
@@ -288,7 +343,27 @@ func UpdateSemiFinished(w http.ResponseWriter, r *http.Request) {
 		for i := range req.Ingredients {
 			req.Ingredients[i].Quantity = normalizeFloat(req.Ingredients[i].Quantity, 3)
 			req.Ingredients[i].PricePerUnit = normalizeFloat(req.Ingredients[i].PricePerUnit, 2)
-			req.Ingredients[i].TotalPrice = normalizeFloat(req.Ingredients[i].TotalPrice, 2)
+		}
+
+		// Единицы строк должны переводиться в единицу склада ингредиента
+		if err := unitService.PriceSemiFinishedLines(req.Ingredients); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// Вложенные полуфабрикаты: проверка циклов и стоимость по текущей себестоимости
+	if req.Components != nil {
+		for i := range req.Components {
+			req.Components[i].Quantity = normalizeFloat(req.Components[i].Quantity, 3)
+		}
+		if err := semiFinishedService.PriceComponents(id, req.Components); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, services.ErrRecipeCycle) {
+				status = http.StatusConflict
+			}
+			utils.RespondWithError(w, status, err.Error())
+			return
 		}
 	}
 
@@ -299,9 +374,29 @@ func UpdateSemiFinished(w http.ResponseWriter, r *http.Request) {
 		sf.OutputQuantity = outputQty
 	}
 
-	// Пересчитываем себестоимость если переданы новые ингредиенты
-	if len(req.Ingredients) > 0 {
-		costPerUnit := calculateCostPerUnit(req.Ingredients, outputQty)
+	// Пересчитываем себестоимость если переданы новые ингредиенты или вложенные полуфабрикаты
+	if len(req.Ingredients) > 0 || req.Components != nil {
+		ingredients, components := req.Ingredients, req.Components
+		if len(ingredients) == 0 {
+			var existing []models.SemiFinishedIngredient
+			database.DB.Where("semi_finished_id = ?", id).Find(&existing)
+			for _, ing := range existing {
+				ingredients = append(ingredients, models.SemiFinishedIngredientInput{TotalPrice: ing.TotalPrice})
+			}
+		}
+		if components == nil {
+			var existing []models.SemiFinishedComponent
+			database.DB.Where("semi_finished_id = ?", id).Find(&existing)
+			for _, c := range existing {
+				components = append(components, models.SemiFinishedComponentInput{TotalCost: c.TotalCost})
+			}
+		}
+		if len(ingredients) == 0 && len(components) == 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, "At least one ingredient or semi-finished component is required")
+			return
+		}
+
+		costPerUnit := calculateCostPerUnit(ingredients, components, outputQty)
 		sf.CostPerUnit = costPerUnit
 		sf.TotalCost = normalizeFloat(costPerUnit*outputQty, 2)
 	} else if req.OutputQuantity != nil {
@@ -323,8 +418,16 @@ func UpdateSemiFinished(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	// Обновляем полуфабрикат
-	if err := tx.Save(&sf).Error; err != nil {
+	// Рецептуры, заведённые до появления версий, сохраняем как исходную версию
+	if err := recipeVersionService.EnsureBaseline(tx, models.CostEntitySemiFinished, id, nil); err != nil {
+		tx.Rollback()
+		log.Printf("Error saving recipe version: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save recipe version")
+		return
+	}
+
+	// Обновляем полуфабрикат (остаток меняется только выпуском и списанием)
+	if err := tx.Omit("stock_quantity").Save(&sf).Error; err != nil {
 		tx.Rollback()
 		log.Printf("Error updating semi-finished: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update semi-finished")
@@ -352,6 +455,8 @@ func UpdateSemiFinished(w http.ResponseWriter, r *http.Request) {
 				Unit:           ing.Unit,
 				PricePerUnit:   ing.PricePerUnit,
 				TotalPrice:     ing.TotalPrice,
+				QuantityType:   ing.QuantityType,
+				CookingLoss:    ing.CookingLoss,
 			}
 			if err := tx.Create(&ingredient).Error; err != nil {
 				tx.Rollback()
@@ -362,6 +467,43 @@ func UpdateSemiFinished(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Заменяем вложенные полуфабрикаты, если они переданы (пустой массив удаляет все)
+	if req.Components != nil {
+		if err := tx.Where("semi_finished_id = ?", id).Delete(&models.SemiFinishedComponent{}).Error; err != nil {
+			tx.Rollback()
+			log.Printf("Error deleting old components: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update components")
+			return
+		}
+		if err := createSemiFinishedComponents(tx, id, req.Components); err != nil {
+			tx.Rollback()
+			log.Printf("Error adding component: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update components")
+			return
+		}
+	}
+
+	// Изменение состава или выхода: пересчёт содержащих полуфабрикатов, продуктов и сетов
+	var costs *models.CostRecalculationResult
+	if len(req.Ingredients) > 0 || req.Components != nil || req.OutputQuantity != nil || req.OutputUnit != nil {
+		var err error
+		costs, err = costService.RecalculateSemiFinished(tx, id, "semi-finished recipe changed")
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Error recalculating costs: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to recalculate costs")
+			return
+		}
+	}
+
+	// Новая версия рецептуры (если состав или выход изменились)
+	if _, err := recipeVersionService.Record(tx, models.CostEntitySemiFinished, id, "Изменение рецептуры", currentUserID(r)); err != nil {
+		tx.Rollback()
+		log.Printf("Error saving recipe version: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save recipe version")
+		return
+	}
+
 	// Коммитим транзакцию
 	if err := tx.Commit().Error; err != nil {
 		log.Printf("Error committing transaction: %v", err)
@@ -371,6 +513,7 @@ func UpdateSemiFinished(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("📦 Semi-finished '%s' обновлён: %.3f %s, себестоимость = %.2f ₽/ед, всего = %.2f ₽ (ID: %s)",
 		sf.Name, sf.OutputQuantity, sf.OutputUnit, sf.CostPerUnit, sf.TotalCost, id)
+	notifyMarginDrops(costs)
 	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Semi-finished updated successfully"})
 }
 
@@ -379,6 +522,25 @@ func DeleteSemiFinished(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	// Нельзя удалить полуфабрикат, входящий в рецептуру другого
+	parents, err := semiFinishedService.UsedIn(id)
+	if err != nil {
+		log.Printf("Error checking semi-finished usage: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete semi-finished")
+		return
+	}
+	if len(parents) > 0 {
+		names := make([]string, 0, len(parents))
+		for _, p := range parents {
+			names = append(names, p.Name)
+		}
+		utils.RespondWithJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":  "Semi-finished is used in other semi-finished recipes",
+			"usedIn": names,
+		})
+		return
+	}
+
 	// Начинаем транзакцию
 	tx := database.DB.Begin()
 	if tx.Error != nil {
@@ -400,6 +562,14 @@ func DeleteSemiFinished(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Удаляем вложенные полуфабрикаты
+	if err := tx.Where("semi_finished_id = ?", id).Delete(&models.SemiFinishedComponent{}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error deleting components: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete semi-finished")
+		return
+	}
+
 	// Удаляем полуфабрикат
 	result := tx.Delete(&models.SemiFinished{}, "id = ?", id)
 	if result.Error != nil {
@@ -425,3 +595,40 @@ func DeleteSemiFinished(w http.ResponseWriter, r *http.Request) {
 	log.Printf("✅ Semi-finished deleted: ID %s", id)
 	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Semi-finished deleted successfully"})
 }
+
+// GetSemiFinishedTree возвращает полностью раскрытую рецептуру полуфабриката
+// GET /api/admin/semi-finished/{id}/tree
+func GetSemiFinishedTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := semiFinishedService.Tree(mux.Vars(r)["id"])
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Semi-finished not found")
+			return
+		}
+		log.Printf("[RECIPE] ❌ Error building semi-finished tree: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to build recipe tree")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, tree)
+}
+
+// createSemiFinishedComponents добавляет вложенные полуфабрикаты в рецептуру (внутри транзакции)
+func createSemiFinishedComponents(tx *gorm.DB, semiFinishedID string, components []models.SemiFinishedComponentInput) error {
+	for _, c := range components {
+		component := models.SemiFinishedComponent{
+			ID:             uuid.New().String(),
+			SemiFinishedID: semiFinishedID,
+			ComponentID:    c.ComponentID,
+			ComponentName:  c.ComponentName,
+			Quantity:       c.Quantity,
+			Unit:           c.Unit,
+			CostPerUnit:    c.CostPerUnit,
+			TotalCost:      c.TotalCost,
+		}
+		if err := tx.Create(&component).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -31,7 +31,7 @@ func GetAdminStats(w http.ResponseWriter, r *http.Request) {
 
 	// Подсчет продуктов
 	var productCount int64
-	database.DB.Table("Product").Count(&productCount)
+	database.DB.Table("Product").Where(`"deletedAt" IS NULL`).Count(&productCount)
 	stats.TotalProducts = productCount
 
 	// Подсчет выручки
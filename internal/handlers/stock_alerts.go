@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+)
+
+var stockAlertService = services.NewStockAlertService()
+
+// GetLowStockIngredients ингредиенты с остатком не выше точки заказа и рекомендуемым объёмом заказа
+func GetLowStockIngredients(w http.ResponseWriter, r *http.Request) {
+	items, err := stockAlertService.GetLowStock()
+	if err != nil {
+		log.Printf("[STOCK] ❌ Error fetching low stock: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch low stock")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, items)
+}
+
+// StartLowStockAlerts запускает фоновую проверку остатков с уведомлениями "stock_low" по WebSocket
+func StartLowStockAlerts(interval time.Duration) {
+	stockAlertService.StartChecker(interval, NotifyLowStock)
+}
+
+// NotifyLowStock отправляет админам WebSocket уведомления о низких остатках
+func NotifyLowStock(items []models.LowStockItem) {
+	for _, item := range items {
+		BroadcastOrderNotification("stock_low", item)
+	}
+}
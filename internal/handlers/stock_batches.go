@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+// GetExpiringBatches партии, срок годности которых истекает в ближайшие N дней (?days=, по умолчанию 3)
+func GetExpiringBatches(w http.ResponseWriter, r *http.Request) {
+	days := parseLimit(r.URL.Query().Get("days"), 3)
+
+	batches, err := stockService.GetExpiring(days, time.Now())
+	if err != nil {
+		log.Printf("[STOCK] ❌ Error fetching expiring batches: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch expiring batches")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"days":    days,
+		"batches": batches,
+	})
+}
+
+// GetIngredientBatches партии ингредиента (?all=true — включая израсходованные)
+func GetIngredientBatches(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	batches, err := stockService.GetBatches(id, r.URL.Query().Get("all") == "true")
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, batches)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+)
+
+var stockJournalService = services.NewStockJournalService()
+
+// GetStockJournal журнал движений склада с фильтрами, пагинацией и остатком после каждого движения
+// GET /api/admin/stock/movements?ingredientId=&type=out&documentType=order&documentId=&userId=&from=&to=&page=1&pageSize=50&format=csv
+func GetStockJournal(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := parseStockJournalFilter(query)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch strings.ToLower(query.Get("format")) {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="stock-movements.csv"`)
+		if err := stockJournalService.WriteCSV(w, filter); err != nil {
+			log.Printf("[STOCK] ❌ Error writing movements CSV: %v", err)
+		}
+	case "", "json":
+		page, err := stockJournalService.GetPage(filter, parseLimit(query.Get("page"), 1), parseLimit(query.Get("pageSize"), 50))
+		if err != nil {
+			log.Printf("[STOCK] ❌ Error fetching stock journal: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch stock movements")
+			return
+		}
+		utils.RespondWithJSON(w, http.StatusOK, page)
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Unsupported format (must be 'csv' or 'json')")
+	}
+}
+
+// parseStockJournalFilter разбирает фильтры журнала; период задаётся только при наличии from/to
+func parseStockJournalFilter(query url.Values) (models.StockJournalFilter, error) {
+	filter := models.StockJournalFilter{
+		IngredientID: query.Get("ingredientId"),
+		Type:         query.Get("type"),
+		DocumentType: query.Get("documentType"),
+		DocumentID:   query.Get("documentId"),
+		UserID:       query.Get("userId"),
+	}
+	if query.Get("from") == "" && query.Get("to") == "" {
+		return filter, nil
+	}
+
+	from, to, err := parsePeriod(query, 30)
+	if err != nil {
+		return filter, err
+	}
+	filter.From = &from
+	filter.To = &to
+	return filter, nil
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+var availabilityService = services.NewAvailabilityService()
+
+// GetStopList получение стоп-листа: остановленные вручную и закончившиеся продукты
+// GET /api/admin/stop-list
+func GetStopList(w http.ResponseWriter, r *http.Request) {
+	stopList, err := availabilityService.StopList()
+	if err != nil {
+		log.Printf("[STOP-LIST] ❌ Error building stop-list: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch stop-list")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, stopList)
+}
+
+// StopProduct ручное добавление продукта в стоп-лист
+// POST /api/admin/products/{id}/stop
+func StopProduct(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	productID := vars["id"]
+
+	var req models.StopProductRequest
+	// Тело запроса необязательно
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	var reason *string
+	if trimmed := strings.TrimSpace(req.Reason); trimmed != "" {
+		reason = &trimmed
+	}
+
+	setProductStopped(w, productID, true, reason)
+}
+
+// UnstopProduct снятие продукта со стоп-листа
+// DELETE /api/admin/products/{id}/stop
+func UnstopProduct(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	productID := vars["id"]
+
+	setProductStopped(w, productID, false, nil)
+}
+
+// setProductStopped обновляет флаг ручного стоп-листа и уведомляет админов
+func setProductStopped(w http.ResponseWriter, productID string, stopped bool, reason *string) {
+	var product models.Product
+	if err := database.DB.Where("id = ?", productID).First(&product).Error; err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+
+	if err := database.DB.Model(&product).Updates(map[string]interface{}{
+		"isStopListed": stopped,
+		"stopReason":   reason,
+	}).Error; err != nil {
+		log.Printf("[STOP-LIST] ❌ Error updating product: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update stop-list")
+		return
+	}
+
+	availability, err := availabilityService.Check([]string{productID})
+	if err != nil {
+		log.Printf("[STOP-LIST] ⚠️ Failed to check availability: %v", err)
+	}
+	result := availability[productID]
+
+	log.Printf("[STOP-LIST] 🛑 Product %s (%s) stopped=%v, available=%v", product.Name, productID, stopped, result.Available)
+
+	BroadcastOrderNotification("stop_list_updated", map[string]interface{}{
+		"productId":   productID,
+		"productName": product.Name,
+		"stopped":     stopped,
+		"available":   result.Available,
+	})
+
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+var supplierService = services.NewSupplierService()
+
+// GetSuppliers справочник поставщиков
+// GET /api/admin/suppliers?all=true
+func GetSuppliers(w http.ResponseWriter, r *http.Request) {
+	suppliers, err := supplierService.GetAll(r.URL.Query().Get("all") == "true")
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch suppliers")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, suppliers)
+}
+
+// GetSupplier поставщик с прайс-листом
+// GET /api/admin/suppliers/{id}
+func GetSupplier(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	supplier, err := supplierService.GetByID(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Supplier not found")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, supplier)
+}
+
+// CreateSupplier создание поставщика
+// POST /api/admin/suppliers
+func CreateSupplier(w http.ResponseWriter, r *http.Request) {
+	var req models.SupplierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	supplier, err := supplierService.Create(req)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, supplier)
+}
+
+// UpdateSupplier изменение карточки поставщика
+// PUT /api/admin/suppliers/{id}
+func UpdateSupplier(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.SupplierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	supplier, err := supplierService.Update(vars["id"], req)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, supplier)
+}
+
+// DeleteSupplier удаление поставщика без заказов
+// DELETE /api/admin/suppliers/{id}
+func DeleteSupplier(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := supplierService.Delete(vars["id"]); err != nil {
+		if errors.Is(err, services.ErrSupplierInUse) {
+			utils.RespondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Supplier deleted"})
+}
+
+// SetSupplierPrice добавление или изменение цены ингредиента в прайс-листе
+// PUT /api/admin/suppliers/{id}/prices
+func SetSupplierPrice(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.SupplierPriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	price, err := supplierService.SetPrice(vars["id"], req)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, price)
+}
+
+// DeleteSupplierPrice удаление строки прайс-листа
+// DELETE /api/admin/suppliers/{id}/prices/{priceId}
+func DeleteSupplierPrice(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := supplierService.DeletePrice(vars["id"], vars["priceId"]); err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Price deleted"})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+var techCardService = services.NewTechCardService()
+
+// GetProductTechCard технологическая карта продукта
+// GET /api/admin/products/{id}/tech-card?format=html|json
+func GetProductTechCard(w http.ResponseWriter, r *http.Request) {
+	card, err := techCardService.ForProduct(mux.Vars(r)["id"])
+	if err != nil {
+		respondTechCardError(w, err)
+		return
+	}
+	writeTechCards(w, r, []models.TechCard{*card}, "tech-card-"+card.ID)
+}
+
+// GetSemiFinishedTechCard технологическая карта полуфабриката
+// GET /api/admin/semi-finished/{id}/tech-card?format=html|json
+func GetSemiFinishedTechCard(w http.ResponseWriter, r *http.Request) {
+	card, err := techCardService.ForSemiFinished(mux.Vars(r)["id"])
+	if err != nil {
+		respondTechCardError(w, err)
+		return
+	}
+	writeTechCards(w, r, []models.TechCard{*card}, "tech-card-"+card.ID)
+}
+
+// GetMenuTechCards технологические карты всего меню и входящих в него полуфабрикатов
+// GET /api/admin/tech-cards?format=html|json&category=Роллы
+func GetMenuTechCards(w http.ResponseWriter, r *http.Request) {
+	cards, err := techCardService.ForMenu(strings.TrimSpace(r.URL.Query().Get("category")))
+	if err != nil {
+		log.Printf("[TECH-CARD] ❌ Error generating tech cards: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to generate tech cards")
+		return
+	}
+	writeTechCards(w, r, cards, "tech-cards")
+}
+
+// writeTechCards отдаёт карты печатной HTML-формой (по умолчанию; PDF — печатью из браузера) или JSON
+func writeTechCards(w http.ResponseWriter, r *http.Request, cards []models.TechCard, filename string) {
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "", "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if r.URL.Query().Get("download") == "true" {
+			w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.html"`)
+		}
+		if err := techCardService.WriteHTML(w, cards); err != nil {
+			log.Printf("[TECH-CARD] ❌ Error writing HTML: %v", err)
+		}
+	case "json":
+		utils.RespondWithJSON(w, http.StatusOK, cards)
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Unsupported format (must be 'html' or 'json')")
+	}
+}
+
+// respondTechCardError 404 для отсутствующей рецептуры, 400 для остальных ошибок
+func respondTechCardError(w http.ResponseWriter, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	log.Printf("[TECH-CARD] ❌ Error generating tech card: %v", err)
+	utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+}
+
+// optionalText обрезает пробелы; пустая строка — nil
+func optionalText(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
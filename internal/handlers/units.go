@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+var unitService = services.NewUnitService()
+
+// GetUnits справочник единиц измерения
+// GET /api/admin/units
+func GetUnits(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, unitService.GetUnits())
+}
+
+// GetUnitConversions коэффициенты перевода единиц ингредиента
+// GET /api/admin/ingredients/{id}/conversions
+func GetUnitConversions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	conversions, err := unitService.GetConversions(vars["id"])
+	if err != nil {
+		log.Printf("[UNITS] ❌ Error fetching conversions: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch unit conversions")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, conversions)
+}
+
+// SetUnitConversion добавление/замена коэффициента перевода (1 pcs = 180 g)
+// POST /api/admin/ingredients/{id}/conversions
+func SetUnitConversion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.UnitConversionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	conversion, costs, err := unitService.SetConversion(vars["id"], req)
+	if errors.Is(err, services.ErrConversionInUse) {
+		utils.RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	notifyMarginDrops(costs)
+
+	utils.RespondWithJSON(w, http.StatusOK, conversion)
+}
+
+// DeleteUnitConversion удаление коэффициента перевода
+// DELETE /api/admin/ingredients/{id}/conversions/{conversionId}
+func DeleteUnitConversion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	costs, err := unitService.DeleteConversion(vars["id"], vars["conversionId"])
+	if errors.Is(err, services.ErrConversionInUse) {
+		utils.RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	notifyMarginDrops(costs)
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Unit conversion deleted"})
+}
+
+// ConvertIngredientUnits перевод количества ингредиента между единицами
+// GET /api/admin/ingredients/{id}/convert?quantity=2&from=pcs&to=g
+func ConvertIngredientUnits(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	quantity := 1.0
+	if value := query.Get("quantity"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid quantity")
+			return
+		}
+		quantity = parsed
+	}
+	if query.Get("from") == "" || query.Get("to") == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Parameters 'from' and 'to' are required")
+		return
+	}
+
+	result, err := unitService.Convert(vars["id"], quantity, query.Get("from"), query.Get("to"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
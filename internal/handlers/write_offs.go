@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/services"
+	"github.com/dmitrijfomin/menu-fodifood/backend/pkg/utils"
+)
+
+var writeOffService = services.NewWriteOffService()
+
+// CreateWriteOff списание ингредиента с причиной и необязательным фото
+// POST /api/admin/write-offs
+func CreateWriteOff(w http.ResponseWriter, r *http.Request) {
+	var req models.WriteOffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	writeOff, err := writeOffService.Create(req, currentUserID(r))
+	if err != nil {
+		log.Printf("[WASTE] ❌ Error creating write-off: %v", err)
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, writeOff)
+}
+
+// GetWriteOffs журнал списаний
+// GET /api/admin/write-offs?from=2026-10-01&to=2026-10-31&reason=spoiled&ingredientId=...
+func GetWriteOffs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to, err := parsePeriod(query, 30)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeOffs, err := writeOffService.GetAll(from, to, query.Get("reason"), query.Get("ingredientId"), parseLimit(query.Get("limit"), 200))
+	if err != nil {
+		log.Printf("[WASTE] ❌ Error fetching write-offs: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch write-offs")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, writeOffs)
+}
+
+// GetWriteOffReasons справочник причин списания
+// GET /api/admin/write-offs/reasons
+func GetWriteOffReasons(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, models.WriteOffReasons)
+}
+
+// GetWasteReport отчёт о потерях за период в сравнении с нормой отходов
+// GET /api/admin/reports/waste?from=2026-10-01&to=2026-10-31
+func GetWasteReport(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parsePeriod(r.URL.Query(), 30)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := writeOffService.WasteReport(from, to)
+	if err != nil {
+		log.Printf("[WASTE] ❌ Error building waste report: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to build waste report")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, report)
+}
+
+// parsePeriod разбирает параметры from/to (дата 2006-01-02 или RFC3339).
+// Дата в to включается целиком; по умолчанию — последние defaultDays дней.
+func parsePeriod(query url.Values, defaultDays int) (time.Time, time.Time, error) {
+	now := time.Now()
+	from := now.AddDate(0, 0, -defaultDays)
+	to := now
+
+	parse := func(name string, endOfDay bool) (*time.Time, error) {
+		value := query.Get(name)
+		if value == "" {
+			return nil, nil
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return &t, nil
+		}
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s date: %s", name, value)
+		}
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+
+	if t, err := parse("from", false); err != nil {
+		return from, to, err
+	} else if t != nil {
+		from = *t
+	}
+	if t, err := parse("to", true); err != nil {
+		return from, to, err
+	} else if t != nil {
+		to = *t
+	}
+	if !to.After(from) {
+		return from, to, fmt.Errorf("period end must be after its start")
+	}
+	return from, to, nil
+}
@@ -0,0 +1,24 @@
+package models
+
+// Причины недоступности продукта
+const (
+	UnavailableStopList   = "stop_list"    // Продукт вручную добавлен в стоп-лист
+	UnavailableOutOfStock = "out_of_stock" // Не хватает ингредиентов на одну порцию
+	UnavailableNotFound   = "not_found"    // Продукт не существует
+)
+
+// ProductAvailability доступность продукта к заказу
+type ProductAvailability struct {
+	ProductID          string   `json:"productId"`
+	ProductName        string   `json:"productName"`
+	Available          bool     `json:"available"`
+	Reason             string   `json:"reason,omitempty"` // "stop_list", "out_of_stock", "not_found"
+	StopReason         *string  `json:"stopReason,omitempty"`
+	MissingIngredients []string `json:"missingIngredients,omitempty"`
+	UnavailableItems   []string `json:"unavailableItems,omitempty"` // Для сетов: недоступные компоненты и слоты без вариантов
+}
+
+// StopProductRequest запрос на ручное добавление продукта в стоп-лист
+type StopProductRequest struct {
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,79 @@
+package models
+
+// Типы продуктов
+const (
+	ProductTypeSingle = "single" // Обычное блюдо со своей рецептурой
+	ProductTypeBundle = "bundle" // Сет / комбо из других продуктов
+)
+
+// BundleItem фиксированный компонент сета
+type BundleItem struct {
+	ID          string `gorm:"primaryKey;column:id" json:"id"`
+	BundleID    string `gorm:"column:bundle_id;not null;index" json:"bundleId"`
+	ProductID   string `gorm:"column:product_id;not null;index" json:"productId"`
+	ProductName string `gorm:"column:product_name" json:"productName"`
+	Quantity    int    `gorm:"column:quantity;default:1" json:"quantity"`
+}
+
+// TableName для BundleItem
+func (BundleItem) TableName() string {
+	return "bundle_items"
+}
+
+// BundleSlot слот выбора в сете ("любые 2 ролла")
+type BundleSlot struct {
+	ID         string     `gorm:"primaryKey;column:id" json:"id"`
+	BundleID   string     `gorm:"column:bundle_id;not null;index" json:"bundleId"`
+	Name       string     `gorm:"column:name" json:"name"`
+	Quantity   int        `gorm:"column:quantity;default:1" json:"quantity"`       // Сколько позиций нужно выбрать
+	Category   string     `gorm:"column:category" json:"category,omitempty"`       // Разрешены все продукты категории
+	ProductIDs StringList `gorm:"column:product_ids;type:jsonb" json:"productIds"` // и/или явный список продуктов
+}
+
+// TableName для BundleSlot
+func (BundleSlot) TableName() string {
+	return "bundle_slots"
+}
+
+// Allows проверяет, можно ли выбрать продукт в слоте
+func (s *BundleSlot) Allows(product *Product) bool {
+	if s.ProductIDs.Contains(product.ID) {
+		return true
+	}
+	return s.Category != "" && product.Category == s.Category && product.Type != ProductTypeBundle
+}
+
+// OrderItemComponent фактический состав сета в позиции заказа (фиксированные компоненты и выбор клиента)
+type OrderItemComponent struct {
+	ID          string  `gorm:"primaryKey;type:text;column:id" json:"id"`
+	OrderItemID string  `gorm:"type:text;not null;index;column:order_item_id" json:"orderItemId"`
+	ProductID   string  `gorm:"type:text;not null;column:product_id" json:"productId"`
+	SlotID      *string `gorm:"type:text;column:slot_id" json:"slotId,omitempty"`
+	Quantity    int     `gorm:"type:int;not null;column:quantity" json:"quantity"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (OrderItemComponent) TableName() string {
+	return "OrderItemComponent"
+}
+
+// BundleItemInput входные данные для компонента сета
+type BundleItemInput struct {
+	ProductID string `json:"productId"`
+	Quantity  int    `json:"quantity"`
+}
+
+// BundleSlotInput входные данные для слота выбора
+type BundleSlotInput struct {
+	Name       string   `json:"name"`
+	Quantity   int      `json:"quantity"`
+	Category   string   `json:"category"`
+	ProductIDs []string `json:"productIds"`
+}
+
+// BundleSelectionInput выбор клиента в слоте сета при заказе
+type BundleSelectionInput struct {
+	SlotID    string `json:"slotId"`
+	ProductID string `json:"productId"`
+	Quantity  int    `json:"quantity"`
+}
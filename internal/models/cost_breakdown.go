@@ -0,0 +1,50 @@
+package models
+
+// Тип количества в строке рецептуры
+const (
+	RecipeQuantityGross = "gross" // Брутто: вес со склада до обработки
+	RecipeQuantityNet   = "net"   // Нетто: вес в готовом блюде после зачистки и тепловой обработки
+)
+
+// RecipeLineBreakdown разбивка строки рецептуры: брутто, нетто и потери (в единице строки)
+type RecipeLineBreakdown struct {
+	IngredientID       string  `json:"ingredientId"`
+	IngredientName     string  `json:"ingredientName"`
+	Unit               string  `json:"unit"`
+	QuantityType       string  `json:"quantityType"`
+	Quantity           float64 `json:"quantity"` // Как указано в рецептуре
+	GrossQuantity      float64 `json:"grossQuantity"`
+	NetQuantity        float64 `json:"netQuantity"`
+	TrimLoss           float64 `json:"trimLoss"`           // Потери при холодной обработке
+	TrimLossPercent    float64 `json:"trimLossPercent"`    // Норма отходов ингредиента
+	CookingLoss        float64 `json:"cookingLoss"`        // Потери при тепловой обработке
+	CookingLossPercent float64 `json:"cookingLossPercent"` // Из строки рецептуры
+	PricePerUnit       float64 `json:"pricePerUnit"`       // За кг/л/шт
+	Cost               float64 `json:"cost"`               // Стоимость брутто
+	LossCost           float64 `json:"lossCost"`           // Стоимость потерь
+	Error              string  `json:"error,omitempty"`    // Единица не переводится в складскую
+}
+
+// RecipeSemiFinishedBreakdown строка полуфабриката в разбивке себестоимости продукта
+type RecipeSemiFinishedBreakdown struct {
+	SemiFinishedID   string  `json:"semiFinishedId"`
+	SemiFinishedName string  `json:"semiFinishedName"`
+	Quantity         float64 `json:"quantity"`
+	Unit             string  `json:"unit"`
+	CostPerUnit      float64 `json:"costPerUnit"`
+	Cost             float64 `json:"cost"`
+}
+
+// RecipeCostBreakdown разбивка себестоимости продукта или полуфабриката
+type RecipeCostBreakdown struct {
+	EntityType     string                        `json:"entityType"` // "product" или "semi_finished"
+	ID             string                        `json:"id"`
+	Name           string                        `json:"name"`
+	Lines          []RecipeLineBreakdown         `json:"lines"`
+	SemiFinished   []RecipeSemiFinishedBreakdown `json:"semiFinished,omitempty"`
+	OutputQuantity float64                       `json:"outputQuantity,omitempty"` // Выход полуфабриката
+	OutputUnit     string                        `json:"outputUnit,omitempty"`
+	TotalCost      float64                       `json:"totalCost"`
+	LossCost       float64                       `json:"lossCost"`              // Из них стоимость потерь
+	CostPerUnit    float64                       `json:"costPerUnit,omitempty"` // За единицу выхода полуфабриката
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Типы сущностей в журнале изменений себестоимости
+const (
+	CostEntitySemiFinished = "semi_finished"
+	CostEntityProduct      = "product"
+)
+
+// CostChangeLog запись журнала изменений себестоимости
+type CostChangeLog struct {
+	ID           string    `gorm:"primaryKey;column:id" json:"id"`
+	EntityType   string    `gorm:"column:entity_type;index" json:"entityType"` // "semi_finished" или "product"
+	EntityID     string    `gorm:"column:entity_id;index" json:"entityId"`
+	EntityName   string    `gorm:"column:entity_name" json:"entityName"`
+	OldCost      float64   `gorm:"column:old_cost;type:decimal(10,2)" json:"oldCost"`
+	NewCost      float64   `gorm:"column:new_cost;type:decimal(10,2)" json:"newCost"`
+	IngredientID string    `gorm:"column:ingredient_id" json:"ingredientId"` // Ингредиент, изменение цены которого вызвало пересчёт
+	Reason       string    `gorm:"column:reason" json:"reason"`
+	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (CostChangeLog) TableName() string {
+	return "cost_change_logs"
+}
+
+// CostChange изменение себестоимости одной сущности
+type CostChange struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name"`
+	OldCost float64 `json:"oldCost"`
+	NewCost float64 `json:"newCost"`
+}
+
+// ProductCostChange изменение себестоимости и маржи продукта
+type ProductCostChange struct {
+	CostChange
+	Price     float64 `json:"price"`
+	OldMargin float64 `json:"oldMargin"` // %
+	NewMargin float64 `json:"newMargin"` // %
+}
+
+// CostRecalculationResult итог каскадного пересчёта себестоимости
+type CostRecalculationResult struct {
+	IngredientID string              `json:"ingredientId"`
+	OldPrice     float64             `json:"oldPrice"`
+	NewPrice     float64             `json:"newPrice"`
+	SemiFinished []CostChange        `json:"semiFinished"`
+	Products     []ProductCostChange `json:"products"`
+}
@@ -0,0 +1,106 @@
+package models
+
+import "time"
+
+// Типы движений по складу
+const (
+	MovementIn  = "in"  // Поступление
+	MovementOut = "out" // Расход
+)
+
+// Типы документов-оснований движений
+const (
+	DocumentGoodsReceipt = "goods_receipt"
+)
+
+// Статусы приходной накладной
+const (
+	ReceiptDraft    = "draft"    // Черновик, можно редактировать
+	ReceiptPosted   = "posted"   // Проведена: остатки увеличены, документ неизменяем
+	ReceiptReversed = "reversed" // Сторнирована: остатки возвращены
+)
+
+// GoodsReceipt приходная накладная от поставщика
+type GoodsReceipt struct {
+	ID              string             `gorm:"primaryKey;column:id" json:"id"`
+	Supplier        string             `gorm:"column:supplier;index" json:"supplier"`
+	SupplierID      *string            `gorm:"column:supplier_id;index" json:"supplierId,omitempty"`
+	PurchaseOrderID *string            `gorm:"column:purchase_order_id;index" json:"purchaseOrderId,omitempty"` // Накладная по заказу поставщику
+	InvoiceNumber   string             `gorm:"column:invoice_number" json:"invoiceNumber"`
+	InvoiceDate     time.Time          `gorm:"column:invoice_date" json:"invoiceDate"`
+	Status          string             `gorm:"column:status;default:draft;index" json:"status"` // "draft", "posted", "reversed"
+	Total           float64            `gorm:"column:total;type:decimal(12,2)" json:"total"`
+	Note            *string            `gorm:"column:note" json:"note,omitempty"`
+	CreatedBy       *string            `gorm:"column:created_by" json:"createdBy,omitempty"`
+	PostedBy        *string            `gorm:"column:posted_by" json:"postedBy,omitempty"`
+	PostedAt        *time.Time         `gorm:"column:posted_at" json:"postedAt,omitempty"`
+	ReversedBy      *string            `gorm:"column:reversed_by" json:"reversedBy,omitempty"`
+	ReversedAt      *time.Time         `gorm:"column:reversed_at" json:"reversedAt,omitempty"`
+	ReversalReason  *string            `gorm:"column:reversal_reason" json:"reversalReason,omitempty"`
+	CreatedAt       time.Time          `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt       time.Time          `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+	Lines           []GoodsReceiptLine `gorm:"foreignKey:ReceiptID;constraint:OnDelete:CASCADE" json:"lines,omitempty"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (GoodsReceipt) TableName() string {
+	return "goods_receipts"
+}
+
+// GoodsReceiptLine строка приходной накладной
+type GoodsReceiptLine struct {
+	ID                  string     `gorm:"primaryKey;column:id" json:"id"`
+	ReceiptID           string     `gorm:"column:receipt_id;not null;index" json:"receiptId"`
+	StockItemID         string     `gorm:"column:stock_item_id;not null" json:"stockItemId"`
+	IngredientID        string     `gorm:"column:ingredient_id;not null" json:"ingredientId"`
+	IngredientName      string     `gorm:"column:ingredient_name" json:"ingredientName"`
+	Quantity            float64    `gorm:"column:quantity;type:decimal(12,3)" json:"quantity"` // В единицах ингредиента
+	Unit                string     `gorm:"column:unit" json:"unit"`
+	BruttoWeight        *float64   `gorm:"column:brutto_weight" json:"bruttoWeight,omitempty"`
+	NettoWeight         *float64   `gorm:"column:netto_weight" json:"nettoWeight,omitempty"`
+	PricePerUnit        float64    `gorm:"column:price_per_unit;type:decimal(10,2)" json:"pricePerUnit"` // За кг/л/шт
+	Total               float64    `gorm:"column:total;type:decimal(12,2)" json:"total"`
+	ExpiresAt           *time.Time `gorm:"column:expires_at" json:"expiresAt,omitempty"` // Пусто — по сроку годности ингредиента
+	BatchID             *string    `gorm:"column:batch_id" json:"batchId,omitempty"`     // Партия, заведённая при проведении
+	PurchaseOrderLineID *string    `gorm:"column:purchase_order_line_id;index" json:"purchaseOrderLineId,omitempty"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (GoodsReceiptLine) TableName() string {
+	return "goods_receipt_lines"
+}
+
+// GoodsReceiptRequest запрос на создание/изменение приходной накладной
+type GoodsReceiptRequest struct {
+	Supplier        string                  `json:"supplier"`
+	InvoiceNumber   string                  `json:"invoiceNumber"`
+	InvoiceDate     time.Time               `json:"invoiceDate"`
+	Note            string                  `json:"note"`
+	Lines           []GoodsReceiptLineInput `json:"lines"`
+	PurchaseOrderID string                  `json:"purchaseOrderId"` // Приёмка по заказу поставщику
+}
+
+// GoodsReceiptLineInput строка накладной во входящем запросе
+type GoodsReceiptLineInput struct {
+	IngredientID        string     `json:"ingredientId"` // ID ингредиента или складской позиции
+	Quantity            float64    `json:"quantity"`
+	BruttoWeight        *float64   `json:"bruttoWeight"`
+	NettoWeight         *float64   `json:"nettoWeight"`
+	PricePerUnit        float64    `json:"pricePerUnit"`
+	ExpiresAt           *time.Time `json:"expiresAt"`
+	PurchaseOrderLineID string     `json:"purchaseOrderLineId"` // Строка заказа поставщику
+}
+
+// ReverseReceiptRequest запрос на сторнирование накладной
+type ReverseReceiptRequest struct {
+	Reason string `json:"reason"`
+}
+
+// IngredientPriceChange изменение закупочной цены ингредиента при проведении документа
+type IngredientPriceChange struct {
+	IngredientID string  `json:"ingredientId"`
+	OldPrice     float64 `json:"oldPrice"`
+	NewPrice     float64 `json:"newPrice"`
+
+	Recalculation *CostRecalculationResult `json:"recalculation,omitempty"` // Каскадный пересчёт себестоимости
+}
@@ -8,6 +8,15 @@ type Ingredient struct {
 	Name      string    `gorm:"column:name" json:"name"`
 	Unit      string    `gorm:"column:unit" json:"unit"` // "g", "ml", "pcs"
 	CreatedAt time.Time `gorm:"column:createdAt;autoCreateTime" json:"createdAt"`
+
+	// Пищевая ценность на 100 г и аллергены
+	Kcal         *float64   `gorm:"column:kcal" json:"kcal,omitempty"`
+	Protein      *float64   `gorm:"column:protein" json:"protein,omitempty"`
+	Fat          *float64   `gorm:"column:fat" json:"fat,omitempty"`
+	Carbs        *float64   `gorm:"column:carbs" json:"carbs,omitempty"`
+	Allergens    StringList `gorm:"column:allergens;type:jsonb" json:"allergens"` // null — не указаны, [] — нет аллергенов; "gluten", "sesame", "fish", ...
+	IsVegetarian bool       `gorm:"column:isVegetarian;default:false" json:"isVegetarian"`
+	IsVegan      bool       `gorm:"column:isVegan;default:false" json:"isVegan"`
 }
 
 // TableName указывает имя таблицы для GORM
@@ -30,7 +39,9 @@ type StockItem struct {
 	Category        *string     `gorm:"column:category" json:"category,omitempty"`
 	PriceBrutto     *float64    `gorm:"column:priceBrutto" json:"priceBrutto,omitempty"`
 	PriceNetto      *float64    `gorm:"column:priceNetto" json:"priceNetto,omitempty"`
-	PricePerUnit    *float64    `gorm:"column:pricePerUnit" json:"pricePerUnit,omitempty"` // Цена за единицу (кг/л/шт)
+	PricePerUnit    *float64    `gorm:"column:pricePerUnit" json:"pricePerUnit,omitempty"`       // Цена за единицу (кг/л/шт)
+	MinStock        *float64    `gorm:"column:minStock" json:"minStock,omitempty"`               // Точка заказа (в единицах ингредиента)
+	ReorderQuantity *float64    `gorm:"column:reorderQuantity" json:"reorderQuantity,omitempty"` // Объём заказа (в единицах ингредиента)
 	Ingredient      *Ingredient `gorm:"foreignKey:IngredientID;references:ID" json:"ingredient,omitempty"`
 }
 
@@ -43,12 +54,19 @@ func (StockItem) TableName() string {
 type StockMovement struct {
 	ID          string    `gorm:"primaryKey;column:id" json:"id"`
 	StockItemID string    `gorm:"column:stockItemId" json:"stockItemId"`
-	Type        string    `gorm:"column:type" json:"type"` // "in" (поступление) или "out" (расход)
+	Type        string    `gorm:"column:type" json:"type"` // "in" (поступление) или "out" (расход), см. MovementIn/MovementOut
 	Quantity    float64   `gorm:"column:quantity" json:"quantity"`
 	PriceBrutto *float64  `gorm:"column:priceBrutto" json:"priceBrutto,omitempty"`
 	PriceNetto  *float64  `gorm:"column:priceNetto" json:"priceNetto,omitempty"`
 	Note        *string   `gorm:"column:note" json:"note,omitempty"`
 	CreatedAt   time.Time `gorm:"column:createdAt;autoCreateTime" json:"createdAt"`
+
+	// Документ-основание движения (см. migrations/014_add_stock_movement_documents.sql)
+	DocumentType *string `gorm:"column:documentType" json:"documentType,omitempty"` // "goods_receipt", "order", "write_off", ...
+	DocumentID   *string `gorm:"column:documentId" json:"documentId,omitempty"`
+
+	// Кто выполнил движение (см. migrations/016_add_stock_movement_user.sql)
+	UserID *string `gorm:"column:userId" json:"userId,omitempty"`
 }
 
 // TableName указывает имя таблицы для GORM
@@ -58,34 +76,76 @@ func (StockMovement) TableName() string {
 
 // CreateIngredientRequest запрос на создание ингредиента
 type CreateIngredientRequest struct {
-	Name            string  `json:"name"`
-	Unit            string  `json:"unit"`
-	Quantity        float64 `json:"quantity"`
-	BruttoWeight    float64 `json:"bruttoWeight"`
-	NettoWeight     float64 `json:"nettoWeight"`
-	WastePercentage float64 `json:"wastePercentage"`
-	ExpiryDays      int     `json:"expiryDays"`
-	Supplier        string  `json:"supplier"`
-	Category        string  `json:"category"`
-	PriceBrutto     float64 `json:"priceBrutto"`
-	PriceNetto      float64 `json:"priceNetto"`
-	PricePerUnit    float64 `json:"pricePerUnit"` // Цена за единицу (кг/л/шт)
+	Name            string   `json:"name"`
+	Unit            string   `json:"unit"`
+	Quantity        float64  `json:"quantity"`
+	BruttoWeight    float64  `json:"bruttoWeight"`
+	NettoWeight     float64  `json:"nettoWeight"`
+	WastePercentage float64  `json:"wastePercentage"`
+	ExpiryDays      int      `json:"expiryDays"`
+	Supplier        string   `json:"supplier"`
+	Category        string   `json:"category"`
+	PriceBrutto     float64  `json:"priceBrutto"`
+	PriceNetto      float64  `json:"priceNetto"`
+	PricePerUnit    float64  `json:"pricePerUnit"`    // Цена за единицу (кг/л/шт)
+	MinStock        *float64 `json:"minStock"`        // Точка заказа, 0 — без контроля
+	ReorderQuantity *float64 `json:"reorderQuantity"` // Объём заказа, 0 — до двух минимумов
+	NutritionInput
 }
 
 // UpdateIngredientRequest запрос на обновление ингредиента
 type UpdateIngredientRequest struct {
-	Name            string  `json:"name"`
-	Unit            string  `json:"unit"`
-	Quantity        float64 `json:"quantity"`
-	BruttoWeight    float64 `json:"bruttoWeight"`
-	NettoWeight     float64 `json:"nettoWeight"`
-	WastePercentage float64 `json:"wastePercentage"`
-	ExpiryDays      int     `json:"expiryDays"`
-	Supplier        string  `json:"supplier"`
-	Category        string  `json:"category"`
-	PriceBrutto     float64 `json:"priceBrutto"`
-	PriceNetto      float64 `json:"priceNetto"`
-	PricePerUnit    float64 `json:"pricePerUnit"` // Цена за единицу (кг/л/шт)
+	Name            string   `json:"name"`
+	Unit            string   `json:"unit"`
+	Quantity        *float64 `json:"quantity"` // Остаток меняется только движениями: допускается лишь текущее значение
+	BruttoWeight    float64  `json:"bruttoWeight"`
+	NettoWeight     float64  `json:"nettoWeight"`
+	WastePercentage *float64 `json:"wastePercentage"` // Не передано — норма отходов не меняется
+	ExpiryDays      int      `json:"expiryDays"`
+	Supplier        string   `json:"supplier"`
+	Category        string   `json:"category"`
+	PriceBrutto     float64  `json:"priceBrutto"`
+	PriceNetto      float64  `json:"priceNetto"`
+	PricePerUnit    float64  `json:"pricePerUnit"`    // Цена за единицу (кг/л/шт)
+	MinStock        *float64 `json:"minStock"`        // Точка заказа, 0 — без контроля
+	ReorderQuantity *float64 `json:"reorderQuantity"` // Объём заказа, 0 — до двух минимумов
+	NutritionInput
+}
+
+// NutritionInput пищевая ценность и аллергены ингредиента во входящих запросах
+type NutritionInput struct {
+	Kcal         *float64 `json:"kcal"`
+	Protein      *float64 `json:"protein"`
+	Fat          *float64 `json:"fat"`
+	Carbs        *float64 `json:"carbs"`
+	Allergens    []string `json:"allergens"`
+	IsVegetarian *bool    `json:"isVegetarian"`
+	IsVegan      *bool    `json:"isVegan"`
+}
+
+// ApplyTo переносит переданные значения пищевой ценности в ингредиент
+func (n *NutritionInput) ApplyTo(ingredient *Ingredient) {
+	if n.Kcal != nil {
+		ingredient.Kcal = n.Kcal
+	}
+	if n.Protein != nil {
+		ingredient.Protein = n.Protein
+	}
+	if n.Fat != nil {
+		ingredient.Fat = n.Fat
+	}
+	if n.Carbs != nil {
+		ingredient.Carbs = n.Carbs
+	}
+	if n.Allergens != nil {
+		ingredient.Allergens = StringList(n.Allergens)
+	}
+	if n.IsVegetarian != nil {
+		ingredient.IsVegetarian = *n.IsVegetarian
+	}
+	if n.IsVegan != nil {
+		ingredient.IsVegan = *n.IsVegan
+	}
 }
 
 // IngredientResponse DTO для ответа API (плоская структура для frontend)
@@ -0,0 +1,114 @@
+package models
+
+import "time"
+
+// DocumentInventoryCount документ-основание корректировок по инвентаризации
+const DocumentInventoryCount = "inventory_count"
+
+// Статусы инвентаризации
+const (
+	InventoryCountOpen      = "open"      // Идёт подсчёт, остатки зафиксированы на момент начала
+	InventoryCountApproved  = "approved"  // Утверждена: расхождения списаны/оприходованы
+	InventoryCountCancelled = "cancelled" // Отменена без корректировок
+)
+
+// InventoryCount документ инвентаризации склада
+type InventoryCount struct {
+	ID           string               `gorm:"primaryKey;column:id" json:"id"`
+	Name         string               `gorm:"column:name" json:"name"`
+	Status       string               `gorm:"column:status;default:open;index" json:"status"` // "open", "approved", "cancelled"
+	Category     *string              `gorm:"column:category" json:"category,omitempty"`      // Инвентаризация одной категории склада
+	StartedBy    *string              `gorm:"column:started_by" json:"startedBy,omitempty"`
+	StartedAt    time.Time            `gorm:"column:started_at" json:"startedAt"`
+	ApprovedBy   *string              `gorm:"column:approved_by" json:"approvedBy,omitempty"`
+	ApprovedAt   *time.Time           `gorm:"column:approved_at" json:"approvedAt,omitempty"`
+	Reason       *string              `gorm:"column:reason" json:"reason,omitempty"` // Причина корректировки при утверждении
+	ShortageCost float64              `gorm:"column:shortage_cost;type:decimal(12,2)" json:"shortageCost"`
+	SurplusCost  float64              `gorm:"column:surplus_cost;type:decimal(12,2)" json:"surplusCost"`
+	CreatedAt    time.Time            `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time            `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+	Lines        []InventoryCountLine `gorm:"foreignKey:CountID;constraint:OnDelete:CASCADE" json:"lines,omitempty"`
+
+	// Сводка по расхождениям (вычисляется)
+	CountedLines int     `gorm:"-" json:"countedLines"`
+	TotalLines   int     `gorm:"-" json:"totalLines"`
+	VarianceCost float64 `gorm:"-" json:"varianceCost"` // Излишки минус недостачи
+}
+
+// TableName указывает имя таблицы для GORM
+func (InventoryCount) TableName() string {
+	return "inventory_counts"
+}
+
+// InventoryCountLine строка инвентаризации: ожидаемый и фактический остаток ингредиента
+type InventoryCountLine struct {
+	ID               string     `gorm:"primaryKey;column:id" json:"id"`
+	CountID          string     `gorm:"column:count_id;not null;uniqueIndex:idx_inventory_count_line" json:"countId"`
+	StockItemID      string     `gorm:"column:stock_item_id;not null;uniqueIndex:idx_inventory_count_line" json:"stockItemId"`
+	IngredientID     string     `gorm:"column:ingredient_id;not null" json:"ingredientId"`
+	IngredientName   string     `gorm:"column:ingredient_name" json:"ingredientName"`
+	Unit             string     `gorm:"column:unit" json:"unit"`
+	ExpectedQuantity float64    `gorm:"column:expected_quantity;type:decimal(12,3)" json:"expectedQuantity"`         // Учётный остаток на начало
+	CountedQuantity  *float64   `gorm:"column:counted_quantity;type:decimal(12,3)" json:"countedQuantity,omitempty"` // nil — ещё не посчитано
+	PricePerUnit     float64    `gorm:"column:price_per_unit;type:decimal(10,2)" json:"pricePerUnit"`                // За кг/л/шт на начало
+	CountedAt        *time.Time `gorm:"column:counted_at" json:"countedAt,omitempty"`
+
+	// Расхождение (вычисляется): плюс — излишек, минус — недостача
+	Variance     *float64 `gorm:"-" json:"variance,omitempty"`
+	VarianceCost *float64 `gorm:"-" json:"varianceCost,omitempty"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (InventoryCountLine) TableName() string {
+	return "inventory_count_lines"
+}
+
+// InventoryCountEntry отдельный ввод подсчёта (журнал для подсчёта с нескольких устройств)
+type InventoryCountEntry struct {
+	ID        string    `gorm:"primaryKey;column:id" json:"id"`
+	CountID   string    `gorm:"column:count_id;not null;index" json:"countId"`
+	LineID    string    `gorm:"column:line_id;not null;index" json:"lineId"`
+	Quantity  float64   `gorm:"column:quantity;type:decimal(12,3)" json:"quantity"`
+	Mode      string    `gorm:"column:mode" json:"mode"`                    // "add" или "set"
+	DeviceID  *string   `gorm:"column:device_id" json:"deviceId,omitempty"` // Устройство, с которого введён подсчёт
+	Location  *string   `gorm:"column:location" json:"location,omitempty"`  // Место хранения ("холодильник 2")
+	CountedBy *string   `gorm:"column:counted_by" json:"countedBy,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (InventoryCountEntry) TableName() string {
+	return "inventory_count_entries"
+}
+
+// Режимы ввода подсчёта
+const (
+	CountModeAdd = "add" // Прибавить к уже посчитанному (ингредиент лежит в нескольких местах)
+	CountModeSet = "set" // Заменить посчитанное значение
+)
+
+// StartInventoryCountRequest запрос на начало инвентаризации
+type StartInventoryCountRequest struct {
+	Name          string   `json:"name"`
+	Category      string   `json:"category"`      // Только позиции категории склада
+	IngredientIDs []string `json:"ingredientIds"` // Или только перечисленные позиции; пусто — весь склад
+}
+
+// InventoryCountEntriesRequest пакет подсчётов с одного устройства
+type InventoryCountEntriesRequest struct {
+	DeviceID string                     `json:"deviceId"`
+	Location string                     `json:"location"`
+	Entries  []InventoryCountEntryInput `json:"entries"`
+}
+
+// InventoryCountEntryInput подсчитанное количество ингредиента
+type InventoryCountEntryInput struct {
+	IngredientID string  `json:"ingredientId"` // ID ингредиента или складской позиции
+	Quantity     float64 `json:"quantity"`     // В единицах ингредиента
+	Mode         string  `json:"mode"`         // "add" (по умолчанию) или "set"
+}
+
+// ApproveInventoryCountRequest запрос на утверждение инвентаризации
+type ApproveInventoryCountRequest struct {
+	Reason string `json:"reason"`
+}
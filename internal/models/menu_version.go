@@ -0,0 +1,123 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Статусы черновика меню
+const (
+	MenuDraftOpen      = "draft"
+	MenuDraftPublished = "published"
+	MenuDraftDiscarded = "discarded"
+)
+
+// MenuSnapshotItem состояние продукта в черновике или опубликованной версии меню
+type MenuSnapshotItem struct {
+	ProductID   string  `json:"productId"`
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Price       float64 `json:"price"`
+	Category    string  `json:"category"`
+	ImageURL    *string `json:"imageUrl,omitempty"`
+	Weight      *string `json:"weight,omitempty"`
+	IsVisible   bool    `json:"isVisible"`
+}
+
+// MenuSnapshot снимок меню, хранящийся в БД как JSON
+type MenuSnapshot []MenuSnapshotItem
+
+// Value сериализует снимок для записи в БД
+func (s MenuSnapshot) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]MenuSnapshotItem(s))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan читает снимок из БД
+func (s *MenuSnapshot) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]MenuSnapshotItem)(s))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]MenuSnapshotItem)(s))
+	default:
+		return fmt.Errorf("cannot scan %T into MenuSnapshot", value)
+	}
+}
+
+// MenuDraft черновик меню: изменения продуктов и категорий до публикации
+type MenuDraft struct {
+	ID          string       `gorm:"primaryKey;column:id" json:"id"`
+	Name        string       `gorm:"column:name" json:"name"`
+	Status      string       `gorm:"column:status;default:draft;index" json:"status"` // "draft", "published", "discarded"
+	Items       MenuSnapshot `gorm:"column:items;type:jsonb" json:"items"`
+	BaseVersion int          `gorm:"column:base_version" json:"baseVersion"` // Версия меню, от которой создан черновик
+	CreatedBy   *string      `gorm:"column:created_by" json:"createdBy,omitempty"`
+	CreatedAt   time.Time    `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time    `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+	PublishedAt *time.Time   `gorm:"column:published_at" json:"publishedAt,omitempty"`
+
+	// Состояние продуктов на момент создания черновика: при публикации применяются
+	// только поля, изменённые в черновике
+	BaseItems MenuSnapshot `gorm:"column:base_items;type:jsonb" json:"-"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (MenuDraft) TableName() string {
+	return "menu_drafts"
+}
+
+// MenuVersion опубликованная версия меню
+type MenuVersion struct {
+	ID          string       `gorm:"primaryKey;column:id" json:"id"`
+	Version     int          `gorm:"column:version;uniqueIndex" json:"version"`
+	Items       MenuSnapshot `gorm:"column:items;type:jsonb" json:"items,omitempty"`
+	DraftID     *string      `gorm:"column:draft_id" json:"draftId,omitempty"`
+	Note        string       `gorm:"column:note" json:"note,omitempty"`
+	PublishedBy *string      `gorm:"column:published_by" json:"publishedBy,omitempty"`
+	CreatedAt   time.Time    `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (MenuVersion) TableName() string {
+	return "menu_versions"
+}
+
+// CreateMenuDraftRequest запрос на создание черновика меню
+type CreateMenuDraftRequest struct {
+	Name string `json:"name"`
+}
+
+// UpdateMenuDraftItemRequest изменение продукта в черновике (nil — без изменений)
+type UpdateMenuDraftItemRequest struct {
+	Name        *string  `json:"name"`
+	Description *string  `json:"description"`
+	Price       *float64 `json:"price"`
+	Category    *string  `json:"category"`
+	ImageURL    *string  `json:"imageUrl"`
+	Weight      *string  `json:"weight"`
+	IsVisible   *bool    `json:"isVisible"`
+}
+
+// RenameMenuCategoryRequest переименование категории в черновике
+type RenameMenuCategoryRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MenuPreviewLink подписанная ссылка на предпросмотр черновика
+type MenuPreviewLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Аллергены (14 основных аллергенов по классификации ЕС)
+const (
+	AllergenGluten      = "gluten"
+	AllergenCrustaceans = "crustaceans"
+	AllergenEggs        = "eggs"
+	AllergenFish        = "fish"
+	AllergenPeanuts     = "peanuts"
+	AllergenSoy         = "soy"
+	AllergenMilk        = "milk"
+	AllergenNuts        = "nuts"
+	AllergenCelery      = "celery"
+	AllergenMustard     = "mustard"
+	AllergenSesame      = "sesame"
+	AllergenSulphites   = "sulphites"
+	AllergenLupin       = "lupin"
+	AllergenMolluscs    = "molluscs"
+)
+
+// KnownAllergens множество допустимых кодов аллергенов
+var KnownAllergens = map[string]bool{
+	AllergenGluten: true, AllergenCrustaceans: true, AllergenEggs: true, AllergenFish: true,
+	AllergenPeanuts: true, AllergenSoy: true, AllergenMilk: true, AllergenNuts: true,
+	AllergenCelery: true, AllergenMustard: true, AllergenSesame: true, AllergenSulphites: true,
+	AllergenLupin: true, AllergenMolluscs: true,
+}
+
+// Диетические метки продукта
+const (
+	DietaryVegetarian  = "vegetarian"
+	DietaryVegan       = "vegan"
+	DietaryGlutenFree  = "gluten_free"
+	DietaryLactoseFree = "lactose_free"
+)
+
+// StringList список строк, хранящийся в БД как JSON
+type StringList []string
+
+// Value сериализует список для записи в БД. nil сохраняется как NULL,
+// чтобы «не указано» отличалось от пустого списка
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan читает список из БД
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(l))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(l))
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", value)
+	}
+}
+
+// Contains проверяет наличие значения в списке
+func (l StringList) Contains(value string) bool {
+	for _, v := range l {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// NutritionFacts пищевая ценность
+type NutritionFacts struct {
+	Kcal    float64 `json:"kcal"`
+	Protein float64 `json:"protein"`
+	Fat     float64 `json:"fat"`
+	Carbs   float64 `json:"carbs"`
+}
+
+// ProductNutrition пищевая ценность, аллергены и диетические метки продукта,
+// рассчитанные по составу (ингредиенты + полуфабрикаты)
+type ProductNutrition struct {
+	Total       NutritionFacts  `json:"total"`             // На порцию
+	Per100g     *NutritionFacts `json:"per100g,omitempty"` // На 100 г (если известен вес)
+	WeightGrams float64         `json:"weightGrams"`       // Расчётный вес порции
+	Allergens   []string        `json:"allergens"`
+	DietaryTags []string        `json:"dietaryTags"`
+	Complete    bool            `json:"complete"` // false, если у части ингредиентов нет данных о КБЖУ или веса в штуках
+
+	AllergensKnown bool `json:"allergensKnown"` // false, если у части ингредиентов аллергены не указаны
+}
@@ -29,6 +29,20 @@ type OrderItem struct {
 	ProductID string  `gorm:"type:text;not null;column:product_id" json:"productId"`
 	Quantity  int     `gorm:"type:int;not null;column:quantity" json:"quantity"`
 	Price     float64 `gorm:"type:decimal(10,2);not null;column:price" json:"price"`
+
+	// Цена до применения ценового правила и само правило (happy hour)
+	OriginalPrice float64 `gorm:"type:decimal(10,2);column:original_price;default:0" json:"originalPrice"`
+	PricingRuleID *string `gorm:"type:text;column:pricing_rule_id" json:"pricingRuleId,omitempty"`
+
+	// Фактическая себестоимость по ценам списанных партий
+	Cost float64 `gorm:"type:decimal(10,2);column:cost;default:0" json:"cost"`
+
+	// Версия рецептуры продукта на момент заказа и плановая себестоимость по ней
+	RecipeVersionID *string `gorm:"type:text;column:recipe_version_id" json:"recipeVersionId,omitempty"`
+	RecipeCost      float64 `gorm:"type:decimal(10,2);column:recipe_cost;default:0" json:"recipeCost"`
+
+	// Версии рецептур входящих полуфабрикатов (включая вложенные) на момент заказа
+	SemiFinishedVersionIDs StringList `gorm:"type:jsonb;column:semi_finished_version_ids" json:"semiFinishedVersionIds,omitempty"`
 }
 
 // TableName указывает имя таблицы для GORM
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Источники изменения цены
+const (
+	PriceSourceInitial   = "initial"   // Цена при создании продукта
+	PriceSourceManual    = "manual"    // Изменение через админку
+	PriceSourceScheduled = "scheduled" // Применено запланированное изменение
+	PriceSourceImport    = "import"    // Массовый импорт продуктов
+	PriceSourceMenu      = "menu"      // Публикация черновика или откат версии меню
+)
+
+// Статусы запланированного изменения цены
+const (
+	ScheduledPricePending   = "pending"
+	ScheduledPriceApplied   = "applied"
+	ScheduledPriceCancelled = "cancelled"
+	ScheduledPriceFailed    = "failed" // Не применено: продукт удалён
+)
+
+// ProductPriceHistory запись истории цен продукта
+type ProductPriceHistory struct {
+	ID        string    `gorm:"primaryKey;column:id" json:"id"`
+	ProductID string    `gorm:"column:product_id;not null;index" json:"productId"`
+	OldPrice  float64   `gorm:"column:old_price;type:decimal(10,2)" json:"oldPrice"`
+	NewPrice  float64   `gorm:"column:new_price;type:decimal(10,2)" json:"newPrice"`
+	Source    string    `gorm:"column:source" json:"source"` // "initial", "manual", "scheduled"
+	ChangedBy *string   `gorm:"column:changed_by" json:"changedBy,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime;index" json:"createdAt"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (ProductPriceHistory) TableName() string {
+	return "product_price_history"
+}
+
+// ScheduledPriceChange запланированное изменение цены
+type ScheduledPriceChange struct {
+	ID          string     `gorm:"primaryKey;column:id" json:"id"`
+	ProductID   string     `gorm:"column:product_id;not null;index" json:"productId"`
+	NewPrice    float64    `gorm:"column:new_price;type:decimal(10,2)" json:"newPrice"`
+	EffectiveAt time.Time  `gorm:"column:effective_at;index" json:"effectiveAt"`
+	Status      string     `gorm:"column:status;default:pending;index" json:"status"` // "pending", "applied", "cancelled", "failed"
+	CreatedBy   *string    `gorm:"column:created_by" json:"createdBy,omitempty"`
+	AppliedAt   *time.Time `gorm:"column:applied_at" json:"appliedAt,omitempty"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+
+	FailureReason *string `gorm:"column:failure_reason" json:"failureReason,omitempty"` // Для статуса "failed"
+}
+
+// TableName указывает имя таблицы для GORM
+func (ScheduledPriceChange) TableName() string {
+	return "scheduled_price_changes"
+}
+
+// SchedulePriceRequest запрос на планирование новой цены
+type SchedulePriceRequest struct {
+	Price       float64   `json:"price"`
+	EffectiveAt time.Time `json:"effectiveAt"`
+}
+
+// PricePeriod период действия цены со статистикой продаж
+type PricePeriod struct {
+	Price         float64    `json:"price"`
+	From          time.Time  `json:"from"`
+	To            *time.Time `json:"to,omitempty"` // nil — цена действует сейчас
+	Source        string     `json:"source"`
+	UnitsSold     int64      `json:"unitsSold"`
+	Revenue       float64    `json:"revenue"`
+	OrdersCount   int64      `json:"ordersCount"`
+	UnitsPerDay   float64    `json:"unitsPerDay"`
+	RevenuePerDay float64    `json:"revenuePerDay"`
+}
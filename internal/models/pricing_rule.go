@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Типы ценовых правил
+const (
+	PricingRulePercent = "percent" // Скидка в процентах
+	PricingRuleFixed   = "fixed"   // Фиксированная цена
+)
+
+// WeekDays коды дней недели (индекс соответствует time.Weekday)
+var WeekDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// PricingRule правило цены по времени ("-20% на роллы с 14:00 до 16:00")
+type PricingRule struct {
+	ID         string     `gorm:"primaryKey;column:id" json:"id"`
+	Name       string     `gorm:"column:name" json:"name"`
+	IsActive   bool       `gorm:"column:is_active;default:true" json:"isActive"`
+	DaysOfWeek StringList `gorm:"column:days_of_week;type:jsonb" json:"daysOfWeek"` // "mon".."sun", пусто — каждый день
+	StartTime  string     `gorm:"column:start_time" json:"startTime"`               // "14:00", пусто — весь день
+	EndTime    string     `gorm:"column:end_time" json:"endTime"`                   // "16:00", может быть меньше StartTime (через полночь)
+	Category   string     `gorm:"column:category" json:"category,omitempty"`        // Область действия: категория
+	ProductIDs StringList `gorm:"column:product_ids;type:jsonb" json:"productIds"`  // и/или конкретные продукты; пусто — всё меню
+	Type       string     `gorm:"column:type" json:"type"`                          // "percent" или "fixed"
+	Value      float64    `gorm:"column:value;type:decimal(10,2)" json:"value"`
+	ValidFrom  *time.Time `gorm:"column:valid_from" json:"validFrom,omitempty"`
+	ValidTo    *time.Time `gorm:"column:valid_to" json:"validTo,omitempty"`
+	CreatedAt  time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (PricingRule) TableName() string {
+	return "pricing_rules"
+}
+
+// AppliesTo проверяет, распространяется ли правило на продукт
+func (r *PricingRule) AppliesTo(product *Product) bool {
+	if r.Category == "" && len(r.ProductIDs) == 0 {
+		return true
+	}
+	if r.ProductIDs.Contains(product.ID) {
+		return true
+	}
+	return r.Category != "" && r.Category == product.Category
+}
+
+// AppliedPricingRule краткая информация о применённом правиле для frontend
+type AppliedPricingRule struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Type  string  `json:"type"`
+	Value float64 `json:"value"`
+}
+
+// PricingRuleRequest запрос на создание/обновление ценового правила
+type PricingRuleRequest struct {
+	Name       string     `json:"name"`
+	IsActive   *bool      `json:"isActive"`
+	DaysOfWeek []string   `json:"daysOfWeek"`
+	StartTime  string     `json:"startTime"`
+	EndTime    string     `json:"endTime"`
+	Category   string     `json:"category"`
+	ProductIDs []string   `json:"productIds"`
+	Type       string     `json:"type"`
+	Value      float64    `json:"value"`
+	ValidFrom  *time.Time `json:"validFrom"`
+	ValidTo    *time.Time `json:"validTo"`
+}
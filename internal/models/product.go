@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Product модель продукта (соответствует Prisma схеме)
 type Product struct {
@@ -8,15 +12,42 @@ type Product struct {
 	Name        string    `gorm:"column:name" json:"name"`
 	Description *string   `gorm:"column:description" json:"description,omitempty"`
 	Price       float64   `gorm:"column:price;type:decimal(10,2)" json:"price"`
+	Cost        float64   `gorm:"column:cost;type:decimal(10,2);default:0" json:"cost"` // Себестоимость по рецептуре
 	ImageURL    *string   `gorm:"column:imageUrl" json:"imageUrl,omitempty"`
 	Weight      *string   `gorm:"column:weight" json:"weight,omitempty"`
 	Category    string    `gorm:"column:category" json:"category"`
+	Type        string    `gorm:"column:type;default:single" json:"type"` // "single" или "bundle"
 	IsVisible   bool      `gorm:"column:isVisible;default:false" json:"isVisible"`
 	CreatedAt   time.Time `gorm:"column:createdAt" json:"createdAt"`
 
+	// Мягкое удаление: удалённые продукты исключаются из всех запросов GORM
+	DeletedAt gorm.DeletedAt `gorm:"column:deletedAt;index" json:"deletedAt,omitempty"`
+
+	// Стоп-лист
+	IsStopListed      bool    `gorm:"column:isStopListed;default:false" json:"isStopListed"` // Ручная остановка продаж
+	StopReason        *string `gorm:"column:stopReason" json:"stopReason,omitempty"`
+	IsAvailable       bool    `gorm:"-" json:"isAvailable"`                 // Вычисляется по стоп-листу и остаткам
+	UnavailableReason string  `gorm:"-" json:"unavailableReason,omitempty"` // "stop_list" или "out_of_stock"
+
+	// Цена с учётом действующих ценовых правил (happy hour)
+	EffectivePrice float64             `gorm:"-" json:"effectivePrice"`
+	AppliedRule    *AppliedPricingRule `gorm:"-" json:"appliedRule,omitempty"`
+
+	// Средняя оценка по одобренным отзывам
+	Rating *ProductRating `gorm:"-" json:"rating,omitempty"`
+
+	// КБЖУ, аллергены и диетические метки (вычисляются по составу)
+	Nutrition *ProductNutrition `gorm:"-" json:"nutrition,omitempty"`
+
+	// Технологическая карта: технология приготовления и условия хранения
+	CookingInstructions *string `gorm:"column:cookingInstructions" json:"cookingInstructions,omitempty"`
+	StorageConditions   *string `gorm:"column:storageConditions" json:"storageConditions,omitempty"`
+
 	// Связи
 	Ingredients  []ProductIngredient   `gorm:"foreignKey:ProductID" json:"ingredients,omitempty"`
 	SemiFinished []ProductSemiFinished `gorm:"foreignKey:ProductID" json:"semiFinished,omitempty"`
+	BundleItems  []BundleItem          `gorm:"foreignKey:BundleID;constraint:OnDelete:CASCADE" json:"bundleItems,omitempty"`
+	BundleSlots  []BundleSlot          `gorm:"foreignKey:BundleID;constraint:OnDelete:CASCADE" json:"bundleSlots,omitempty"`
 }
 
 // ProductIngredient связь продукта с ингредиентом
@@ -29,6 +60,10 @@ type ProductIngredient struct {
 	Unit           string  `gorm:"column:unit" json:"unit"`
 	PricePerUnit   float64 `gorm:"column:price_per_unit;type:decimal(10,2)" json:"pricePerUnit"`
 	TotalPrice     float64 `gorm:"column:total_price;type:decimal(10,2)" json:"totalPrice"`
+
+	// Брутто или нетто и потери при тепловой обработке (см. RecipeQuantityGross)
+	QuantityType string   `gorm:"column:quantity_type;default:gross" json:"quantityType"`
+	CookingLoss  *float64 `gorm:"column:cooking_loss" json:"cookingLoss,omitempty"` // %
 }
 
 // TableName для ProductIngredient
@@ -69,6 +104,12 @@ type CreateProductRequest struct {
 	IsVisible    bool                       `json:"isVisible"`
 	Ingredients  []ProductIngredientInput   `json:"ingredients,omitempty"`
 	SemiFinished []ProductSemiFinishedInput `json:"semiFinished,omitempty"`
+	Type         string                     `json:"type,omitempty"`        // "single" (по умолчанию) или "bundle"
+	BundleItems  []BundleItemInput          `json:"bundleItems,omitempty"` // Для сетов
+	BundleSlots  []BundleSlotInput          `json:"bundleSlots,omitempty"` // Для сетов
+
+	CookingInstructions *string `json:"cookingInstructions,omitempty"`
+	StorageConditions   *string `json:"storageConditions,omitempty"`
 }
 
 // UpdateProductRequest запрос на обновление продукта
@@ -82,16 +123,24 @@ type UpdateProductRequest struct {
 	IsVisible    *bool                      `json:"isVisible"`
 	Ingredients  []ProductIngredientInput   `json:"ingredients,omitempty"`
 	SemiFinished []ProductSemiFinishedInput `json:"semiFinished,omitempty"`
+	Type         string                     `json:"type,omitempty"`        // "single" (по умолчанию) или "bundle"
+	BundleItems  []BundleItemInput          `json:"bundleItems,omitempty"` // Для сетов
+	BundleSlots  []BundleSlotInput          `json:"bundleSlots,omitempty"` // Для сетов
+
+	CookingInstructions *string `json:"cookingInstructions,omitempty"` // nil — без изменений, пустая строка очищает
+	StorageConditions   *string `json:"storageConditions,omitempty"`
 }
 
 // ProductIngredientInput входные данные для ингредиента продукта
 type ProductIngredientInput struct {
-	IngredientID   string  `json:"ingredientId" binding:"required"`
-	IngredientName string  `json:"ingredientName" binding:"required"`
-	Quantity       float64 `json:"quantity" binding:"required"`
-	Unit           string  `json:"unit" binding:"required"`
-	PricePerUnit   float64 `json:"pricePerUnit" binding:"required"`
-	TotalPrice     float64 `json:"totalPrice" binding:"required"`
+	IngredientID   string   `json:"ingredientId" binding:"required"`
+	IngredientName string   `json:"ingredientName" binding:"required"`
+	Quantity       float64  `json:"quantity" binding:"required"`
+	Unit           string   `json:"unit" binding:"required"`
+	PricePerUnit   float64  `json:"pricePerUnit" binding:"required"`
+	TotalPrice     float64  `json:"totalPrice" binding:"required"`
+	QuantityType   string   `json:"quantityType"` // "gross" (по умолчанию) или "net"
+	CookingLoss    *float64 `json:"cookingLoss"`  // Потери при тепловой обработке, %
 }
 
 // ProductSemiFinishedInput входные данные для полуфабриката продукта
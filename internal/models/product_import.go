@@ -0,0 +1,53 @@
+package models
+
+// Действия со строкой импорта продуктов
+const (
+	ImportActionCreate = "create"
+	ImportActionUpdate = "update"
+)
+
+// ProductImportRow строка импорта/экспорта продукта
+type ProductImportRow struct {
+	Name         string              `json:"name"`
+	Category     string              `json:"category"`
+	Price        float64             `json:"price"`
+	Description  string              `json:"description,omitempty"`
+	Weight       string              `json:"weight,omitempty"`
+	ImageURL     string              `json:"imageUrl,omitempty"`
+	IsVisible    *bool               `json:"isVisible,omitempty"`    // Не указано — новый продукт скрыт, существующий не меняется
+	Ingredients  []ProductImportLine `json:"ingredients,omitempty"`  // Пусто — у существующего продукта не меняется
+	SemiFinished []ProductImportLine `json:"semiFinished,omitempty"` // Пусто — у существующего продукта не меняется
+}
+
+// ProductImportLine строка состава: ингредиент или полуфабрикат по ID или названию
+type ProductImportLine struct {
+	ID       string  `json:"id,omitempty"`
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+
+	// Только для ингредиентов в JSON: брутто/нетто и потери при тепловой обработке, %
+	QuantityType string   `json:"quantityType,omitempty"`
+	CookingLoss  *float64 `json:"cookingLoss,omitempty"`
+}
+
+// ProductImportRowResult результат проверки/импорта одной строки
+type ProductImportRowResult struct {
+	Row       int      `json:"row"` // Номер строки (с 1, без заголовка CSV)
+	Name      string   `json:"name"`
+	Category  string   `json:"category"`
+	Action    string   `json:"action,omitempty"` // "create" или "update"
+	ProductID string   `json:"productId,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// ProductImportResult итог импорта продуктов
+type ProductImportResult struct {
+	DryRun  bool                     `json:"dryRun"`
+	Applied bool                     `json:"applied"` // false, если есть ошибки или dry-run
+	Total   int                      `json:"total"`
+	Created int                      `json:"created"`
+	Updated int                      `json:"updated"`
+	Failed  int                      `json:"failed"`
+	Rows    []ProductImportRowResult `json:"rows"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// Документы-основания движений по производству полуфабрикатов
+const (
+	DocumentProduction = "production" // Списание сырья на выпуск партии полуфабриката
+)
+
+// ProductionBatch партия произведённого полуфабриката: выпуск и его остаток на складе
+type ProductionBatch struct {
+	ID                string     `gorm:"primaryKey;column:id" json:"id"`
+	BatchNumber       string     `gorm:"column:batch_number" json:"batchNumber"`
+	SemiFinishedID    string     `gorm:"column:semi_finished_id;not null;index" json:"semiFinishedId"`
+	SemiFinishedName  string     `gorm:"column:semi_finished_name" json:"semiFinishedName"`
+	Batches           float64    `gorm:"column:batches;type:decimal(10,3)" json:"batches"`                      // Число закладок по рецептуре
+	OutputQuantity    float64    `gorm:"column:output_quantity;type:decimal(12,3)" json:"outputQuantity"`       // В единице выхода
+	RemainingQuantity float64    `gorm:"column:remaining_quantity;type:decimal(12,3)" json:"remainingQuantity"` // В единице выхода
+	OutputUnit        string     `gorm:"column:output_unit" json:"outputUnit"`
+	Cost              float64    `gorm:"column:cost;type:decimal(12,2)" json:"cost"`                 // Фактическая стоимость списанного сырья
+	CostPerUnit       float64    `gorm:"column:cost_per_unit;type:decimal(10,2)" json:"costPerUnit"` // За единицу выхода
+	ProducedAt        time.Time  `gorm:"column:produced_at;index" json:"producedAt"`
+	ExpiresAt         *time.Time `gorm:"column:expires_at;index" json:"expiresAt,omitempty"`
+	ProducedBy        *string    `gorm:"column:produced_by" json:"producedBy,omitempty"`
+	Note              *string    `gorm:"column:note" json:"note,omitempty"`
+	CreatedAt         time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+
+	// Для ответа
+	IsExpired bool `gorm:"-" json:"isExpired"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (ProductionBatch) TableName() string {
+	return "production_batches"
+}
+
+// SemiFinishedMovement движение остатка полуфабриката: выпуск или расход по заказу
+type SemiFinishedMovement struct {
+	ID             string    `gorm:"primaryKey;column:id" json:"id"`
+	SemiFinishedID string    `gorm:"column:semi_finished_id;not null;index" json:"semiFinishedId"`
+	BatchID        string    `gorm:"column:batch_id;not null;index" json:"batchId"`
+	Type           string    `gorm:"column:type" json:"type"` // "in" или "out"
+	Quantity       float64   `gorm:"column:quantity;type:decimal(12,3)" json:"quantity"`
+	CostPerUnit    float64   `gorm:"column:cost_per_unit;type:decimal(10,2)" json:"costPerUnit"`
+	Cost           float64   `gorm:"column:cost;type:decimal(12,2)" json:"cost"`
+	DocumentType   string    `gorm:"column:document_type;index:idx_sf_movement_document" json:"documentType"`
+	DocumentID     string    `gorm:"column:document_id;index:idx_sf_movement_document" json:"documentId"`
+	Note           *string   `gorm:"column:note" json:"note,omitempty"`
+	UserID         *string   `gorm:"column:user_id" json:"userId,omitempty"`
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (SemiFinishedMovement) TableName() string {
+	return "semi_finished_movements"
+}
+
+// ProductionRequest запрос на выпуск полуфабриката
+type ProductionRequest struct {
+	Batches   float64    `json:"batches"`   // Число закладок, по умолчанию 1
+	ExpiresAt *time.Time `json:"expiresAt"` // По умолчанию — по сроку годности полуфабриката
+	Note      string     `json:"note"`
+}
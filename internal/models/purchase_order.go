@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+// Статусы заказа поставщику
+const (
+	PurchaseOrderDraft             = "draft"              // Черновик, можно менять
+	PurchaseOrderSent              = "sent"               // Отправлен поставщику
+	PurchaseOrderPartiallyReceived = "partially_received" // Часть товара оприходована
+	PurchaseOrderReceived          = "received"           // Всё оприходовано
+	PurchaseOrderCancelled         = "cancelled"
+)
+
+// PurchaseOrder заказ поставщику
+type PurchaseOrder struct {
+	ID           string              `gorm:"primaryKey;column:id" json:"id"`
+	Number       string              `gorm:"column:number;uniqueIndex" json:"number"`
+	SupplierID   string              `gorm:"column:supplier_id;not null;index" json:"supplierId"`
+	SupplierName string              `gorm:"column:supplier_name" json:"supplierName"`
+	Status       string              `gorm:"column:status;default:draft;index" json:"status"` // "draft", "sent", "partially_received", "received", "cancelled"
+	ExpectedAt   *time.Time          `gorm:"column:expected_at" json:"expectedAt,omitempty"`  // Ожидаемая дата поставки
+	Total        float64             `gorm:"column:total;type:decimal(12,2)" json:"total"`
+	Note         *string             `gorm:"column:note" json:"note,omitempty"`
+	CreatedBy    *string             `gorm:"column:created_by" json:"createdBy,omitempty"`
+	SentAt       *time.Time          `gorm:"column:sent_at" json:"sentAt,omitempty"`
+	ReceivedAt   *time.Time          `gorm:"column:received_at" json:"receivedAt,omitempty"`
+	CreatedAt    time.Time           `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time           `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+	Lines        []PurchaseOrderLine `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"lines,omitempty"`
+
+	BelowMinimum bool `gorm:"-" json:"belowMinimum"` // Сумма меньше минимального заказа поставщика
+}
+
+// TableName указывает имя таблицы для GORM
+func (PurchaseOrder) TableName() string {
+	return "purchase_orders"
+}
+
+// PurchaseOrderLine строка заказа поставщику
+type PurchaseOrderLine struct {
+	ID               string  `gorm:"primaryKey;column:id" json:"id"`
+	OrderID          string  `gorm:"column:order_id;not null;index" json:"orderId"`
+	StockItemID      string  `gorm:"column:stock_item_id;not null" json:"stockItemId"`
+	IngredientID     string  `gorm:"column:ingredient_id;not null" json:"ingredientId"`
+	IngredientName   string  `gorm:"column:ingredient_name" json:"ingredientName"`
+	Unit             string  `gorm:"column:unit" json:"unit"`
+	Quantity         float64 `gorm:"column:quantity;type:decimal(12,3)" json:"quantity"`                  // В единицах ингредиента
+	ReceivedQuantity float64 `gorm:"column:received_quantity;type:decimal(12,3)" json:"receivedQuantity"` // По проведённым накладным
+	PricePerUnit     float64 `gorm:"column:price_per_unit;type:decimal(10,2)" json:"pricePerUnit"`        // За кг/л/шт
+	Total            float64 `gorm:"column:total;type:decimal(12,2)" json:"total"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (PurchaseOrderLine) TableName() string {
+	return "purchase_order_lines"
+}
+
+// PurchaseOrderRequest запрос на создание/изменение заказа поставщику
+type PurchaseOrderRequest struct {
+	SupplierID string                   `json:"supplierId"`
+	ExpectedAt *time.Time               `json:"expectedAt"`
+	Note       string                   `json:"note"`
+	Lines      []PurchaseOrderLineInput `json:"lines"`
+}
+
+// PurchaseOrderLineInput строка заказа во входящем запросе
+type PurchaseOrderLineInput struct {
+	IngredientID string   `json:"ingredientId"` // ID ингредиента или складской позиции
+	Quantity     float64  `json:"quantity"`
+	PricePerUnit *float64 `json:"pricePerUnit"` // Пусто — цена из прайс-листа поставщика
+}
+
+// GeneratePurchaseOrdersRequest запрос на формирование заказов по низким остаткам
+type GeneratePurchaseOrdersRequest struct {
+	IngredientIDs []string `json:"ingredientIds"` // Пусто — все позиции ниже точки заказа
+}
+
+// GeneratedPurchaseOrders результат формирования заказов по низким остаткам
+type GeneratedPurchaseOrders struct {
+	Orders     []PurchaseOrder `json:"orders"`
+	Unassigned []LowStockItem  `json:"unassigned"` // Позиции без поставщика в прайс-листах
+	Skipped    []LowStockItem  `json:"skipped"`    // Уже есть в открытых заказах
+}
+
+// ReceivePurchaseOrderRequest приёмка заказа: формирует приходную накладную
+type ReceivePurchaseOrderRequest struct {
+	InvoiceNumber string                     `json:"invoiceNumber"`
+	InvoiceDate   time.Time                  `json:"invoiceDate"`
+	Note          string                     `json:"note"`
+	Lines         []ReceivePurchaseOrderLine `json:"lines"` // Пусто — весь недопоставленный остаток по цене заказа
+	Post          *bool                      `json:"post"`  // Провести накладную сразу (по умолчанию да)
+}
+
+// ReceivePurchaseOrderLine фактически поставленное количество по строке заказа
+type ReceivePurchaseOrderLine struct {
+	LineID       string     `json:"lineId"`
+	Quantity     float64    `json:"quantity"`
+	PricePerUnit *float64   `json:"pricePerUnit"` // Пусто — цена заказа
+	ExpiresAt    *time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// Типы узлов дерева рецептуры
+const (
+	RecipeNodeSemiFinished = "semi_finished"
+	RecipeNodeIngredient   = "ingredient"
+)
+
+// RecipeTreeNode узел полностью раскрытой рецептуры полуфабриката.
+// Количества и стоимость — на выход корневого полуфабриката.
+type RecipeTreeNode struct {
+	Type         string           `json:"type"` // "semi_finished" или "ingredient"
+	ID           string           `json:"id"`
+	Name         string           `json:"name"`
+	Quantity     float64          `json:"quantity"`
+	Unit         string           `json:"unit"`
+	QuantityType string           `json:"quantityType,omitempty"` // Для ингредиентов: брутто или нетто
+	Cost         float64          `json:"cost"`
+	CostPerUnit  float64          `json:"costPerUnit,omitempty"` // Для полуфабрикатов: за единицу выхода
+	Children     []RecipeTreeNode `json:"children,omitempty"`
+}
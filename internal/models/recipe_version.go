@@ -0,0 +1,148 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RecipeSnapshotIngredient строка сырья в снимке рецептуры
+type RecipeSnapshotIngredient struct {
+	IngredientID   string   `json:"ingredientId"`
+	IngredientName string   `json:"ingredientName"`
+	Quantity       float64  `json:"quantity"`
+	Unit           string   `json:"unit"`
+	QuantityType   string   `json:"quantityType"`
+	CookingLoss    *float64 `json:"cookingLoss,omitempty"`
+	PricePerUnit   float64  `json:"pricePerUnit"`
+	TotalPrice     float64  `json:"totalPrice"`
+}
+
+// RecipeSnapshotSemiFinished строка полуфабриката в снимке рецептуры
+// (для полуфабриката — вложенный полуфабрикат)
+type RecipeSnapshotSemiFinished struct {
+	SemiFinishedID   string  `json:"semiFinishedId"`
+	SemiFinishedName string  `json:"semiFinishedName"`
+	Quantity         float64 `json:"quantity"`
+	Unit             string  `json:"unit"`
+	CostPerUnit      float64 `json:"costPerUnit"`
+	TotalCost        float64 `json:"totalCost"`
+}
+
+// RecipeSnapshot состав рецептуры продукта или полуфабриката, хранящийся в БД как JSON
+type RecipeSnapshot struct {
+	OutputQuantity float64                      `json:"outputQuantity,omitempty"` // Только для полуфабриката
+	OutputUnit     string                       `json:"outputUnit,omitempty"`
+	Ingredients    []RecipeSnapshotIngredient   `json:"ingredients"`
+	SemiFinished   []RecipeSnapshotSemiFinished `json:"semiFinished"`
+}
+
+// Value сериализует снимок для записи в БД
+func (s RecipeSnapshot) Value() (driver.Value, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan читает снимок из БД
+func (s *RecipeSnapshot) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*s = RecipeSnapshot{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("cannot scan %T into RecipeSnapshot", value)
+	}
+}
+
+// RecipeVersion сохранённая версия рецептуры продукта или полуфабриката
+type RecipeVersion struct {
+	ID         string         `gorm:"primaryKey;column:id" json:"id"`
+	EntityType string         `gorm:"column:entity_type;not null;uniqueIndex:idx_recipe_version" json:"entityType"` // "product" или "semi_finished"
+	EntityID   string         `gorm:"column:entity_id;not null;uniqueIndex:idx_recipe_version" json:"entityId"`
+	Version    int            `gorm:"column:version;not null;uniqueIndex:idx_recipe_version" json:"version"`
+	Recipe     RecipeSnapshot `gorm:"column:recipe;type:jsonb" json:"recipe,omitempty"`
+	Cost       float64        `gorm:"column:cost;type:decimal(10,2)" json:"cost"` // Себестоимость порции продукта или единицы выхода полуфабриката
+	Note       string         `gorm:"column:note" json:"note,omitempty"`
+	CreatedBy  *string        `gorm:"column:created_by" json:"createdBy,omitempty"`
+	CreatedAt  time.Time      `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (RecipeVersion) TableName() string {
+	return "recipe_versions"
+}
+
+// Виды изменения строки между версиями рецептуры
+const (
+	RecipeLineAdded   = "added"
+	RecipeLineRemoved = "removed"
+	RecipeLineChanged = "changed"
+)
+
+// RecipeDiffValue состояние строки рецептуры в одной из сравниваемых версий
+type RecipeDiffValue struct {
+	Quantity     float64  `json:"quantity"`
+	Unit         string   `json:"unit"`
+	QuantityType string   `json:"quantityType,omitempty"`
+	CookingLoss  *float64 `json:"cookingLoss,omitempty"`
+	Cost         float64  `json:"cost"`
+}
+
+// RecipeLineDiff изменение строки рецептуры между версиями
+type RecipeLineDiff struct {
+	Kind   string           `json:"kind"` // "ingredient" или "semi_finished"
+	ID     string           `json:"id"`
+	Name   string           `json:"name"`
+	Change string           `json:"change"` // "added", "removed" или "changed"
+	Before *RecipeDiffValue `json:"before,omitempty"`
+	After  *RecipeDiffValue `json:"after,omitempty"`
+}
+
+// RecipeVersionDiff различия двух версий рецептуры
+type RecipeVersionDiff struct {
+	EntityType  string           `json:"entityType"`
+	EntityID    string           `json:"entityId"`
+	FromVersion int              `json:"fromVersion"`
+	ToVersion   int              `json:"toVersion"`
+	FromOutput  string           `json:"fromOutput,omitempty"` // Выход полуфабриката, если изменился
+	ToOutput    string           `json:"toOutput,omitempty"`
+	FromCost    float64          `json:"fromCost"`
+	ToCost      float64          `json:"toCost"`
+	Lines       []RecipeLineDiff `json:"lines"`
+}
+
+// OrderItemCosting себестоимость позиции заказа по рецептуре, действовавшей на момент заказа
+type OrderItemCosting struct {
+	OrderItemID     string  `json:"orderItemId"`
+	ProductID       string  `json:"productId"`
+	ProductName     string  `json:"productName"`
+	Quantity        int     `json:"quantity"`
+	Revenue         float64 `json:"revenue"`
+	RecipeVersionID *string `json:"recipeVersionId,omitempty"`
+	RecipeVersion   *int    `json:"recipeVersion,omitempty"`
+	RecipeCost      float64 `json:"recipeCost"` // Плановая себестоимость по рецептуре
+	ActualCost      float64 `json:"actualCost"` // Фактическая по ценам списанных партий
+	Variance        float64 `json:"variance"`   // Факт − план
+
+	// Версии полуфабрикатов, по которым готовилась позиция
+	SemiFinishedVersionIDs []string `json:"semiFinishedVersionIds,omitempty"`
+}
+
+// OrderCosting себестоимость заказа по позициям
+type OrderCosting struct {
+	OrderID    string             `json:"orderId"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	Items      []OrderItemCosting `json:"items"`
+	Revenue    float64            `json:"revenue"`
+	RecipeCost float64            `json:"recipeCost"`
+	ActualCost float64            `json:"actualCost"`
+	Variance   float64            `json:"variance"`
+}
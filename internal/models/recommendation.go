@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ProductRecommendation рекомендация "хорошо сочетается с" по совместным покупкам
+type ProductRecommendation struct {
+	ID            string    `gorm:"primaryKey;column:id" json:"id"`
+	ProductID     string    `gorm:"column:product_id;not null;uniqueIndex:idx_recommendation_pair;index" json:"productId"`
+	RecommendedID string    `gorm:"column:recommended_id;not null;uniqueIndex:idx_recommendation_pair" json:"recommendedId"`
+	Score         float64   `gorm:"column:score" json:"score"`          // Сила связи с учётом давности заказов (0..1)
+	PairCount     int64     `gorm:"column:pair_count" json:"pairCount"` // Количество заказов с обоими продуктами
+	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updatedAt"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (ProductRecommendation) TableName() string {
+	return "product_recommendations"
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Статусы модерации отзыва
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// ProductReview отзыв и оценка продукта от пользователя
+type ProductReview struct {
+	ID             string     `gorm:"primaryKey;column:id" json:"id"`
+	ProductID      string     `gorm:"column:product_id;not null;uniqueIndex:idx_review_product_user;index" json:"productId"`
+	UserID         string     `gorm:"column:user_id;not null;uniqueIndex:idx_review_product_user" json:"userId"`
+	OrderID        string     `gorm:"column:order_id" json:"orderId"` // Доставленный заказ, подтверждающий покупку
+	Rating         int        `gorm:"column:rating;not null" json:"rating"`
+	Text           string     `gorm:"column:text;type:text" json:"text,omitempty"`
+	Status         string     `gorm:"column:status;default:pending;index" json:"status"` // "pending", "approved", "rejected"
+	ModerationNote *string    `gorm:"column:moderation_note" json:"moderationNote,omitempty"`
+	ModeratedBy    *string    `gorm:"column:moderated_by" json:"moderatedBy,omitempty"`
+	ModeratedAt    *time.Time `gorm:"column:moderated_at" json:"moderatedAt,omitempty"`
+	CreatedAt      time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+
+	// Для ответа: имя автора
+	UserName string `gorm:"-" json:"userName,omitempty"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (ProductReview) TableName() string {
+	return "product_reviews"
+}
+
+// ProductRating средняя оценка продукта по одобренным отзывам
+type ProductRating struct {
+	Average float64 `json:"average"`
+	Count   int64   `json:"count"`
+}
+
+// CreateReviewRequest запрос на создание отзыва
+type CreateReviewRequest struct {
+	Rating int    `json:"rating"`
+	Text   string `json:"text"`
+}
+
+// ModerateReviewRequest запрос на модерацию отзыва
+type ModerateReviewRequest struct {
+	Status string `json:"status"` // "approved" или "rejected"
+	Note   string `json:"note"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// ProductSearchResult продукт в результатах поиска с оценкой релевантности
+type ProductSearchResult struct {
+	Product
+	Relevance float64 `json:"relevance"`
+}
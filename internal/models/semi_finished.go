@@ -2,8 +2,9 @@ package models
 
 import (
 	"math"
-	"strings"
 	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
 )
 
 // SemiFinished представляет полуфабрикат
@@ -18,10 +19,17 @@ type SemiFinished struct {
 	Category       string                   `gorm:"column:category" json:"category"`
 	IsVisible      bool                     `gorm:"column:is_visible;default:true" json:"isVisible"`
 	IsArchived     bool                     `gorm:"column:is_archived;default:false" json:"isArchived"`
+	StockQuantity  float64                  `gorm:"column:stock_quantity;default:0" json:"stockQuantity"`    // Остаток произведённого, в единице выхода
+	ShelfLifeHours *int                     `gorm:"column:shelf_life_hours" json:"shelfLifeHours,omitempty"` // Срок годности партии
 	CreatedAt      time.Time                `gorm:"column:created_at" json:"createdAt"`
 	UpdatedAt      time.Time                `gorm:"column:updated_at" json:"updatedAt"`
 	DeletedAt      *time.Time               `gorm:"column:deleted_at" json:"deletedAt,omitempty"`
 	Ingredients    []SemiFinishedIngredient `gorm:"foreignKey:SemiFinishedID;constraint:OnDelete:CASCADE" json:"ingredients,omitempty"`
+	Components     []SemiFinishedComponent  `gorm:"foreignKey:SemiFinishedID;constraint:OnDelete:CASCADE" json:"components,omitempty"`
+
+	// Технологическая карта: технология приготовления и условия хранения
+	CookingInstructions *string `gorm:"column:cooking_instructions" json:"cookingInstructions,omitempty"`
+	StorageConditions   *string `gorm:"column:storage_conditions" json:"storageConditions,omitempty"`
 }
 
 // TableName указывает имя таблицы для GORM
@@ -39,6 +47,10 @@ type SemiFinishedIngredient struct {
 	Unit           string  `gorm:"column:unit" json:"unit"`
 	PricePerUnit   float64 `gorm:"column:price_per_unit" json:"pricePerUnit"`
 	TotalPrice     float64 `gorm:"column:total_price" json:"totalPrice"`
+
+	// Брутто или нетто и потери при тепловой обработке (см. RecipeQuantityGross)
+	QuantityType string   `gorm:"column:quantity_type;default:gross" json:"quantityType"`
+	CookingLoss  *float64 `gorm:"column:cooking_loss" json:"cookingLoss,omitempty"` // %
 }
 
 // TableName указывает имя таблицы для GORM
@@ -46,6 +58,23 @@ func (SemiFinishedIngredient) TableName() string {
 	return "semi_finished_ingredients"
 }
 
+// SemiFinishedComponent вложенный полуфабрикат в составе полуфабриката (например, базовый майонез в соусе)
+type SemiFinishedComponent struct {
+	ID             string  `gorm:"column:id;primaryKey" json:"id"`
+	SemiFinishedID string  `gorm:"column:semi_finished_id;not null;index" json:"semiFinishedId"`
+	ComponentID    string  `gorm:"column:component_id;not null;index" json:"componentId"`
+	ComponentName  string  `gorm:"column:component_name" json:"componentName"`
+	Quantity       float64 `gorm:"column:quantity;type:decimal(10,3)" json:"quantity"`
+	Unit           string  `gorm:"column:unit" json:"unit"`
+	CostPerUnit    float64 `gorm:"column:cost_per_unit;type:decimal(10,2)" json:"costPerUnit"` // За единицу выхода вложенного полуфабриката
+	TotalCost      float64 `gorm:"column:total_cost;type:decimal(10,2)" json:"totalCost"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (SemiFinishedComponent) TableName() string {
+	return "semi_finished_components"
+}
+
 // CreateSemiFinishedRequest запрос на создание полуфабриката
 type CreateSemiFinishedRequest struct {
 	Name           string                        `json:"name"`
@@ -53,17 +82,34 @@ type CreateSemiFinishedRequest struct {
 	OutputQuantity float64                       `json:"outputQuantity"`
 	OutputUnit     string                        `json:"outputUnit"`
 	Category       string                        `json:"category"`
+	ShelfLifeHours *int                          `json:"shelfLifeHours"`
 	Ingredients    []SemiFinishedIngredientInput `json:"ingredients"`
+	Components     []SemiFinishedComponentInput  `json:"components"`
+
+	CookingInstructions string `json:"cookingInstructions"`
+	StorageConditions   string `json:"storageConditions"`
 }
 
 // SemiFinishedIngredientInput входные данные для ингредиента полуфабриката
 type SemiFinishedIngredientInput struct {
-	IngredientID   string  `json:"ingredientId"`
-	IngredientName string  `json:"ingredientName"`
-	Quantity       float64 `json:"quantity"`
-	Unit           string  `json:"unit"`
-	PricePerUnit   float64 `json:"pricePerUnit"`
-	TotalPrice     float64 `json:"totalPrice"`
+	IngredientID   string   `json:"ingredientId"`
+	IngredientName string   `json:"ingredientName"`
+	Quantity       float64  `json:"quantity"`
+	Unit           string   `json:"unit"`
+	PricePerUnit   float64  `json:"pricePerUnit"`
+	TotalPrice     float64  `json:"totalPrice"`
+	QuantityType   string   `json:"quantityType"` // "gross" (по умолчанию) или "net"
+	CookingLoss    *float64 `json:"cookingLoss"`  // Потери при тепловой обработке, %
+}
+
+// SemiFinishedComponentInput входные данные для вложенного полуфабриката
+type SemiFinishedComponentInput struct {
+	ComponentID   string  `json:"componentId"`
+	ComponentName string  `json:"componentName"`
+	Quantity      float64 `json:"quantity"`
+	Unit          string  `json:"unit"`
+	CostPerUnit   float64 `json:"costPerUnit"` // Рассчитывается на сервере
+	TotalCost     float64 `json:"totalCost"`   // Рассчитывается на сервере
 }
 
 // UpdateSemiFinishedRequest запрос на обновление полуфабриката
@@ -73,7 +119,12 @@ type UpdateSemiFinishedRequest struct {
 	OutputQuantity *float64                      `json:"outputQuantity,omitempty"`
 	OutputUnit     *string                       `json:"outputUnit,omitempty"`
 	Category       *string                       `json:"category,omitempty"`
+	ShelfLifeHours *int                          `json:"shelfLifeHours,omitempty"` // 0 — без срока годности
 	Ingredients    []SemiFinishedIngredientInput `json:"ingredients,omitempty"`
+	Components     []SemiFinishedComponentInput  `json:"components,omitempty"` // Пустой массив удаляет вложенные полуфабрикаты
+
+	CookingInstructions *string `json:"cookingInstructions,omitempty"` // Пустая строка очищает
+	StorageConditions   *string `json:"storageConditions,omitempty"`
 }
 
 // normalizeFloat округляет число до указанного количества знаков
@@ -91,19 +142,7 @@ func (sf *SemiFinished) Normalize() {
 
 // NormalizeUnit приводит единицу измерения к стандартному виду
 func (sfi *SemiFinishedIngredient) NormalizeUnit() {
-	unit := strings.ToLower(sfi.Unit)
-	switch {
-	case strings.Contains(unit, "гр") || strings.Contains(unit, "gram"):
-		sfi.Unit = "g"
-	case strings.Contains(unit, "кг") || strings.Contains(unit, "kg") || strings.Contains(unit, "килограмм"):
-		sfi.Unit = "kg"
-	case strings.Contains(unit, "мл") || strings.Contains(unit, "ml") || strings.Contains(unit, "миллилитр"):
-		sfi.Unit = "ml"
-	case strings.Contains(unit, "литр") || unit == "л" || unit == "l":
-		sfi.Unit = "l"
-	case strings.Contains(unit, "шт") || strings.Contains(unit, "pcs") || strings.Contains(unit, "штук"):
-		sfi.Unit = "pcs"
-	}
+	sfi.Unit = units.Normalize(sfi.Unit)
 }
 
 // NormalizeIngredient нормализует все числовые значения ингредиента
@@ -0,0 +1,16 @@
+package models
+
+// LowStockItem ингредиент, остаток которого опустился до точки заказа
+type LowStockItem struct {
+	StockItemID     string   `json:"stockItemId"`
+	IngredientID    string   `json:"ingredientId"`
+	IngredientName  string   `json:"ingredientName"`
+	Unit            string   `json:"unit"`
+	Quantity        float64  `json:"quantity"`
+	MinStock        float64  `json:"minStock"`
+	ReorderQuantity *float64 `json:"reorderQuantity,omitempty"`
+	SuggestedOrder  float64  `json:"suggestedOrder"` // Рекомендуемый объём заказа в единицах ингредиента
+	Supplier        *string  `json:"supplier,omitempty"`
+	PricePerUnit    *float64 `json:"pricePerUnit,omitempty"`
+	EstimatedCost   float64  `json:"estimatedCost"` // Ориентировочная стоимость заказа по текущей цене
+}
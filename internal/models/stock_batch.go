@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Правила списания партий
+const (
+	ConsumptionFIFO = "fifo" // Сначала самые ранние поступления
+	ConsumptionFEFO = "fefo" // Сначала партии с ближайшим сроком годности
+)
+
+// Документы-основания движений по заказам
+const (
+	DocumentOrder       = "order"        // Списание ингредиентов по заказу
+	DocumentOrderReturn = "order_return" // Возврат на склад при отмене заказа
+)
+
+// StockBatch партия ингредиента на складе
+type StockBatch struct {
+	ID                string     `gorm:"primaryKey;column:id" json:"id"`
+	StockItemID       string     `gorm:"column:stock_item_id;not null;index" json:"stockItemId"`
+	IngredientID      string     `gorm:"column:ingredient_id;not null;index" json:"ingredientId"`
+	BatchNumber       string     `gorm:"column:batch_number" json:"batchNumber"`
+	ReceivedAt        time.Time  `gorm:"column:received_at;index" json:"receivedAt"`
+	ExpiresAt         *time.Time `gorm:"column:expires_at;index" json:"expiresAt,omitempty"`
+	InitialQuantity   float64    `gorm:"column:initial_quantity;type:decimal(12,3)" json:"initialQuantity"`     // В единицах ингредиента
+	RemainingQuantity float64    `gorm:"column:remaining_quantity;type:decimal(12,3)" json:"remainingQuantity"` // В единицах ингредиента
+	PricePerUnit      float64    `gorm:"column:price_per_unit;type:decimal(10,2)" json:"pricePerUnit"`          // Закупочная цена за кг/л/шт
+	DocumentType      *string    `gorm:"column:document_type" json:"documentType,omitempty"`
+	DocumentID        *string    `gorm:"column:document_id;index" json:"documentId,omitempty"`
+	CreatedAt         time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+
+	// Для ответа
+	IngredientName string `gorm:"-" json:"ingredientName,omitempty"`
+	Unit           string `gorm:"-" json:"unit,omitempty"`
+	DaysLeft       *int   `gorm:"-" json:"daysLeft,omitempty"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (StockBatch) TableName() string {
+	return "stock_batches"
+}
+
+// StockBatchConsumption списание из конкретной партии в рамках движения "out"
+type StockBatchConsumption struct {
+	ID           string    `gorm:"primaryKey;column:id" json:"id"`
+	MovementID   string    `gorm:"column:movement_id;not null;index" json:"movementId"`
+	StockItemID  string    `gorm:"column:stock_item_id;not null" json:"stockItemId"`
+	BatchID      *string   `gorm:"column:batch_id;index" json:"batchId,omitempty"` // nil — остаток без партии (до учёта партий)
+	Quantity     float64   `gorm:"column:quantity;type:decimal(12,3)" json:"quantity"`
+	PricePerUnit float64   `gorm:"column:price_per_unit;type:decimal(10,2)" json:"pricePerUnit"`
+	Cost         float64   `gorm:"column:cost;type:decimal(12,2)" json:"cost"`
+	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (StockBatchConsumption) TableName() string {
+	return "stock_batch_consumptions"
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// StockJournalFilter фильтры журнала движений склада
+type StockJournalFilter struct {
+	IngredientID string // ID ингредиента или складской позиции
+	Type         string // "in", "out", ...
+	DocumentType string // "order", "goods_receipt", "write_off", ...
+	DocumentID   string
+	UserID       string
+	From         *time.Time
+	To           *time.Time
+}
+
+// StockJournalEntry строка журнала движений с остатком позиции после движения
+type StockJournalEntry struct {
+	ID             string    `gorm:"column:id" json:"id"`
+	StockItemID    string    `gorm:"column:stock_item_id" json:"stockItemId"`
+	IngredientID   string    `gorm:"column:ingredient_id" json:"ingredientId"`
+	IngredientName string    `gorm:"column:ingredient_name" json:"ingredientName"`
+	Unit           string    `gorm:"column:unit" json:"unit"`
+	Type           string    `gorm:"column:type" json:"type"`
+	Quantity       float64   `gorm:"column:quantity" json:"quantity"`
+	Delta          float64   `gorm:"column:delta" json:"delta"`                // Изменение остатка со знаком
+	BalanceAfter   float64   `gorm:"column:balance_after" json:"balanceAfter"` // Остаток позиции после движения
+	PriceBrutto    *float64  `gorm:"column:price_brutto" json:"priceBrutto,omitempty"`
+	PriceNetto     *float64  `gorm:"column:price_netto" json:"priceNetto,omitempty"`
+	Note           *string   `gorm:"column:note" json:"note,omitempty"`
+	DocumentType   *string   `gorm:"column:document_type" json:"documentType,omitempty"`
+	DocumentID     *string   `gorm:"column:document_id" json:"documentId,omitempty"`
+	UserID         *string   `gorm:"column:user_id" json:"userId,omitempty"`
+	UserName       *string   `gorm:"column:user_name" json:"userName,omitempty"`
+	CreatedAt      time.Time `gorm:"column:created_at" json:"createdAt"`
+}
+
+// StockJournalPage страница журнала движений
+type StockJournalPage struct {
+	Entries    []StockJournalEntry `json:"entries"`
+	Page       int                 `json:"page"`
+	PageSize   int                 `json:"pageSize"`
+	Total      int64               `json:"total"`
+	TotalPages int                 `json:"totalPages"`
+}
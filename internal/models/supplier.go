@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Supplier поставщик
+type Supplier struct {
+	ID             string     `gorm:"primaryKey;column:id" json:"id"`
+	Name           string     `gorm:"column:name;uniqueIndex" json:"name"`
+	ContactName    *string    `gorm:"column:contact_name" json:"contactName,omitempty"`
+	Phone          *string    `gorm:"column:phone" json:"phone,omitempty"`
+	Email          *string    `gorm:"column:email" json:"email,omitempty"`
+	Address        *string    `gorm:"column:address" json:"address,omitempty"`
+	LeadTimeDays   int        `gorm:"column:lead_time_days;default:1" json:"leadTimeDays"`              // Срок поставки с момента заказа
+	DeliveryDays   StringList `gorm:"column:delivery_days;type:jsonb" json:"deliveryDays"`              // "mon".."sun", пусто — любой день
+	MinOrderAmount float64    `gorm:"column:min_order_amount;type:decimal(12,2)" json:"minOrderAmount"` // Минимальная сумма заказа
+	Note           *string    `gorm:"column:note" json:"note,omitempty"`
+	IsActive       bool       `gorm:"column:is_active;default:true" json:"isActive"`
+	CreatedAt      time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+
+	Prices []SupplierPrice `gorm:"foreignKey:SupplierID;constraint:OnDelete:CASCADE" json:"prices,omitempty"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (Supplier) TableName() string {
+	return "suppliers"
+}
+
+// SupplierPrice строка прайс-листа поставщика по ингредиенту
+type SupplierPrice struct {
+	ID             string    `gorm:"primaryKey;column:id" json:"id"`
+	SupplierID     string    `gorm:"column:supplier_id;not null;uniqueIndex:idx_supplier_price_item" json:"supplierId"`
+	StockItemID    string    `gorm:"column:stock_item_id;not null;uniqueIndex:idx_supplier_price_item" json:"stockItemId"`
+	IngredientID   string    `gorm:"column:ingredient_id;not null;index" json:"ingredientId"`
+	IngredientName string    `gorm:"column:ingredient_name" json:"ingredientName"`
+	Unit           string    `gorm:"column:unit" json:"unit"`
+	PricePerUnit   float64   `gorm:"column:price_per_unit;type:decimal(10,2)" json:"pricePerUnit"` // За кг/л/шт
+	PackSize       *float64  `gorm:"column:pack_size" json:"packSize,omitempty"`                   // Кратность заказа в единицах ингредиента
+	SupplierSKU    *string   `gorm:"column:supplier_sku" json:"supplierSku,omitempty"`
+	IsPreferred    bool      `gorm:"column:is_preferred;default:false" json:"isPreferred"` // Основной поставщик ингредиента
+	UpdatedAt      time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (SupplierPrice) TableName() string {
+	return "supplier_prices"
+}
+
+// SupplierRequest запрос на создание/обновление поставщика
+type SupplierRequest struct {
+	Name           string   `json:"name"`
+	ContactName    string   `json:"contactName"`
+	Phone          string   `json:"phone"`
+	Email          string   `json:"email"`
+	Address        string   `json:"address"`
+	LeadTimeDays   int      `json:"leadTimeDays"`
+	DeliveryDays   []string `json:"deliveryDays"`
+	MinOrderAmount float64  `json:"minOrderAmount"`
+	Note           string   `json:"note"`
+	IsActive       *bool    `json:"isActive"`
+}
+
+// SupplierPriceRequest запрос на добавление/изменение цены в прайс-листе
+type SupplierPriceRequest struct {
+	IngredientID string   `json:"ingredientId"` // ID ингредиента или складской позиции
+	PricePerUnit float64  `json:"pricePerUnit"`
+	PackSize     *float64 `json:"packSize"`
+	SupplierSKU  string   `json:"supplierSku"`
+	IsPreferred  bool     `json:"isPreferred"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// TechCardLine строка технологической карты: сырьё или полуфабрикат
+// с массой брутто и нетто (в единице строки рецептуры)
+type TechCardLine struct {
+	Kind               string   `json:"kind"` // "ingredient" или "semi_finished"
+	ID                 string   `json:"id"`
+	Name               string   `json:"name"`
+	Unit               string   `json:"unit"`
+	GrossQuantity      float64  `json:"grossQuantity"`
+	NetQuantity        float64  `json:"netQuantity"`
+	TrimLossPercent    float64  `json:"trimLossPercent,omitempty"`    // Отходы при холодной обработке
+	CookingLossPercent float64  `json:"cookingLossPercent,omitempty"` // Потери при тепловой обработке
+	Allergens          []string `json:"allergens"`
+}
+
+// TechCard технологическая карта продукта или полуфабриката для санитарной проверки
+type TechCard struct {
+	EntityType          string            `json:"entityType"` // "product" или "semi_finished"
+	ID                  string            `json:"id"`
+	Name                string            `json:"name"`
+	Category            string            `json:"category"`
+	Description         *string           `json:"description,omitempty"`
+	RecipeVersion       *int              `json:"recipeVersion,omitempty"` // Действующая версия рецептуры
+	Lines               []TechCardLine    `json:"lines"`
+	YieldQuantity       float64           `json:"yieldQuantity"` // Выход: для полуфабриката — заявленный, для продукта — сумма нетто в граммах
+	YieldUnit           string            `json:"yieldUnit"`
+	YieldComplete       bool              `json:"yieldComplete"`            // false, если часть строк не переводится в граммы
+	DeclaredWeight      *string           `json:"declaredWeight,omitempty"` // Вес порции, указанный в меню
+	CookingInstructions *string           `json:"cookingInstructions,omitempty"`
+	StorageConditions   *string           `json:"storageConditions,omitempty"`
+	ShelfLifeHours      *int              `json:"shelfLifeHours,omitempty"`
+	Allergens           []string          `json:"allergens"`
+	AllergensComplete   bool              `json:"allergensComplete"` // false, если у части ингредиентов аллергены не указаны
+	Nutrition           *ProductNutrition `json:"nutrition,omitempty"`
+	GeneratedAt         time.Time         `json:"generatedAt"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// IngredientUnitConversion коэффициент перевода единиц конкретного ингредиента:
+// 1 FromUnit = Factor ToUnit (1 pcs авокадо = 180 g, 1 l масла = 0.92 kg)
+type IngredientUnitConversion struct {
+	ID           string    `gorm:"primaryKey;column:id" json:"id"`
+	IngredientID string    `gorm:"column:ingredient_id;not null;uniqueIndex:idx_ingredient_unit_conversion" json:"ingredientId"`
+	FromUnit     string    `gorm:"column:from_unit;not null;uniqueIndex:idx_ingredient_unit_conversion" json:"fromUnit"`
+	ToUnit       string    `gorm:"column:to_unit;not null;uniqueIndex:idx_ingredient_unit_conversion" json:"toUnit"`
+	Factor       float64   `gorm:"column:factor;type:decimal(14,6);not null" json:"factor"`
+	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (IngredientUnitConversion) TableName() string {
+	return "ingredient_unit_conversions"
+}
+
+// UnitConversionRequest запрос на добавление/изменение коэффициента перевода
+type UnitConversionRequest struct {
+	FromUnit string  `json:"fromUnit"`
+	ToUnit   string  `json:"toUnit"`
+	Factor   float64 `json:"factor"`
+}
+
+// UnitInfo описание единицы измерения для справочника
+type UnitInfo struct {
+	Code      string `json:"code"`
+	Dimension string `json:"dimension"` // "mass", "volume", "count"
+	BaseUnit  string `json:"baseUnit"`
+}
+
+// UnitConversionResult результат перевода количества для ингредиента
+type UnitConversionResult struct {
+	IngredientID string  `json:"ingredientId"`
+	Quantity     float64 `json:"quantity"`
+	FromUnit     string  `json:"fromUnit"`
+	Result       float64 `json:"result"`
+	ToUnit       string  `json:"toUnit"`
+}
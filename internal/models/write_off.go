@@ -0,0 +1,95 @@
+package models
+
+import "time"
+
+// DocumentWriteOff документ-основание списания порчи и потерь
+const DocumentWriteOff = "write_off"
+
+// Причины списания
+const (
+	WriteOffExpired   = "expired"    // Истёк срок годности
+	WriteOffSpoiled   = "spoiled"    // Испорчено
+	WriteOffStaffMeal = "staff_meal" // Питание персонала
+	WriteOffTasting   = "tasting"    // Дегустация, проработка
+	WriteOffDamaged   = "damaged"    // Уронили, повредили
+)
+
+// WriteOffReasons допустимые причины списания с описанием
+var WriteOffReasons = map[string]string{
+	WriteOffExpired:   "Истёк срок годности",
+	WriteOffSpoiled:   "Испорчено",
+	WriteOffStaffMeal: "Питание персонала",
+	WriteOffTasting:   "Дегустация",
+	WriteOffDamaged:   "Повреждено",
+}
+
+// IsLossReason причина означает потерю продукта (сравнивается с нормой отходов),
+// а не плановый расход вроде питания персонала
+func IsLossReason(reason string) bool {
+	return reason == WriteOffExpired || reason == WriteOffSpoiled || reason == WriteOffDamaged
+}
+
+// WriteOff списание ингредиента (порча, потери, питание персонала)
+type WriteOff struct {
+	ID             string    `gorm:"primaryKey;column:id" json:"id"`
+	StockItemID    string    `gorm:"column:stock_item_id;not null;index" json:"stockItemId"`
+	IngredientID   string    `gorm:"column:ingredient_id;not null;index" json:"ingredientId"`
+	IngredientName string    `gorm:"column:ingredient_name" json:"ingredientName"`
+	Quantity       float64   `gorm:"column:quantity;type:decimal(12,3)" json:"quantity"` // В единицах ингредиента
+	Unit           string    `gorm:"column:unit" json:"unit"`
+	Reason         string    `gorm:"column:reason;index" json:"reason"`
+	Comment        *string   `gorm:"column:comment" json:"comment,omitempty"`
+	PhotoURL       *string   `gorm:"column:photo_url" json:"photoUrl,omitempty"`
+	BatchID        *string   `gorm:"column:batch_id" json:"batchId,omitempty"`   // Списана конкретная партия
+	Cost           float64   `gorm:"column:cost;type:decimal(12,2)" json:"cost"` // Себестоимость по ценам партий
+	MovementID     string    `gorm:"column:movement_id" json:"movementId"`       // Движение "out" на складе
+	CreatedBy      *string   `gorm:"column:created_by" json:"createdBy,omitempty"`
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime;index" json:"createdAt"`
+}
+
+// TableName указывает имя таблицы для GORM
+func (WriteOff) TableName() string {
+	return "write_offs"
+}
+
+// WriteOffRequest запрос на списание
+type WriteOffRequest struct {
+	IngredientID string  `json:"ingredientId"` // ID ингредиента или складской позиции
+	Quantity     float64 `json:"quantity"`     // В единицах ингредиента
+	Reason       string  `json:"reason"`
+	Comment      string  `json:"comment"`
+	PhotoURL     string  `json:"photoUrl"`
+	BatchID      string  `json:"batchId"` // Необязательно: списать конкретную партию (например, просроченную)
+}
+
+// WasteReport отчёт о потерях за период
+type WasteReport struct {
+	From        time.Time              `json:"from"`
+	To          time.Time              `json:"to"`
+	TotalCost   float64                `json:"totalCost"`
+	ByReason    []WasteReasonTotal     `json:"byReason"`
+	Ingredients []WasteIngredientTotal `json:"ingredients"`
+}
+
+// WasteReasonTotal итог списаний по причине
+type WasteReasonTotal struct {
+	Reason string  `json:"reason"`
+	Label  string  `json:"label"`
+	Count  int     `json:"count"`
+	Cost   float64 `json:"cost"`
+}
+
+// WasteIngredientTotal списания ингредиента за период в сравнении с нормой отходов
+type WasteIngredientTotal struct {
+	IngredientID     string             `json:"ingredientId"`
+	IngredientName   string             `json:"ingredientName"`
+	Unit             string             `json:"unit"`
+	Quantity         float64            `json:"quantity"`                  // Всего списано
+	Cost             float64            `json:"cost"`                      // Себестоимость списаний
+	ByReason         map[string]float64 `json:"byReason"`                  // Количество по причинам
+	LossQuantity     float64            `json:"lossQuantity"`              // Потери (срок, порча, повреждение)
+	ConsumedQuantity float64            `json:"consumedQuantity"`          // Весь расход за период, включая списания
+	WastePercent     float64            `json:"wastePercent"`              // Доля потерь в расходе, %
+	ExpectedPercent  *float64           `json:"expectedPercent,omitempty"` // Норма отходов (WastePercentage)
+	IsAbnormal       bool               `json:"isAbnormal"`
+}
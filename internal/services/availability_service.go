@@ -0,0 +1,293 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+)
+
+// AvailabilityService - сервис расчёта доступности продуктов (стоп-лист)
+type AvailabilityService struct {
+	bundleService *BundleService
+}
+
+// NewAvailabilityService создает новый экземпляр AvailabilityService
+func NewAvailabilityService() *AvailabilityService {
+	return &AvailabilityService{
+		bundleService: NewBundleService(),
+	}
+}
+
+// Check рассчитывает доступность продуктов: продукт недоступен, если он вручную
+// остановлен или складского остатка хотя бы одного ингредиента не хватает на одну порцию.
+// Полуфабрикаты сначала берутся из готовых партий, до сырья раскрывается только нехватка.
+func (s *AvailabilityService) Check(productIDs []string) (map[string]models.ProductAvailability, error) {
+	db := database.GetDB()
+	result := make(map[string]models.ProductAvailability, len(productIDs))
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	var products []models.Product
+	if err := db.Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+
+	requirements, err := loadPortionRequirements(productIDs)
+	if err != nil {
+		return nil, err
+	}
+	// Готовые полуфабрикаты: непросроченные партии выпуска (в единице выхода)
+	sfStock, err := s.semiFinishedStock(requirements.semiFinishedIDs())
+	if err != nil {
+		return nil, err
+	}
+
+	// Остатки на складе по ингредиентам (в базовых единицах) — по полному раскрытию до сырья
+	ingredientIDs := []string{}
+	for _, req := range requirements.flatten() {
+		for ingredientID := range req {
+			ingredientIDs = appendUnique(ingredientIDs, ingredientID)
+		}
+	}
+	stock, names, err := s.stockLevels(ingredientIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, product := range products {
+		availability := models.ProductAvailability{
+			ProductID:   product.ID,
+			ProductName: product.Name,
+			Available:   true,
+		}
+
+		if product.IsStopListed {
+			availability.Available = false
+			availability.Reason = models.UnavailableStopList
+			availability.StopReason = product.StopReason
+		} else {
+			needs := requirements.portionRawNeeds(product.ID, sfStock)
+			availability.MissingIngredients = missingIngredients(needs, stock, names)
+			if len(availability.MissingIngredients) > 0 {
+				availability.Available = false
+				availability.Reason = models.UnavailableOutOfStock
+			}
+		}
+
+		result[product.ID] = availability
+	}
+
+	if err := s.checkBundles(products, result); err != nil {
+		return nil, err
+	}
+
+	// Несуществующие продукты считаем недоступными
+	for _, id := range productIDs {
+		if _, ok := result[id]; !ok {
+			result[id] = models.ProductAvailability{
+				ProductID: id,
+				Available: false,
+				Reason:    models.UnavailableNotFound,
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// checkBundles дополнительно проверяет сеты: все фиксированные компоненты должны быть
+// доступны, а в каждом слоте выбора должен быть хотя бы один доступный вариант
+func (s *AvailabilityService) checkBundles(products []models.Product, result map[string]models.ProductAvailability) error {
+	bundleIDs := []string{}
+	for _, p := range products {
+		if p.Type == models.ProductTypeBundle {
+			bundleIDs = append(bundleIDs, p.ID)
+		}
+	}
+	if len(bundleIDs) == 0 {
+		return nil
+	}
+
+	db := database.GetDB()
+	var items []models.BundleItem
+	if err := db.Where("bundle_id IN ?", bundleIDs).Find(&items).Error; err != nil {
+		return fmt.Errorf("failed to fetch bundle items: %w", err)
+	}
+	var slots []models.BundleSlot
+	if err := db.Where("bundle_id IN ?", bundleIDs).Find(&slots).Error; err != nil {
+		return fmt.Errorf("failed to fetch bundle slots: %w", err)
+	}
+
+	// Собираем все компоненты и варианты слотов для одной проверки
+	slotOptions := map[string][]string{}
+	componentIDs := []string{}
+	for _, item := range items {
+		componentIDs = appendUnique(componentIDs, item.ProductID)
+	}
+	for i := range slots {
+		options, err := s.bundleService.SlotOptions(&slots[i])
+		if err != nil {
+			return err
+		}
+		slotOptions[slots[i].ID] = options
+		componentIDs = appendUnique(componentIDs, options...)
+	}
+
+	components, err := s.Check(componentIDs)
+	if err != nil {
+		return err
+	}
+
+	markUnavailable := func(bundleID, item string) {
+		a := result[bundleID]
+		if a.Available {
+			a.Available = false
+			a.Reason = models.UnavailableOutOfStock
+		}
+		a.UnavailableItems = append(a.UnavailableItems, item)
+		result[bundleID] = a
+	}
+
+	for _, item := range items {
+		if !components[item.ProductID].Available {
+			markUnavailable(item.BundleID, item.ProductName)
+		}
+	}
+	for _, slot := range slots {
+		hasOption := false
+		for _, id := range slotOptions[slot.ID] {
+			if components[id].Available {
+				hasOption = true
+				break
+			}
+		}
+		if !hasOption {
+			markUnavailable(slot.BundleID, slot.Name)
+		}
+	}
+
+	return nil
+}
+
+// Apply заполняет вычисляемые поля доступности у списка продуктов
+func (s *AvailabilityService) Apply(products []models.Product) error {
+	ids := make([]string, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	availability, err := s.Check(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		a := availability[products[i].ID]
+		products[i].IsAvailable = a.Available
+		products[i].UnavailableReason = a.Reason
+	}
+	return nil
+}
+
+// StopList возвращает все недоступные продукты (ручной стоп-лист и нехватка остатков)
+func (s *AvailabilityService) StopList() ([]models.ProductAvailability, error) {
+	db := database.GetDB()
+
+	var ids []string
+	if err := db.Model(&models.Product{}).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+
+	availability, err := s.Check(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	stopList := []models.ProductAvailability{}
+	for _, id := range ids {
+		if a := availability[id]; !a.Available {
+			stopList = append(stopList, a)
+		}
+	}
+	return stopList, nil
+}
+
+// semiFinishedStock остатки готовых полуфабрикатов по непросроченным партиям выпуска
+func (s *AvailabilityService) semiFinishedStock(sfIDs []string) (map[string]float64, error) {
+	stock := map[string]float64{}
+	if len(sfIDs) == 0 {
+		return stock, nil
+	}
+
+	var rows []struct {
+		SemiFinishedID string
+		Quantity       float64
+	}
+	if err := database.GetDB().Model(&models.ProductionBatch{}).
+		Select("semi_finished_id, SUM(remaining_quantity) AS quantity").
+		Where("semi_finished_id IN ? AND remaining_quantity > 0 AND (expires_at IS NULL OR expires_at > ?)", sfIDs, time.Now()).
+		Group("semi_finished_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch semi-finished stock: %w", err)
+	}
+	for _, row := range rows {
+		stock[row.SemiFinishedID] = row.Quantity
+	}
+	return stock, nil
+}
+
+// missingIngredients ингредиенты, остатка которых не хватает на расход (по алфавиту)
+func missingIngredients(required, stock map[string]float64, names map[string]string) []string {
+	var missing []string
+	for ingredientID, qty := range required {
+		if stock[ingredientID] < qty-stockEpsilon {
+			missing = append(missing, names[ingredientID])
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// stockLevels возвращает складские остатки и названия ингредиентов (остатки в базовых единицах)
+func (s *AvailabilityService) stockLevels(ingredientIDs []string) (map[string]float64, map[string]string, error) {
+	stock := map[string]float64{}
+	names := map[string]string{}
+	if len(ingredientIDs) == 0 {
+		return stock, names, nil
+	}
+
+	var stockItems []models.StockItem
+	if err := database.GetDB().
+		Preload("Ingredient").
+		Where(`"ingredientId" IN ?`, ingredientIDs).
+		Find(&stockItems).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch stock: %w", err)
+	}
+
+	for _, item := range stockItems {
+		unit := ""
+		if item.Ingredient != nil {
+			unit = item.Ingredient.Unit
+			names[item.IngredientID] = item.Ingredient.Name
+		}
+		stock[item.IngredientID] += units.ToBase(item.Quantity, unit)
+	}
+
+	// Ингредиенты без складской записи — берём названия из справочника
+	var ingredients []models.Ingredient
+	if err := database.GetDB().Where("id IN ?", ingredientIDs).Find(&ingredients).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch ingredients: %w", err)
+	}
+	for _, ing := range ingredients {
+		if names[ing.ID] == "" {
+			names[ing.ID] = ing.Name
+		}
+	}
+
+	return stock, names, nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPortionRawNeeds(t *testing.T) {
+	// Ролл: 0.05 кг лосося и 0.2 кг риса для суши; рис для суши: 0.5 кг риса
+	// и 0.1 кг заправки на 1 кг выхода; заправка: 0.8 л уксуса на 1 кг
+	reqs := &PortionRequirements{
+		ingredients:  map[string]map[string]float64{"roll": {"salmon": 0.05}},
+		semiFinished: map[string]map[string]float64{"roll": {"sushi-rice": 0.2}},
+		recipes: &semiFinishedRequirements{
+			ingredients: map[string]map[string]float64{
+				"sushi-rice": {"rice": 0.5},
+				"dressing":   {"vinegar": 0.8},
+			},
+			components: map[string]map[string]float64{
+				"sushi-rice": {"dressing": 0.1},
+			},
+		},
+	}
+	stock := map[string]float64{"salmon": 1, "rice": 0, "vinegar": 0}
+	names := map[string]string{"salmon": "Лосось", "rice": "Рис", "vinegar": "Уксус"}
+
+	tests := []struct {
+		name        string
+		sfStock     map[string]float64
+		wantNeeds   map[string]float64
+		wantMissing string
+	}{
+		{
+			name:        "no prepared stock, raw recipe is needed",
+			sfStock:     map[string]float64{},
+			wantNeeds:   map[string]float64{"salmon": 0.05, "rice": 0.1, "vinegar": 0.016},
+			wantMissing: "Рис,Уксус",
+		},
+		{
+			name:      "raw stock used up by production, order still accepted",
+			sfStock:   map[string]float64{"sushi-rice": 3},
+			wantNeeds: map[string]float64{"salmon": 0.05},
+		},
+		{
+			name:        "prepared stock covers only part of the portion",
+			sfStock:     map[string]float64{"sushi-rice": 0.15},
+			wantNeeds:   map[string]float64{"salmon": 0.05, "rice": 0.025, "vinegar": 0.004},
+			wantMissing: "Рис,Уксус",
+		},
+		{
+			name:        "nested semi-finished taken from its own stock",
+			sfStock:     map[string]float64{"dressing": 1},
+			wantNeeds:   map[string]float64{"salmon": 0.05, "rice": 0.1},
+			wantMissing: "Рис",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			needs := reqs.portionRawNeeds("roll", tt.sfStock)
+			for id, want := range tt.wantNeeds {
+				if math.Abs(needs[id]-want) > 1e-9 {
+					t.Errorf("needs[%s] = %v, want %v", id, needs[id], want)
+				}
+			}
+			for id, got := range needs {
+				if _, ok := tt.wantNeeds[id]; !ok && got > stockEpsilon {
+					t.Errorf("unexpected need %s = %v", id, got)
+				}
+			}
+			if got := strings.Join(missingIngredients(needs, stock, names), ","); got != tt.wantMissing {
+				t.Errorf("missingIngredients() = %q, want %q", got, tt.wantMissing)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BundleService - сервис для работы с сетами (комбо-наборами)
+type BundleService struct{}
+
+// NewBundleService создает новый экземпляр BundleService
+func NewBundleService() *BundleService {
+	return &BundleService{}
+}
+
+// SaveComposition проверяет и полностью заменяет состав сета внутри транзакции
+func (s *BundleService) SaveComposition(tx *gorm.DB, bundleID string, items []models.BundleItemInput, slots []models.BundleSlotInput) error {
+	if len(items) == 0 && len(slots) == 0 {
+		return fmt.Errorf("bundle must contain at least one item or slot")
+	}
+
+	componentIDs := []string{}
+	for _, item := range items {
+		if item.ProductID == "" {
+			return fmt.Errorf("productId is required for all bundle items")
+		}
+		if item.ProductID == bundleID {
+			return fmt.Errorf("bundle cannot contain itself")
+		}
+		if item.Quantity <= 0 {
+			return fmt.Errorf("bundle item quantity must be positive")
+		}
+		componentIDs = appendUnique(componentIDs, item.ProductID)
+	}
+	for _, slot := range slots {
+		if strings.TrimSpace(slot.Name) == "" {
+			return fmt.Errorf("slot name is required")
+		}
+		if slot.Quantity <= 0 {
+			return fmt.Errorf("slot quantity must be positive")
+		}
+		if slot.Category == "" && len(slot.ProductIDs) == 0 {
+			return fmt.Errorf("slot '%s' must have a category or a list of products", slot.Name)
+		}
+		for _, id := range slot.ProductIDs {
+			if id == bundleID {
+				return fmt.Errorf("bundle cannot contain itself")
+			}
+			componentIDs = appendUnique(componentIDs, id)
+		}
+	}
+
+	// Компоненты должны существовать и не быть сетами (вложенные сеты не поддерживаются)
+	components := map[string]models.Product{}
+	if len(componentIDs) > 0 {
+		var list []models.Product
+		if err := tx.Where("id IN ?", componentIDs).Find(&list).Error; err != nil {
+			return fmt.Errorf("failed to fetch bundle components: %w", err)
+		}
+		for _, p := range list {
+			if p.Type == models.ProductTypeBundle {
+				return fmt.Errorf("product '%s' is a bundle and cannot be nested", p.Name)
+			}
+			components[p.ID] = p
+		}
+		for _, id := range componentIDs {
+			if _, ok := components[id]; !ok {
+				return fmt.Errorf("product with ID '%s' does not exist", id)
+			}
+		}
+	}
+
+	// Заменяем состав
+	if err := tx.Where("bundle_id = ?", bundleID).Delete(&models.BundleItem{}).Error; err != nil {
+		return fmt.Errorf("failed to delete bundle items: %w", err)
+	}
+	if err := tx.Where("bundle_id = ?", bundleID).Delete(&models.BundleSlot{}).Error; err != nil {
+		return fmt.Errorf("failed to delete bundle slots: %w", err)
+	}
+
+	for _, item := range items {
+		bundleItem := models.BundleItem{
+			ID:          uuid.New().String(),
+			BundleID:    bundleID,
+			ProductID:   item.ProductID,
+			ProductName: components[item.ProductID].Name,
+			Quantity:    item.Quantity,
+		}
+		if err := tx.Create(&bundleItem).Error; err != nil {
+			return fmt.Errorf("failed to add bundle item: %w", err)
+		}
+	}
+	for _, slot := range slots {
+		bundleSlot := models.BundleSlot{
+			ID:         uuid.New().String(),
+			BundleID:   bundleID,
+			Name:       strings.TrimSpace(slot.Name),
+			Quantity:   slot.Quantity,
+			Category:   slot.Category,
+			ProductIDs: models.StringList(slot.ProductIDs),
+		}
+		if err := tx.Create(&bundleSlot).Error; err != nil {
+			return fmt.Errorf("failed to add bundle slot: %w", err)
+		}
+	}
+
+	return s.UpdateCost(tx, bundleID)
+}
+
+// EnsureNotComponent проверяет, что продукт не входит в состав других сетов:
+// иначе после превращения в сет получились бы вложенные сеты
+func (s *BundleService) EnsureNotComponent(tx *gorm.DB, productID string) error {
+	var bundleNames []string
+	if err := tx.Model(&models.Product{}).
+		Where(`id IN (SELECT bundle_id FROM bundle_items WHERE product_id = ?)
+			OR id IN (SELECT bundle_id FROM bundle_slots WHERE product_ids @> ?::jsonb)`,
+			productID, fmt.Sprintf("[%q]", productID)).
+		Pluck("name", &bundleNames).Error; err != nil {
+		return fmt.Errorf("failed to check bundles containing product: %w", err)
+	}
+	if len(bundleNames) > 0 {
+		return fmt.Errorf("product is a component of bundle(s) %s and cannot become a bundle", strings.Join(bundleNames, ", "))
+	}
+	return nil
+}
+
+// UpdateCost пересчитывает себестоимость сета: фиксированные компоненты плюс
+// самый дорогой вариант в каждом слоте (консервативная оценка маржи)
+func (s *BundleService) UpdateCost(tx *gorm.DB, bundleID string) error {
+	var items []models.BundleItem
+	if err := tx.Where("bundle_id = ?", bundleID).Find(&items).Error; err != nil {
+		return fmt.Errorf("failed to fetch bundle items: %w", err)
+	}
+	var slots []models.BundleSlot
+	if err := tx.Where("bundle_id = ?", bundleID).Find(&slots).Error; err != nil {
+		return fmt.Errorf("failed to fetch bundle slots: %w", err)
+	}
+
+	var cost float64
+	for _, item := range items {
+		var component models.Product
+		if err := tx.Select("id", "cost").First(&component, "id = ?", item.ProductID).Error; err != nil {
+			continue
+		}
+		cost += component.Cost * float64(item.Quantity)
+	}
+	for _, slot := range slots {
+		var maxCost float64
+		query := tx.Model(&models.Product{}).Where("type <> ?", models.ProductTypeBundle)
+		if slot.Category != "" && len(slot.ProductIDs) > 0 {
+			query = query.Where("category = ? OR id IN ?", slot.Category, []string(slot.ProductIDs))
+		} else if slot.Category != "" {
+			query = query.Where("category = ?", slot.Category)
+		} else {
+			query = query.Where("id IN ?", []string(slot.ProductIDs))
+		}
+		if err := query.Select("COALESCE(MAX(cost), 0)").Row().Scan(&maxCost); err != nil {
+			return fmt.Errorf("failed to estimate slot cost: %w", err)
+		}
+		cost += maxCost * float64(slot.Quantity)
+	}
+
+	if err := tx.Model(&models.Product{}).Where("id = ?", bundleID).Update("cost", roundCost(cost)).Error; err != nil {
+		return fmt.Errorf("failed to update bundle cost: %w", err)
+	}
+	return nil
+}
+
+// RecalculateContaining пересчитывает себестоимость сетов, в которые входят указанные продукты
+func (s *BundleService) RecalculateContaining(tx *gorm.DB, productIDs []string) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	var bundleIDs []string
+	if err := tx.Model(&models.BundleItem{}).
+		Where("product_id IN ?", productIDs).
+		Distinct().Pluck("bundle_id", &bundleIDs).Error; err != nil {
+		return fmt.Errorf("failed to fetch bundles: %w", err)
+	}
+
+	// Слоты с категориями или списками продуктов проще пересчитать целиком
+	var slotBundleIDs []string
+	if err := tx.Model(&models.BundleSlot{}).Distinct().Pluck("bundle_id", &slotBundleIDs).Error; err != nil {
+		return fmt.Errorf("failed to fetch bundle slots: %w", err)
+	}
+	bundleIDs = appendUnique(bundleIDs, slotBundleIDs...)
+
+	for _, id := range bundleIDs {
+		if err := s.UpdateCost(tx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResolveComponents проверяет выбор клиента и возвращает фактический состав одного сета.
+// Возвращаемые компоненты рассчитаны на один сет (без учёта количества в заказе).
+func (s *BundleService) ResolveComponents(bundle *models.Product, selections []models.BundleSelectionInput) ([]models.OrderItemComponent, error) {
+	components := []models.OrderItemComponent{}
+	for _, item := range bundle.BundleItems {
+		components = append(components, models.OrderItemComponent{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	selectedIDs := []string{}
+	for _, sel := range selections {
+		selectedIDs = appendUnique(selectedIDs, sel.ProductID)
+	}
+	selected := map[string]models.Product{}
+	if len(selectedIDs) > 0 {
+		var list []models.Product
+		if err := database.GetDB().Where("id IN ?", selectedIDs).Find(&list).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch selected products: %w", err)
+		}
+		for _, p := range list {
+			selected[p.ID] = p
+		}
+	}
+
+	chosen := map[string]int{}
+	for _, sel := range selections {
+		var slot *models.BundleSlot
+		for i := range bundle.BundleSlots {
+			if bundle.BundleSlots[i].ID == sel.SlotID {
+				slot = &bundle.BundleSlots[i]
+				break
+			}
+		}
+		if slot == nil {
+			return nil, fmt.Errorf("slot '%s' not found in bundle '%s'", sel.SlotID, bundle.Name)
+		}
+		product, ok := selected[sel.ProductID]
+		if !ok || !slot.Allows(&product) {
+			return nil, fmt.Errorf("product '%s' is not allowed in slot '%s'", sel.ProductID, slot.Name)
+		}
+		if sel.Quantity <= 0 {
+			sel.Quantity = 1
+		}
+		chosen[slot.ID] += sel.Quantity
+
+		slotID := slot.ID
+		components = append(components, models.OrderItemComponent{
+			ProductID: sel.ProductID,
+			SlotID:    &slotID,
+			Quantity:  sel.Quantity,
+		})
+	}
+
+	for _, slot := range bundle.BundleSlots {
+		if chosen[slot.ID] != slot.Quantity {
+			return nil, fmt.Errorf("slot '%s' requires exactly %d item(s), got %d", slot.Name, slot.Quantity, chosen[slot.ID])
+		}
+	}
+
+	return components, nil
+}
+
+// SlotOptions возвращает ID продуктов, которые можно выбрать в слоте
+func (s *BundleService) SlotOptions(slot *models.BundleSlot) ([]string, error) {
+	ids := append([]string{}, slot.ProductIDs...)
+	if slot.Category != "" {
+		var byCategory []string
+		if err := database.GetDB().Model(&models.Product{}).
+			Where("category = ? AND type <> ?", slot.Category, models.ProductTypeBundle).
+			Pluck("id", &byCategory).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch slot options: %w", err)
+		}
+		ids = appendUnique(ids, byCategory...)
+	}
+	return ids, nil
+}
@@ -0,0 +1,492 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CostService - сервис пересчёта себестоимости полуфабрикатов и продуктов
+type CostService struct {
+	bundleService *BundleService
+}
+
+// NewCostService создает новый экземпляр CostService
+func NewCostService() *CostService {
+	return &CostService{
+		bundleService: NewBundleService(),
+	}
+}
+
+// RecalculateForIngredient каскадно пересчитывает себестоимость после изменения цены ингредиента:
+// ингредиент → полуфабрикаты → продукты. Все изменения выполняются в одной транзакции.
+func (s *CostService) RecalculateForIngredient(ingredientID string, oldPrice, newPrice float64) (*models.CostRecalculationResult, error) {
+	var result *models.CostRecalculationResult
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = s.RecalculateForIngredientTx(tx, ingredientID, oldPrice, newPrice)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RecalculateForIngredientTx как RecalculateForIngredient, но внутри транзакции, в которой
+// меняется цена: пересчёт откатывается вместе с ней
+func (s *CostService) RecalculateForIngredientTx(tx *gorm.DB, ingredientID string, oldPrice, newPrice float64) (*models.CostRecalculationResult, error) {
+	price := roundCost(newPrice)
+	result, err := s.recalculateIngredient(tx, ingredientID, &price,
+		fmt.Sprintf("Цена ингредиента изменена: %.2f → %.2f", oldPrice, newPrice))
+	if err != nil {
+		return nil, err
+	}
+	result.OldPrice, result.NewPrice = oldPrice, newPrice
+
+	log.Printf("[COST] 💰 Ingredient %s price %.2f → %.2f: %d semi-finished, %d products recalculated",
+		ingredientID, oldPrice, newPrice, len(result.SemiFinished), len(result.Products))
+	return result, nil
+}
+
+// RecalculateIngredientUnits пересчитывает строки рецептур ингредиента по их текущим ценам
+// после изменения коэффициентов перевода единиц или норм отходов (внутри транзакции)
+func (s *CostService) RecalculateIngredientUnits(tx *gorm.DB, ingredientID, reason string) (*models.CostRecalculationResult, error) {
+	result, err := s.recalculateIngredient(tx, ingredientID, nil, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[COST] 💰 Ingredient %s: %s, %d semi-finished, %d products recalculated",
+		ingredientID, reason, len(result.SemiFinished), len(result.Products))
+	return result, nil
+}
+
+// RecalculateSemiFinished каскадно пересчитывает себестоимость полуфабриката после изменения
+// его рецептуры или выхода: сам полуфабрикат → содержащие его → продукты → сеты (внутри транзакции)
+func (s *CostService) RecalculateSemiFinished(tx *gorm.DB, id, reason string) (*models.CostRecalculationResult, error) {
+	result := &models.CostRecalculationResult{
+		SemiFinished: []models.CostChange{},
+		Products:     []models.ProductCostChange{},
+	}
+	if err := s.cascade(tx, []string{id}, nil, "", reason, result); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[COST] 💰 Semi-finished %s: %s, %d semi-finished, %d products recalculated",
+		id, reason, len(result.SemiFinished), len(result.Products))
+	return result, nil
+}
+
+// recalculateIngredient обновляет строки рецептур с ингредиентом и запускает каскад.
+// newPrice == nil — цена строк не меняется, пересчитываются только количества брутто.
+func (s *CostService) recalculateIngredient(tx *gorm.DB, ingredientID string, newPrice *float64, reason string) (*models.CostRecalculationResult, error) {
+	result := &models.CostRecalculationResult{
+		IngredientID: ingredientID,
+		SemiFinished: []models.CostChange{},
+		Products:     []models.ProductCostChange{},
+	}
+
+	ingUnits, err := loadIngredientUnits(tx, []string{ingredientID})
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Обновляем строки рецептур полуфабрикатов и продуктов
+	var sfLines []models.SemiFinishedIngredient
+	if err := tx.Where("ingredient_id = ?", ingredientID).Find(&sfLines).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch semi-finished lines: %w", err)
+	}
+	sfIDs := []string{}
+	for _, line := range sfLines {
+		price := line.PricePerUnit
+		if newPrice != nil {
+			price = *newPrice
+		}
+		if err := tx.Model(&models.SemiFinishedIngredient{}).
+			Where("id = ?", line.ID).
+			Updates(map[string]interface{}{
+				"price_per_unit": price,
+				"total_price":    roundCost(ingUnits.stockGross(semiFinishedLineQuantity(line)) * price),
+			}).Error; err != nil {
+			return nil, fmt.Errorf("failed to update semi-finished line: %w", err)
+		}
+		sfIDs = appendUnique(sfIDs, line.SemiFinishedID)
+	}
+
+	var productLines []models.ProductIngredient
+	if err := tx.Where("ingredient_id = ?", ingredientID).Find(&productLines).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch product lines: %w", err)
+	}
+	productIDs := []string{}
+	for _, line := range productLines {
+		price := line.PricePerUnit
+		if newPrice != nil {
+			price = *newPrice
+		}
+		if err := tx.Model(&models.ProductIngredient{}).
+			Where("id = ?", line.ID).
+			Updates(map[string]interface{}{
+				"price_per_unit": price,
+				"total_price":    roundCost(ingUnits.stockGross(productLineQuantity(line)) * price),
+			}).Error; err != nil {
+			return nil, fmt.Errorf("failed to update product line: %w", err)
+		}
+		productIDs = appendUnique(productIDs, line.ProductID)
+	}
+
+	if err := s.cascade(tx, sfIDs, productIDs, ingredientID, reason, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// cascade пересчитывает себестоимость полуфабрикатов sfIDs и всех, в которые они вложены,
+// затем продуктов (productIDs и использующих эти полуфабрикаты) и сетов с ними
+func (s *CostService) cascade(tx *gorm.DB, sfIDs, productIDs []string, ingredientID, reason string, result *models.CostRecalculationResult) error {
+	now := time.Now()
+
+	// 2. Полуфабрикаты: вложенные — раньше содержащих их
+	sfOrder, err := semiFinishedAncestors(tx, sfIDs)
+	if err != nil {
+		return err
+	}
+	sfUpdated := map[string]*models.SemiFinished{}
+	for _, id := range sfOrder {
+		var sf models.SemiFinished
+		if err := tx.Preload("Ingredients").Preload("Components").First(&sf, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("semi-finished %s not found: %w", id, err)
+		}
+
+		for i := range sf.Components {
+			line := &sf.Components[i]
+			child, ok := sfUpdated[line.ComponentID]
+			if !ok {
+				continue
+			}
+			total, err := semiFinishedLineCost(child, line.Quantity, line.Unit)
+			if err != nil {
+				log.Printf("[UNITS] ⚠️ Semi-finished %s, component %s: %v", sf.Name, child.Name, err)
+				total = roundCost(units.ToBase(line.Quantity, line.Unit) / units.ToBase(1, child.OutputUnit) * child.CostPerUnit)
+			}
+			line.CostPerUnit = child.CostPerUnit
+			line.TotalCost = total
+			if err := tx.Save(line).Error; err != nil {
+				return fmt.Errorf("failed to update semi-finished component: %w", err)
+			}
+		}
+
+		oldCost := sf.CostPerUnit
+		newCost, err := semiFinishedCostPerUnit(tx, &sf)
+		if err != nil {
+			return err
+		}
+		sf.CostPerUnit = newCost
+		sfUpdated[id] = &sf
+
+		if err := tx.Model(&models.SemiFinished{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"cost_per_unit": newCost,
+				"total_cost":    roundCost(newCost * sf.OutputQuantity),
+				"updated_at":    now,
+			}).Error; err != nil {
+			return fmt.Errorf("failed to update semi-finished cost: %w", err)
+		}
+
+		if oldCost != newCost {
+			if err := s.logChange(tx, models.CostEntitySemiFinished, sf.ID, sf.Name, oldCost, newCost, ingredientID, reason); err != nil {
+				return err
+			}
+			result.SemiFinished = append(result.SemiFinished, models.CostChange{
+				ID: sf.ID, Name: sf.Name, OldCost: oldCost, NewCost: newCost,
+			})
+		}
+	}
+
+	// 3. Продукты, использующие пересчитанные полуфабрикаты
+	if len(sfOrder) > 0 {
+		var viaSF []string
+		if err := tx.Model(&models.ProductSemiFinished{}).
+			Where("semi_finished_id IN ?", sfOrder).
+			Distinct().Pluck("product_id", &viaSF).Error; err != nil {
+			return fmt.Errorf("failed to fetch product semi-finished lines: %w", err)
+		}
+		productIDs = appendUnique(productIDs, viaSF...)
+	}
+
+	// 4. Строки полуфабрикатов и себестоимость продуктов
+	for _, productID := range productIDs {
+		var product models.Product
+		if err := tx.Preload("Ingredients").Preload("SemiFinished").First(&product, "id = ?", productID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue // Удалённый продукт: строки остаются до окончательного удаления
+			}
+			return fmt.Errorf("product %s not found: %w", productID, err)
+		}
+
+		for i := range product.SemiFinished {
+			line := &product.SemiFinished[i]
+			sf, ok := sfUpdated[line.SemiFinishedID]
+			if !ok {
+				continue
+			}
+			total, err := semiFinishedLineCost(sf, line.Quantity, line.Unit)
+			if err != nil {
+				log.Printf("[UNITS] ⚠️ Product %s, semi-finished %s: %v", product.Name, sf.Name, err)
+				total = roundCost(units.ToBase(line.Quantity, line.Unit) / units.ToBase(1, sf.OutputUnit) * sf.CostPerUnit)
+			}
+			line.CostPerUnit = sf.CostPerUnit
+			line.TotalCost = total
+			if err := tx.Save(line).Error; err != nil {
+				return fmt.Errorf("failed to update product semi-finished line: %w", err)
+			}
+		}
+
+		oldCost := product.Cost
+		newCost := ProductCost(&product)
+		if err := tx.Model(&models.Product{}).Where("id = ?", productID).Update("cost", newCost).Error; err != nil {
+			return fmt.Errorf("failed to update product cost: %w", err)
+		}
+
+		if oldCost != newCost {
+			if err := s.logChange(tx, models.CostEntityProduct, product.ID, product.Name, oldCost, newCost, ingredientID, reason); err != nil {
+				return err
+			}
+			result.Products = append(result.Products, models.ProductCostChange{
+				CostChange: models.CostChange{ID: product.ID, Name: product.Name, OldCost: oldCost, NewCost: newCost},
+				Price:      product.Price,
+				OldMargin:  Margin(product.Price, oldCost),
+				NewMargin:  Margin(product.Price, newCost),
+			})
+		}
+	}
+
+	// 5. Сеты, в которые входят изменившиеся продукты
+	return s.bundleService.RecalculateContaining(tx, productIDs)
+}
+
+// BackfillProductCosts рассчитывает себестоимость продуктов, у которых она ещё не заполнена
+// (заведены до появления колонки cost), и сетов с ними. Вызывается при запуске сервера.
+func (s *CostService) BackfillProductCosts() error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var products []models.Product
+		if err := tx.Preload("Ingredients").Preload("SemiFinished").
+			Where("(cost IS NULL OR cost = 0) AND (type = ? OR type IS NULL)", models.ProductTypeSingle).
+			Find(&products).Error; err != nil {
+			return fmt.Errorf("failed to fetch products: %w", err)
+		}
+
+		updated := []string{}
+		for i := range products {
+			cost := ProductCost(&products[i])
+			if cost == 0 {
+				continue
+			}
+			if err := tx.Model(&models.Product{}).Where("id = ?", products[i].ID).Update("cost", cost).Error; err != nil {
+				return fmt.Errorf("failed to update product cost: %w", err)
+			}
+			updated = append(updated, products[i].ID)
+		}
+		if err := s.bundleService.RecalculateContaining(tx, updated); err != nil {
+			return err
+		}
+
+		if len(updated) > 0 {
+			log.Printf("[COST] 💰 Backfilled cost of %d product(s)", len(updated))
+		}
+		return nil
+	})
+}
+
+// GetChanges возвращает журнал изменений себестоимости
+func (s *CostService) GetChanges(entityType, entityID string, limit int) ([]models.CostChangeLog, error) {
+	db := database.GetDB()
+
+	query := db.Order("created_at DESC").Limit(limit)
+	if entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+
+	var changes []models.CostChangeLog
+	if err := query.Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch cost changes: %w", err)
+	}
+	return changes, nil
+}
+
+// ProductBreakdown разбивка себестоимости продукта по строкам: брутто, нетто и потери
+func (s *CostService) ProductBreakdown(productID string) (*models.RecipeCostBreakdown, error) {
+	db := database.GetDB()
+
+	var product models.Product
+	if err := db.Preload("Ingredients").Preload("SemiFinished").First(&product, "id = ?", productID).Error; err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+
+	ids := make([]string, 0, len(product.Ingredients))
+	for _, line := range product.Ingredients {
+		ids = appendUnique(ids, line.IngredientID)
+	}
+	ingUnits, err := loadIngredientUnits(db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.RecipeCostBreakdown{
+		EntityType:   models.CostEntityProduct,
+		ID:           product.ID,
+		Name:         product.Name,
+		Lines:        make([]models.RecipeLineBreakdown, 0, len(product.Ingredients)),
+		SemiFinished: make([]models.RecipeSemiFinishedBreakdown, 0, len(product.SemiFinished)),
+	}
+	for _, line := range product.Ingredients {
+		b := ingUnits.breakdown(productLineQuantity(line), line.IngredientName, line.PricePerUnit)
+		result.Lines = append(result.Lines, b)
+		result.TotalCost += b.Cost
+		result.LossCost += b.LossCost
+	}
+	for _, line := range product.SemiFinished {
+		result.SemiFinished = append(result.SemiFinished, models.RecipeSemiFinishedBreakdown{
+			SemiFinishedID:   line.SemiFinishedID,
+			SemiFinishedName: line.SemiFinishedName,
+			Quantity:         line.Quantity,
+			Unit:             line.Unit,
+			CostPerUnit:      line.CostPerUnit,
+			Cost:             line.TotalCost,
+		})
+		result.TotalCost += line.TotalCost
+	}
+
+	result.TotalCost = roundCost(result.TotalCost)
+	result.LossCost = roundCost(result.LossCost)
+	return result, nil
+}
+
+// SemiFinishedBreakdown разбивка себестоимости полуфабриката по строкам: брутто, нетто и потери
+func (s *CostService) SemiFinishedBreakdown(id string) (*models.RecipeCostBreakdown, error) {
+	db := database.GetDB()
+
+	var sf models.SemiFinished
+	if err := db.Preload("Ingredients").Preload("Components").First(&sf, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("semi-finished not found: %w", err)
+	}
+
+	ids := make([]string, 0, len(sf.Ingredients))
+	for _, line := range sf.Ingredients {
+		ids = appendUnique(ids, line.IngredientID)
+	}
+	ingUnits, err := loadIngredientUnits(db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.RecipeCostBreakdown{
+		EntityType:     models.CostEntitySemiFinished,
+		ID:             sf.ID,
+		Name:           sf.Name,
+		Lines:          make([]models.RecipeLineBreakdown, 0, len(sf.Ingredients)),
+		OutputQuantity: sf.OutputQuantity,
+		OutputUnit:     sf.OutputUnit,
+	}
+	for _, line := range sf.Ingredients {
+		b := ingUnits.breakdown(semiFinishedLineQuantity(line), line.IngredientName, line.PricePerUnit)
+		result.Lines = append(result.Lines, b)
+		result.TotalCost += b.Cost
+		result.LossCost += b.LossCost
+	}
+	if len(sf.Components) > 0 {
+		result.SemiFinished = make([]models.RecipeSemiFinishedBreakdown, 0, len(sf.Components))
+	}
+	for _, line := range sf.Components {
+		result.SemiFinished = append(result.SemiFinished, models.RecipeSemiFinishedBreakdown{
+			SemiFinishedID:   line.ComponentID,
+			SemiFinishedName: line.ComponentName,
+			Quantity:         line.Quantity,
+			Unit:             line.Unit,
+			CostPerUnit:      line.CostPerUnit,
+			Cost:             line.TotalCost,
+		})
+		result.TotalCost += line.TotalCost
+	}
+
+	result.TotalCost = roundCost(result.TotalCost)
+	result.LossCost = roundCost(result.LossCost)
+	if sf.OutputQuantity > 0 {
+		result.CostPerUnit = roundCost(result.TotalCost / sf.OutputQuantity)
+	}
+	return result, nil
+}
+
+// logChange записывает изменение себестоимости в журнал
+func (s *CostService) logChange(tx *gorm.DB, entityType, entityID, entityName string, oldCost, newCost float64, ingredientID, reason string) error {
+	entry := models.CostChangeLog{
+		ID:           uuid.New().String(),
+		EntityType:   entityType,
+		EntityID:     entityID,
+		EntityName:   entityName,
+		OldCost:      oldCost,
+		NewCost:      newCost,
+		IngredientID: ingredientID,
+		Reason:       reason,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to write cost change log: %w", err)
+	}
+	return nil
+}
+
+// semiFinishedCostPerUnit рассчитывает себестоимость единицы полуфабриката по строкам рецептуры
+// и вложенным полуфабрикатам (sf — с загруженными Ingredients и Components)
+func semiFinishedCostPerUnit(db *gorm.DB, sf *models.SemiFinished) (float64, error) {
+	if sf.OutputQuantity == 0 {
+		return 0, nil
+	}
+	ids := make([]string, 0, len(sf.Ingredients))
+	for _, line := range sf.Ingredients {
+		ids = appendUnique(ids, line.IngredientID)
+	}
+	ingUnits, err := loadIngredientUnits(db, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	var total float64
+	for _, line := range sf.Ingredients {
+		total += ingUnits.stockGross(semiFinishedLineQuantity(line)) * line.PricePerUnit
+	}
+	for _, line := range sf.Components {
+		total += line.TotalCost
+	}
+	return roundCost(total / sf.OutputQuantity), nil
+}
+
+// ProductCost рассчитывает себестоимость продукта как сумму строк рецептуры
+func ProductCost(product *models.Product) float64 {
+	var total float64
+	for _, line := range product.Ingredients {
+		total += line.TotalPrice
+	}
+	for _, line := range product.SemiFinished {
+		total += line.TotalCost
+	}
+	return roundCost(total)
+}
+
+// Margin рассчитывает маржинальность в процентах
+func Margin(price, cost float64) float64 {
+	if price <= 0 {
+		return 0
+	}
+	return roundCost((price - cost) / price * 100)
+}
@@ -0,0 +1,443 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrReceiptNotEditable проведённую или сторнированную накладную нельзя изменять
+var ErrReceiptNotEditable = errors.New("only draft receipts can be changed")
+
+// GoodsReceiptService - сервис приходных накладных (поступление товара от поставщиков)
+type GoodsReceiptService struct {
+	costService *CostService
+}
+
+// NewGoodsReceiptService создает новый экземпляр GoodsReceiptService
+func NewGoodsReceiptService() *GoodsReceiptService {
+	return &GoodsReceiptService{
+		costService: NewCostService(),
+	}
+}
+
+// GetAll возвращает накладные с фильтром по статусу и поставщику
+func (s *GoodsReceiptService) GetAll(status, supplier string, limit int) ([]models.GoodsReceipt, error) {
+	query := database.GetDB().Order("invoice_date DESC, created_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if supplier != "" {
+		query = query.Where("LOWER(supplier) = LOWER(?)", supplier)
+	}
+
+	var receipts []models.GoodsReceipt
+	if err := query.Find(&receipts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch receipts: %w", err)
+	}
+	return receipts, nil
+}
+
+// GetByID возвращает накладную со строками
+func (s *GoodsReceiptService) GetByID(id string) (*models.GoodsReceipt, error) {
+	var receipt models.GoodsReceipt
+	if err := database.GetDB().Preload("Lines").First(&receipt, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("receipt not found: %w", err)
+	}
+	return &receipt, nil
+}
+
+// Create создает черновик накладной
+func (s *GoodsReceiptService) Create(req models.GoodsReceiptRequest, createdBy *string) (*models.GoodsReceipt, error) {
+	receipt := models.GoodsReceipt{
+		ID:        uuid.New().String(),
+		Status:    models.ReceiptDraft,
+		CreatedBy: createdBy,
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return s.createTx(tx, &receipt, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[RECEIPT] 📝 Draft receipt %s from %s (invoice %s), %d lines",
+		receipt.ID, receipt.Supplier, receipt.InvoiceNumber, len(receipt.Lines))
+	return &receipt, nil
+}
+
+// createTx заполняет и сохраняет черновик накладной внутри транзакции
+func (s *GoodsReceiptService) createTx(tx *gorm.DB, receipt *models.GoodsReceipt, req models.GoodsReceiptRequest) error {
+	if err := s.fill(tx, receipt, req); err != nil {
+		return err
+	}
+	return tx.Create(receipt).Error
+}
+
+// Update заменяет содержимое черновика накладной
+func (s *GoodsReceiptService) Update(id string, req models.GoodsReceiptRequest) (*models.GoodsReceipt, error) {
+	var receipt models.GoodsReceipt
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&receipt, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("receipt not found: %w", err)
+		}
+		if receipt.Status != models.ReceiptDraft {
+			return ErrReceiptNotEditable
+		}
+		if err := tx.Where("receipt_id = ?", id).Delete(&models.GoodsReceiptLine{}).Error; err != nil {
+			return fmt.Errorf("failed to replace lines: %w", err)
+		}
+		if err := s.fill(tx, &receipt, req); err != nil {
+			return err
+		}
+		return tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(&receipt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// Delete удаляет черновик накладной
+func (s *GoodsReceiptService) Delete(id string) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var receipt models.GoodsReceipt
+		if err := tx.First(&receipt, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("receipt not found: %w", err)
+		}
+		if receipt.Status != models.ReceiptDraft {
+			return ErrReceiptNotEditable
+		}
+		if err := tx.Where("receipt_id = ?", id).Delete(&models.GoodsReceiptLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&receipt).Error
+	})
+}
+
+// Post проводит накладную: увеличивает остатки, пишет движения "in" и обновляет закупочные цены.
+// Себестоимость по изменившимся ценам пересчитывается в той же транзакции.
+func (s *GoodsReceiptService) Post(id string, postedBy *string) (*models.GoodsReceipt, []models.IngredientPriceChange, error) {
+	var receipt *models.GoodsReceipt
+	var priceChanges []models.IngredientPriceChange
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		receipt, priceChanges, err = s.postTx(tx, id, postedBy)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("[RECEIPT] ✅ Posted receipt %s (invoice %s): %d lines, total %.2f",
+		receipt.ID, receipt.InvoiceNumber, len(receipt.Lines), receipt.Total)
+	return receipt, priceChanges, nil
+}
+
+// postTx проводит черновик накладной внутри транзакции
+func (s *GoodsReceiptService) postTx(tx *gorm.DB, id string, postedBy *string) (*models.GoodsReceipt, []models.IngredientPriceChange, error) {
+	var receipt models.GoodsReceipt
+	priceChanges := []models.IngredientPriceChange{}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Lines").
+		First(&receipt, "id = ?", id).Error; err != nil {
+		return nil, nil, fmt.Errorf("receipt not found: %w", err)
+	}
+	if receipt.Status != models.ReceiptDraft {
+		return nil, nil, fmt.Errorf("receipt is already %s", receipt.Status)
+	}
+	if len(receipt.Lines) == 0 {
+		return nil, nil, fmt.Errorf("receipt has no lines")
+	}
+
+	now := time.Now()
+	note := fmt.Sprintf("Приход по накладной №%s от %s", receipt.InvoiceNumber, receipt.Supplier)
+	for i := range receipt.Lines {
+		line := &receipt.Lines[i]
+		item, err := lockStockItem(tx, line.StockItemID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		price := line.PricePerUnit
+		if _, err := recordStockMovement(tx, item, stockMovementInput{
+			Type:         models.MovementIn,
+			Quantity:     line.Quantity,
+			Price:        &price,
+			Note:         note,
+			DocumentType: models.DocumentGoodsReceipt,
+			DocumentID:   receipt.ID,
+			UserID:       postedBy,
+		}); err != nil {
+			return nil, nil, err
+		}
+
+		batch, err := addStockBatch(tx, item, stockBatchInput{
+			Quantity:     line.Quantity,
+			Price:        price,
+			ReceivedAt:   now,
+			ExpiresAt:    line.ExpiresAt,
+			DocumentType: models.DocumentGoodsReceipt,
+			DocumentID:   receipt.ID,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := tx.Model(&models.GoodsReceiptLine{}).Where("id = ?", line.ID).
+			Update("batch_id", batch.ID).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to link receipt batch: %w", err)
+		}
+
+		// Последняя закупочная цена становится ценой ингредиента
+		oldPrice := 0.0
+		if item.PricePerUnit != nil {
+			oldPrice = *item.PricePerUnit
+		}
+		if price > 0 && price != oldPrice {
+			if err := tx.Model(&models.StockItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+				"pricePerUnit": price,
+				"supplier":     receipt.Supplier,
+			}).Error; err != nil {
+				return nil, nil, fmt.Errorf("failed to update purchase price: %w", err)
+			}
+			// Себестоимость пересчитывается в той же транзакции, что и цена
+			costs, err := s.costService.RecalculateForIngredientTx(tx, item.IngredientID, oldPrice, price)
+			if err != nil {
+				return nil, nil, err
+			}
+			priceChanges = append(priceChanges, models.IngredientPriceChange{
+				IngredientID:  item.IngredientID,
+				OldPrice:      oldPrice,
+				NewPrice:      price,
+				Recalculation: costs,
+			})
+		}
+	}
+
+	receipt.Status = models.ReceiptPosted
+	receipt.PostedBy = postedBy
+	receipt.PostedAt = &now
+	if err := tx.Model(&receipt).Updates(map[string]interface{}{
+		"status":    receipt.Status,
+		"posted_by": postedBy,
+		"posted_at": now,
+	}).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := syncPurchaseOrder(tx, receipt.PurchaseOrderID); err != nil {
+		return nil, nil, err
+	}
+	return &receipt, priceChanges, nil
+}
+
+// Reverse сторнирует проведённую накладную: уменьшает остатки и пишет движения "out".
+// Отказывает, если часть поступившего товара уже израсходована.
+func (s *GoodsReceiptService) Reverse(id, reason string, reversedBy *string) (*models.GoodsReceipt, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("reversal reason is required")
+	}
+
+	var receipt models.GoodsReceipt
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Lines").
+			First(&receipt, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("receipt not found: %w", err)
+		}
+		if receipt.Status != models.ReceiptPosted {
+			return fmt.Errorf("only posted receipts can be reversed")
+		}
+
+		note := fmt.Sprintf("Сторно накладной №%s: %s", receipt.InvoiceNumber, reason)
+		for _, line := range receipt.Lines {
+			item, err := lockStockItem(tx, line.StockItemID)
+			if err != nil {
+				return err
+			}
+			if line.BatchID != nil {
+				// Партия накладной должна быть нетронутой: списанное уже ушло в заказы
+				var batch models.StockBatch
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+					First(&batch, "id = ?", *line.BatchID).Error; err != nil {
+					return fmt.Errorf("receipt batch not found: %w", err)
+				}
+				if batch.RemainingQuantity < batch.InitialQuantity-stockEpsilon {
+					return fmt.Errorf("batch %s of %s is already partially consumed: %.3f of %.3f left",
+						batch.BatchNumber, line.IngredientName, batch.RemainingQuantity, batch.InitialQuantity)
+				}
+				if err := tx.Model(&batch).Update("remaining_quantity", 0).Error; err != nil {
+					return fmt.Errorf("failed to close receipt batch: %w", err)
+				}
+			} else if item.Quantity < line.Quantity {
+				return fmt.Errorf("insufficient stock of %s to reverse: %.3f left, %.3f received",
+					line.IngredientName, item.Quantity, line.Quantity)
+			}
+
+			price := line.PricePerUnit
+			if _, err := recordStockMovement(tx, item, stockMovementInput{
+				Type:         models.MovementOut,
+				Quantity:     line.Quantity,
+				Price:        &price,
+				Note:         note,
+				DocumentType: models.DocumentGoodsReceipt,
+				DocumentID:   receipt.ID,
+				UserID:       reversedBy,
+			}); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		receipt.Status = models.ReceiptReversed
+		receipt.ReversedBy = reversedBy
+		receipt.ReversedAt = &now
+		receipt.ReversalReason = &reason
+		if err := tx.Model(&receipt).Updates(map[string]interface{}{
+			"status":          receipt.Status,
+			"reversed_by":     reversedBy,
+			"reversed_at":     now,
+			"reversal_reason": reason,
+		}).Error; err != nil {
+			return err
+		}
+		return syncPurchaseOrder(tx, receipt.PurchaseOrderID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[RECEIPT] ↩️ Reversed receipt %s (invoice %s): %s", receipt.ID, receipt.InvoiceNumber, reason)
+	return &receipt, nil
+}
+
+// fill валидирует запрос и заполняет шапку и строки накладной
+func (s *GoodsReceiptService) fill(tx *gorm.DB, receipt *models.GoodsReceipt, req models.GoodsReceiptRequest) error {
+	supplier := strings.TrimSpace(req.Supplier)
+	invoice := strings.TrimSpace(req.InvoiceNumber)
+
+	// Приёмка по заказу поставщику: поставщик и строки берутся из заказа
+	var order *models.PurchaseOrder
+	receipt.PurchaseOrderID = nil
+	if req.PurchaseOrderID != "" {
+		order = &models.PurchaseOrder{}
+		if err := tx.Preload("Lines").First(order, "id = ?", req.PurchaseOrderID).Error; err != nil {
+			return fmt.Errorf("purchase order not found: %w", err)
+		}
+		if order.Status != models.PurchaseOrderSent && order.Status != models.PurchaseOrderPartiallyReceived {
+			return fmt.Errorf("purchase order %s is %s and cannot be received", order.Number, order.Status)
+		}
+		if supplier == "" {
+			supplier = order.SupplierName
+		}
+		receipt.PurchaseOrderID = &order.ID
+	}
+
+	if supplier == "" {
+		return fmt.Errorf("supplier is required")
+	}
+	if invoice == "" {
+		return fmt.Errorf("invoice number is required")
+	}
+	if len(req.Lines) == 0 {
+		return fmt.Errorf("receipt must contain at least one line")
+	}
+
+	// Одна и та же накладная поставщика не должна оприходоваться дважды
+	// (в БД это дополнительно гарантирует уникальный индекс idx_goods_receipt_invoice)
+	var duplicates int64
+	if err := tx.Model(&models.GoodsReceipt{}).
+		Where("LOWER(supplier) = LOWER(?) AND invoice_number = ? AND status <> ? AND id <> ?",
+			supplier, invoice, models.ReceiptReversed, receipt.ID).
+		Count(&duplicates).Error; err != nil {
+		return fmt.Errorf("failed to check duplicate invoice: %w", err)
+	}
+	if duplicates > 0 {
+		return fmt.Errorf("invoice %s from %s is already registered", invoice, supplier)
+	}
+
+	receipt.Supplier = supplier
+	receipt.SupplierID = nil
+	if order != nil {
+		receipt.SupplierID = &order.SupplierID
+	} else {
+		var known models.Supplier
+		if err := tx.Where("LOWER(name) = LOWER(?)", supplier).First(&known).Error; err == nil {
+			receipt.SupplierID = &known.ID
+		}
+	}
+	receipt.InvoiceNumber = invoice
+	receipt.InvoiceDate = req.InvoiceDate
+	if receipt.InvoiceDate.IsZero() {
+		receipt.InvoiceDate = time.Now()
+	}
+	receipt.Note = optionalString(req.Note)
+	receipt.Lines = make([]models.GoodsReceiptLine, 0, len(req.Lines))
+	receipt.Total = 0
+
+	for i, input := range req.Lines {
+		if input.Quantity <= 0 {
+			return fmt.Errorf("line %d: quantity must be positive", i+1)
+		}
+		if input.PricePerUnit < 0 {
+			return fmt.Errorf("line %d: price must be positive", i+1)
+		}
+
+		var item models.StockItem
+		if err := tx.Preload("Ingredient").
+			Where(`id = ? OR "ingredientId" = ?`, input.IngredientID, input.IngredientID).
+			First(&item).Error; err != nil {
+			return fmt.Errorf("line %d: ingredient %s not found", i+1, input.IngredientID)
+		}
+
+		line := models.GoodsReceiptLine{
+			ID:           uuid.New().String(),
+			ReceiptID:    receipt.ID,
+			StockItemID:  item.ID,
+			IngredientID: item.IngredientID,
+			Quantity:     input.Quantity,
+			BruttoWeight: input.BruttoWeight,
+			NettoWeight:  input.NettoWeight,
+			PricePerUnit: roundCost(input.PricePerUnit),
+			ExpiresAt:    input.ExpiresAt,
+		}
+		if input.PurchaseOrderLineID != "" {
+			if order == nil {
+				return fmt.Errorf("line %d: purchase order line given without purchase order", i+1)
+			}
+			found := false
+			for _, orderLine := range order.Lines {
+				if orderLine.ID == input.PurchaseOrderLineID && orderLine.StockItemID == item.ID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("line %d: purchase order line %s does not match ingredient", i+1, input.PurchaseOrderLineID)
+			}
+			orderLineID := input.PurchaseOrderLineID
+			line.PurchaseOrderLineID = &orderLineID
+		}
+		if item.Ingredient != nil {
+			line.IngredientName = item.Ingredient.Name
+			line.Unit = item.Ingredient.Unit
+		}
+		line.Total = roundCost(units.ToBase(line.Quantity, line.Unit) * line.PricePerUnit)
+		receipt.Total += line.Total
+		receipt.Lines = append(receipt.Lines, line)
+	}
+	receipt.Total = roundCost(receipt.Total)
+
+	return nil
+}
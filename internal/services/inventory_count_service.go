@@ -0,0 +1,338 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInventoryCountClosed утверждённую или отменённую инвентаризацию нельзя изменять
+var ErrInventoryCountClosed = errors.New("inventory count is already closed")
+
+// InventoryCountService - сервис инвентаризации склада
+type InventoryCountService struct{}
+
+// NewInventoryCountService создает новый экземпляр InventoryCountService
+func NewInventoryCountService() *InventoryCountService {
+	return &InventoryCountService{}
+}
+
+// GetAll возвращает инвентаризации с фильтром по статусу
+func (s *InventoryCountService) GetAll(status string, limit int) ([]models.InventoryCount, error) {
+	query := database.GetDB().Order("started_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var counts []models.InventoryCount
+	if err := query.Find(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch inventory counts: %w", err)
+	}
+	return counts, nil
+}
+
+// GetByID возвращает инвентаризацию со строками и расхождениями
+func (s *InventoryCountService) GetByID(id string) (*models.InventoryCount, error) {
+	var count models.InventoryCount
+	if err := database.GetDB().
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("ingredient_name ASC") }).
+		First(&count, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("inventory count not found: %w", err)
+	}
+	fillVariance(&count)
+	return &count, nil
+}
+
+// GetEntries возвращает журнал вводов подсчёта
+func (s *InventoryCountService) GetEntries(id string) ([]models.InventoryCountEntry, error) {
+	var entries []models.InventoryCountEntry
+	if err := database.GetDB().Where("count_id = ?", id).Order("created_at ASC").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch count entries: %w", err)
+	}
+	return entries, nil
+}
+
+// Start начинает инвентаризацию: фиксирует учётные остатки и цены на текущий момент
+func (s *InventoryCountService) Start(req models.StartInventoryCountRequest, startedBy *string) (*models.InventoryCount, error) {
+	now := time.Now()
+	count := models.InventoryCount{
+		ID:        uuid.New().String(),
+		Name:      strings.TrimSpace(req.Name),
+		Status:    models.InventoryCountOpen,
+		StartedBy: startedBy,
+		StartedAt: now,
+		Category:  optionalString(req.Category),
+	}
+	if count.Name == "" {
+		count.Name = fmt.Sprintf("Инвентаризация %s", now.Format("02.01.2006"))
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Одновременно идёт только одна инвентаризация, иначе корректировки наложатся.
+		// Параллельные запуски ждут друг друга на блокировке транзакции, а уникальный
+		// индекс idx_inventory_count_open страхует на уровне БД.
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('inventory_count_start'))").Error; err != nil {
+			return fmt.Errorf("failed to lock inventory counts: %w", err)
+		}
+		var open int64
+		if err := tx.Model(&models.InventoryCount{}).Where("status = ?", models.InventoryCountOpen).Count(&open).Error; err != nil {
+			return fmt.Errorf("failed to check open counts: %w", err)
+		}
+		if open > 0 {
+			return fmt.Errorf("another inventory count is already in progress")
+		}
+
+		query := tx.Preload("Ingredient")
+		if count.Category != nil {
+			query = query.Where("category = ?", *count.Category)
+		}
+		if len(req.IngredientIDs) > 0 {
+			query = query.Where(`id IN ? OR "ingredientId" IN ?`, req.IngredientIDs, req.IngredientIDs)
+		}
+		var items []models.StockItem
+		if err := query.Find(&items).Error; err != nil {
+			return fmt.Errorf("failed to fetch stock: %w", err)
+		}
+		if len(items) == 0 {
+			return fmt.Errorf("no stock items to count")
+		}
+
+		if err := tx.Create(&count).Error; err != nil {
+			return fmt.Errorf("failed to create inventory count: %w", err)
+		}
+
+		count.Lines = make([]models.InventoryCountLine, 0, len(items))
+		for _, item := range items {
+			line := models.InventoryCountLine{
+				ID:               uuid.New().String(),
+				CountID:          count.ID,
+				StockItemID:      item.ID,
+				IngredientID:     item.IngredientID,
+				ExpectedQuantity: item.Quantity,
+			}
+			if item.Ingredient != nil {
+				line.IngredientName = item.Ingredient.Name
+				line.Unit = item.Ingredient.Unit
+			}
+			if item.PricePerUnit != nil {
+				line.PricePerUnit = *item.PricePerUnit
+			}
+			count.Lines = append(count.Lines, line)
+		}
+		if err := tx.Create(&count.Lines).Error; err != nil {
+			return fmt.Errorf("failed to create count lines: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fillVariance(&count)
+	log.Printf("[INVENTORY] 📋 Started inventory count %s: %d items", count.ID, len(count.Lines))
+	return &count, nil
+}
+
+// AddEntries принимает подсчитанные количества. Несколько устройств могут считать одновременно:
+// режим "add" атомарно прибавляет количество к уже посчитанному, "set" заменяет его.
+func (s *InventoryCountService) AddEntries(id string, req models.InventoryCountEntriesRequest, countedBy *string) (*models.InventoryCount, error) {
+	if len(req.Entries) == 0 {
+		return nil, fmt.Errorf("no counted quantities")
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Разделяемая блокировка: вводы с разных устройств идут параллельно, но не во время утверждения
+		var count models.InventoryCount
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&count, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("inventory count not found: %w", err)
+		}
+		if count.Status != models.InventoryCountOpen {
+			return ErrInventoryCountClosed
+		}
+
+		now := time.Now()
+		for i, input := range req.Entries {
+			mode := input.Mode
+			if mode == "" {
+				mode = models.CountModeAdd
+			}
+			if mode != models.CountModeAdd && mode != models.CountModeSet {
+				return fmt.Errorf("entry %d: invalid mode %q", i+1, input.Mode)
+			}
+			if input.Quantity < 0 {
+				return fmt.Errorf("entry %d: quantity must not be negative", i+1)
+			}
+
+			var line models.InventoryCountLine
+			if err := tx.Where("count_id = ? AND (stock_item_id = ? OR ingredient_id = ?)", id, input.IngredientID, input.IngredientID).
+				First(&line).Error; err != nil {
+				return fmt.Errorf("entry %d: ingredient %s is not part of this count", i+1, input.IngredientID)
+			}
+
+			counted := gorm.Expr("COALESCE(counted_quantity, 0) + ?", input.Quantity)
+			if mode == models.CountModeSet {
+				counted = gorm.Expr("?", input.Quantity)
+			}
+			if err := tx.Model(&models.InventoryCountLine{}).Where("id = ?", line.ID).
+				Updates(map[string]interface{}{
+					"counted_quantity": counted,
+					"counted_at":       now,
+				}).Error; err != nil {
+				return fmt.Errorf("failed to save counted quantity: %w", err)
+			}
+
+			entry := models.InventoryCountEntry{
+				ID:        uuid.New().String(),
+				CountID:   id,
+				LineID:    line.ID,
+				Quantity:  input.Quantity,
+				Mode:      mode,
+				DeviceID:  optionalString(req.DeviceID),
+				Location:  optionalString(req.Location),
+				CountedBy: countedBy,
+			}
+			if err := tx.Create(&entry).Error; err != nil {
+				return fmt.Errorf("failed to record count entry: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(id)
+}
+
+// Approve утверждает инвентаризацию: расхождение между фактом и учётным остатком на начало
+// применяется к текущему остатку движениями "in"/"out" с указанной причиной.
+// Непосчитанные позиции не корректируются.
+func (s *InventoryCountService) Approve(id, reason string, approvedBy *string) (*models.InventoryCount, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("adjustment reason is required")
+	}
+
+	var count models.InventoryCount
+	adjusted := 0
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Lines").
+			First(&count, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("inventory count not found: %w", err)
+		}
+		if count.Status != models.InventoryCountOpen {
+			return ErrInventoryCountClosed
+		}
+		fillVariance(&count)
+
+		note := fmt.Sprintf("Инвентаризация «%s»: %s", count.Name, reason)
+		for _, line := range count.Lines {
+			if line.Variance == nil || *line.Variance == 0 {
+				continue
+			}
+			item, err := lockStockItem(tx, line.StockItemID)
+			if err != nil {
+				return err
+			}
+
+			input := stockMovementInput{
+				Note:         note,
+				DocumentType: models.DocumentInventoryCount,
+				DocumentID:   count.ID,
+				UserID:       approvedBy,
+			}
+			if *line.Variance < 0 {
+				// Недостача списывается из партий так же, как расход
+				if _, _, err := consumeStock(tx, item, -*line.Variance, "", input); err != nil {
+					return err
+				}
+			} else {
+				price := line.PricePerUnit
+				input.Type = models.MovementIn
+				input.Quantity = *line.Variance
+				input.Price = &price
+				if _, err := recordStockMovement(tx, item, input); err != nil {
+					return err
+				}
+			}
+			adjusted++
+		}
+
+		now := time.Now()
+		count.Status = models.InventoryCountApproved
+		count.ApprovedBy = approvedBy
+		count.ApprovedAt = &now
+		count.Reason = &reason
+		return tx.Model(&count).Updates(map[string]interface{}{
+			"status":        count.Status,
+			"approved_by":   approvedBy,
+			"approved_at":   now,
+			"reason":        reason,
+			"shortage_cost": count.ShortageCost,
+			"surplus_cost":  count.SurplusCost,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INVENTORY] ✅ Approved inventory count %s: %d adjustments, shortage %.2f, surplus %.2f",
+		count.ID, adjusted, count.ShortageCost, count.SurplusCost)
+	return &count, nil
+}
+
+// Cancel отменяет инвентаризацию без корректировки остатков
+func (s *InventoryCountService) Cancel(id string) error {
+	result := database.GetDB().Model(&models.InventoryCount{}).
+		Where("id = ? AND status = ?", id, models.InventoryCountOpen).
+		Update("status", models.InventoryCountCancelled)
+	if result.Error != nil {
+		return fmt.Errorf("failed to cancel inventory count: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrInventoryCountClosed
+	}
+	return nil
+}
+
+// fillVariance рассчитывает расхождения строк в единицах и деньгах и сводку по документу
+func fillVariance(count *models.InventoryCount) {
+	count.TotalLines = len(count.Lines)
+	count.CountedLines = 0
+	shortage, surplus := 0.0, 0.0
+
+	for i := range count.Lines {
+		line := &count.Lines[i]
+		if line.CountedQuantity == nil {
+			continue
+		}
+		count.CountedLines++
+
+		variance := roundQuantity(*line.CountedQuantity - line.ExpectedQuantity)
+		cost := roundCost(units.ToBase(variance, line.Unit) * line.PricePerUnit)
+		line.Variance = &variance
+		line.VarianceCost = &cost
+		if cost < 0 {
+			shortage -= cost
+		} else {
+			surplus += cost
+		}
+	}
+
+	// Для утверждённых документов суммы зафиксированы в момент утверждения
+	if count.Status != models.InventoryCountApproved {
+		count.ShortageCost = roundCost(shortage)
+		count.SurplusCost = roundCost(surplus)
+	}
+	count.VarianceCost = roundCost(count.SurplusCost - count.ShortageCost)
+}
@@ -0,0 +1,658 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MenuPreviewTTL срок действия ссылки на предпросмотр черновика
+const MenuPreviewTTL = 24 * time.Hour
+
+// ErrMenuDraftOutdated после создания черновика меню уже публиковалось
+var ErrMenuDraftOutdated = errors.New("menu has been published since the draft was created, create a new draft")
+
+// ErrMenuDuplicateName в категории уже есть продукт с таким названием (без учёта регистра, как в CreateProduct)
+var ErrMenuDuplicateName = errors.New("product with this name already exists in this category")
+
+// MenuService - сервис черновиков, публикации и версий меню
+type MenuService struct {
+	priceService *PriceService
+}
+
+// NewMenuService создает новый экземпляр MenuService
+func NewMenuService() *MenuService {
+	return &MenuService{priceService: NewPriceService()}
+}
+
+// CreateDraft создает черновик из текущего состояния меню
+func (s *MenuService) CreateDraft(name string, createdBy *string) (*models.MenuDraft, error) {
+	db := database.GetDB()
+
+	items, err := captureMenu(db)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Черновик от " + time.Now().Format("02.01.2006 15:04")
+	}
+
+	draft := models.MenuDraft{
+		ID:          uuid.New().String(),
+		Name:        name,
+		Status:      models.MenuDraftOpen,
+		Items:       items,
+		BaseItems:   append(models.MenuSnapshot{}, items...),
+		BaseVersion: latestMenuVersion(db),
+		CreatedBy:   createdBy,
+	}
+	if err := db.Create(&draft).Error; err != nil {
+		return nil, fmt.Errorf("failed to create menu draft: %w", err)
+	}
+
+	log.Printf("[MENU] 📝 Draft %s created from version %d (%d products)", draft.ID, draft.BaseVersion, len(items))
+	return &draft, nil
+}
+
+// GetDrafts возвращает черновики меню (без содержимого)
+func (s *MenuService) GetDrafts() ([]models.MenuDraft, error) {
+	var drafts []models.MenuDraft
+	if err := database.GetDB().
+		Omit("items").
+		Order("created_at DESC").
+		Find(&drafts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch menu drafts: %w", err)
+	}
+	return drafts, nil
+}
+
+// GetDraft возвращает черновик с содержимым
+func (s *MenuService) GetDraft(id string) (*models.MenuDraft, error) {
+	var draft models.MenuDraft
+	if err := database.GetDB().First(&draft, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("menu draft not found: %w", err)
+	}
+	return &draft, nil
+}
+
+// UpdateItem изменяет продукт в черновике
+func (s *MenuService) UpdateItem(draftID, productID string, req models.UpdateMenuDraftItemRequest) (*models.MenuDraft, error) {
+	draft, err := s.openDraft(draftID)
+	if err != nil {
+		return nil, err
+	}
+
+	var item *models.MenuSnapshotItem
+	for i := range draft.Items {
+		if draft.Items[i].ProductID == productID {
+			item = &draft.Items[i]
+			break
+		}
+	}
+	if item == nil {
+		// Продукт создан после черновика — добавляем его текущее состояние
+		var product models.Product
+		if err := database.GetDB().First(&product, "id = ?", productID).Error; err != nil {
+			return nil, fmt.Errorf("product not found: %w", err)
+		}
+		draft.Items = append(draft.Items, snapshotItem(&product))
+		draft.BaseItems = append(draft.BaseItems, snapshotItem(&product))
+		item = &draft.Items[len(draft.Items)-1]
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len(name) > 100 {
+			return nil, fmt.Errorf("name must be 1-100 characters")
+		}
+		item.Name = name
+	}
+	if req.Price != nil {
+		if *req.Price < 0 {
+			return nil, fmt.Errorf("price must be positive")
+		}
+		item.Price = roundCost(*req.Price)
+	}
+	if req.Category != nil {
+		category := strings.TrimSpace(*req.Category)
+		if category == "" {
+			return nil, fmt.Errorf("category is required")
+		}
+		item.Category = category
+	}
+	if req.Description != nil {
+		item.Description = optionalString(*req.Description)
+	}
+	if req.ImageURL != nil {
+		item.ImageURL = optionalString(*req.ImageURL)
+	}
+	if req.Weight != nil {
+		item.Weight = optionalString(*req.Weight)
+	}
+	if req.IsVisible != nil {
+		item.IsVisible = *req.IsVisible
+	}
+
+	if req.Name != nil || req.Category != nil {
+		if err := s.checkDraftNames(database.GetDB(), draft); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := database.GetDB().Model(draft).Updates(map[string]interface{}{
+		"items":      draft.Items,
+		"base_items": draft.BaseItems,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update menu draft: %w", err)
+	}
+	return draft, nil
+}
+
+// RenameCategory переименовывает категорию у всех продуктов черновика
+func (s *MenuService) RenameCategory(draftID, from, to string) (*models.MenuDraft, int, error) {
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if from == "" || to == "" {
+		return nil, 0, fmt.Errorf("both 'from' and 'to' are required")
+	}
+
+	draft, err := s.openDraft(draftID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	renamed := 0
+	for i := range draft.Items {
+		if strings.EqualFold(draft.Items[i].Category, from) {
+			draft.Items[i].Category = to
+			renamed++
+		}
+	}
+	if renamed == 0 {
+		return nil, 0, fmt.Errorf("category %q not found in draft", from)
+	}
+	if err := s.checkDraftNames(database.GetDB(), draft); err != nil {
+		return nil, 0, err
+	}
+
+	if err := database.GetDB().Model(draft).Update("items", draft.Items).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to update menu draft: %w", err)
+	}
+	return draft, renamed, nil
+}
+
+// Discard отменяет черновик
+func (s *MenuService) Discard(draftID string) error {
+	result := database.GetDB().Model(&models.MenuDraft{}).
+		Where("id = ? AND status = ?", draftID, models.MenuDraftOpen).
+		Update("status", models.MenuDraftDiscarded)
+	if result.Error != nil {
+		return fmt.Errorf("failed to discard menu draft: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("open menu draft not found")
+	}
+	return nil
+}
+
+// PreviewLink создает подписанную ссылку на предпросмотр черновика
+func (s *MenuService) PreviewLink(draftID string) (*models.MenuPreviewLink, error) {
+	if _, err := s.openDraft(draftID); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(MenuPreviewTTL).Truncate(time.Second)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	url := fmt.Sprintf("/api/menu/preview/%s?expires=%s&signature=%s",
+		draftID, expires, previewSignature(draftID, expires))
+
+	return &models.MenuPreviewLink{URL: url, ExpiresAt: expiresAt}, nil
+}
+
+// VerifyPreview проверяет подпись и срок действия ссылки на предпросмотр
+func (s *MenuService) VerifyPreview(draftID, expires, signature string) error {
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid preview link")
+	}
+	if time.Now().Unix() > expiresUnix {
+		return fmt.Errorf("preview link expired")
+	}
+	if !hmac.Equal([]byte(signature), []byte(previewSignature(draftID, expires))) {
+		return fmt.Errorf("invalid preview link")
+	}
+	return nil
+}
+
+// PreviewProducts возвращает видимые продукты так, как они будут выглядеть после публикации
+func (s *MenuService) PreviewProducts(draftID string) ([]models.Product, error) {
+	draft, err := s.openDraft(draftID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(draft.Items))
+	for _, item := range draft.Items {
+		if item.IsVisible {
+			ids = append(ids, item.ProductID)
+		}
+	}
+	if len(ids) == 0 {
+		return []models.Product{}, nil
+	}
+
+	var live []models.Product
+	if err := database.GetDB().Where("id IN ?", ids).Find(&live).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	liveByID := make(map[string]models.Product, len(live))
+	for _, p := range live {
+		liveByID[p.ID] = p
+	}
+
+	products := make([]models.Product, 0, len(ids))
+	for _, item := range draft.Items {
+		product, ok := liveByID[item.ProductID]
+		if !ok || !item.IsVisible {
+			continue
+		}
+		applySnapshotItem(&product, item)
+		products = append(products, product)
+	}
+	return products, nil
+}
+
+// Publish атомарно применяет черновик к меню и сохраняет новую версию.
+// Применяются только поля, изменённые в черновике, поэтому правки продуктов,
+// сделанные напрямую после создания черновика, не откатываются. Если за это
+// время меню уже публиковалось, возвращается ErrMenuDraftOutdated.
+func (s *MenuService) Publish(draftID string, publishedBy *string) (*models.MenuVersion, error) {
+	var version *models.MenuVersion
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var draft models.MenuDraft
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&draft, "id = ?", draftID).Error; err != nil {
+			return fmt.Errorf("menu draft not found: %w", err)
+		}
+		if draft.Status != models.MenuDraftOpen {
+			return fmt.Errorf("menu draft is already %s", draft.Status)
+		}
+		if latestMenuVersion(tx) != draft.BaseVersion {
+			return ErrMenuDraftOutdated
+		}
+
+		changes, err := s.draftChanges(tx, &draft)
+		if err != nil {
+			return err
+		}
+		if err := checkMenuNames(tx, changes); err != nil {
+			return err
+		}
+		if err := s.applySnapshot(tx, changes, false, publishedBy); err != nil {
+			return err
+		}
+
+		version, err = s.saveVersion(tx, &draft.ID, "Публикация: "+draft.Name, publishedBy)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&draft).Updates(map[string]interface{}{
+			"status":       models.MenuDraftPublished,
+			"published_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[MENU] 🚀 Draft %s published as version %d", draftID, version.Version)
+	return version, nil
+}
+
+// GetVersions возвращает опубликованные версии меню (без содержимого)
+func (s *MenuService) GetVersions() ([]models.MenuVersion, error) {
+	var versions []models.MenuVersion
+	if err := database.GetDB().
+		Omit("items").
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch menu versions: %w", err)
+	}
+	return versions, nil
+}
+
+// GetVersion возвращает версию меню с содержимым
+func (s *MenuService) GetVersion(id string) (*models.MenuVersion, error) {
+	var version models.MenuVersion
+	if err := database.GetDB().First(&version, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("menu version not found: %w", err)
+	}
+	return &version, nil
+}
+
+// Rollback возвращает меню к состоянию версии. Продукты, которых не было в версии, скрываются.
+// Откат сохраняется как новая версия.
+func (s *MenuService) Rollback(versionID string, publishedBy *string) (*models.MenuVersion, error) {
+	target, err := s.GetVersion(versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var version *models.MenuVersion
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := checkMenuNames(tx, target.Items); err != nil {
+			return err
+		}
+		if err := s.applySnapshot(tx, target.Items, true, publishedBy); err != nil {
+			return err
+		}
+		var err error
+		version, err = s.saveVersion(tx, nil, fmt.Sprintf("Откат к версии %d", target.Version), publishedBy)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[MENU] ⏪ Menu rolled back to version %d (new version %d)", target.Version, version.Version)
+	return version, nil
+}
+
+// openDraft загружает черновик, доступный для изменения
+func (s *MenuService) openDraft(id string) (*models.MenuDraft, error) {
+	draft, err := s.GetDraft(id)
+	if err != nil {
+		return nil, err
+	}
+	if draft.Status != models.MenuDraftOpen {
+		return nil, fmt.Errorf("menu draft is already %s", draft.Status)
+	}
+	return draft, nil
+}
+
+// draftChanges возвращает изменённые в черновике продукты: текущее состояние
+// продукта, в котором заменены только поля, отличающиеся от исходного снимка черновика
+func (s *MenuService) draftChanges(tx *gorm.DB, draft *models.MenuDraft) (models.MenuSnapshot, error) {
+	baseByID := make(map[string]models.MenuSnapshotItem, len(draft.BaseItems))
+	for _, item := range draft.BaseItems {
+		baseByID[item.ProductID] = item
+	}
+
+	changedIDs := []string{}
+	for _, item := range draft.Items {
+		if base, ok := baseByID[item.ProductID]; !ok || !sameSnapshotItem(base, item) {
+			changedIDs = append(changedIDs, item.ProductID)
+		}
+	}
+	if len(changedIDs) == 0 {
+		return models.MenuSnapshot{}, nil
+	}
+
+	var products []models.Product
+	if err := tx.Where("id IN ?", changedIDs).Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	liveByID := make(map[string]models.MenuSnapshotItem, len(products))
+	for i := range products {
+		liveByID[products[i].ID] = snapshotItem(&products[i])
+	}
+
+	changes := make(models.MenuSnapshot, 0, len(changedIDs))
+	for _, item := range draft.Items {
+		live, ok := liveByID[item.ProductID]
+		if !ok {
+			continue
+		}
+		base, ok := baseByID[item.ProductID]
+		if !ok {
+			// Черновик создан до появления исходного снимка — применяем продукт целиком
+			changes = append(changes, item)
+			continue
+		}
+		changes = append(changes, mergeDraftItem(live, base, item))
+	}
+	return changes, nil
+}
+
+// checkDraftNames проверяет, что публикация черновика не создаст продуктов с одинаковыми
+// названием и категорией
+func (s *MenuService) checkDraftNames(db *gorm.DB, draft *models.MenuDraft) error {
+	changes, err := s.draftChanges(db, draft)
+	if err != nil {
+		return err
+	}
+	return checkMenuNames(db, changes)
+}
+
+// checkMenuNames проверяет уникальность названия в категории для меню, в котором
+// к текущим продуктам применены items
+func checkMenuNames(db *gorm.DB, items models.MenuSnapshot) error {
+	var products []models.Product
+	if err := db.Select("id", "name", "category").Find(&products).Error; err != nil {
+		return fmt.Errorf("failed to fetch products: %w", err)
+	}
+
+	menu := make(map[string]models.MenuSnapshotItem, len(products))
+	for _, p := range products {
+		menu[p.ID] = models.MenuSnapshotItem{ProductID: p.ID, Name: p.Name, Category: p.Category}
+	}
+	for _, item := range items {
+		if _, ok := menu[item.ProductID]; ok {
+			menu[item.ProductID] = item
+		}
+	}
+
+	list := make([]models.MenuSnapshotItem, 0, len(menu))
+	for _, item := range menu {
+		list = append(list, item)
+	}
+	return duplicateMenuName(list)
+}
+
+// duplicateMenuName возвращает ErrMenuDuplicateName, если у двух продуктов совпадают
+// название и категория без учёта регистра
+func duplicateMenuName(items []models.MenuSnapshotItem) error {
+	sort.Slice(items, func(i, j int) bool { return items[i].ProductID < items[j].ProductID })
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		key := importKey(item.Name, item.Category)
+		if seen[key] {
+			return fmt.Errorf("%w: %q in %q", ErrMenuDuplicateName, item.Name, item.Category)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// applySnapshot применяет снимок к продуктам (внутри транзакции).
+// hideMissing скрывает продукты, отсутствующие в снимке.
+func (s *MenuService) applySnapshot(tx *gorm.DB, items models.MenuSnapshot, hideMissing bool, changedBy *string) error {
+	var products []models.Product
+	if err := tx.Find(&products).Error; err != nil {
+		return fmt.Errorf("failed to fetch products: %w", err)
+	}
+	itemsByID := make(map[string]models.MenuSnapshotItem, len(items))
+	for _, item := range items {
+		itemsByID[item.ProductID] = item
+	}
+
+	changed := 0
+	for _, product := range products {
+		item, ok := itemsByID[product.ID]
+		if !ok {
+			if hideMissing && product.IsVisible {
+				if err := tx.Model(&models.Product{}).Where("id = ?", product.ID).Update("isVisible", false).Error; err != nil {
+					return fmt.Errorf("failed to hide product %s: %w", product.ID, err)
+				}
+				changed++
+			}
+			continue
+		}
+
+		updated := product
+		applySnapshotItem(&updated, item)
+		if sameSnapshotItem(snapshotItem(&updated), snapshotItem(&product)) {
+			continue
+		}
+
+		if err := tx.Model(&models.Product{}).Where("id = ?", product.ID).Updates(map[string]interface{}{
+			"name":        updated.Name,
+			"description": updated.Description,
+			"price":       updated.Price,
+			"category":    updated.Category,
+			"imageUrl":    updated.ImageURL,
+			"weight":      updated.Weight,
+			"isVisible":   updated.IsVisible,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update product %s: %w", product.ID, err)
+		}
+		if updated.Price != product.Price {
+			if err := s.priceService.RecordChange(tx, product.ID, product.Price, updated.Price, models.PriceSourceMenu, changedBy); err != nil {
+				return err
+			}
+		}
+		changed++
+	}
+
+	log.Printf("[MENU] 🔄 Applied menu snapshot: %d products changed", changed)
+	return nil
+}
+
+// saveVersion сохраняет текущее состояние меню как новую версию (внутри транзакции)
+func (s *MenuService) saveVersion(tx *gorm.DB, draftID *string, note string, publishedBy *string) (*models.MenuVersion, error) {
+	items, err := captureMenu(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	version := models.MenuVersion{
+		ID:          uuid.New().String(),
+		Version:     latestMenuVersion(tx) + 1,
+		Items:       items,
+		DraftID:     draftID,
+		Note:        note,
+		PublishedBy: publishedBy,
+	}
+	if err := tx.Create(&version).Error; err != nil {
+		return nil, fmt.Errorf("failed to save menu version: %w", err)
+	}
+	return &version, nil
+}
+
+// captureMenu снимает текущее состояние всех продуктов
+func captureMenu(db *gorm.DB) (models.MenuSnapshot, error) {
+	var products []models.Product
+	if err := db.Order("category ASC, name ASC").Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+
+	items := make(models.MenuSnapshot, 0, len(products))
+	for i := range products {
+		items = append(items, snapshotItem(&products[i]))
+	}
+	return items, nil
+}
+
+// latestMenuVersion возвращает номер последней опубликованной версии (0 — версий нет)
+func latestMenuVersion(db *gorm.DB) int {
+	var version int
+	db.Model(&models.MenuVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&version)
+	return version
+}
+
+func snapshotItem(p *models.Product) models.MenuSnapshotItem {
+	return models.MenuSnapshotItem{
+		ProductID:   p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		Category:    p.Category,
+		ImageURL:    p.ImageURL,
+		Weight:      p.Weight,
+		IsVisible:   p.IsVisible,
+	}
+}
+
+// sameSnapshotItem сравнивает состояния продукта по значениям (а не по указателям)
+func sameSnapshotItem(a, b models.MenuSnapshotItem) bool {
+	return a.ProductID == b.ProductID &&
+		a.Name == b.Name &&
+		a.Price == b.Price &&
+		a.Category == b.Category &&
+		a.IsVisible == b.IsVisible &&
+		sameOptionalString(a.Description, b.Description) &&
+		sameOptionalString(a.ImageURL, b.ImageURL) &&
+		sameOptionalString(a.Weight, b.Weight)
+}
+
+func sameOptionalString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// mergeDraftItem переносит в текущее состояние продукта поля, изменённые в черновике
+func mergeDraftItem(live, base, draft models.MenuSnapshotItem) models.MenuSnapshotItem {
+	merged := live
+	if draft.Name != base.Name {
+		merged.Name = draft.Name
+	}
+	if draft.Price != base.Price {
+		merged.Price = draft.Price
+	}
+	if draft.Category != base.Category {
+		merged.Category = draft.Category
+	}
+	if draft.IsVisible != base.IsVisible {
+		merged.IsVisible = draft.IsVisible
+	}
+	if !sameOptionalString(draft.Description, base.Description) {
+		merged.Description = draft.Description
+	}
+	if !sameOptionalString(draft.ImageURL, base.ImageURL) {
+		merged.ImageURL = draft.ImageURL
+	}
+	if !sameOptionalString(draft.Weight, base.Weight) {
+		merged.Weight = draft.Weight
+	}
+	return merged
+}
+
+func applySnapshotItem(p *models.Product, item models.MenuSnapshotItem) {
+	p.Name = item.Name
+	p.Description = item.Description
+	p.Price = item.Price
+	p.Category = item.Category
+	p.ImageURL = item.ImageURL
+	p.Weight = item.Weight
+	p.IsVisible = item.IsVisible
+}
+
+// previewSignature подписывает ссылку на предпросмотр черновика (HMAC-SHA256)
+func previewSignature(draftID, expires string) string {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "your-secret-key-change-this-in-production"
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("menu-preview:" + draftID + ":" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
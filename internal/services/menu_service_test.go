@@ -0,0 +1,34 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+)
+
+func TestDuplicateMenuName(t *testing.T) {
+	item := func(id, name, category string) models.MenuSnapshotItem {
+		return models.MenuSnapshotItem{ProductID: id, Name: name, Category: category}
+	}
+
+	tests := []struct {
+		name    string
+		items   []models.MenuSnapshotItem
+		wantErr bool
+	}{
+		{name: "unique names", items: []models.MenuSnapshotItem{item("1", "Филадельфия", "Роллы"), item("2", "Калифорния", "Роллы")}},
+		{name: "same name in another category", items: []models.MenuSnapshotItem{item("1", "Мисо", "Супы"), item("2", "Мисо", "Соусы")}},
+		{name: "same name and category", items: []models.MenuSnapshotItem{item("1", "Мисо", "Супы"), item("2", "Мисо", "Супы")}, wantErr: true},
+		{name: "case and spaces are ignored", items: []models.MenuSnapshotItem{item("1", "мисо ", "супы"), item("2", "Мисо", " Супы")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := duplicateMenuName(tt.items)
+			if tt.wantErr != errors.Is(err, ErrMenuDuplicateName) {
+				t.Errorf("duplicateMenuName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+)
+
+// NutritionService - сервис расчёта КБЖУ, аллергенов и диетических меток по составу продуктов
+type NutritionService struct{}
+
+// NewNutritionService создает новый экземпляр NutritionService
+func NewNutritionService() *NutritionService {
+	return &NutritionService{}
+}
+
+// ForProducts рассчитывает пищевую ценность порции для каждого продукта
+func (s *NutritionService) ForProducts(productIDs []string) (map[string]*models.ProductNutrition, error) {
+	result := make(map[string]*models.ProductNutrition, len(productIDs))
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	requirements, err := portionRequirements(productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	ingredientIDs := []string{}
+	for _, req := range requirements {
+		for ingredientID := range req {
+			ingredientIDs = appendUnique(ingredientIDs, ingredientID)
+		}
+	}
+
+	ingredients := map[string]models.Ingredient{}
+	if len(ingredientIDs) > 0 {
+		var list []models.Ingredient
+		if err := database.GetDB().Where("id IN ?", ingredientIDs).Find(&list).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch ingredients: %w", err)
+		}
+		for _, ing := range list {
+			ingredients[ing.ID] = ing
+		}
+	}
+
+	ingUnits, err := loadIngredientUnits(database.GetDB(), ingredientIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, productID := range productIDs {
+		result[productID] = calculateNutrition(requirements[productID], ingredients, ingUnits)
+	}
+
+	return result, nil
+}
+
+// Apply заполняет пищевую ценность у списка продуктов
+func (s *NutritionService) Apply(products []models.Product) error {
+	ids := make([]string, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	nutrition, err := s.ForProducts(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		products[i].Nutrition = nutrition[products[i].ID]
+	}
+	return nil
+}
+
+// calculateNutrition суммирует КБЖУ и аллергены ингредиентов одной порции
+func calculateNutrition(requirements map[string]float64, ingredients map[string]models.Ingredient, ingUnits *ingredientUnits) *models.ProductNutrition {
+	n := &models.ProductNutrition{
+		Complete:    len(requirements) > 0,
+		Allergens:   []string{},
+		DietaryTags: []string{},
+	}
+	if len(requirements) == 0 {
+		return n
+	}
+
+	allergens := map[string]bool{}
+	vegetarian, vegan, allergensKnown := true, true, true
+
+	for ingredientID, qty := range requirements {
+		ing, ok := ingredients[ingredientID]
+		if !ok {
+			n.Complete = false
+			vegetarian, vegan, allergensKnown = false, false, false
+			continue
+		}
+
+		for _, a := range ing.Allergens {
+			allergens[a] = true
+		}
+		if ing.Allergens == nil {
+			allergensKnown = false
+		}
+		vegetarian = vegetarian && (ing.IsVegetarian || ing.IsVegan)
+		vegan = vegan && ing.IsVegan
+
+		grams, ok := gramsOf(ingUnits, ing, qty)
+		if !ok || ing.Kcal == nil || ing.Protein == nil || ing.Fat == nil || ing.Carbs == nil {
+			n.Complete = false
+		}
+		if !ok {
+			continue
+		}
+
+		n.WeightGrams += grams
+		if ing.Kcal != nil {
+			n.Total.Kcal += *ing.Kcal * grams / 100
+		}
+		if ing.Protein != nil {
+			n.Total.Protein += *ing.Protein * grams / 100
+		}
+		if ing.Fat != nil {
+			n.Total.Fat += *ing.Fat * grams / 100
+		}
+		if ing.Carbs != nil {
+			n.Total.Carbs += *ing.Carbs * grams / 100
+		}
+	}
+
+	n.WeightGrams = roundCost(n.WeightGrams)
+	if n.WeightGrams > 0 {
+		n.Per100g = &models.NutritionFacts{
+			Kcal:    roundCost(n.Total.Kcal / n.WeightGrams * 100),
+			Protein: roundCost(n.Total.Protein / n.WeightGrams * 100),
+			Fat:     roundCost(n.Total.Fat / n.WeightGrams * 100),
+			Carbs:   roundCost(n.Total.Carbs / n.WeightGrams * 100),
+		}
+	}
+	n.Total = models.NutritionFacts{
+		Kcal:    roundCost(n.Total.Kcal),
+		Protein: roundCost(n.Total.Protein),
+		Fat:     roundCost(n.Total.Fat),
+		Carbs:   roundCost(n.Total.Carbs),
+	}
+
+	for a := range allergens {
+		n.Allergens = append(n.Allergens, a)
+	}
+	sort.Strings(n.Allergens)
+
+	if vegetarian {
+		n.DietaryTags = append(n.DietaryTags, models.DietaryVegetarian)
+	}
+	if vegan {
+		n.DietaryTags = append(n.DietaryTags, models.DietaryVegan)
+	}
+	// "Без глютена/лактозы" ставим только если аллергены заданы у всех ингредиентов
+	if allergensKnown && !allergens[models.AllergenGluten] {
+		n.DietaryTags = append(n.DietaryTags, models.DietaryGlutenFree)
+	}
+	if allergensKnown && !allergens[models.AllergenMilk] {
+		n.DietaryTags = append(n.DietaryTags, models.DietaryLactoseFree)
+	}
+	n.AllergensKnown = allergensKnown
+
+	return n
+}
+
+// gramsOf переводит количество в базовой единице склада (кг/л/шт) в граммы по коэффициентам
+// ингредиента. Без коэффициента для жидкостей принимается плотность 1 г/мл,
+// штучные ингредиенты без веса штуки не пересчитываются.
+func gramsOf(ingUnits *ingredientUnits, ing models.Ingredient, baseQty float64) (float64, bool) {
+	base := units.BaseUnit(units.Normalize(ing.Unit))
+	if grams, err := ingUnits.converter(ing.ID).Convert(baseQty, base, units.Gram); err == nil {
+		return grams, true
+	}
+	if units.Dimension(base) == units.DimensionVolume {
+		return baseQty * 1000, true
+	}
+	return 0, false
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+)
+
+func emptyIngredientUnits() *ingredientUnits {
+	return &ingredientUnits{
+		stockUnits: map[string]string{},
+		converters: map[string]*units.Converter{},
+		trimLoss:   map[string]float64{},
+	}
+}
+
+func TestCalculateNutritionAllergens(t *testing.T) {
+	kcal := 100.0
+
+	tests := []struct {
+		name           string
+		allergens      [][]string // Аллергены ингредиентов; nil — не указаны
+		wantKnown      bool
+		wantGlutenFree bool
+		wantAllergens  []string
+	}{
+		{
+			name:           "all ingredients have no allergens",
+			allergens:      [][]string{{}, {}},
+			wantKnown:      true,
+			wantGlutenFree: true,
+			wantAllergens:  []string{},
+		},
+		{
+			name:           "one ingredient without allergen data",
+			allergens:      [][]string{{}, nil},
+			wantKnown:      false,
+			wantGlutenFree: false,
+			wantAllergens:  []string{},
+		},
+		{
+			name:           "gluten is declared",
+			allergens:      [][]string{{models.AllergenGluten}, {}},
+			wantKnown:      true,
+			wantGlutenFree: false,
+			wantAllergens:  []string{models.AllergenGluten},
+		},
+		{
+			name:           "milk declared, another ingredient without data",
+			allergens:      [][]string{{models.AllergenMilk}, nil},
+			wantKnown:      false,
+			wantGlutenFree: false,
+			wantAllergens:  []string{models.AllergenMilk},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirements := map[string]float64{}
+			ingredients := map[string]models.Ingredient{}
+			for i, a := range tt.allergens {
+				id := string(rune('a' + i))
+				requirements[id] = 0.1
+				ing := models.Ingredient{ID: id, Unit: units.Kilogram, Kcal: &kcal, Protein: &kcal, Fat: &kcal, Carbs: &kcal}
+				if a != nil {
+					ing.Allergens = models.StringList(a)
+				}
+				ingredients[id] = ing
+			}
+
+			n := calculateNutrition(requirements, ingredients, emptyIngredientUnits())
+
+			if n.AllergensKnown != tt.wantKnown {
+				t.Errorf("AllergensKnown = %v, want %v", n.AllergensKnown, tt.wantKnown)
+			}
+			if got := models.StringList(n.DietaryTags).Contains(models.DietaryGlutenFree); got != tt.wantGlutenFree {
+				t.Errorf("gluten_free tag = %v, want %v (tags %v)", got, tt.wantGlutenFree, n.DietaryTags)
+			}
+			if len(n.Allergens) != len(tt.wantAllergens) {
+				t.Fatalf("Allergens = %v, want %v", n.Allergens, tt.wantAllergens)
+			}
+			for i := range n.Allergens {
+				if n.Allergens[i] != tt.wantAllergens[i] {
+					t.Errorf("Allergens = %v, want %v", n.Allergens, tt.wantAllergens)
+				}
+			}
+		})
+	}
+}
+
+func TestStringListValueKeepsUnknown(t *testing.T) {
+	tests := []struct {
+		name string
+		list models.StringList
+		want interface{}
+	}{
+		{name: "nil is stored as NULL", list: nil, want: nil},
+		{name: "empty list is stored as []", list: models.StringList{}, want: "[]"},
+		{name: "values", list: models.StringList{"gluten", "milk"}, want: `["gluten","milk"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.list.Value()
+			if err != nil {
+				t.Fatalf("Value() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Value() = %#v, want %#v", got, tt.want)
+			}
+
+			var scanned models.StringList
+			if err := scanned.Scan(got); err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			if (scanned == nil) != (tt.list == nil) {
+				t.Errorf("Scan(Value()) nil = %v, want %v", scanned == nil, tt.list == nil)
+			}
+		})
+	}
+}
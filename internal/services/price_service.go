@@ -0,0 +1,270 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// errScheduledPriceTaken изменение цены уже применено или отменено параллельно
+var errScheduledPriceTaken = errors.New("scheduled price change is no longer pending")
+
+// PriceService - сервис истории цен и запланированных изменений цены
+type PriceService struct{}
+
+// NewPriceService создает новый экземпляр PriceService
+func NewPriceService() *PriceService {
+	return &PriceService{}
+}
+
+// RecordChange записывает изменение цены продукта в историю (внутри транзакции)
+func (s *PriceService) RecordChange(tx *gorm.DB, productID string, oldPrice, newPrice float64, source string, changedBy *string) error {
+	entry := models.ProductPriceHistory{
+		ID:        uuid.New().String(),
+		ProductID: productID,
+		OldPrice:  oldPrice,
+		NewPrice:  newPrice,
+		Source:    source,
+		ChangedBy: changedBy,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to record price change: %w", err)
+	}
+	return nil
+}
+
+// Schedule планирует новую цену продукта на будущую дату
+func (s *PriceService) Schedule(productID string, price float64, effectiveAt time.Time, createdBy *string) (*models.ScheduledPriceChange, error) {
+	db := database.GetDB()
+
+	if price <= 0 {
+		return nil, fmt.Errorf("price must be positive")
+	}
+	if !effectiveAt.After(time.Now()) {
+		return nil, fmt.Errorf("effective date must be in the future")
+	}
+
+	var product models.Product
+	if err := db.First(&product, "id = ?", productID).Error; err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+
+	change := models.ScheduledPriceChange{
+		ID:          uuid.New().String(),
+		ProductID:   productID,
+		NewPrice:    roundCost(price),
+		EffectiveAt: effectiveAt,
+		Status:      models.ScheduledPricePending,
+		CreatedBy:   createdBy,
+	}
+	if err := db.Create(&change).Error; err != nil {
+		return nil, fmt.Errorf("failed to schedule price change: %w", err)
+	}
+
+	log.Printf("[PRICE] 🗓️ Scheduled price %.2f for product %s (%s) at %s",
+		change.NewPrice, product.Name, productID, effectiveAt.Format(time.RFC3339))
+	return &change, nil
+}
+
+// GetScheduled возвращает запланированные изменения цены продукта
+func (s *PriceService) GetScheduled(productID string) ([]models.ScheduledPriceChange, error) {
+	var changes []models.ScheduledPriceChange
+	if err := database.GetDB().
+		Where("product_id = ?", productID).
+		Order("effective_at ASC").
+		Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch scheduled prices: %w", err)
+	}
+	return changes, nil
+}
+
+// Cancel отменяет ещё не применённое изменение цены
+func (s *PriceService) Cancel(id string) error {
+	result := database.GetDB().Model(&models.ScheduledPriceChange{}).
+		Where("id = ? AND status = ?", id, models.ScheduledPricePending).
+		Update("status", models.ScheduledPriceCancelled)
+	if result.Error != nil {
+		return fmt.Errorf("failed to cancel scheduled price: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("pending scheduled price not found")
+	}
+	return nil
+}
+
+// ApplyDue применяет все запланированные изменения цены, срок которых наступил
+func (s *PriceService) ApplyDue(now time.Time) (int, error) {
+	db := database.GetDB()
+
+	var due []models.ScheduledPriceChange
+	if err := db.Where("status = ? AND effective_at <= ?", models.ScheduledPricePending, now).
+		Order("effective_at ASC").
+		Find(&due).Error; err != nil {
+		return 0, fmt.Errorf("failed to fetch due price changes: %w", err)
+	}
+
+	applied := 0
+	for _, change := range due {
+		failed := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Сначала забираем изменение: если его уже применил другой экземпляр
+			// планировщика или отменили, строка не обновится
+			claim := tx.Model(&models.ScheduledPriceChange{}).
+				Where("id = ? AND status = ?", change.ID, models.ScheduledPricePending).
+				Updates(map[string]interface{}{
+					"status":     models.ScheduledPriceApplied,
+					"applied_at": now,
+				})
+			if claim.Error != nil {
+				return fmt.Errorf("failed to mark price change applied: %w", claim.Error)
+			}
+			if claim.RowsAffected == 0 {
+				return errScheduledPriceTaken
+			}
+
+			var product models.Product
+			err := tx.First(&product, "id = ?", change.ProductID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// Продукт удалён — изменение больше не применить, закрываем его с причиной,
+				// чтобы планировщик не повторял попытку
+				failed = true
+				return tx.Model(&models.ScheduledPriceChange{}).Where("id = ?", change.ID).
+					Updates(map[string]interface{}{
+						"status":         models.ScheduledPriceFailed,
+						"applied_at":     nil,
+						"failure_reason": "product deleted",
+					}).Error
+			}
+			if err != nil {
+				return fmt.Errorf("failed to fetch product: %w", err)
+			}
+
+			if err := tx.Model(&models.Product{}).Where("id = ?", product.ID).Update("price", change.NewPrice).Error; err != nil {
+				return fmt.Errorf("failed to update price: %w", err)
+			}
+			return s.RecordChange(tx, product.ID, product.Price, change.NewPrice, models.PriceSourceScheduled, change.CreatedBy)
+		})
+		if errors.Is(err, errScheduledPriceTaken) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[PRICE] ❌ Failed to apply scheduled price %s: %v", change.ID, err)
+			continue
+		}
+		if failed {
+			log.Printf("[PRICE] ⚠️ Scheduled price %s not applied: product %s was deleted", change.ID, change.ProductID)
+			continue
+		}
+
+		applied++
+		log.Printf("[PRICE] ✅ Applied scheduled price %.2f for product %s", change.NewPrice, change.ProductID)
+	}
+
+	return applied, nil
+}
+
+// StartScheduler запускает фоновую проверку запланированных цен с заданным интервалом
+func (s *PriceService) StartScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.ApplyDue(time.Now()); err != nil {
+				log.Printf("[PRICE] ❌ Scheduler error: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("[PRICE] ⏰ Price scheduler started (interval: %s)", interval)
+}
+
+// GetHistory возвращает периоды действия цен продукта со статистикой продаж в каждом периоде
+func (s *PriceService) GetHistory(productID string) ([]models.PricePeriod, error) {
+	db := database.GetDB()
+
+	var product models.Product
+	if err := db.First(&product, "id = ?", productID).Error; err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+
+	var history []models.ProductPriceHistory
+	if err := db.Where("product_id = ?", productID).Order("created_at ASC").Find(&history).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch price history: %w", err)
+	}
+
+	// Строим периоды действия цен
+	periods := []models.PricePeriod{}
+	if len(history) == 0 {
+		periods = append(periods, models.PricePeriod{
+			Price:  product.Price,
+			From:   product.CreatedAt,
+			Source: models.PriceSourceInitial,
+		})
+	} else {
+		if history[0].Source != models.PriceSourceInitial {
+			// Продукт создан до появления истории цен
+			to := history[0].CreatedAt
+			periods = append(periods, models.PricePeriod{
+				Price:  history[0].OldPrice,
+				From:   product.CreatedAt,
+				To:     &to,
+				Source: models.PriceSourceInitial,
+			})
+		}
+		for i, entry := range history {
+			period := models.PricePeriod{
+				Price:  entry.NewPrice,
+				From:   entry.CreatedAt,
+				Source: entry.Source,
+			}
+			if i+1 < len(history) {
+				to := history[i+1].CreatedAt
+				period.To = &to
+			}
+			periods = append(periods, period)
+		}
+	}
+
+	// Статистика продаж в каждом периоде
+	for i := range periods {
+		p := &periods[i]
+		to := time.Now()
+		if p.To != nil {
+			to = *p.To
+		}
+
+		row := db.Raw(`
+			SELECT
+				COALESCE(SUM(oi.quantity), 0),
+				COALESCE(SUM(oi.quantity * oi.price), 0),
+				COUNT(DISTINCT o.id)
+			FROM "OrderItem" oi
+			JOIN "Order" o ON o.id = oi.order_id
+			WHERE oi.product_id = ?
+				AND o.status <> 'cancelled'
+				AND o.created_at >= ?
+				AND o.created_at < ?
+		`, productID, p.From, to).Row()
+		if err := row.Scan(&p.UnitsSold, &p.Revenue, &p.OrdersCount); err != nil {
+			return nil, fmt.Errorf("failed to calculate sales: %w", err)
+		}
+
+		days := to.Sub(p.From).Hours() / 24
+		if days < 1 {
+			days = 1
+		}
+		p.Revenue = roundCost(p.Revenue)
+		p.UnitsPerDay = roundCost(float64(p.UnitsSold) / days)
+		p.RevenuePerDay = roundCost(p.Revenue / days)
+	}
+
+	return periods, nil
+}
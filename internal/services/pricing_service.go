@@ -0,0 +1,277 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// PricingService - сервис ценовых правил по времени (happy hour, скидки по дням недели)
+type PricingService struct {
+	location *time.Location
+}
+
+// NewPricingService создает новый экземпляр PricingService
+func NewPricingService() *PricingService {
+	location := time.Local
+	if tz := os.Getenv("PRICING_TIMEZONE"); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			location = loc
+		} else {
+			log.Printf("[PRICING] ⚠️ Unknown PRICING_TIMEZONE %q, using local time: %v", tz, err)
+		}
+	}
+	return &PricingService{location: location}
+}
+
+// GetAll возвращает все ценовые правила
+func (s *PricingService) GetAll() ([]models.PricingRule, error) {
+	var rules []models.PricingRule
+	if err := database.GetDB().Order("created_at DESC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch pricing rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Create создает ценовое правило
+func (s *PricingService) Create(req models.PricingRuleRequest) (*models.PricingRule, error) {
+	rule := models.PricingRule{ID: uuid.New().String(), IsActive: true}
+	if err := fillPricingRule(&rule, req); err != nil {
+		return nil, err
+	}
+
+	if err := database.GetDB().Create(&rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to create pricing rule: %w", err)
+	}
+
+	log.Printf("[PRICING] ✅ Created rule %s (%s %.2f)", rule.Name, rule.Type, rule.Value)
+	return &rule, nil
+}
+
+// Update обновляет ценовое правило
+func (s *PricingService) Update(id string, req models.PricingRuleRequest) (*models.PricingRule, error) {
+	db := database.GetDB()
+
+	var rule models.PricingRule
+	if err := db.First(&rule, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("pricing rule not found: %w", err)
+	}
+	if err := fillPricingRule(&rule, req); err != nil {
+		return nil, err
+	}
+
+	if err := db.Save(&rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to update pricing rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// Delete удаляет ценовое правило
+func (s *PricingService) Delete(id string) error {
+	result := database.GetDB().Delete(&models.PricingRule{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete pricing rule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("pricing rule not found")
+	}
+	return nil
+}
+
+// ActiveRules возвращает правила, действующие в указанный момент
+func (s *PricingService) ActiveRules(now time.Time) ([]models.PricingRule, error) {
+	var rules []models.PricingRule
+	if err := database.GetDB().Where("is_active = ?", true).Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch pricing rules: %w", err)
+	}
+
+	now = now.In(s.location)
+	active := make([]models.PricingRule, 0, len(rules))
+	for _, rule := range rules {
+		if s.isActiveAt(&rule, now) {
+			active = append(active, rule)
+		}
+	}
+	return active, nil
+}
+
+// EffectivePrice рассчитывает цену продукта с учётом действующих правил.
+// Если подходит несколько правил, применяется самое выгодное для гостя.
+func (s *PricingService) EffectivePrice(product *models.Product, rules []models.PricingRule) (float64, *models.PricingRule) {
+	best := product.Price
+	var applied *models.PricingRule
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.AppliesTo(product) {
+			continue
+		}
+
+		price := product.Price
+		switch rule.Type {
+		case models.PricingRulePercent:
+			price = product.Price * (1 - rule.Value/100)
+		case models.PricingRuleFixed:
+			price = rule.Value
+		}
+		price = roundCost(price)
+
+		if price < best {
+			best = price
+			applied = rule
+		}
+	}
+
+	return best, applied
+}
+
+// Apply заполняет действующую цену у списка продуктов
+func (s *PricingService) Apply(products []models.Product) error {
+	rules, err := s.ActiveRules(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		price, rule := s.EffectivePrice(&products[i], rules)
+		products[i].EffectivePrice = price
+		products[i].AppliedRule = nil
+		if rule != nil {
+			products[i].AppliedRule = &models.AppliedPricingRule{
+				ID:    rule.ID,
+				Name:  rule.Name,
+				Type:  rule.Type,
+				Value: rule.Value,
+			}
+		}
+	}
+	return nil
+}
+
+// isActiveAt проверяет срок действия, день недели и время правила
+func (s *PricingService) isActiveAt(rule *models.PricingRule, now time.Time) bool {
+	if rule.ValidFrom != nil && now.Before(*rule.ValidFrom) {
+		return false
+	}
+	if rule.ValidTo != nil && !now.Before(*rule.ValidTo) {
+		return false
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	start, _ := parseClock(rule.StartTime)
+	end, _ := parseClock(rule.EndTime)
+	day := now.Weekday()
+
+	if rule.StartTime != "" && rule.EndTime != "" && end <= start {
+		// Интервал через полночь: после полуночи действует правило предыдущего дня
+		if minute < end {
+			day = (day + 6) % 7
+		} else if minute < start {
+			return false
+		}
+	} else {
+		if rule.StartTime != "" && minute < start {
+			return false
+		}
+		if rule.EndTime != "" && minute >= end {
+			return false
+		}
+	}
+
+	return len(rule.DaysOfWeek) == 0 || rule.DaysOfWeek.Contains(models.WeekDays[day])
+}
+
+// fillPricingRule валидирует запрос и переносит его в модель
+func fillPricingRule(rule *models.PricingRule, req models.PricingRuleRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	switch req.Type {
+	case models.PricingRulePercent:
+		if req.Value <= 0 || req.Value > 100 {
+			return fmt.Errorf("percent value must be between 0 and 100")
+		}
+	case models.PricingRuleFixed:
+		// Нулевая цена сделала бы продукт бесплатным — как и в запланированных ценах
+		if roundCost(req.Value) <= 0 {
+			return fmt.Errorf("fixed price must be positive")
+		}
+	default:
+		return fmt.Errorf("type must be 'percent' or 'fixed'")
+	}
+
+	for _, value := range []string{req.StartTime, req.EndTime} {
+		if value == "" {
+			continue
+		}
+		if _, err := parseClock(value); err != nil {
+			return err
+		}
+	}
+
+	days := models.StringList{}
+	for _, day := range req.DaysOfWeek {
+		day = strings.ToLower(strings.TrimSpace(day))
+		valid := false
+		for _, known := range models.WeekDays {
+			if day == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unknown day of week: %s", day)
+		}
+		if !days.Contains(day) {
+			days = append(days, day)
+		}
+	}
+
+	if req.ValidFrom != nil && req.ValidTo != nil && !req.ValidTo.After(*req.ValidFrom) {
+		return fmt.Errorf("validTo must be after validFrom")
+	}
+
+	rule.Name = name
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+	rule.DaysOfWeek = days
+	rule.StartTime = req.StartTime
+	rule.EndTime = req.EndTime
+	rule.Category = strings.TrimSpace(req.Category)
+	rule.ProductIDs = models.StringList(req.ProductIDs)
+	if rule.ProductIDs == nil {
+		rule.ProductIDs = models.StringList{}
+	}
+	rule.Type = req.Type
+	rule.Value = roundCost(req.Value)
+	rule.ValidFrom = req.ValidFrom
+	rule.ValidTo = req.ValidTo
+	return nil
+}
+
+// parseClock разбирает время "HH:MM" в минуты от начала суток
+func parseClock(value string) (int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil || hours < 0 || hours > 23 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes < 0 || minutes > 59 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return hours*60 + minutes, nil
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+)
+
+func TestPricingServiceIsActiveAt(t *testing.T) {
+	s := &PricingService{location: time.UTC}
+	// 16 октября 2026 — пятница
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		rule models.PricingRule
+		now  time.Time
+		want bool
+	}{
+		{
+			name: "daytime window inside",
+			rule: models.PricingRule{StartTime: "14:00", EndTime: "16:00"},
+			now:  at(16, 15, 0),
+			want: true,
+		},
+		{
+			name: "daytime window end is exclusive",
+			rule: models.PricingRule{StartTime: "14:00", EndTime: "16:00"},
+			now:  at(16, 16, 0),
+			want: false,
+		},
+		{
+			name: "overnight window before midnight",
+			rule: models.PricingRule{StartTime: "22:00", EndTime: "02:00"},
+			now:  at(16, 23, 30),
+			want: true,
+		},
+		{
+			name: "overnight window after midnight",
+			rule: models.PricingRule{StartTime: "22:00", EndTime: "02:00"},
+			now:  at(17, 1, 30),
+			want: true,
+		},
+		{
+			name: "overnight window gap during the day",
+			rule: models.PricingRule{StartTime: "22:00", EndTime: "02:00"},
+			now:  at(17, 12, 0),
+			want: false,
+		},
+		{
+			name: "overnight window end is exclusive",
+			rule: models.PricingRule{StartTime: "22:00", EndTime: "02:00"},
+			now:  at(17, 2, 0),
+			want: false,
+		},
+		{
+			name: "after midnight belongs to the previous day",
+			rule: models.PricingRule{StartTime: "22:00", EndTime: "02:00", DaysOfWeek: models.StringList{"fri"}},
+			now:  at(17, 1, 0), // суббота, но окно открылось в пятницу
+			want: true,
+		},
+		{
+			name: "after midnight of a day without the rule",
+			rule: models.PricingRule{StartTime: "22:00", EndTime: "02:00", DaysOfWeek: models.StringList{"fri"}},
+			now:  at(16, 1, 0), // пятница, окно открылось в четверг
+			want: false,
+		},
+		{
+			name: "before midnight on the rule day",
+			rule: models.PricingRule{StartTime: "22:00", EndTime: "02:00", DaysOfWeek: models.StringList{"fri"}},
+			now:  at(16, 22, 0),
+			want: true,
+		},
+		{
+			name: "equal start and end cover the whole day",
+			rule: models.PricingRule{StartTime: "10:00", EndTime: "10:00"},
+			now:  at(16, 9, 59),
+			want: true,
+		},
+		{
+			name: "no window, valid period has ended",
+			rule: models.PricingRule{ValidTo: timePtr(at(16, 0, 0))},
+			now:  at(16, 12, 0),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.isActiveAt(&tt.rule, tt.now); got != tt.want {
+				t.Errorf("isActiveAt(%s) = %v, want %v", tt.now.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestFillPricingRuleValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		ruleType  string
+		value     float64
+		wantError bool
+	}{
+		{name: "percent discount", ruleType: models.PricingRulePercent, value: 20},
+		{name: "zero percent", ruleType: models.PricingRulePercent, value: 0, wantError: true},
+		{name: "percent above 100", ruleType: models.PricingRulePercent, value: 120, wantError: true},
+		{name: "fixed price", ruleType: models.PricingRuleFixed, value: 199},
+		{name: "zero fixed price makes the item free", ruleType: models.PricingRuleFixed, value: 0, wantError: true},
+		{name: "fixed price rounded to zero", ruleType: models.PricingRuleFixed, value: 0.001, wantError: true},
+		{name: "negative fixed price", ruleType: models.PricingRuleFixed, value: -10, wantError: true},
+		{name: "unknown type", ruleType: "free", value: 10, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rule models.PricingRule
+			err := fillPricingRule(&rule, models.PricingRuleRequest{Name: "Счастливые часы", Type: tt.ruleType, Value: tt.value})
+			if (err != nil) != tt.wantError {
+				t.Errorf("fillPricingRule() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,660 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// productCSVHeader колонки CSV импорта/экспорта продуктов.
+// Состав записывается как "Лосось:50:g;Рис:100:g".
+var productCSVHeader = []string{"name", "category", "price", "description", "weight", "imageUrl", "isVisible", "ingredients", "semiFinished"}
+
+// ProductImportService - сервис массового импорта и экспорта продуктов
+type ProductImportService struct {
+	priceService   *PriceService
+	bundleService  *BundleService
+	recipeVersions *RecipeVersionService
+}
+
+// NewProductImportService создает новый экземпляр ProductImportService
+func NewProductImportService() *ProductImportService {
+	return &ProductImportService{
+		priceService:   NewPriceService(),
+		bundleService:  NewBundleService(),
+		recipeVersions: NewRecipeVersionService(),
+	}
+}
+
+// preparedImportRow проверенная строка импорта, готовая к записи
+type preparedImportRow struct {
+	result       *models.ProductImportRowResult
+	row          models.ProductImportRow
+	existing     *models.Product
+	ingredients  []models.ProductIngredient
+	semiFinished []models.ProductSemiFinished
+	cost         float64
+}
+
+// Import проверяет строки и, если ошибок нет и это не dry-run, атомарно создаёт/обновляет продукты.
+// Продукты сопоставляются по названию и категории без учёта регистра (как в CreateProduct).
+// rowErrors — ошибки разбора по номерам строк (для CSV).
+func (s *ProductImportService) Import(rows []models.ProductImportRow, rowErrors map[int][]string, dryRun bool, userID *string) (*models.ProductImportResult, error) {
+	db := database.GetDB()
+	result := &models.ProductImportResult{
+		DryRun: dryRun,
+		Total:  len(rows),
+		Rows:   make([]models.ProductImportRowResult, len(rows)),
+	}
+
+	refs, err := loadImportReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []models.Product
+	if err := db.Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	existingByKey := make(map[string]*models.Product, len(existing))
+	for i := range existing {
+		existingByKey[importKey(existing[i].Name, existing[i].Category)] = &existing[i]
+	}
+
+	prepared := make([]preparedImportRow, 0, len(rows))
+	seen := map[string]int{}
+	for i, row := range rows {
+		row.Name = strings.TrimSpace(row.Name)
+		row.Category = strings.TrimSpace(row.Category)
+
+		rowResult := &result.Rows[i]
+		*rowResult = models.ProductImportRowResult{
+			Row:      i + 1,
+			Name:     row.Name,
+			Category: row.Category,
+			Errors:   append([]string{}, rowErrors[i+1]...),
+		}
+
+		p := preparedImportRow{result: rowResult, row: row}
+		s.validateRow(&p, refs)
+
+		key := importKey(row.Name, row.Category)
+		if first, ok := seen[key]; ok && row.Name != "" {
+			rowResult.Errors = append(rowResult.Errors, fmt.Sprintf("duplicate of row %d", first))
+		} else {
+			seen[key] = i + 1
+		}
+
+		if product, ok := existingByKey[key]; ok {
+			if product.Type == models.ProductTypeBundle {
+				rowResult.Errors = append(rowResult.Errors, "bundles cannot be updated by import")
+			}
+			p.existing = product
+			rowResult.Action = models.ImportActionUpdate
+			rowResult.ProductID = product.ID
+		} else {
+			rowResult.Action = models.ImportActionCreate
+		}
+
+		if len(rowResult.Errors) > 0 {
+			result.Failed++
+			continue
+		}
+		if p.existing != nil {
+			result.Updated++
+		} else {
+			result.Created++
+		}
+		prepared = append(prepared, p)
+	}
+
+	if dryRun || result.Failed > 0 {
+		return result, nil
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", tx.Error)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	updatedIDs := []string{}
+	for _, p := range prepared {
+		// Новые продукты скрыты, пока их явно не опубликуют (колонка isVisible)
+		product := models.Product{Type: models.ProductTypeSingle, IsVisible: false}
+		oldPrice := 0.0
+		source := models.PriceSourceInitial
+		if p.existing != nil {
+			product = *p.existing
+			oldPrice = p.existing.Price
+			source = models.PriceSourceImport
+		} else {
+			product.ID = uuid.New().String()
+		}
+
+		product.Name = p.row.Name
+		product.Category = p.row.Category
+		product.Price = roundCost(p.row.Price)
+		product.Cost = p.cost
+		// Состав существующего продукта заменяется, только если он задан в строке:
+		// пустая ячейка (или отсутствующий ключ JSON) оставляет текущие строки рецептуры
+		replaceIngredients := p.existing == nil || len(p.row.Ingredients) > 0
+		replaceSemiFinished := p.existing == nil || len(p.row.SemiFinished) > 0
+		if !replaceIngredients || !replaceSemiFinished {
+			cost, err := importedProductCost(tx, product.ID, p, replaceIngredients, replaceSemiFinished)
+			if err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("row %d: %w", p.result.Row, err)
+			}
+			product.Cost = cost
+		}
+		// Необязательные поля перезаписываются, только если заданы в строке:
+		// пустая ячейка не стирает описание, вес и фото существующего продукта
+		if value := optionalString(p.row.Description); value != nil {
+			product.Description = value
+		}
+		if value := optionalString(p.row.Weight); value != nil {
+			product.Weight = value
+		}
+		if value := optionalString(p.row.ImageURL); value != nil {
+			product.ImageURL = value
+		}
+		if p.row.IsVisible != nil {
+			product.IsVisible = *p.row.IsVisible
+		}
+
+		versioned := product.Type != models.ProductTypeBundle
+		if p.existing != nil {
+			// Рецептура до импорта сохраняется как исходная версия
+			if versioned {
+				if err := s.recipeVersions.EnsureBaseline(tx, models.CostEntityProduct, product.ID, nil); err != nil {
+					tx.Rollback()
+					return nil, fmt.Errorf("row %d: %w", p.result.Row, err)
+				}
+			}
+			if err := tx.Save(&product).Error; err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("row %d: failed to update product: %w", p.result.Row, err)
+			}
+			if replaceIngredients {
+				if err := tx.Where("product_id = ?", product.ID).Delete(&models.ProductIngredient{}).Error; err != nil {
+					tx.Rollback()
+					return nil, fmt.Errorf("row %d: failed to replace ingredients: %w", p.result.Row, err)
+				}
+			}
+			if replaceSemiFinished {
+				if err := tx.Where("product_id = ?", product.ID).Delete(&models.ProductSemiFinished{}).Error; err != nil {
+					tx.Rollback()
+					return nil, fmt.Errorf("row %d: failed to replace semi-finished: %w", p.result.Row, err)
+				}
+			}
+			updatedIDs = append(updatedIDs, product.ID)
+		} else {
+			if err := tx.Create(&product).Error; err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("row %d: failed to create product: %w", p.result.Row, err)
+			}
+			p.result.ProductID = product.ID
+		}
+
+		for _, line := range p.ingredients {
+			line.ID = uuid.New().String()
+			line.ProductID = product.ID
+			if err := tx.Create(&line).Error; err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("row %d: failed to add ingredient: %w", p.result.Row, err)
+			}
+		}
+		for _, line := range p.semiFinished {
+			line.ID = uuid.New().String()
+			line.ProductID = product.ID
+			if err := tx.Create(&line).Error; err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("row %d: failed to add semi-finished: %w", p.result.Row, err)
+			}
+		}
+		if versioned {
+			if _, err := s.recipeVersions.Record(tx, models.CostEntityProduct, product.ID, "Импорт", userID); err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("row %d: %w", p.result.Row, err)
+			}
+		}
+
+		if p.existing == nil || oldPrice != product.Price {
+			if err := s.priceService.RecordChange(tx, product.ID, oldPrice, product.Price, source, userID); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	}
+
+	// Себестоимость сетов с обновлёнными продуктами
+	if err := s.bundleService.RecalculateContaining(tx, updatedIDs); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	result.Applied = true
+	log.Printf("[IMPORT] ✅ Imported products: %d created, %d updated", result.Created, result.Updated)
+	return result, nil
+}
+
+// importedProductCost себестоимость существующего продукта после импорта: строки из файла
+// плюс сохранённые строки той части состава, которая в строке не задана
+func importedProductCost(tx *gorm.DB, productID string, p preparedImportRow, replaceIngredients, replaceSemiFinished bool) (float64, error) {
+	product := models.Product{Ingredients: p.ingredients, SemiFinished: p.semiFinished}
+	if !replaceIngredients {
+		if err := tx.Where("product_id = ?", productID).Find(&product.Ingredients).Error; err != nil {
+			return 0, fmt.Errorf("failed to fetch product ingredients: %w", err)
+		}
+	}
+	if !replaceSemiFinished {
+		if err := tx.Where("product_id = ?", productID).Find(&product.SemiFinished).Error; err != nil {
+			return 0, fmt.Errorf("failed to fetch product semi-finished: %w", err)
+		}
+	}
+	return ProductCost(&product), nil
+}
+
+// validateRow проверяет поля строки и разрешает ссылки на ингредиенты и полуфабрикаты
+func (s *ProductImportService) validateRow(p *preparedImportRow, refs *importReferences) {
+	row := p.row
+	addError := func(format string, args ...interface{}) {
+		p.result.Errors = append(p.result.Errors, fmt.Sprintf(format, args...))
+	}
+
+	if row.Name == "" {
+		addError("name is required")
+	} else if len(row.Name) > 100 {
+		addError("name too long (max 100 characters)")
+	}
+	if row.Category == "" {
+		addError("category is required")
+	}
+	if row.Price < 0 {
+		addError("price must be positive")
+	}
+
+	for _, line := range row.Ingredients {
+		ingredient, ok := refs.ingredient(line)
+		if !ok {
+			addError("ingredient %q not found", lineRef(line))
+			continue
+		}
+		if err := validateImportQuantity(line); err != nil {
+			addError("ingredient %q: %v", ingredient.Name, err)
+			continue
+		}
+		quantityType, err := normalizeRecipeQuantity(line.QuantityType, line.CookingLoss)
+		if err != nil {
+			addError("ingredient %q: %v", ingredient.Name, err)
+			continue
+		}
+		unit := units.Normalize(line.Unit)
+		qty, err := refs.units.toStockGross(recipeQuantity{
+			IngredientID: ingredient.ID,
+			Quantity:     line.Quantity,
+			Unit:         unit,
+			QuantityType: quantityType,
+			CookingLoss:  line.CookingLoss,
+		})
+		if err != nil {
+			addError("ingredient %q: %v", ingredient.Name, err)
+			continue
+		}
+		price := refs.prices[ingredient.ID]
+		total := roundCost(qty * price)
+		p.ingredients = append(p.ingredients, models.ProductIngredient{
+			IngredientID:   ingredient.ID,
+			IngredientName: ingredient.Name,
+			Quantity:       line.Quantity,
+			Unit:           unit,
+			PricePerUnit:   roundCost(price),
+			TotalPrice:     total,
+			QuantityType:   quantityType,
+			CookingLoss:    line.CookingLoss,
+		})
+		p.cost += total
+	}
+
+	for _, line := range row.SemiFinished {
+		sf, ok := refs.semiFinishedItem(line)
+		if !ok {
+			addError("semi-finished %q not found", lineRef(line))
+			continue
+		}
+		if err := validateImportQuantity(line); err != nil {
+			addError("semi-finished %q: %v", sf.Name, err)
+			continue
+		}
+		unit := units.Normalize(line.Unit)
+		total, err := semiFinishedLineCost(&sf, line.Quantity, unit)
+		if err != nil {
+			addError("semi-finished %q: %v", sf.Name, err)
+			continue
+		}
+		p.semiFinished = append(p.semiFinished, models.ProductSemiFinished{
+			SemiFinishedID:   sf.ID,
+			SemiFinishedName: sf.Name,
+			Quantity:         line.Quantity,
+			Unit:             unit,
+			CostPerUnit:      sf.CostPerUnit,
+			TotalCost:        total,
+		})
+		p.cost += total
+	}
+
+	p.cost = roundCost(p.cost)
+}
+
+// Export возвращает обычные продукты с составом (сеты экспортом не переносятся)
+func (s *ProductImportService) Export() ([]models.ProductImportRow, error) {
+	var products []models.Product
+	if err := database.GetDB().
+		Preload("Ingredients").
+		Preload("SemiFinished").
+		Where("type = ? OR type IS NULL", models.ProductTypeSingle).
+		Order("category ASC, name ASC").
+		Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+
+	rows := make([]models.ProductImportRow, 0, len(products))
+	for _, p := range products {
+		visible := p.IsVisible
+		row := models.ProductImportRow{
+			Name:      p.Name,
+			Category:  p.Category,
+			Price:     p.Price,
+			IsVisible: &visible,
+		}
+		if p.Description != nil {
+			row.Description = *p.Description
+		}
+		if p.Weight != nil {
+			row.Weight = *p.Weight
+		}
+		if p.ImageURL != nil {
+			row.ImageURL = *p.ImageURL
+		}
+		for _, line := range p.Ingredients {
+			row.Ingredients = append(row.Ingredients, models.ProductImportLine{
+				ID: line.IngredientID, Name: line.IngredientName, Quantity: line.Quantity, Unit: line.Unit,
+				QuantityType: line.QuantityType, CookingLoss: line.CookingLoss,
+			})
+		}
+		for _, line := range p.SemiFinished {
+			row.SemiFinished = append(row.SemiFinished, models.ProductImportLine{
+				ID: line.SemiFinishedID, Name: line.SemiFinishedName, Quantity: line.Quantity, Unit: line.Unit,
+			})
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// ParseCSV разбирает CSV с заголовком; ошибки формата возвращаются по номерам строк
+func (s *ProductImportService) ParseCSV(r io.Reader) ([]models.ProductImportRow, map[int][]string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, required := range []string{"name", "category", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("CSV header must contain %q column", required)
+		}
+	}
+
+	rows := []models.ProductImportRow{}
+	rowErrors := map[int][]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		rowNum := len(rows) + 1
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		addError := func(format string, args ...interface{}) {
+			rowErrors[rowNum] = append(rowErrors[rowNum], fmt.Sprintf(format, args...))
+		}
+
+		row := models.ProductImportRow{
+			Name:        get("name"),
+			Category:    get("category"),
+			Description: get("description"),
+			Weight:      get("weight"),
+			ImageURL:    get("imageUrl"),
+		}
+		if price, err := strconv.ParseFloat(strings.Replace(get("price"), ",", ".", 1), 64); err == nil {
+			row.Price = price
+		} else {
+			addError("invalid price %q", get("price"))
+		}
+		if value := get("isVisible"); value != "" {
+			if visible, err := strconv.ParseBool(value); err == nil {
+				row.IsVisible = &visible
+			} else {
+				addError("invalid isVisible %q", value)
+			}
+		}
+		if row.Ingredients, err = parseCompositionCell(get("ingredients")); err != nil {
+			addError("ingredients: %v", err)
+		}
+		if row.SemiFinished, err = parseCompositionCell(get("semiFinished")); err != nil {
+			addError("semiFinished: %v", err)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, rowErrors, nil
+}
+
+// WriteCSV записывает продукты в CSV в формате, принимаемом ParseCSV
+func (s *ProductImportService) WriteCSV(w io.Writer, rows []models.ProductImportRow) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(productCSVHeader); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		visible := ""
+		if row.IsVisible != nil {
+			visible = strconv.FormatBool(*row.IsVisible)
+		}
+		record := []string{
+			row.Name,
+			row.Category,
+			strconv.FormatFloat(row.Price, 'f', 2, 64),
+			row.Description,
+			row.Weight,
+			row.ImageURL,
+			visible,
+			formatCompositionCell(row.Ingredients),
+			formatCompositionCell(row.SemiFinished),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// importReferences справочники для разрешения ссылок в строках импорта
+type importReferences struct {
+	ingredientsByID    map[string]models.Ingredient
+	ingredientsByName  map[string]models.Ingredient
+	semiFinishedByID   map[string]models.SemiFinished
+	semiFinishedByName map[string]models.SemiFinished
+	prices             map[string]float64 // Цена за базовую единицу по ID ингредиента
+	units              *ingredientUnits
+}
+
+func (r *importReferences) ingredient(line models.ProductImportLine) (models.Ingredient, bool) {
+	if line.ID != "" {
+		ing, ok := r.ingredientsByID[line.ID]
+		return ing, ok
+	}
+	ing, ok := r.ingredientsByName[strings.ToLower(strings.TrimSpace(line.Name))]
+	return ing, ok
+}
+
+func (r *importReferences) semiFinishedItem(line models.ProductImportLine) (models.SemiFinished, bool) {
+	if line.ID != "" {
+		sf, ok := r.semiFinishedByID[line.ID]
+		return sf, ok
+	}
+	sf, ok := r.semiFinishedByName[strings.ToLower(strings.TrimSpace(line.Name))]
+	return sf, ok
+}
+
+// loadImportReferences загружает ингредиенты, их цены и активные полуфабрикаты
+func loadImportReferences() (*importReferences, error) {
+	db := database.GetDB()
+	refs := &importReferences{
+		ingredientsByID:    map[string]models.Ingredient{},
+		ingredientsByName:  map[string]models.Ingredient{},
+		semiFinishedByID:   map[string]models.SemiFinished{},
+		semiFinishedByName: map[string]models.SemiFinished{},
+		prices:             map[string]float64{},
+	}
+
+	var ingredients []models.Ingredient
+	if err := db.Find(&ingredients).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch ingredients: %w", err)
+	}
+	ingredientIDs := make([]string, 0, len(ingredients))
+	for _, ing := range ingredients {
+		refs.ingredientsByID[ing.ID] = ing
+		refs.ingredientsByName[strings.ToLower(strings.TrimSpace(ing.Name))] = ing
+		ingredientIDs = append(ingredientIDs, ing.ID)
+	}
+	ingUnits, err := loadIngredientUnits(db, ingredientIDs)
+	if err != nil {
+		return nil, err
+	}
+	refs.units = ingUnits
+
+	var stockItems []models.StockItem
+	if err := db.Find(&stockItems).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch stock items: %w", err)
+	}
+	for _, item := range stockItems {
+		if item.PricePerUnit != nil {
+			refs.prices[item.IngredientID] = *item.PricePerUnit
+		}
+	}
+
+	var semiFinished []models.SemiFinished
+	if err := db.Where("deleted_at IS NULL AND is_archived = ?", false).Find(&semiFinished).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch semi-finished: %w", err)
+	}
+	for _, sf := range semiFinished {
+		refs.semiFinishedByID[sf.ID] = sf
+		refs.semiFinishedByName[strings.ToLower(strings.TrimSpace(sf.Name))] = sf
+	}
+
+	return refs, nil
+}
+
+// parseCompositionCell разбирает ячейку состава "Лосось:50:g;Рис:100:g"
+func parseCompositionCell(cell string) ([]models.ProductImportLine, error) {
+	lines := []models.ProductImportLine{}
+	for _, part := range strings.Split(cell, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		// Название может содержать двоеточие — количество и единица берутся с конца
+		fields := strings.Split(part, ":")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid entry %q, expected name:quantity:unit", part)
+		}
+		n := len(fields)
+		quantity, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(fields[n-2]), ",", ".", 1), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity in %q", part)
+		}
+		lines = append(lines, models.ProductImportLine{
+			Name:     strings.TrimSpace(strings.Join(fields[:n-2], ":")),
+			Quantity: quantity,
+			Unit:     strings.TrimSpace(fields[n-1]),
+		})
+	}
+	return lines, nil
+}
+
+// formatCompositionCell записывает состав в формате parseCompositionCell
+func formatCompositionCell(lines []models.ProductImportLine) string {
+	parts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		parts = append(parts, fmt.Sprintf("%s:%s:%s", line.Name, strconv.FormatFloat(line.Quantity, 'f', -1, 64), line.Unit))
+	}
+	return strings.Join(parts, ";")
+}
+
+// validateImportQuantity проверяет количество и единицу строки состава
+func validateImportQuantity(line models.ProductImportLine) error {
+	if line.Quantity <= 0 {
+		return fmt.Errorf("quantity must be positive")
+	}
+	if strings.TrimSpace(line.Unit) == "" {
+		return fmt.Errorf("unit is required")
+	}
+	return nil
+}
+
+func lineRef(line models.ProductImportLine) string {
+	if line.Name != "" {
+		return line.Name
+	}
+	return line.ID
+}
+
+func importKey(name, category string) string {
+	return strings.ToLower(strings.TrimSpace(name)) + "|" + strings.ToLower(strings.TrimSpace(category))
+}
+
+func optionalString(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
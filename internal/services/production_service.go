@@ -0,0 +1,399 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProductionService - сервис выпуска полуфабрикатов и учёта их остатков
+type ProductionService struct{}
+
+// NewProductionService создает новый экземпляр ProductionService
+func NewProductionService() *ProductionService {
+	return &ProductionService{}
+}
+
+// Produce выпускает партию полуфабриката: списывает сырьё рецептуры на заданное число
+// закладок и приходует выход на остаток полуфабриката со сроком годности
+func (s *ProductionService) Produce(semiFinishedID string, req models.ProductionRequest, userID *string) (*models.ProductionBatch, error) {
+	batches := req.Batches
+	if batches == 0 {
+		batches = 1
+	}
+	if batches < 0 {
+		return nil, fmt.Errorf("batches must be positive")
+	}
+
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("expiresAt must be in the future")
+	}
+
+	var batch *models.ProductionBatch
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var sf models.SemiFinished
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&sf, "id = ?", semiFinishedID).Error; err != nil {
+			return fmt.Errorf("semi-finished not found: %w", err)
+		}
+		if sf.DeletedAt != nil {
+			return fmt.Errorf("semi-finished not found")
+		}
+		if sf.IsArchived {
+			return fmt.Errorf("semi-finished %s is archived", sf.Name)
+		}
+		if sf.OutputQuantity <= 0 {
+			return fmt.Errorf("semi-finished %s has no output quantity", sf.Name)
+		}
+
+		var lines []models.SemiFinishedIngredient
+		if err := tx.Where("semi_finished_id = ?", sf.ID).Find(&lines).Error; err != nil {
+			return fmt.Errorf("failed to fetch semi-finished ingredients: %w", err)
+		}
+		var components []models.SemiFinishedComponent
+		if err := tx.Where("semi_finished_id = ?", sf.ID).Find(&components).Error; err != nil {
+			return fmt.Errorf("failed to fetch semi-finished components: %w", err)
+		}
+		if len(lines) == 0 && len(components) == 0 {
+			return fmt.Errorf("semi-finished %s has no ingredients", sf.Name)
+		}
+
+		ingredientIDs := []string{}
+		for _, line := range lines {
+			ingredientIDs = appendUnique(ingredientIDs, line.IngredientID)
+		}
+		ingUnits, err := loadIngredientUnits(tx, ingredientIDs)
+		if err != nil {
+			return err
+		}
+		needs := StockNeeds{
+			Ingredients:  make(map[string]float64, len(ingredientIDs)),
+			SemiFinished: make(map[string]float64, len(components)),
+		}
+		for _, line := range lines {
+			qty, err := ingUnits.toStockGross(semiFinishedLineQuantity(line))
+			if err != nil {
+				return fmt.Errorf("ingredient %s: %w", line.IngredientName, err)
+			}
+			needs.Ingredients[line.IngredientID] += qty * batches
+		}
+
+		// Вложенные полуфабрикаты списываются с их остатка, нехватка — по их рецептуре
+		if len(components) > 0 {
+			componentIDs := []string{}
+			for _, c := range components {
+				componentIDs = appendUnique(componentIDs, c.ComponentID)
+			}
+			closure, err := loadSemiFinishedClosure(tx, componentIDs)
+			if err != nil {
+				return err
+			}
+			for _, c := range components {
+				child, ok := closure[c.ComponentID]
+				if !ok {
+					return fmt.Errorf("semi-finished component %s not found", c.ComponentName)
+				}
+				qty, err := units.Convert(c.Quantity, c.Unit, child.OutputUnit)
+				if err != nil {
+					return fmt.Errorf("semi-finished component %s: %w", c.ComponentName, err)
+				}
+				needs.SemiFinished[child.ID] += qty * batches
+			}
+			if needs.recipes, err = semiFinishedRecipes(tx, closure); err != nil {
+				return err
+			}
+		}
+
+		output := roundQuantity(sf.OutputQuantity * batches)
+		batch = &models.ProductionBatch{
+			ID:                uuid.New().String(),
+			BatchNumber:       GenerateBatchNumber(sf.Name, now),
+			SemiFinishedID:    sf.ID,
+			SemiFinishedName:  sf.Name,
+			Batches:           batches,
+			OutputQuantity:    output,
+			RemainingQuantity: output,
+			OutputUnit:        sf.OutputUnit,
+			ProducedAt:        now,
+			ExpiresAt:         req.ExpiresAt,
+			ProducedBy:        userID,
+		}
+		if batch.ExpiresAt == nil && sf.ShelfLifeHours != nil && *sf.ShelfLifeHours > 0 {
+			at := now.Add(time.Duration(*sf.ShelfLifeHours) * time.Hour)
+			batch.ExpiresAt = &at
+		}
+		if note := strings.TrimSpace(req.Note); note != "" {
+			batch.Note = &note
+		}
+
+		cost, err := consumeNeeds(tx, needs, stockMovementInput{
+			Note:         fmt.Sprintf("Производство %s: %s × %g", batch.BatchNumber, sf.Name, batches),
+			DocumentType: models.DocumentProduction,
+			DocumentID:   batch.ID,
+			UserID:       userID,
+		})
+		if err != nil {
+			return err
+		}
+		batch.Cost = roundCost(cost)
+		batch.CostPerUnit = roundCost(cost / output)
+
+		if err := tx.Create(batch).Error; err != nil {
+			return fmt.Errorf("failed to create production batch: %w", err)
+		}
+		if err := recordSemiFinishedMovement(tx, batch, models.MovementIn, output, stockMovementInput{
+			Note:         fmt.Sprintf("Выпуск партии %s", batch.BatchNumber),
+			DocumentType: models.DocumentProduction,
+			DocumentID:   batch.ID,
+			UserID:       userID,
+		}); err != nil {
+			return err
+		}
+		return updateSemiFinishedStock(tx, sf.ID, output)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[PRODUCTION] ✅ Produced %s: %.3f %s of %s (cost %.2f)",
+		batch.BatchNumber, batch.OutputQuantity, batch.OutputUnit, batch.SemiFinishedName, batch.Cost)
+	return batch, nil
+}
+
+// GetBatches возвращает партии выпуска полуфабриката, новые первыми.
+// activeOnly — только партии с ненулевым остатком.
+func (s *ProductionService) GetBatches(semiFinishedID string, activeOnly bool, limit int) ([]models.ProductionBatch, error) {
+	query := database.GetDB().Where("semi_finished_id = ?", semiFinishedID)
+	if activeOnly {
+		query = query.Where("remaining_quantity > 0")
+	}
+
+	var batches []models.ProductionBatch
+	if err := query.Order("produced_at DESC").Limit(limit).Find(&batches).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch production batches: %w", err)
+	}
+
+	now := time.Now()
+	for i := range batches {
+		batches[i].IsExpired = batches[i].ExpiresAt != nil && !batches[i].ExpiresAt.After(now)
+	}
+	return batches, nil
+}
+
+// WriteOffExpired списывает остатки просроченных партий выпуска, чтобы остаток
+// полуфабриката совпадал с партиями, которые можно использовать
+func (s *ProductionService) WriteOffExpired(now time.Time) (int, error) {
+	var sfIDs []string
+	if err := database.GetDB().Model(&models.ProductionBatch{}).
+		Where("remaining_quantity > 0 AND expires_at <= ?", now).
+		Distinct().Pluck("semi_finished_id", &sfIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to fetch expired production batches: %w", err)
+	}
+
+	written := 0
+	for _, sfID := range sfIDs {
+		err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+			// Полуфабрикат блокируется раньше партий — тот же порядок, что и при списании по заказу
+			var sf models.SemiFinished
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&sf, "id = ?", sfID).Error; err != nil {
+				return fmt.Errorf("failed to lock semi-finished: %w", err)
+			}
+			count, err := writeOffExpiredBatches(tx, sfID, now)
+			written += count
+			return err
+		})
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// StartExpiryWriteOff запускает фоновое списание просроченных партий с заданным интервалом
+func (s *ProductionService) StartExpiryWriteOff(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.WriteOffExpired(time.Now()); err != nil {
+				log.Printf("[PRODUCTION] ❌ Expiry write-off error: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("[PRODUCTION] ⏰ Expiry write-off started (interval: %s)", interval)
+}
+
+// writeOffExpiredBatches списывает остатки просроченных партий полуфабриката движением
+// с основанием «списание» (внутри транзакции, полуфабрикат уже заблокирован)
+func writeOffExpiredBatches(tx *gorm.DB, semiFinishedID string, now time.Time) (int, error) {
+	var batches []models.ProductionBatch
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("semi_finished_id = ? AND remaining_quantity > 0 AND expires_at <= ?", semiFinishedID, now).
+		Order("id").
+		Find(&batches).Error; err != nil {
+		return 0, fmt.Errorf("failed to fetch expired production batches: %w", err)
+	}
+
+	for i := range batches {
+		batch := &batches[i]
+		if err := tx.Model(&models.ProductionBatch{}).Where("id = ?", batch.ID).
+			Update("remaining_quantity", 0).Error; err != nil {
+			return 0, fmt.Errorf("failed to update production batch: %w", err)
+		}
+		if err := recordSemiFinishedMovement(tx, batch, models.MovementOut, batch.RemainingQuantity, stockMovementInput{
+			Note:         fmt.Sprintf("Истёк срок годности партии %s", batch.BatchNumber),
+			DocumentType: models.DocumentWriteOff,
+			DocumentID:   batch.ID,
+		}); err != nil {
+			return 0, err
+		}
+		if err := updateSemiFinishedStock(tx, semiFinishedID, -batch.RemainingQuantity); err != nil {
+			return 0, err
+		}
+		log.Printf("[PRODUCTION] 🗑️ Expired batch %s written off: %.3f %s of %s",
+			batch.BatchNumber, batch.RemainingQuantity, batch.OutputUnit, batch.SemiFinishedName)
+	}
+	return len(batches), nil
+}
+
+// consumeSemiFinished списывает полуфабрикат (в единице выхода) с непросроченных партий
+// выпуска по правилу FIFO/FEFO. Возвращает себестоимость списанного и нехватку, которую
+// вызывающий код покрывает сырьём.
+func consumeSemiFinished(tx *gorm.DB, semiFinishedID string, quantity float64, input stockMovementInput) (float64, float64, error) {
+	var sf models.SemiFinished
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "name", "stock_quantity").
+		First(&sf, "id = ?", semiFinishedID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, quantity, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to lock semi-finished: %w", err)
+	}
+
+	// Просроченные партии не используются — списываем их, чтобы остаток не расходился с партиями
+	now := time.Now()
+	if _, err := writeOffExpiredBatches(tx, sf.ID, now); err != nil {
+		return 0, 0, err
+	}
+
+	order := "produced_at ASC, created_at ASC"
+	if consumptionPolicy() == models.ConsumptionFEFO {
+		order = "expires_at ASC NULLS LAST, produced_at ASC, created_at ASC"
+	}
+	var batches []models.ProductionBatch
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("semi_finished_id = ? AND remaining_quantity > 0 AND (expires_at IS NULL OR expires_at > ?)", sf.ID, now).
+		Order(order).
+		Find(&batches).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to fetch production batches: %w", err)
+	}
+
+	left, cost := quantity, 0.0
+	for i := range batches {
+		if left <= stockEpsilon {
+			break
+		}
+		batch := &batches[i]
+		qty := math.Min(left, batch.RemainingQuantity)
+		if err := tx.Model(&models.ProductionBatch{}).Where("id = ?", batch.ID).
+			Update("remaining_quantity", gorm.Expr("remaining_quantity - ?", qty)).Error; err != nil {
+			return 0, 0, fmt.Errorf("failed to update production batch: %w", err)
+		}
+		if err := recordSemiFinishedMovement(tx, batch, models.MovementOut, qty, input); err != nil {
+			return 0, 0, err
+		}
+		cost += qty * batch.CostPerUnit
+		left -= qty
+	}
+
+	taken := quantity - math.Max(left, 0)
+	if taken > stockEpsilon {
+		if err := updateSemiFinishedStock(tx, sf.ID, -taken); err != nil {
+			return 0, 0, err
+		}
+	}
+	if left <= stockEpsilon {
+		return cost, 0, nil
+	}
+	if taken > stockEpsilon {
+		log.Printf("[PRODUCTION] ⚠️ Insufficient stock of %s: short by %.3f, consuming raw ingredients", sf.Name, left)
+	}
+	return cost, left, nil
+}
+
+// returnSemiFinished возвращает полуфабрикаты, списанные по документу, в те же партии выпуска
+func returnSemiFinished(tx *gorm.DB, documentType, documentID string, input stockMovementInput) (int, error) {
+	var movements []models.SemiFinishedMovement
+	if err := tx.Where("document_type = ? AND document_id = ? AND type = ?", documentType, documentID, models.MovementOut).
+		Order("semi_finished_id ASC").
+		Find(&movements).Error; err != nil {
+		return 0, fmt.Errorf("failed to fetch semi-finished movements: %w", err)
+	}
+
+	for _, movement := range movements {
+		var batch models.ProductionBatch
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&batch, "id = ?", movement.BatchID).Error; err != nil {
+			return 0, fmt.Errorf("production batch %s not found: %w", movement.BatchID, err)
+		}
+		if err := tx.Model(&models.ProductionBatch{}).Where("id = ?", batch.ID).
+			Update("remaining_quantity", gorm.Expr("remaining_quantity + ?", movement.Quantity)).Error; err != nil {
+			return 0, fmt.Errorf("failed to return production batch: %w", err)
+		}
+		if err := recordSemiFinishedMovement(tx, &batch, models.MovementIn, movement.Quantity, input); err != nil {
+			return 0, err
+		}
+		if err := updateSemiFinishedStock(tx, batch.SemiFinishedID, movement.Quantity); err != nil {
+			return 0, err
+		}
+	}
+	return len(movements), nil
+}
+
+// recordSemiFinishedMovement записывает движение по партии полуфабриката (внутри транзакции)
+func recordSemiFinishedMovement(tx *gorm.DB, batch *models.ProductionBatch, movementType string, quantity float64, input stockMovementInput) error {
+	movement := models.SemiFinishedMovement{
+		ID:             uuid.New().String(),
+		SemiFinishedID: batch.SemiFinishedID,
+		BatchID:        batch.ID,
+		Type:           movementType,
+		Quantity:       quantity,
+		CostPerUnit:    batch.CostPerUnit,
+		Cost:           roundCost(quantity * batch.CostPerUnit),
+		DocumentType:   input.DocumentType,
+		DocumentID:     input.DocumentID,
+		UserID:         input.UserID,
+	}
+	if input.Note != "" {
+		note := input.Note
+		movement.Note = &note
+	}
+	if err := tx.Create(&movement).Error; err != nil {
+		return fmt.Errorf("failed to record semi-finished movement: %w", err)
+	}
+	return nil
+}
+
+// updateSemiFinishedStock изменяет остаток полуфабриката на delta (в единице выхода)
+func updateSemiFinishedStock(tx *gorm.DB, semiFinishedID string, delta float64) error {
+	if err := tx.Model(&models.SemiFinished{}).Where("id = ?", semiFinishedID).
+		Update("stock_quantity", gorm.Expr("stock_quantity + ?", delta)).Error; err != nil {
+		return fmt.Errorf("failed to update semi-finished stock: %w", err)
+	}
+	return nil
+}
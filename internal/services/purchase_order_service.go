@@ -0,0 +1,586 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPurchaseOrderNotEditable отправленный заказ поставщику нельзя изменять
+var ErrPurchaseOrderNotEditable = errors.New("only draft purchase orders can be changed")
+
+// openPurchaseOrderStatuses заказы, по которым ещё ожидается поставка
+var openPurchaseOrderStatuses = []string{
+	models.PurchaseOrderDraft,
+	models.PurchaseOrderSent,
+	models.PurchaseOrderPartiallyReceived,
+}
+
+// PurchaseOrderService - сервис заказов поставщикам
+type PurchaseOrderService struct {
+	alerts   *StockAlertService
+	receipts *GoodsReceiptService
+}
+
+// NewPurchaseOrderService создает новый экземпляр PurchaseOrderService
+func NewPurchaseOrderService() *PurchaseOrderService {
+	return &PurchaseOrderService{
+		alerts:   NewStockAlertService(),
+		receipts: NewGoodsReceiptService(),
+	}
+}
+
+// GetAll возвращает заказы поставщикам с фильтром по статусу и поставщику
+func (s *PurchaseOrderService) GetAll(status, supplierID string, limit int) ([]models.PurchaseOrder, error) {
+	query := database.GetDB().Order("created_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if supplierID != "" {
+		query = query.Where("supplier_id = ?", supplierID)
+	}
+
+	var orders []models.PurchaseOrder
+	if err := query.Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch purchase orders: %w", err)
+	}
+	return orders, nil
+}
+
+// GetByID возвращает заказ поставщику со строками
+func (s *PurchaseOrderService) GetByID(id string) (*models.PurchaseOrder, error) {
+	db := database.GetDB()
+
+	var order models.PurchaseOrder
+	if err := db.Preload("Lines").First(&order, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("purchase order not found: %w", err)
+	}
+
+	var supplier models.Supplier
+	if err := db.First(&supplier, "id = ?", order.SupplierID).Error; err == nil {
+		order.BelowMinimum = order.Total < supplier.MinOrderAmount
+	}
+	return &order, nil
+}
+
+// Create создает черновик заказа поставщику
+func (s *PurchaseOrderService) Create(req models.PurchaseOrderRequest, createdBy *string) (*models.PurchaseOrder, error) {
+	order := models.PurchaseOrder{
+		ID:        uuid.New().String(),
+		Number:    purchaseOrderNumber(time.Now()),
+		Status:    models.PurchaseOrderDraft,
+		CreatedBy: createdBy,
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := s.fill(tx, &order, req); err != nil {
+			return err
+		}
+		return tx.Create(&order).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[PURCHASE] 📝 Draft purchase order %s to %s: %d lines, total %.2f",
+		order.Number, order.SupplierName, len(order.Lines), order.Total)
+	return &order, nil
+}
+
+// Update заменяет содержимое черновика заказа
+func (s *PurchaseOrderService) Update(id string, req models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("purchase order not found: %w", err)
+		}
+		if order.Status != models.PurchaseOrderDraft {
+			return ErrPurchaseOrderNotEditable
+		}
+		if err := tx.Where("order_id = ?", id).Delete(&models.PurchaseOrderLine{}).Error; err != nil {
+			return fmt.Errorf("failed to replace lines: %w", err)
+		}
+		if err := s.fill(tx, &order, req); err != nil {
+			return err
+		}
+		return tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(&order).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Delete удаляет черновик заказа
+func (s *PurchaseOrderService) Delete(id string) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var order models.PurchaseOrder
+		if err := tx.First(&order, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("purchase order not found: %w", err)
+		}
+		if order.Status != models.PurchaseOrderDraft {
+			return ErrPurchaseOrderNotEditable
+		}
+		if err := tx.Where("order_id = ?", id).Delete(&models.PurchaseOrderLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&order).Error
+	})
+}
+
+// Send отмечает заказ отправленным поставщику и рассчитывает ожидаемую дату поставки
+func (s *PurchaseOrderService) Send(id string) (*models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Lines").First(&order, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("purchase order not found: %w", err)
+		}
+		if order.Status != models.PurchaseOrderDraft {
+			return ErrPurchaseOrderNotEditable
+		}
+		if len(order.Lines) == 0 {
+			return fmt.Errorf("purchase order has no lines")
+		}
+
+		var supplier models.Supplier
+		if err := tx.First(&supplier, "id = ?", order.SupplierID).Error; err != nil {
+			return fmt.Errorf("supplier not found: %w", err)
+		}
+
+		now := time.Now()
+		order.Status = models.PurchaseOrderSent
+		order.SentAt = &now
+		if order.ExpectedAt == nil {
+			expected := nextDeliveryDate(&supplier, now)
+			order.ExpectedAt = &expected
+		}
+		order.BelowMinimum = order.Total < supplier.MinOrderAmount
+		return tx.Model(&order).Updates(map[string]interface{}{
+			"status":      order.Status,
+			"sent_at":     now,
+			"expected_at": order.ExpectedAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[PURCHASE] 📤 Purchase order %s sent to %s, expected %s",
+		order.Number, order.SupplierName, order.ExpectedAt.Format("2006-01-02"))
+	return &order, nil
+}
+
+// Cancel отменяет заказ, по которому ещё ничего не оприходовано
+func (s *PurchaseOrderService) Cancel(id string) error {
+	result := database.GetDB().Model(&models.PurchaseOrder{}).
+		Where("id = ? AND status IN ?", id, []string{models.PurchaseOrderDraft, models.PurchaseOrderSent}).
+		Update("status", models.PurchaseOrderCancelled)
+	if result.Error != nil {
+		return fmt.Errorf("failed to cancel purchase order: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("only draft or sent purchase orders without receipts can be cancelled")
+	}
+	return nil
+}
+
+// GenerateFromLowStock формирует черновики заказов по позициям ниже точки заказа,
+// сгруппированные по основному (или самому дешёвому) поставщику из прайс-листов.
+// Позиции, уже заказанные в открытых заказах, пропускаются.
+func (s *PurchaseOrderService) GenerateFromLowStock(req models.GeneratePurchaseOrdersRequest, createdBy *string) (*models.GeneratedPurchaseOrders, error) {
+	db := database.GetDB()
+	result := &models.GeneratedPurchaseOrders{
+		Orders:     []models.PurchaseOrder{},
+		Unassigned: []models.LowStockItem{},
+		Skipped:    []models.LowStockItem{},
+	}
+
+	lowStock, err := s.alerts.GetLowStock()
+	if err != nil {
+		return nil, err
+	}
+	if len(req.IngredientIDs) > 0 {
+		filtered := lowStock[:0]
+		for _, item := range lowStock {
+			for _, id := range req.IngredientIDs {
+				if id == item.StockItemID || id == item.IngredientID {
+					filtered = append(filtered, item)
+					break
+				}
+			}
+		}
+		lowStock = filtered
+	}
+	if len(lowStock) == 0 {
+		return result, nil
+	}
+
+	stockItemIDs := make([]string, 0, len(lowStock))
+	for _, item := range lowStock {
+		stockItemIDs = append(stockItemIDs, item.StockItemID)
+	}
+
+	// Уже заказанные позиции
+	var ordered []string
+	if err := db.Model(&models.PurchaseOrderLine{}).
+		Joins("JOIN purchase_orders po ON po.id = purchase_order_lines.order_id").
+		Where("purchase_order_lines.stock_item_id IN ? AND po.status IN ?", stockItemIDs, openPurchaseOrderStatuses).
+		Distinct().
+		Pluck("purchase_order_lines.stock_item_id", &ordered).Error; err != nil {
+		return nil, fmt.Errorf("failed to check open purchase orders: %w", err)
+	}
+	alreadyOrdered := map[string]bool{}
+	for _, id := range ordered {
+		alreadyOrdered[id] = true
+	}
+
+	// Прайс-листы активных поставщиков: основной поставщик, иначе самый дешёвый
+	var prices []models.SupplierPrice
+	if err := db.Joins("JOIN suppliers s ON s.id = supplier_prices.supplier_id").
+		Where("supplier_prices.stock_item_id IN ? AND s.is_active = ?", stockItemIDs, true).
+		Order("supplier_prices.is_preferred DESC, supplier_prices.price_per_unit ASC").
+		Find(&prices).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch supplier prices: %w", err)
+	}
+	bestPrice := map[string]models.SupplierPrice{}
+	for _, price := range prices {
+		if _, ok := bestPrice[price.StockItemID]; !ok {
+			bestPrice[price.StockItemID] = price
+		}
+	}
+
+	requests := map[string]*models.PurchaseOrderRequest{}
+	supplierIDs := []string{}
+	for _, item := range lowStock {
+		if alreadyOrdered[item.StockItemID] {
+			result.Skipped = append(result.Skipped, item)
+			continue
+		}
+		price, ok := bestPrice[item.StockItemID]
+		if !ok {
+			result.Unassigned = append(result.Unassigned, item)
+			continue
+		}
+
+		quantity := item.SuggestedOrder
+		if price.PackSize != nil {
+			// Заказ кратен упаковке поставщика
+			quantity = math.Ceil(quantity / *price.PackSize - stockEpsilon) * *price.PackSize
+		}
+		if _, ok := requests[price.SupplierID]; !ok {
+			requests[price.SupplierID] = &models.PurchaseOrderRequest{
+				SupplierID: price.SupplierID,
+				Note:       "Сформирован по низким остаткам",
+			}
+			supplierIDs = append(supplierIDs, price.SupplierID)
+		}
+		requests[price.SupplierID].Lines = append(requests[price.SupplierID].Lines, models.PurchaseOrderLineInput{
+			IngredientID: item.StockItemID,
+			Quantity:     roundQuantity(quantity),
+		})
+	}
+
+	sort.Strings(supplierIDs)
+	for _, supplierID := range supplierIDs {
+		order, err := s.Create(*requests[supplierID], createdBy)
+		if err != nil {
+			return nil, err
+		}
+		result.Orders = append(result.Orders, *order)
+	}
+
+	return result, nil
+}
+
+// Receive оприходует поставку по заказу: формирует приходную накладную по строкам заказа
+// и по умолчанию сразу проводит её в той же транзакции. Статус заказа пересчитывается
+// при проведении. Без номера накладной используется номер заказа, для следующих
+// частичных поставок — с суффиксом (PO-20261018-1A2B/2).
+// Возвращает накладную и изменения закупочных цен для каскадного пересчёта себестоимости.
+func (s *PurchaseOrderService) Receive(id string, req models.ReceivePurchaseOrderRequest, userID *string) (*models.GoodsReceipt, []models.IngredientPriceChange, error) {
+	post := req.Post == nil || *req.Post
+	receipt := models.GoodsReceipt{
+		ID:        uuid.New().String(),
+		Status:    models.ReceiptDraft,
+		CreatedBy: userID,
+	}
+	var posted *models.GoodsReceipt
+	var priceChanges []models.IngredientPriceChange
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Блокировка заказа упорядочивает параллельные приёмки и нумерацию накладных
+		var order models.PurchaseOrder
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Lines").
+			First(&order, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("purchase order not found: %w", err)
+		}
+		if order.Status != models.PurchaseOrderSent && order.Status != models.PurchaseOrderPartiallyReceived {
+			return fmt.Errorf("purchase order %s is %s and cannot be received", order.Number, order.Status)
+		}
+
+		receiptReq, err := s.receiptRequest(tx, &order, req)
+		if err != nil {
+			return err
+		}
+		if err := s.receipts.createTx(tx, &receipt, receiptReq); err != nil {
+			return err
+		}
+		if !post {
+			return nil
+		}
+		posted, priceChanges, err = s.receipts.postTx(tx, receipt.ID, userID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !post {
+		log.Printf("[PURCHASE] 📝 Draft receipt %s (invoice %s) for purchase order %s",
+			receipt.ID, receipt.InvoiceNumber, id)
+		return &receipt, nil, nil
+	}
+	log.Printf("[PURCHASE] ✅ Received purchase order %s: receipt %s (invoice %s), total %.2f",
+		id, posted.ID, posted.InvoiceNumber, posted.Total)
+	return posted, priceChanges, nil
+}
+
+// receiptRequest формирует запрос накладной по строкам заказа (внутри транзакции)
+func (s *PurchaseOrderService) receiptRequest(tx *gorm.DB, order *models.PurchaseOrder, req models.ReceivePurchaseOrderRequest) (models.GoodsReceiptRequest, error) {
+	linesByID := make(map[string]models.PurchaseOrderLine, len(order.Lines))
+	for _, line := range order.Lines {
+		linesByID[line.ID] = line
+	}
+
+	receiptReq := models.GoodsReceiptRequest{
+		Supplier:        order.SupplierName,
+		InvoiceNumber:   strings.TrimSpace(req.InvoiceNumber),
+		InvoiceDate:     req.InvoiceDate,
+		Note:            req.Note,
+		PurchaseOrderID: order.ID,
+	}
+	if receiptReq.InvoiceNumber == "" {
+		// Номер заказа занят первой накладной — следующие получают суффикс
+		var previous int64
+		if err := tx.Model(&models.GoodsReceipt{}).
+			Where("purchase_order_id = ?", order.ID).
+			Count(&previous).Error; err != nil {
+			return receiptReq, fmt.Errorf("failed to count purchase order receipts: %w", err)
+		}
+		receiptReq.InvoiceNumber = receiptInvoiceNumber(order.Number, previous)
+	}
+
+	if len(req.Lines) == 0 {
+		// Весь недопоставленный остаток по ценам заказа
+		for _, line := range order.Lines {
+			left := roundQuantity(line.Quantity - line.ReceivedQuantity)
+			if left <= 0 {
+				continue
+			}
+			receiptReq.Lines = append(receiptReq.Lines, models.GoodsReceiptLineInput{
+				IngredientID:        line.StockItemID,
+				Quantity:            left,
+				PricePerUnit:        line.PricePerUnit,
+				PurchaseOrderLineID: line.ID,
+			})
+		}
+	}
+	for i, input := range req.Lines {
+		line, ok := linesByID[input.LineID]
+		if !ok {
+			return receiptReq, fmt.Errorf("line %d: purchase order line %s not found", i+1, input.LineID)
+		}
+		price := line.PricePerUnit
+		if input.PricePerUnit != nil {
+			price = *input.PricePerUnit
+		}
+		receiptReq.Lines = append(receiptReq.Lines, models.GoodsReceiptLineInput{
+			IngredientID:        line.StockItemID,
+			Quantity:            input.Quantity,
+			PricePerUnit:        price,
+			ExpiresAt:           input.ExpiresAt,
+			PurchaseOrderLineID: line.ID,
+		})
+	}
+	if len(receiptReq.Lines) == 0 {
+		return receiptReq, fmt.Errorf("nothing left to receive for purchase order %s", order.Number)
+	}
+	return receiptReq, nil
+}
+
+// fill валидирует запрос и заполняет заказ; цены по умолчанию берутся из прайс-листа поставщика
+func (s *PurchaseOrderService) fill(tx *gorm.DB, order *models.PurchaseOrder, req models.PurchaseOrderRequest) error {
+	if len(req.Lines) == 0 {
+		return fmt.Errorf("purchase order must contain at least one line")
+	}
+
+	var supplier models.Supplier
+	if err := tx.First(&supplier, "id = ?", req.SupplierID).Error; err != nil {
+		return fmt.Errorf("supplier not found: %w", err)
+	}
+	if !supplier.IsActive {
+		return fmt.Errorf("supplier %s is inactive", supplier.Name)
+	}
+
+	var prices []models.SupplierPrice
+	if err := tx.Where("supplier_id = ?", supplier.ID).Find(&prices).Error; err != nil {
+		return fmt.Errorf("failed to fetch supplier prices: %w", err)
+	}
+	priceByItem := make(map[string]float64, len(prices))
+	for _, price := range prices {
+		priceByItem[price.StockItemID] = price.PricePerUnit
+	}
+
+	order.SupplierID = supplier.ID
+	order.SupplierName = supplier.Name
+	order.ExpectedAt = req.ExpectedAt
+	order.Note = optionalString(req.Note)
+	order.Lines = make([]models.PurchaseOrderLine, 0, len(req.Lines))
+	order.Total = 0
+
+	for i, input := range req.Lines {
+		if input.Quantity <= 0 {
+			return fmt.Errorf("line %d: quantity must be positive", i+1)
+		}
+
+		var item models.StockItem
+		if err := tx.Preload("Ingredient").
+			Where(`id = ? OR "ingredientId" = ?`, input.IngredientID, input.IngredientID).
+			First(&item).Error; err != nil {
+			return fmt.Errorf("line %d: ingredient %s not found", i+1, input.IngredientID)
+		}
+
+		price, ok := priceByItem[item.ID]
+		if input.PricePerUnit != nil {
+			price = *input.PricePerUnit
+		} else if !ok && item.PricePerUnit != nil {
+			price = *item.PricePerUnit
+		}
+		if price < 0 {
+			return fmt.Errorf("line %d: price must be positive", i+1)
+		}
+
+		line := models.PurchaseOrderLine{
+			ID:           uuid.New().String(),
+			OrderID:      order.ID,
+			StockItemID:  item.ID,
+			IngredientID: item.IngredientID,
+			Quantity:     input.Quantity,
+			PricePerUnit: roundCost(price),
+		}
+		if item.Ingredient != nil {
+			line.IngredientName = item.Ingredient.Name
+			line.Unit = item.Ingredient.Unit
+		}
+		line.Total = roundCost(units.ToBase(line.Quantity, line.Unit) * line.PricePerUnit)
+		order.Total += line.Total
+		order.Lines = append(order.Lines, line)
+	}
+	order.Total = roundCost(order.Total)
+	order.BelowMinimum = order.Total < supplier.MinOrderAmount
+
+	return nil
+}
+
+// syncPurchaseOrder пересчитывает оприходованные количества и статус заказа
+// по проведённым накладным (внутри транзакции проведения или сторно накладной)
+func syncPurchaseOrder(tx *gorm.DB, orderID *string) error {
+	if orderID == nil {
+		return nil
+	}
+
+	var order models.PurchaseOrder
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Lines").First(&order, "id = ?", *orderID).Error; err != nil {
+		return fmt.Errorf("purchase order not found: %w", err)
+	}
+
+	type receivedRow struct {
+		LineID   string
+		Quantity float64
+	}
+	var received []receivedRow
+	if err := tx.Table("goods_receipt_lines grl").
+		Select("grl.purchase_order_line_id AS line_id, SUM(grl.quantity) AS quantity").
+		Joins("JOIN goods_receipts gr ON gr.id = grl.receipt_id").
+		Where("gr.purchase_order_id = ? AND gr.status = ?", order.ID, models.ReceiptPosted).
+		Group("grl.purchase_order_line_id").
+		Scan(&received).Error; err != nil {
+		return fmt.Errorf("failed to calculate received quantities: %w", err)
+	}
+	receivedByLine := map[string]float64{}
+	for _, row := range received {
+		receivedByLine[row.LineID] = row.Quantity
+	}
+
+	anyReceived, allReceived := false, true
+	for _, line := range order.Lines {
+		qty := roundQuantity(receivedByLine[line.ID])
+		if qty > 0 {
+			anyReceived = true
+		}
+		if qty < line.Quantity-stockEpsilon {
+			allReceived = false
+		}
+		if err := tx.Model(&models.PurchaseOrderLine{}).Where("id = ?", line.ID).
+			Update("received_quantity", qty).Error; err != nil {
+			return fmt.Errorf("failed to update received quantity: %w", err)
+		}
+	}
+
+	updates := map[string]interface{}{}
+	switch {
+	case anyReceived && allReceived:
+		updates["status"] = models.PurchaseOrderReceived
+		updates["received_at"] = time.Now()
+	case anyReceived:
+		updates["status"] = models.PurchaseOrderPartiallyReceived
+		updates["received_at"] = nil
+	default:
+		updates["status"] = models.PurchaseOrderSent
+		updates["received_at"] = nil
+	}
+	return tx.Model(&order).Updates(updates).Error
+}
+
+// nextDeliveryDate ближайший день поставки с учётом срока поставки и графика поставщика
+func nextDeliveryDate(supplier *models.Supplier, from time.Time) time.Time {
+	date := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location()).
+		AddDate(0, 0, supplier.LeadTimeDays)
+	if len(supplier.DeliveryDays) == 0 {
+		return date
+	}
+	for i := 0; i < 7; i++ {
+		if supplier.DeliveryDays.Contains(models.WeekDays[date.Weekday()]) {
+			return date
+		}
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// receiptInvoiceNumber номер накладной по заказу без номера поставщика:
+// номер заказа для первой поставки, далее с порядковым суффиксом
+func receiptInvoiceNumber(orderNumber string, previousReceipts int64) string {
+	if previousReceipts == 0 {
+		return orderNumber
+	}
+	return fmt.Sprintf("%s/%d", orderNumber, previousReceipts+1)
+}
+
+// purchaseOrderNumber номер заказа поставщику: PO-20261018-1A2B
+func purchaseOrderNumber(at time.Time) string {
+	suffix := strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:4])
+	return fmt.Sprintf("PO-%s-%s", at.Format("20060102"), suffix)
+}
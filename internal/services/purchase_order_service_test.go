@@ -0,0 +1,54 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+)
+
+func TestNextDeliveryDate(t *testing.T) {
+	// 14 октября 2026 — среда
+	from := time.Date(2026, time.October, 14, 15, 30, 0, 0, time.UTC)
+	date := func(day int) time.Time {
+		return time.Date(2026, time.October, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		supplier models.Supplier
+		want     time.Time
+	}{
+		{name: "any day, no lead time", supplier: models.Supplier{}, want: date(14)},
+		{name: "any day with lead time", supplier: models.Supplier{LeadTimeDays: 2}, want: date(16)},
+		{name: "delivery day is today", supplier: models.Supplier{DeliveryDays: models.StringList{"wed"}}, want: date(14)},
+		{name: "next delivery day this week", supplier: models.Supplier{DeliveryDays: models.StringList{"mon", "fri"}}, want: date(16)},
+		{name: "delivery day next week", supplier: models.Supplier{DeliveryDays: models.StringList{"tue"}}, want: date(20)},
+		{name: "lead time skips the nearest day", supplier: models.Supplier{LeadTimeDays: 3, DeliveryDays: models.StringList{"fri", "tue"}}, want: date(20)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextDeliveryDate(&tt.supplier, from); !got.Equal(tt.want) {
+				t.Errorf("nextDeliveryDate() = %s, want %s", got.Format("Mon 2006-01-02"), tt.want.Format("Mon 2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestReceiptInvoiceNumber(t *testing.T) {
+	tests := []struct {
+		previous int64
+		want     string
+	}{
+		{previous: 0, want: "PO-20261018-1A2B"},
+		{previous: 1, want: "PO-20261018-1A2B/2"},
+		{previous: 4, want: "PO-20261018-1A2B/5"},
+	}
+
+	for _, tt := range tests {
+		if got := receiptInvoiceNumber("PO-20261018-1A2B", tt.previous); got != tt.want {
+			t.Errorf("receiptInvoiceNumber(%d) = %q, want %q", tt.previous, got, tt.want)
+		}
+	}
+}
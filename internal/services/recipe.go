@@ -0,0 +1,372 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+	"gorm.io/gorm"
+)
+
+// PortionRequirements расход на одну порцию продуктов: ингредиенты рецептуры и полуфабрикаты
+// отдельно, чтобы полуфабрикаты можно было списать с их собственного остатка
+type PortionRequirements struct {
+	ingredients  map[string]map[string]float64 // Продукт → ингредиент → брутто в базовых единицах склада
+	semiFinished map[string]map[string]float64 // Продукт → полуфабрикат → количество в единице выхода
+	recipes      *semiFinishedRequirements     // Рецептуры полуфабрикатов на единицу выхода
+}
+
+// semiFinishedRequirements расход на единицу выхода полуфабрикатов, включая вложенные
+type semiFinishedRequirements struct {
+	ingredients map[string]map[string]float64 // Полуфабрикат → ингредиент → брутто в базовых единицах склада
+	components  map[string]map[string]float64 // Полуфабрикат → вложенный полуфабрикат → количество в его единице выхода
+}
+
+// portionRequirements рассчитывает расход ингредиентов на одну порцию каждого продукта
+// (брутто в базовых единицах складского учёта: кг/л/шт), раскрывая полуфабрикаты и
+// фиксированные компоненты сетов до сырья
+func portionRequirements(productIDs []string) (map[string]map[string]float64, error) {
+	reqs, err := loadPortionRequirements(productIDs)
+	if err != nil {
+		return nil, err
+	}
+	return reqs.flatten(), nil
+}
+
+// loadPortionRequirements рассчитывает расход на одну порцию каждого продукта,
+// раскрывая фиксированные компоненты сетов
+func loadPortionRequirements(productIDs []string) (*PortionRequirements, error) {
+	db := database.GetDB()
+	reqs := &PortionRequirements{
+		ingredients:  make(map[string]map[string]float64, len(productIDs)),
+		semiFinished: map[string]map[string]float64{},
+		recipes:      newSemiFinishedRequirements(),
+	}
+
+	var ingredientLines []models.ProductIngredient
+	if err := db.Where("product_id IN ?", productIDs).Find(&ingredientLines).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch product ingredients: %w", err)
+	}
+	ingredientIDs := []string{}
+	for _, line := range ingredientLines {
+		ingredientIDs = appendUnique(ingredientIDs, line.IngredientID)
+	}
+	ingUnits, err := loadIngredientUnits(db, ingredientIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range ingredientLines {
+		addRequirement(reqs.ingredients, line.ProductID, line.IngredientID, ingUnits.stockGross(productLineQuantity(line)))
+	}
+
+	// Сеты: расход фиксированных компонентов (вложенные сеты запрещены валидацией)
+	var bundleItems []models.BundleItem
+	if err := db.Where("bundle_id IN ?", productIDs).Find(&bundleItems).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch bundle items: %w", err)
+	}
+	if len(bundleItems) > 0 {
+		componentIDs := []string{}
+		for _, item := range bundleItems {
+			componentIDs = appendUnique(componentIDs, item.ProductID)
+		}
+		componentReqs, err := loadPortionRequirements(componentIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range bundleItems {
+			for ingredientID, qty := range componentReqs.ingredients[item.ProductID] {
+				addRequirement(reqs.ingredients, item.BundleID, ingredientID, qty*float64(item.Quantity))
+			}
+			for sfID, qty := range componentReqs.semiFinished[item.ProductID] {
+				addRequirement(reqs.semiFinished, item.BundleID, sfID, qty*float64(item.Quantity))
+			}
+		}
+		reqs.recipes.merge(componentReqs.recipes)
+	}
+
+	var sfLines []models.ProductSemiFinished
+	if err := db.Where("product_id IN ?", productIDs).Find(&sfLines).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch product semi-finished: %w", err)
+	}
+	if len(sfLines) == 0 {
+		return reqs, nil
+	}
+
+	sfIDs := []string{}
+	for _, line := range sfLines {
+		sfIDs = appendUnique(sfIDs, line.SemiFinishedID)
+	}
+	semiFinished, err := loadSemiFinishedClosure(db, sfIDs)
+	if err != nil {
+		return nil, err
+	}
+	recipes, err := semiFinishedRecipes(db, semiFinished)
+	if err != nil {
+		return nil, err
+	}
+	reqs.recipes.merge(recipes)
+
+	for _, line := range sfLines {
+		sf, ok := semiFinished[line.SemiFinishedID]
+		if !ok || sf.OutputQuantity <= 0 {
+			continue
+		}
+		qty, err := units.Convert(line.Quantity, line.Unit, sf.OutputUnit)
+		if err != nil {
+			log.Printf("[UNITS] ⚠️ Product %s, semi-finished %s: %v", line.ProductID, sf.Name, err)
+			qty = units.ToBase(line.Quantity, line.Unit) / units.ToBase(1, sf.OutputUnit)
+		}
+		addRequirement(reqs.semiFinished, line.ProductID, sf.ID, qty)
+	}
+
+	return reqs, nil
+}
+
+// flatten раскрывает полуфабрикаты до сырья: продукт → ингредиент → брутто в базовых единицах
+func (r *PortionRequirements) flatten() map[string]map[string]float64 {
+	result := make(map[string]map[string]float64, len(r.ingredients))
+	for productID, ingredients := range r.ingredients {
+		for ingredientID, qty := range ingredients {
+			addRequirement(result, productID, ingredientID, qty)
+		}
+	}
+	raw := map[string]map[string]float64{}
+	for productID, sfs := range r.semiFinished {
+		for sfID, qty := range sfs {
+			for ingredientID, perUnit := range r.recipes.raw(sfID, raw, nil) {
+				addRequirement(result, productID, ingredientID, perUnit*qty)
+			}
+		}
+	}
+	return result
+}
+
+// portionRawNeeds расход сырья на одну порцию продукта с учётом готовых полуфабрикатов:
+// полуфабрикаты берутся с остатка sfStock (в единице выхода), а до сырья раскрывается
+// только нехватка — так же, как при списании по заказу
+func (r *PortionRequirements) portionRawNeeds(productID string, sfStock map[string]float64) map[string]float64 {
+	result := map[string]float64{}
+	for ingredientID, qty := range r.ingredients[productID] {
+		result[ingredientID] += qty
+	}
+
+	available := make(map[string]float64, len(sfStock))
+	for id, qty := range sfStock {
+		available[id] = qty
+	}
+	var expand func(sfNeeds map[string]float64, depth int)
+	expand = func(sfNeeds map[string]float64, depth int) {
+		if depth > maxRecipeDepth {
+			log.Printf("[RECIPE] ⚠️ Semi-finished recipes of %s are nested too deeply, skipping", productID)
+			return
+		}
+		ids := make([]string, 0, len(sfNeeds))
+		for id := range sfNeeds {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, sfID := range ids {
+			taken := math.Min(sfNeeds[sfID], math.Max(available[sfID], 0))
+			available[sfID] -= taken
+			shortage := sfNeeds[sfID] - taken
+			if shortage <= stockEpsilon {
+				continue
+			}
+			for ingredientID, perUnit := range r.recipes.ingredients[sfID] {
+				result[ingredientID] += perUnit * shortage
+			}
+			nested := map[string]float64{}
+			for childID, perUnit := range r.recipes.components[sfID] {
+				nested[childID] += perUnit * shortage
+			}
+			expand(nested, depth+1)
+		}
+	}
+	expand(r.semiFinished[productID], 0)
+	return result
+}
+
+// semiFinishedIDs полуфабрикаты, которые могут понадобиться на порции, включая вложенные
+func (r *PortionRequirements) semiFinishedIDs() []string {
+	ids := []string{}
+	for _, sfs := range r.semiFinished {
+		for sfID := range sfs {
+			ids = appendUnique(ids, sfID)
+		}
+	}
+	for sfID, children := range r.recipes.components {
+		ids = appendUnique(ids, sfID)
+		for childID := range children {
+			ids = appendUnique(ids, childID)
+		}
+	}
+	return ids
+}
+
+// newSemiFinishedRequirements создаёт пустой набор рецептур
+func newSemiFinishedRequirements() *semiFinishedRequirements {
+	return &semiFinishedRequirements{
+		ingredients: map[string]map[string]float64{},
+		components:  map[string]map[string]float64{},
+	}
+}
+
+// merge добавляет рецептуры другого набора
+func (r *semiFinishedRequirements) merge(other *semiFinishedRequirements) {
+	for sfID, perUnit := range other.ingredients {
+		r.ingredients[sfID] = perUnit
+	}
+	for sfID, perUnit := range other.components {
+		r.components[sfID] = perUnit
+	}
+}
+
+// raw раскрывает полуфабрикат до сырья на единицу выхода (memo — уже раскрытые,
+// path — текущая цепочка вложенности для защиты от циклов в старых данных)
+func (r *semiFinishedRequirements) raw(sfID string, memo map[string]map[string]float64, path map[string]bool) map[string]float64 {
+	if result, ok := memo[sfID]; ok {
+		return result
+	}
+	if path[sfID] {
+		log.Printf("[RECIPE] ⚠️ Cycle in semi-finished recipe at %s, skipping", sfID)
+		return nil
+	}
+	if path == nil {
+		path = map[string]bool{}
+	}
+	path[sfID] = true
+	defer delete(path, sfID)
+
+	result := map[string]float64{}
+	for ingredientID, qty := range r.ingredients[sfID] {
+		result[ingredientID] += qty
+	}
+	for childID, qty := range r.components[sfID] {
+		for ingredientID, perUnit := range r.raw(childID, memo, path) {
+			result[ingredientID] += perUnit * qty
+		}
+	}
+	memo[sfID] = result
+	return result
+}
+
+// loadSemiFinishedClosure загружает полуфабрикаты вместе со всеми вложенными (с рецептурами)
+func loadSemiFinishedClosure(db *gorm.DB, ids []string) (map[string]models.SemiFinished, error) {
+	result := map[string]models.SemiFinished{}
+	for len(ids) > 0 {
+		var list []models.SemiFinished
+		if err := db.Preload("Ingredients").Preload("Components").Where("id IN ?", ids).Find(&list).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch semi-finished: %w", err)
+		}
+		next := []string{}
+		for _, sf := range list {
+			result[sf.ID] = sf
+			for _, c := range sf.Components {
+				if _, ok := result[c.ComponentID]; !ok {
+					next = appendUnique(next, c.ComponentID)
+				}
+			}
+		}
+		ids = next
+	}
+	return result, nil
+}
+
+// semiFinishedRecipes рассчитывает расход на единицу выхода загруженных полуфабрикатов:
+// сырьё (брутто в базовых единицах склада) и вложенные полуфабрикаты (в их единице выхода)
+func semiFinishedRecipes(db *gorm.DB, semiFinished map[string]models.SemiFinished) (*semiFinishedRequirements, error) {
+	recipes := newSemiFinishedRequirements()
+
+	ingredientIDs := []string{}
+	for _, sf := range semiFinished {
+		for _, ing := range sf.Ingredients {
+			ingredientIDs = appendUnique(ingredientIDs, ing.IngredientID)
+		}
+	}
+	ingUnits, err := loadIngredientUnits(db, ingredientIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sf := range semiFinished {
+		if sf.OutputQuantity <= 0 {
+			continue
+		}
+		for _, ing := range sf.Ingredients {
+			addRequirement(recipes.ingredients, sf.ID, ing.IngredientID, ingUnits.stockGross(semiFinishedLineQuantity(ing))/sf.OutputQuantity)
+		}
+		for _, c := range sf.Components {
+			child, ok := semiFinished[c.ComponentID]
+			if !ok {
+				continue
+			}
+			qty, err := units.Convert(c.Quantity, c.Unit, child.OutputUnit)
+			if err != nil {
+				log.Printf("[UNITS] ⚠️ Semi-finished %s, component %s: %v", sf.Name, child.Name, err)
+				qty = units.ToBase(c.Quantity, c.Unit) / units.ToBase(1, child.OutputUnit)
+			}
+			addRequirement(recipes.components, sf.ID, child.ID, qty/sf.OutputQuantity)
+		}
+	}
+	return recipes, nil
+}
+
+// addRequirement прибавляет количество в двухуровневую карту расхода
+func addRequirement(m map[string]map[string]float64, key, id string, qty float64) {
+	if m[key] == nil {
+		m[key] = map[string]float64{}
+	}
+	m[key][id] += qty
+}
+
+// productLineQuantity количество строки рецептуры продукта
+func productLineQuantity(line models.ProductIngredient) recipeQuantity {
+	return recipeQuantity{
+		IngredientID: line.IngredientID,
+		Quantity:     line.Quantity,
+		Unit:         line.Unit,
+		QuantityType: line.QuantityType,
+		CookingLoss:  line.CookingLoss,
+	}
+}
+
+// semiFinishedLineQuantity количество строки рецептуры полуфабриката
+func semiFinishedLineQuantity(line models.SemiFinishedIngredient) recipeQuantity {
+	return recipeQuantity{
+		IngredientID: line.IngredientID,
+		Quantity:     line.Quantity,
+		Unit:         line.Unit,
+		QuantityType: line.QuantityType,
+		CookingLoss:  line.CookingLoss,
+	}
+}
+
+// roundCost округляет стоимость до копеек
+func roundCost(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// roundQuantity округляет количество до тысячных (граммов для кг)
+func roundQuantity(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+// appendUnique добавляет значения, которых ещё нет в срезе
+func appendUnique(list []string, values ...string) []string {
+	seen := make(map[string]bool, len(list))
+	for _, v := range list {
+		seen[v] = true
+	}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			list = append(list, v)
+		}
+	}
+	return list
+}
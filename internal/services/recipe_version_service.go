@@ -0,0 +1,742 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecipeVersionService - сервис версий рецептур продуктов и полуфабрикатов
+type RecipeVersionService struct {
+	bundleService *BundleService
+	costService   *CostService
+}
+
+// NewRecipeVersionService создает новый экземпляр RecipeVersionService
+func NewRecipeVersionService() *RecipeVersionService {
+	return &RecipeVersionService{
+		bundleService: NewBundleService(),
+		costService:   NewCostService(),
+	}
+}
+
+// EnsureBaseline сохраняет текущую рецептуру как первую версию, если версий ещё нет.
+// Вызывается перед изменением рецептуры, заведённой до появления версий.
+func (s *RecipeVersionService) EnsureBaseline(tx *gorm.DB, entityType, entityID string, userID *string) error {
+	latest, err := latestRecipeVersion(tx, entityType, entityID)
+	if err != nil || latest != nil {
+		return err
+	}
+	_, err = s.Record(tx, entityType, entityID, "Исходная рецептура", userID)
+	return err
+}
+
+// Record сохраняет текущую рецептуру как новую версию (внутри транзакции).
+// Если состав не изменился, возвращается последняя версия.
+func (s *RecipeVersionService) Record(tx *gorm.DB, entityType, entityID, note string, userID *string) (*models.RecipeVersion, error) {
+	recipe, cost, err := captureRecipe(tx, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := latestRecipeVersion(tx, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && sameRecipe(latest.Recipe, recipe) {
+		return latest, nil
+	}
+
+	version := models.RecipeVersion{
+		ID:         uuid.New().String(),
+		EntityType: entityType,
+		EntityID:   entityID,
+		Version:    1,
+		Recipe:     recipe,
+		Cost:       cost,
+		Note:       note,
+		CreatedBy:  userID,
+	}
+	if latest != nil {
+		version.Version = latest.Version + 1
+	}
+	if err := tx.Create(&version).Error; err != nil {
+		return nil, fmt.Errorf("failed to save recipe version: %w", err)
+	}
+
+	log.Printf("[RECIPE] 📝 %s %s: version %d saved (%s)", entityType, entityID, version.Version, note)
+	return &version, nil
+}
+
+// ActiveVersion возвращает действующую версию рецептуры. Для рецептур без версий
+// первая версия заводится на лету, чтобы заказ всегда был привязан к версии.
+func (s *RecipeVersionService) ActiveVersion(tx *gorm.DB, entityType, entityID string) (*models.RecipeVersion, error) {
+	latest, err := latestRecipeVersion(tx, entityType, entityID)
+	if err != nil || latest != nil {
+		return latest, err
+	}
+
+	recipe, cost, err := captureRecipe(tx, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	// Параллельный заказ мог завести версию раньше — тогда используем её
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RecipeVersion{
+		ID:         uuid.New().String(),
+		EntityType: entityType,
+		EntityID:   entityID,
+		Version:    1,
+		Recipe:     recipe,
+		Cost:       cost,
+		Note:       "Исходная рецептура",
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to save recipe version: %w", err)
+	}
+	return latestRecipeVersion(tx, entityType, entityID)
+}
+
+// OrderItemVersions возвращает действующую версию рецептуры продукта и версии всех
+// входящих в неё полуфабрикатов, включая вложенные, — состав позиции на момент заказа
+func (s *RecipeVersionService) OrderItemVersions(tx *gorm.DB, productID string) (*models.RecipeVersion, models.StringList, error) {
+	version, err := s.ActiveVersion(tx, models.CostEntityProduct, productID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sfVersionIDs := models.StringList{}
+	seen := map[string]bool{}
+	queue := version.Recipe.SemiFinished
+	for len(queue) > 0 {
+		line := queue[0]
+		queue = queue[1:]
+		if seen[line.SemiFinishedID] {
+			continue
+		}
+		seen[line.SemiFinishedID] = true
+
+		sfVersion, err := s.ActiveVersion(tx, models.CostEntitySemiFinished, line.SemiFinishedID)
+		if err != nil {
+			return nil, nil, err
+		}
+		sfVersionIDs = append(sfVersionIDs, sfVersion.ID)
+		queue = append(queue, sfVersion.Recipe.SemiFinished...)
+	}
+	return version, sfVersionIDs, nil
+}
+
+// GetVersions возвращает версии рецептуры (без состава), новые первыми
+func (s *RecipeVersionService) GetVersions(entityType, entityID string) ([]models.RecipeVersion, error) {
+	if err := validateRecipeEntity(entityType); err != nil {
+		return nil, err
+	}
+	var versions []models.RecipeVersion
+	if err := database.GetDB().
+		Omit("recipe").
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch recipe versions: %w", err)
+	}
+	return versions, nil
+}
+
+// GetVersion возвращает версию рецептуры с составом
+func (s *RecipeVersionService) GetVersion(entityType, entityID string, version int) (*models.RecipeVersion, error) {
+	if err := validateRecipeEntity(entityType); err != nil {
+		return nil, err
+	}
+	var result models.RecipeVersion
+	if err := database.GetDB().
+		Where("entity_type = ? AND entity_id = ? AND version = ?", entityType, entityID, version).
+		First(&result).Error; err != nil {
+		return nil, fmt.Errorf("recipe version %d not found: %w", version, err)
+	}
+	return &result, nil
+}
+
+// Diff сравнивает две версии рецептуры
+func (s *RecipeVersionService) Diff(entityType, entityID string, from, to int) (*models.RecipeVersionDiff, error) {
+	before, err := s.GetVersion(entityType, entityID, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.GetVersion(entityType, entityID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.RecipeVersionDiff{
+		EntityType:  entityType,
+		EntityID:    entityID,
+		FromVersion: from,
+		ToVersion:   to,
+		FromCost:    before.Cost,
+		ToCost:      after.Cost,
+		Lines:       diffRecipeLines(before.Recipe, after.Recipe),
+	}
+	if !sameOutput(before.Recipe, after.Recipe) {
+		diff.FromOutput = fmt.Sprintf("%g %s", before.Recipe.OutputQuantity, before.Recipe.OutputUnit)
+		diff.ToOutput = fmt.Sprintf("%g %s", after.Recipe.OutputQuantity, after.Recipe.OutputUnit)
+	}
+	return diff, nil
+}
+
+// Restore восстанавливает рецептуру из версии по текущим ценам ингредиентов
+// и себестоимости полуфабрикатов. Восстановление сохраняется как новая версия.
+// Для полуфабриката себестоимость каскадно пересчитывается в содержащих его рецептурах.
+func (s *RecipeVersionService) Restore(entityType, entityID string, version int, userID *string) (*models.RecipeVersion, *models.CostRecalculationResult, error) {
+	target, err := s.GetVersion(entityType, entityID, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var restored *models.RecipeVersion
+	var costs *models.CostRecalculationResult
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		switch entityType {
+		case models.CostEntitySemiFinished:
+			if err := restoreSemiFinishedRecipe(tx, entityID, target.Recipe); err != nil {
+				return err
+			}
+			var err error
+			costs, err = s.costService.RecalculateSemiFinished(tx, entityID, fmt.Sprintf("recipe version %d restored", version))
+			if err != nil {
+				return err
+			}
+		case models.CostEntityProduct:
+			if err := restoreProductRecipe(tx, entityID, target.Recipe); err != nil {
+				return err
+			}
+			if err := s.bundleService.RecalculateContaining(tx, []string{entityID}); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		restored, err = s.Record(tx, entityType, entityID, fmt.Sprintf("Восстановлена версия %d", version), userID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("[RECIPE] ⏪ %s %s restored to version %d (now version %d)", entityType, entityID, version, restored.Version)
+	return restored, costs, nil
+}
+
+// OrderCosting себестоимость заказа по позициям: плановая по версии рецептуры,
+// действовавшей на момент заказа, и фактическая по списанным партиям
+func (s *RecipeVersionService) OrderCosting(orderID string) (*models.OrderCosting, error) {
+	db := database.GetDB()
+
+	var order models.Order
+	if err := db.First(&order, "id = ?", orderID).Error; err != nil {
+		return nil, fmt.Errorf("order not found: %w", err)
+	}
+	var items []models.OrderItem
+	if err := db.Where("order_id = ?", orderID).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch order items: %w", err)
+	}
+
+	productIDs, versionIDs := []string{}, []string{}
+	for _, item := range items {
+		productIDs = appendUnique(productIDs, item.ProductID)
+		if item.RecipeVersionID != nil {
+			versionIDs = appendUnique(versionIDs, *item.RecipeVersionID)
+		}
+	}
+	names := map[string]string{}
+	if len(productIDs) > 0 {
+		var products []models.Product
+		if err := db.Unscoped().Select("id", "name").Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch products: %w", err)
+		}
+		for _, p := range products {
+			names[p.ID] = p.Name
+		}
+	}
+	versions := map[string]int{}
+	if len(versionIDs) > 0 {
+		var list []models.RecipeVersion
+		if err := db.Omit("recipe").Where("id IN ?", versionIDs).Find(&list).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch recipe versions: %w", err)
+		}
+		for _, v := range list {
+			versions[v.ID] = v.Version
+		}
+	}
+
+	result := &models.OrderCosting{
+		OrderID:   order.ID,
+		CreatedAt: order.CreatedAt,
+		Items:     make([]models.OrderItemCosting, 0, len(items)),
+	}
+	for _, item := range items {
+		costing := models.OrderItemCosting{
+			OrderItemID:     item.ID,
+			ProductID:       item.ProductID,
+			ProductName:     names[item.ProductID],
+			Quantity:        item.Quantity,
+			Revenue:         roundCost(item.Price * float64(item.Quantity)),
+			RecipeVersionID: item.RecipeVersionID,
+			RecipeCost:      item.RecipeCost,
+			ActualCost:      item.Cost,
+			Variance:        roundCost(item.Cost - item.RecipeCost),
+
+			SemiFinishedVersionIDs: item.SemiFinishedVersionIDs,
+		}
+		if item.RecipeVersionID != nil {
+			if v, ok := versions[*item.RecipeVersionID]; ok {
+				costing.RecipeVersion = &v
+			}
+		}
+		result.Items = append(result.Items, costing)
+		result.Revenue += costing.Revenue
+		result.RecipeCost += costing.RecipeCost
+		result.ActualCost += costing.ActualCost
+	}
+	result.Revenue = roundCost(result.Revenue)
+	result.RecipeCost = roundCost(result.RecipeCost)
+	result.ActualCost = roundCost(result.ActualCost)
+	result.Variance = roundCost(result.ActualCost - result.RecipeCost)
+	return result, nil
+}
+
+// validateRecipeEntity проверяет тип сущности с рецептурой
+func validateRecipeEntity(entityType string) error {
+	if entityType != models.CostEntityProduct && entityType != models.CostEntitySemiFinished {
+		return fmt.Errorf("entity type must be '%s' or '%s'", models.CostEntityProduct, models.CostEntitySemiFinished)
+	}
+	return nil
+}
+
+// latestRecipeVersion возвращает последнюю версию рецептуры (nil — версий нет)
+func latestRecipeVersion(db *gorm.DB, entityType, entityID string) (*models.RecipeVersion, error) {
+	var version models.RecipeVersion
+	err := db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("version DESC").
+		First(&version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch recipe version: %w", err)
+	}
+	return &version, nil
+}
+
+// captureRecipe снимает текущий состав рецептуры и себестоимость
+func captureRecipe(db *gorm.DB, entityType, entityID string) (models.RecipeSnapshot, float64, error) {
+	recipe := models.RecipeSnapshot{
+		Ingredients:  []models.RecipeSnapshotIngredient{},
+		SemiFinished: []models.RecipeSnapshotSemiFinished{},
+	}
+
+	var ingredients []models.RecipeSnapshotIngredient
+	var semiFinished []models.RecipeSnapshotSemiFinished
+	var cost float64
+
+	switch entityType {
+	case models.CostEntitySemiFinished:
+		var sf models.SemiFinished
+		if err := db.Preload("Ingredients").Preload("Components").First(&sf, "id = ?", entityID).Error; err != nil {
+			return recipe, 0, fmt.Errorf("semi-finished not found: %w", err)
+		}
+		recipe.OutputQuantity, recipe.OutputUnit = sf.OutputQuantity, sf.OutputUnit
+		for _, line := range sf.Ingredients {
+			ingredients = append(ingredients, snapshotIngredient(semiFinishedLineQuantity(line), line.IngredientName, line.PricePerUnit, line.TotalPrice))
+		}
+		for _, c := range sf.Components {
+			semiFinished = append(semiFinished, models.RecipeSnapshotSemiFinished{
+				SemiFinishedID:   c.ComponentID,
+				SemiFinishedName: c.ComponentName,
+				Quantity:         c.Quantity,
+				Unit:             c.Unit,
+				CostPerUnit:      c.CostPerUnit,
+				TotalCost:        c.TotalCost,
+			})
+		}
+		cost = sf.CostPerUnit
+
+	case models.CostEntityProduct:
+		var product models.Product
+		if err := db.Preload("Ingredients").Preload("SemiFinished").First(&product, "id = ?", entityID).Error; err != nil {
+			return recipe, 0, fmt.Errorf("product not found: %w", err)
+		}
+		for _, line := range product.Ingredients {
+			ingredients = append(ingredients, snapshotIngredient(productLineQuantity(line), line.IngredientName, line.PricePerUnit, line.TotalPrice))
+		}
+		for _, line := range product.SemiFinished {
+			semiFinished = append(semiFinished, models.RecipeSnapshotSemiFinished{
+				SemiFinishedID:   line.SemiFinishedID,
+				SemiFinishedName: line.SemiFinishedName,
+				Quantity:         line.Quantity,
+				Unit:             line.Unit,
+				CostPerUnit:      line.CostPerUnit,
+				TotalCost:        line.TotalCost,
+			})
+		}
+		cost = product.Cost
+
+	default:
+		return recipe, 0, validateRecipeEntity(entityType)
+	}
+
+	// Фиксированный порядок строк — чтобы одинаковые рецептуры давали одинаковые снимки
+	sort.SliceStable(ingredients, func(i, j int) bool {
+		return ingredients[i].IngredientName+ingredients[i].IngredientID < ingredients[j].IngredientName+ingredients[j].IngredientID
+	})
+	sort.SliceStable(semiFinished, func(i, j int) bool {
+		return semiFinished[i].SemiFinishedName+semiFinished[i].SemiFinishedID < semiFinished[j].SemiFinishedName+semiFinished[j].SemiFinishedID
+	})
+	recipe.Ingredients = append(recipe.Ingredients, ingredients...)
+	recipe.SemiFinished = append(recipe.SemiFinished, semiFinished...)
+	return recipe, cost, nil
+}
+
+// snapshotIngredient строка сырья для снимка рецептуры
+func snapshotIngredient(line recipeQuantity, name string, pricePerUnit, totalPrice float64) models.RecipeSnapshotIngredient {
+	quantityType := line.QuantityType
+	if quantityType == "" {
+		quantityType = models.RecipeQuantityGross
+	}
+	return models.RecipeSnapshotIngredient{
+		IngredientID:   line.IngredientID,
+		IngredientName: name,
+		Quantity:       line.Quantity,
+		Unit:           line.Unit,
+		QuantityType:   quantityType,
+		CookingLoss:    line.CookingLoss,
+		PricePerUnit:   pricePerUnit,
+		TotalPrice:     totalPrice,
+	}
+}
+
+// sameRecipe проверяет, совпадает ли состав рецептур (цены не учитываются)
+func sameRecipe(a, b models.RecipeSnapshot) bool {
+	return sameOutput(a, b) && len(diffRecipeLines(a, b)) == 0
+}
+
+// sameOutput проверяет, совпадает ли выход полуфабриката
+func sameOutput(a, b models.RecipeSnapshot) bool {
+	return a.OutputQuantity == b.OutputQuantity && a.OutputUnit == b.OutputUnit
+}
+
+// recipeDiffEntry строка рецептуры, приведённая к виду для сравнения
+type recipeDiffEntry struct {
+	kind, id, name string
+	value          models.RecipeDiffValue
+}
+
+// diffRecipeLines сравнивает строки двух снимков рецептуры
+func diffRecipeLines(before, after models.RecipeSnapshot) []models.RecipeLineDiff {
+	beforeLines, beforeKeys := recipeDiffEntries(before)
+	afterLines, afterKeys := recipeDiffEntries(after)
+
+	diff := []models.RecipeLineDiff{}
+	for _, key := range beforeKeys {
+		b := beforeLines[key]
+		a, ok := afterLines[key]
+		if !ok {
+			value := b.value
+			diff = append(diff, models.RecipeLineDiff{Kind: b.kind, ID: b.id, Name: b.name, Change: models.RecipeLineRemoved, Before: &value})
+			continue
+		}
+		if !sameDiffValue(b.value, a.value) {
+			beforeValue, afterValue := b.value, a.value
+			diff = append(diff, models.RecipeLineDiff{Kind: a.kind, ID: a.id, Name: a.name, Change: models.RecipeLineChanged, Before: &beforeValue, After: &afterValue})
+		}
+	}
+	for _, key := range afterKeys {
+		if _, ok := beforeLines[key]; ok {
+			continue
+		}
+		a := afterLines[key]
+		value := a.value
+		diff = append(diff, models.RecipeLineDiff{Kind: a.kind, ID: a.id, Name: a.name, Change: models.RecipeLineAdded, After: &value})
+	}
+	return diff
+}
+
+// recipeDiffEntries строки снимка по ключу "вид:ID#n" (n — повтор одного ингредиента в рецептуре)
+func recipeDiffEntries(recipe models.RecipeSnapshot) (map[string]recipeDiffEntry, []string) {
+	entries := map[string]recipeDiffEntry{}
+	keys := []string{}
+	seen := map[string]int{}
+	add := func(entry recipeDiffEntry) {
+		base := entry.kind + ":" + entry.id
+		key := fmt.Sprintf("%s#%d", base, seen[base])
+		seen[base]++
+		entries[key] = entry
+		keys = append(keys, key)
+	}
+
+	for _, line := range recipe.Ingredients {
+		add(recipeDiffEntry{
+			kind: models.RecipeNodeIngredient, id: line.IngredientID, name: line.IngredientName,
+			value: models.RecipeDiffValue{
+				Quantity:     line.Quantity,
+				Unit:         line.Unit,
+				QuantityType: line.QuantityType,
+				CookingLoss:  line.CookingLoss,
+				Cost:         line.TotalPrice,
+			},
+		})
+	}
+	for _, line := range recipe.SemiFinished {
+		add(recipeDiffEntry{
+			kind: models.RecipeNodeSemiFinished, id: line.SemiFinishedID, name: line.SemiFinishedName,
+			value: models.RecipeDiffValue{
+				Quantity: line.Quantity,
+				Unit:     line.Unit,
+				Cost:     line.TotalCost,
+			},
+		})
+	}
+	return entries, keys
+}
+
+// sameDiffValue сравнивает количество строки без учёта стоимости
+func sameDiffValue(a, b models.RecipeDiffValue) bool {
+	if a.Quantity != b.Quantity || a.Unit != b.Unit || a.QuantityType != b.QuantityType {
+		return false
+	}
+	if (a.CookingLoss == nil) != (b.CookingLoss == nil) {
+		return false
+	}
+	return a.CookingLoss == nil || *a.CookingLoss == *b.CookingLoss
+}
+
+// currentIngredientPrices текущие цены ингредиентов со склада (за кг/л/шт)
+func currentIngredientPrices(db *gorm.DB, ingredientIDs []string) (map[string]float64, error) {
+	prices := map[string]float64{}
+	if len(ingredientIDs) == 0 {
+		return prices, nil
+	}
+
+	var count int64
+	if err := db.Model(&models.Ingredient{}).Where("id IN ?", ingredientIDs).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch ingredients: %w", err)
+	}
+	if int(count) != len(ingredientIDs) {
+		return nil, fmt.Errorf("some ingredients of this recipe version no longer exist")
+	}
+
+	var items []models.StockItem
+	if err := db.Where(`"ingredientId" IN ?`, ingredientIDs).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch stock items: %w", err)
+	}
+	for _, item := range items {
+		if item.PricePerUnit != nil {
+			prices[item.IngredientID] = *item.PricePerUnit
+		}
+	}
+	return prices, nil
+}
+
+// restoreSemiFinishedRecipe заменяет рецептуру полуфабриката составом из снимка
+func restoreSemiFinishedRecipe(tx *gorm.DB, id string, recipe models.RecipeSnapshot) error {
+	var sf models.SemiFinished
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sf, "id = ?", id).Error; err != nil {
+		return fmt.Errorf("semi-finished not found: %w", err)
+	}
+	if recipe.OutputUnit != sf.OutputUnit && sf.StockQuantity > 0 {
+		return fmt.Errorf("cannot change output unit while semi-finished is in stock")
+	}
+
+	lines, err := restoredIngredientLines(tx, recipe.Ingredients)
+	if err != nil {
+		return err
+	}
+	total := 0.0
+	for _, line := range lines {
+		total += line.TotalPrice
+	}
+
+	componentIDs := []string{}
+	components := make([]models.SemiFinishedComponent, 0, len(recipe.SemiFinished))
+	for _, c := range recipe.SemiFinished {
+		var child models.SemiFinished
+		if err := tx.First(&child, "id = ?", c.SemiFinishedID).Error; err != nil {
+			return fmt.Errorf("semi-finished component %s no longer exists: %w", c.SemiFinishedName, err)
+		}
+		lineCost, err := semiFinishedLineCost(&child, c.Quantity, c.Unit)
+		if err != nil {
+			return fmt.Errorf("semi-finished component %s: %w", child.Name, err)
+		}
+		components = append(components, models.SemiFinishedComponent{
+			ID:             uuid.New().String(),
+			SemiFinishedID: id,
+			ComponentID:    child.ID,
+			ComponentName:  child.Name,
+			Quantity:       c.Quantity,
+			Unit:           c.Unit,
+			CostPerUnit:    child.CostPerUnit,
+			TotalCost:      lineCost,
+		})
+		componentIDs = appendUnique(componentIDs, child.ID)
+		total += lineCost
+	}
+	if len(componentIDs) > 0 {
+		if err := checkRecipeCycle(tx, id, componentIDs); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Where("semi_finished_id = ?", id).Delete(&models.SemiFinishedIngredient{}).Error; err != nil {
+		return fmt.Errorf("failed to replace ingredients: %w", err)
+	}
+	if err := tx.Where("semi_finished_id = ?", id).Delete(&models.SemiFinishedComponent{}).Error; err != nil {
+		return fmt.Errorf("failed to replace components: %w", err)
+	}
+	for _, line := range lines {
+		if err := tx.Create(&models.SemiFinishedIngredient{
+			ID:             uuid.New().String(),
+			SemiFinishedID: id,
+			IngredientID:   line.IngredientID,
+			IngredientName: line.IngredientName,
+			Quantity:       line.Quantity,
+			Unit:           line.Unit,
+			PricePerUnit:   line.PricePerUnit,
+			TotalPrice:     line.TotalPrice,
+			QuantityType:   line.QuantityType,
+			CookingLoss:    line.CookingLoss,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to restore ingredient: %w", err)
+		}
+	}
+	for i := range components {
+		if err := tx.Create(&components[i]).Error; err != nil {
+			return fmt.Errorf("failed to restore component: %w", err)
+		}
+	}
+
+	costPerUnit := 0.0
+	if recipe.OutputQuantity > 0 {
+		costPerUnit = roundCost(total / recipe.OutputQuantity)
+	}
+	if err := tx.Model(&models.SemiFinished{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"output_quantity": recipe.OutputQuantity,
+		"output_unit":     recipe.OutputUnit,
+		"cost_per_unit":   costPerUnit,
+		"total_cost":      roundCost(costPerUnit * recipe.OutputQuantity),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update semi-finished: %w", err)
+	}
+	return nil
+}
+
+// restoreProductRecipe заменяет рецептуру продукта составом из снимка
+func restoreProductRecipe(tx *gorm.DB, id string, recipe models.RecipeSnapshot) error {
+	var product models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, "id = ?", id).Error; err != nil {
+		return fmt.Errorf("product not found: %w", err)
+	}
+
+	lines, err := restoredIngredientLines(tx, recipe.Ingredients)
+	if err != nil {
+		return err
+	}
+	product.Ingredients = make([]models.ProductIngredient, 0, len(lines))
+	for _, line := range lines {
+		product.Ingredients = append(product.Ingredients, models.ProductIngredient{
+			ID:             uuid.New().String(),
+			ProductID:      id,
+			IngredientID:   line.IngredientID,
+			IngredientName: line.IngredientName,
+			Quantity:       line.Quantity,
+			Unit:           line.Unit,
+			PricePerUnit:   line.PricePerUnit,
+			TotalPrice:     line.TotalPrice,
+			QuantityType:   line.QuantityType,
+			CookingLoss:    line.CookingLoss,
+		})
+	}
+
+	product.SemiFinished = make([]models.ProductSemiFinished, 0, len(recipe.SemiFinished))
+	for _, line := range recipe.SemiFinished {
+		var sf models.SemiFinished
+		if err := tx.First(&sf, "id = ?", line.SemiFinishedID).Error; err != nil {
+			return fmt.Errorf("semi-finished %s no longer exists: %w", line.SemiFinishedName, err)
+		}
+		lineCost, err := semiFinishedLineCost(&sf, line.Quantity, line.Unit)
+		if err != nil {
+			return fmt.Errorf("semi-finished %s: %w", sf.Name, err)
+		}
+		product.SemiFinished = append(product.SemiFinished, models.ProductSemiFinished{
+			ID:               uuid.New().String(),
+			ProductID:        id,
+			SemiFinishedID:   sf.ID,
+			SemiFinishedName: sf.Name,
+			Quantity:         line.Quantity,
+			Unit:             line.Unit,
+			CostPerUnit:      sf.CostPerUnit,
+			TotalCost:        lineCost,
+		})
+	}
+
+	if err := tx.Where("product_id = ?", id).Delete(&models.ProductIngredient{}).Error; err != nil {
+		return fmt.Errorf("failed to replace ingredients: %w", err)
+	}
+	if err := tx.Where("product_id = ?", id).Delete(&models.ProductSemiFinished{}).Error; err != nil {
+		return fmt.Errorf("failed to replace semi-finished: %w", err)
+	}
+	for i := range product.Ingredients {
+		if err := tx.Create(&product.Ingredients[i]).Error; err != nil {
+			return fmt.Errorf("failed to restore ingredient: %w", err)
+		}
+	}
+	for i := range product.SemiFinished {
+		if err := tx.Create(&product.SemiFinished[i]).Error; err != nil {
+			return fmt.Errorf("failed to restore semi-finished: %w", err)
+		}
+	}
+
+	if err := tx.Model(&models.Product{}).Where("id = ?", id).Update("cost", ProductCost(&product)).Error; err != nil {
+		return fmt.Errorf("failed to update product cost: %w", err)
+	}
+	return nil
+}
+
+// restoredIngredientLines строки сырья из снимка по текущим ценам склада
+// (если цены нет — по цене из версии)
+func restoredIngredientLines(tx *gorm.DB, lines []models.RecipeSnapshotIngredient) ([]models.RecipeSnapshotIngredient, error) {
+	ids := make([]string, 0, len(lines))
+	for _, line := range lines {
+		ids = appendUnique(ids, line.IngredientID)
+	}
+	prices, err := currentIngredientPrices(tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	ingUnits, err := loadIngredientUnits(tx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.RecipeSnapshotIngredient, 0, len(lines))
+	for _, line := range lines {
+		if price, ok := prices[line.IngredientID]; ok {
+			line.PricePerUnit = roundCost(price)
+		}
+		qty, err := ingUnits.toStockGross(recipeQuantity{
+			IngredientID: line.IngredientID,
+			Quantity:     line.Quantity,
+			Unit:         line.Unit,
+			QuantityType: line.QuantityType,
+			CookingLoss:  line.CookingLoss,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ingredient %s: %w", line.IngredientName, err)
+		}
+		line.TotalPrice = roundCost(qty * line.PricePerUnit)
+		result = append(result, line)
+	}
+	return result, nil
+}
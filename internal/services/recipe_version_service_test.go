@@ -0,0 +1,87 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+)
+
+func TestDiffRecipeLines(t *testing.T) {
+	flour := func(qty, cost float64) models.RecipeSnapshotIngredient {
+		return models.RecipeSnapshotIngredient{IngredientID: "flour", IngredientName: "Мука", Quantity: qty, Unit: "g", QuantityType: models.RecipeQuantityGross, TotalPrice: cost}
+	}
+	salt := models.RecipeSnapshotIngredient{IngredientID: "salt", IngredientName: "Соль", Quantity: 5, Unit: "g", QuantityType: models.RecipeQuantityGross}
+	sauce := func(qty float64) models.RecipeSnapshotSemiFinished {
+		return models.RecipeSnapshotSemiFinished{SemiFinishedID: "sauce", SemiFinishedName: "Соус", Quantity: qty, Unit: "g"}
+	}
+	recipe := func(ingredients []models.RecipeSnapshotIngredient, semiFinished ...models.RecipeSnapshotSemiFinished) models.RecipeSnapshot {
+		return models.RecipeSnapshot{Ingredients: ingredients, SemiFinished: semiFinished}
+	}
+
+	tests := []struct {
+		name   string
+		before models.RecipeSnapshot
+		after  models.RecipeSnapshot
+		want   []string // "вид:ID:изменение"
+	}{
+		{
+			name:   "same recipe",
+			before: recipe([]models.RecipeSnapshotIngredient{flour(200, 10), salt}, sauce(50)),
+			after:  recipe([]models.RecipeSnapshotIngredient{flour(200, 10), salt}, sauce(50)),
+			want:   []string{},
+		},
+		{
+			name:   "price change alone is not a change",
+			before: recipe([]models.RecipeSnapshotIngredient{flour(200, 10)}),
+			after:  recipe([]models.RecipeSnapshotIngredient{flour(200, 14)}),
+			want:   []string{},
+		},
+		{
+			name:   "quantity changed",
+			before: recipe([]models.RecipeSnapshotIngredient{flour(200, 10)}, sauce(50)),
+			after:  recipe([]models.RecipeSnapshotIngredient{flour(250, 12.5)}, sauce(40)),
+			want:   []string{"ingredient:flour:changed", "semi_finished:sauce:changed"},
+		},
+		{
+			name:   "lines added and removed",
+			before: recipe([]models.RecipeSnapshotIngredient{flour(200, 10), salt}),
+			after:  recipe([]models.RecipeSnapshotIngredient{flour(200, 10)}, sauce(50)),
+			want:   []string{"ingredient:salt:removed", "semi_finished:sauce:added"},
+		},
+		{
+			name:   "repeated ingredient is matched by position",
+			before: recipe([]models.RecipeSnapshotIngredient{flour(200, 10), flour(20, 1)}),
+			after:  recipe([]models.RecipeSnapshotIngredient{flour(200, 10), flour(30, 1.5), flour(10, 0.5)}),
+			want:   []string{"ingredient:flour:changed", "ingredient:flour:added"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := diffRecipeLines(tt.before, tt.after)
+			got := make([]string, 0, len(diff))
+			for _, line := range diff {
+				got = append(got, line.Kind+":"+line.ID+":"+line.Change)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("diffRecipeLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("cooking loss change", func(t *testing.T) {
+		loss := 20.0
+		before := recipe([]models.RecipeSnapshotIngredient{flour(200, 10)})
+		after := recipe([]models.RecipeSnapshotIngredient{flour(200, 10)})
+		after.Ingredients[0].CookingLoss = &loss
+
+		diff := diffRecipeLines(before, after)
+		if len(diff) != 1 || diff[0].Change != models.RecipeLineChanged {
+			t.Fatalf("diffRecipeLines() = %+v, want one changed line", diff)
+		}
+		if diff[0].Before.CookingLoss != nil || diff[0].After.CookingLoss == nil || *diff[0].After.CookingLoss != loss {
+			t.Errorf("cooking loss before = %v, after = %v", diff[0].Before.CookingLoss, diff[0].After.CookingLoss)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+)
+
+// recipeQuantity количество строки рецептуры с типом (брутто/нетто) и потерями при варке
+type recipeQuantity struct {
+	IngredientID string
+	Quantity     float64
+	Unit         string
+	QuantityType string
+	CookingLoss  *float64
+}
+
+// normalizeRecipeQuantity проверяет тип количества и процент потерь при тепловой обработке
+func normalizeRecipeQuantity(quantityType string, cookingLoss *float64) (string, error) {
+	quantityType = strings.ToLower(strings.TrimSpace(quantityType))
+	switch quantityType {
+	case "":
+		quantityType = models.RecipeQuantityGross
+	case models.RecipeQuantityGross, models.RecipeQuantityNet:
+	default:
+		return "", fmt.Errorf("quantityType must be 'gross' or 'net'")
+	}
+	if cookingLoss != nil && (*cookingLoss < 0 || *cookingLoss >= 100) {
+		return "", fmt.Errorf("cookingLoss must be between 0 and 100")
+	}
+	return quantityType, nil
+}
+
+// TrimLossOf доля потерь при холодной обработке: норма отходов, а если она не задана —
+// соотношение нетто/брутто складской позиции
+func TrimLossOf(item *models.StockItem) float64 {
+	loss := 0.0
+	if item.WastePercentage != nil && *item.WastePercentage > 0 {
+		loss = *item.WastePercentage / 100
+	} else if item.BruttoWeight != nil && item.NettoWeight != nil && *item.BruttoWeight > 0 && *item.NettoWeight < *item.BruttoWeight {
+		loss = 1 - *item.NettoWeight / *item.BruttoWeight
+	}
+	if loss < 0 || loss >= 1 {
+		return 0
+	}
+	return loss
+}
+
+// losses доли потерь строки: при холодной обработке (по ингредиенту) и при тепловой (по строке)
+func (u *ingredientUnits) losses(line recipeQuantity) (trim, cooking float64) {
+	trim = u.trimLoss[line.IngredientID]
+	if line.CookingLoss != nil && *line.CookingLoss > 0 && *line.CookingLoss < 100 {
+		cooking = *line.CookingLoss / 100
+	}
+	return trim, cooking
+}
+
+// grossQuantity количество брутто в единице строки: нетто пересчитывается с учётом
+// потерь при зачистке и тепловой обработке
+func (u *ingredientUnits) grossQuantity(line recipeQuantity) float64 {
+	if line.QuantityType != models.RecipeQuantityNet {
+		return line.Quantity
+	}
+	trim, cooking := u.losses(line)
+	return line.Quantity / ((1 - trim) * (1 - cooking))
+}
+
+// toStockGross количество брутто строки в базовой единице склада — по нему списывается
+// склад и считается себестоимость
+func (u *ingredientUnits) toStockGross(line recipeQuantity) (float64, error) {
+	return u.toStockBase(line.IngredientID, u.grossQuantity(line), line.Unit)
+}
+
+// stockGross как toStockGross, но для пересчётов по уже сохранённым рецептурам
+func (u *ingredientUnits) stockGross(line recipeQuantity) float64 {
+	return u.stockBase(line.IngredientID, u.grossQuantity(line), line.Unit)
+}
+
+// breakdown разбивка строки рецептуры: брутто, нетто, потери и их стоимость
+func (u *ingredientUnits) breakdown(line recipeQuantity, name string, pricePerUnit float64) models.RecipeLineBreakdown {
+	trim, cooking := u.losses(line)
+	gross := u.grossQuantity(line)
+	afterTrim := gross * (1 - trim)
+	net := afterTrim * (1 - cooking)
+
+	result := models.RecipeLineBreakdown{
+		IngredientID:       line.IngredientID,
+		IngredientName:     name,
+		Unit:               line.Unit,
+		QuantityType:       line.QuantityType,
+		Quantity:           line.Quantity,
+		GrossQuantity:      roundQuantity(gross),
+		NetQuantity:        roundQuantity(net),
+		TrimLoss:           roundQuantity(gross - afterTrim),
+		TrimLossPercent:    roundCost(trim * 100),
+		CookingLoss:        roundQuantity(afterTrim - net),
+		CookingLossPercent: roundCost(cooking * 100),
+		PricePerUnit:       pricePerUnit,
+	}
+	if result.QuantityType == "" {
+		result.QuantityType = models.RecipeQuantityGross
+	}
+
+	qty, err := u.toStockBase(line.IngredientID, gross, line.Unit)
+	if err != nil {
+		log.Printf("[UNITS] ⚠️ Ingredient %s: %v", line.IngredientID, err)
+		result.Error = err.Error()
+		return result
+	}
+	result.Cost = roundCost(qty * pricePerUnit)
+	if gross > 0 {
+		result.LossCost = roundCost(result.Cost * (gross - net) / gross)
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+)
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestNormalizeRecipeQuantity(t *testing.T) {
+	tests := []struct {
+		name         string
+		quantityType string
+		cookingLoss  *float64
+		want         string
+		wantErr      bool
+	}{
+		{name: "empty means gross", quantityType: "", want: models.RecipeQuantityGross},
+		{name: "gross", quantityType: "gross", want: models.RecipeQuantityGross},
+		{name: "net is case insensitive", quantityType: " NET ", want: models.RecipeQuantityNet},
+		{name: "unknown type", quantityType: "raw", wantErr: true},
+		{name: "cooking loss in range", quantityType: "net", cookingLoss: floatPtr(35), want: models.RecipeQuantityNet},
+		{name: "zero cooking loss", quantityType: "net", cookingLoss: floatPtr(0), want: models.RecipeQuantityNet},
+		{name: "negative cooking loss", quantityType: "net", cookingLoss: floatPtr(-5), wantErr: true},
+		{name: "cooking loss of 100 percent", quantityType: "net", cookingLoss: floatPtr(100), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeRecipeQuantity(tt.quantityType, tt.cookingLoss)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeRecipeQuantity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeRecipeQuantity() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimLossOf(t *testing.T) {
+	tests := []struct {
+		name string
+		item models.StockItem
+		want float64
+	}{
+		{name: "no data", item: models.StockItem{}, want: 0},
+		{name: "waste percentage", item: models.StockItem{WastePercentage: floatPtr(20)}, want: 0.2},
+		{name: "brutto and netto", item: models.StockItem{BruttoWeight: floatPtr(1), NettoWeight: floatPtr(0.75)}, want: 0.25},
+		{name: "waste percentage wins", item: models.StockItem{WastePercentage: floatPtr(10), BruttoWeight: floatPtr(1), NettoWeight: floatPtr(0.5)}, want: 0.1},
+		{name: "netto above brutto is ignored", item: models.StockItem{BruttoWeight: floatPtr(1), NettoWeight: floatPtr(1.2)}, want: 0},
+		{name: "full loss is ignored", item: models.StockItem{WastePercentage: floatPtr(100)}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TrimLossOf(&tt.item); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("TrimLossOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngredientUnitsGrossQuantity(t *testing.T) {
+	ingUnits := &ingredientUnits{
+		stockUnits: map[string]string{"potato": units.Kilogram, "egg": units.Piece},
+		converters: map[string]*units.Converter{},
+		trimLoss:   map[string]float64{"potato": 0.2},
+	}
+
+	tests := []struct {
+		name      string
+		line      recipeQuantity
+		wantGross float64
+		wantStock float64 // В базовой единице склада
+		wantErr   bool
+	}{
+		{
+			name:      "gross is taken as is",
+			line:      recipeQuantity{IngredientID: "potato", Quantity: 250, Unit: units.Gram, QuantityType: models.RecipeQuantityGross},
+			wantGross: 250, wantStock: 0.25,
+		},
+		{
+			name:      "net with trim loss",
+			line:      recipeQuantity{IngredientID: "potato", Quantity: 200, Unit: units.Gram, QuantityType: models.RecipeQuantityNet},
+			wantGross: 250, wantStock: 0.25,
+		},
+		{
+			name:      "net with trim and cooking loss",
+			line:      recipeQuantity{IngredientID: "potato", Quantity: 100, Unit: units.Gram, QuantityType: models.RecipeQuantityNet, CookingLoss: floatPtr(50)},
+			wantGross: 250, wantStock: 0.25,
+		},
+		{
+			name:      "gross ignores cooking loss",
+			line:      recipeQuantity{IngredientID: "potato", Quantity: 100, Unit: units.Gram, QuantityType: models.RecipeQuantityGross, CookingLoss: floatPtr(50)},
+			wantGross: 100, wantStock: 0.1,
+		},
+		{
+			name:      "ingredient without trim loss",
+			line:      recipeQuantity{IngredientID: "egg", Quantity: 2, Unit: units.Piece, QuantityType: models.RecipeQuantityNet},
+			wantGross: 2, wantStock: 2,
+		},
+		{
+			name:      "grams of a piece ingredient need a conversion",
+			line:      recipeQuantity{IngredientID: "egg", Quantity: 50, Unit: units.Gram, QuantityType: models.RecipeQuantityGross},
+			wantGross: 50, wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ingUnits.grossQuantity(tt.line); math.Abs(got-tt.wantGross) > 1e-9 {
+				t.Errorf("grossQuantity() = %v, want %v", got, tt.wantGross)
+			}
+			got, err := ingUnits.toStockGross(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("toStockGross() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && math.Abs(got-tt.wantStock) > 1e-9 {
+				t.Errorf("toStockGross() = %v, want %v", got, tt.wantStock)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Параметры расчёта рекомендаций
+const (
+	recommendationHalfLifeDays = 30  // Вес заказа уменьшается вдвое каждые 30 дней
+	recommendationWindowDays   = 180 // Старые заказы не учитываются
+	recommendationMinPairs     = 2   // Минимум совместных заказов для связи
+	recommendationTopN         = 10  // Сколько рекомендаций хранить на продукт
+)
+
+// recommendationQuery считает связи продуктов по совместным заказам.
+// Вес заказа затухает экспоненциально с давностью, связь нормируется
+// на популярность обоих продуктов (косинусная мера), чтобы хиты не рекомендовались ко всему.
+const recommendationQuery = `
+WITH weighted AS (
+	SELECT DISTINCT
+		oi.order_id,
+		oi.product_id,
+		EXP(-LN(2) * EXTRACT(EPOCH FROM (@now - o.created_at)) / 86400.0 / @half_life) AS weight
+	FROM "OrderItem" oi
+	JOIN "Order" o ON o.id = oi.order_id
+	WHERE o.status <> 'cancelled'
+		AND o.created_at >= @since
+),
+totals AS (
+	SELECT product_id, SUM(weight) AS total
+	FROM weighted
+	GROUP BY product_id
+),
+pairs AS (
+	SELECT a.product_id, b.product_id AS recommended_id, SUM(a.weight) AS co, COUNT(*) AS pair_count
+	FROM weighted a
+	JOIN weighted b ON a.order_id = b.order_id AND a.product_id <> b.product_id
+	GROUP BY a.product_id, b.product_id
+),
+ranked AS (
+	SELECT
+		p.product_id,
+		p.recommended_id,
+		p.pair_count,
+		p.co / SQRT(ta.total * tb.total) AS score
+	FROM pairs p
+	JOIN totals ta ON ta.product_id = p.product_id
+	JOIN totals tb ON tb.product_id = p.recommended_id
+	WHERE p.pair_count >= @min_pairs
+),
+numbered AS (
+	SELECT *, ROW_NUMBER() OVER (PARTITION BY product_id ORDER BY score DESC) AS rn
+	FROM ranked
+)
+SELECT product_id, recommended_id, score, pair_count
+FROM numbered
+WHERE rn <= @top`
+
+// RecommendationService - сервис рекомендаций "хорошо сочетается с" по совместным заказам
+type RecommendationService struct{}
+
+// NewRecommendationService создает новый экземпляр RecommendationService
+func NewRecommendationService() *RecommendationService {
+	return &RecommendationService{}
+}
+
+// Refresh пересчитывает таблицу рекомендаций
+func (s *RecommendationService) Refresh(now time.Time) (int, error) {
+	db := database.GetDB()
+
+	var rows []models.ProductRecommendation
+	if err := db.Raw(recommendationQuery, map[string]interface{}{
+		"now":       now,
+		"since":     now.AddDate(0, 0, -recommendationWindowDays),
+		"half_life": float64(recommendationHalfLifeDays),
+		"min_pairs": recommendationMinPairs,
+		"top":       recommendationTopN,
+	}).Scan(&rows).Error; err != nil {
+		return 0, fmt.Errorf("failed to calculate recommendations: %w", err)
+	}
+
+	for i := range rows {
+		rows[i].ID = uuid.New().String()
+		rows[i].UpdatedAt = now
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.ProductRecommendation{}).Error; err != nil {
+			return fmt.Errorf("failed to clear recommendations: %w", err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(&rows, 500).Error; err != nil {
+			return fmt.Errorf("failed to save recommendations: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(rows), nil
+}
+
+// StartRefresher запускает фоновый пересчёт рекомендаций с заданным интервалом
+func (s *RecommendationService) StartRefresher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if count, err := s.Refresh(time.Now()); err != nil {
+				log.Printf("[RECOMMEND] ❌ Refresh error: %v", err)
+			} else {
+				log.Printf("[RECOMMEND] 🔁 Recommendations refreshed: %d pairs", count)
+			}
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("[RECOMMEND] ⏰ Recommendation refresher started (interval: %s)", interval)
+}
+
+// ForProduct возвращает видимые продукты, которые чаще всего заказывают вместе с данным
+func (s *RecommendationService) ForProduct(productID string, limit int) ([]models.Product, error) {
+	return s.ForProducts([]string{productID}, limit)
+}
+
+// ForProducts возвращает дополнения к набору продуктов (например, к корзине):
+// оценки связей суммируются, сами продукты набора исключаются
+func (s *RecommendationService) ForProducts(productIDs []string, limit int) ([]models.Product, error) {
+	if len(productIDs) == 0 {
+		return []models.Product{}, nil
+	}
+	db := database.GetDB()
+
+	var recs []models.ProductRecommendation
+	if err := db.Where("product_id IN ? AND recommended_id NOT IN ?", productIDs, productIDs).
+		Find(&recs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch recommendations: %w", err)
+	}
+
+	scores := map[string]float64{}
+	ids := []string{}
+	for _, rec := range recs {
+		if _, ok := scores[rec.RecommendedID]; !ok {
+			ids = append(ids, rec.RecommendedID)
+		}
+		scores[rec.RecommendedID] += rec.Score
+	}
+	if len(ids) == 0 {
+		return []models.Product{}, nil
+	}
+
+	var products []models.Product
+	if err := db.Where(`id IN ? AND "isVisible" = ?`, ids, true).Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+
+	sort.SliceStable(products, func(i, j int) bool {
+		return scores[products[i].ID] > scores[products[j].ID]
+	})
+	if len(products) > limit {
+		products = products[:limit]
+	}
+	return products, nil
+}
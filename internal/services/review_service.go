@@ -0,0 +1,238 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxReviewLength максимальная длина текста отзыва
+const maxReviewLength = 2000
+
+// ErrReviewNotAllowed пользователь не получал доставленный заказ с этим продуктом
+var ErrReviewNotAllowed = errors.New("you can only review products from your delivered orders")
+
+// ReviewService - сервис отзывов и оценок продуктов
+type ReviewService struct{}
+
+// NewReviewService создает новый экземпляр ReviewService
+func NewReviewService() *ReviewService {
+	return &ReviewService{}
+}
+
+// Create создает отзыв или обновляет существующий отзыв пользователя (с повторной модерацией)
+func (s *ReviewService) Create(userID, productID string, req models.CreateReviewRequest) (*models.ProductReview, error) {
+	db := database.GetDB()
+
+	if req.Rating < 1 || req.Rating > 5 {
+		return nil, fmt.Errorf("rating must be between 1 and 5")
+	}
+	text := strings.TrimSpace(req.Text)
+	if len([]rune(text)) > maxReviewLength {
+		return nil, fmt.Errorf("review text too long (max %d characters)", maxReviewLength)
+	}
+
+	orderID, err := s.deliveredOrderWith(userID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	var review models.ProductReview
+	err = db.Where("product_id = ? AND user_id = ?", productID, userID).First(&review).Error
+	switch {
+	case err == nil:
+		review.Rating = req.Rating
+		review.Text = text
+		review.OrderID = orderID
+		review.Status = models.ReviewPending
+		review.ModerationNote = nil
+		review.ModeratedBy = nil
+		review.ModeratedAt = nil
+		if err := db.Save(&review).Error; err != nil {
+			return nil, fmt.Errorf("failed to update review: %w", err)
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		review = models.ProductReview{
+			ID:        uuid.New().String(),
+			ProductID: productID,
+			UserID:    userID,
+			OrderID:   orderID,
+			Rating:    req.Rating,
+			Text:      text,
+			Status:    models.ReviewPending,
+		}
+		if err := db.Create(&review).Error; err != nil {
+			return nil, fmt.Errorf("failed to create review: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("failed to fetch review: %w", err)
+	}
+
+	log.Printf("[REVIEW] ⭐ User %s rated product %s: %d", userID, productID, review.Rating)
+	return &review, nil
+}
+
+// GetApproved возвращает одобренные отзывы о продукте
+func (s *ReviewService) GetApproved(productID string, limit int) ([]models.ProductReview, error) {
+	var reviews []models.ProductReview
+	if err := database.GetDB().
+		Where("product_id = ? AND status = ?", productID, models.ReviewApproved).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&reviews).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch reviews: %w", err)
+	}
+	if err := fillReviewAuthors(reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// GetForModeration возвращает отзывы для админки с фильтром по статусу
+func (s *ReviewService) GetForModeration(status string, limit int) ([]models.ProductReview, error) {
+	query := database.GetDB().Order("created_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var reviews []models.ProductReview
+	if err := query.Find(&reviews).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch reviews: %w", err)
+	}
+	if err := fillReviewAuthors(reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// Moderate одобряет или отклоняет отзыв
+func (s *ReviewService) Moderate(id string, req models.ModerateReviewRequest, moderatorID *string) (*models.ProductReview, error) {
+	if req.Status != models.ReviewApproved && req.Status != models.ReviewRejected {
+		return nil, fmt.Errorf("status must be 'approved' or 'rejected'")
+	}
+
+	db := database.GetDB()
+	var review models.ProductReview
+	if err := db.First(&review, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("review not found: %w", err)
+	}
+
+	now := time.Now()
+	review.Status = req.Status
+	review.ModeratedBy = moderatorID
+	review.ModeratedAt = &now
+	review.ModerationNote = nil
+	if note := strings.TrimSpace(req.Note); note != "" {
+		review.ModerationNote = &note
+	}
+
+	if err := db.Save(&review).Error; err != nil {
+		return nil, fmt.Errorf("failed to moderate review: %w", err)
+	}
+
+	log.Printf("[REVIEW] 🛡️ Review %s %s", review.ID, review.Status)
+	return &review, nil
+}
+
+// Delete удаляет отзыв
+func (s *ReviewService) Delete(id string) error {
+	result := database.GetDB().Delete(&models.ProductReview{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete review: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("review not found")
+	}
+	return nil
+}
+
+// Apply заполняет среднюю оценку и количество одобренных отзывов у списка продуктов
+func (s *ReviewService) Apply(products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	var rows []struct {
+		ProductID string
+		Average   float64
+		Count     int64
+	}
+	if err := database.GetDB().Model(&models.ProductReview{}).
+		Select("product_id, AVG(rating) AS average, COUNT(*) AS count").
+		Where("product_id IN ? AND status = ?", ids, models.ReviewApproved).
+		Group("product_id").
+		Scan(&rows).Error; err != nil {
+		return fmt.Errorf("failed to aggregate ratings: %w", err)
+	}
+
+	ratings := make(map[string]*models.ProductRating, len(rows))
+	for _, row := range rows {
+		ratings[row.ProductID] = &models.ProductRating{
+			Average: math.Round(row.Average*10) / 10,
+			Count:   row.Count,
+		}
+	}
+	for i := range products {
+		products[i].Rating = ratings[products[i].ID]
+	}
+	return nil
+}
+
+// deliveredOrderWith находит последний доставленный заказ пользователя с продуктом
+// (в том числе в составе сета) и возвращает его ID
+func (s *ReviewService) deliveredOrderWith(userID, productID string) (string, error) {
+	var orderIDs []string
+	if err := database.GetDB().Raw(`
+		SELECT o.id
+		FROM "Order" o
+		JOIN "OrderItem" oi ON oi.order_id = o.id
+		LEFT JOIN "OrderItemComponent" c ON c.order_item_id = oi.id
+		WHERE o.user_id = ?
+			AND o.status = 'delivered'
+			AND (oi.product_id = ? OR c.product_id = ?)
+		ORDER BY o.created_at DESC
+		LIMIT 1
+	`, userID, productID, productID).Scan(&orderIDs).Error; err != nil {
+		return "", fmt.Errorf("failed to check orders: %w", err)
+	}
+	if len(orderIDs) == 0 {
+		return "", ErrReviewNotAllowed
+	}
+	return orderIDs[0], nil
+}
+
+// fillReviewAuthors подставляет имена авторов отзывов
+func fillReviewAuthors(reviews []models.ProductReview) error {
+	if len(reviews) == 0 {
+		return nil
+	}
+	userIDs := []string{}
+	for _, r := range reviews {
+		userIDs = appendUnique(userIDs, r.UserID)
+	}
+
+	var users []models.User
+	if err := database.GetDB().Select("id, name").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return fmt.Errorf("failed to fetch review authors: %w", err)
+	}
+	names := make(map[string]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Name
+	}
+	for i := range reviews {
+		reviews[i].UserName = names[reviews[i].UserID]
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+)
+
+// searchSimilarityThreshold минимальная триграммная похожесть для нечёткого совпадения
+const searchSimilarityThreshold = 0.3
+
+// SearchService - сервис полнотекстового и нечёткого поиска по меню
+type SearchService struct{}
+
+// NewSearchService создает новый экземпляр SearchService
+func NewSearchService() *SearchService {
+	return &SearchService{}
+}
+
+// productSearchQuery ранжирует видимые продукты по полнотекстовому совпадению
+// (название > категория > состав > описание) и триграммной похожести.
+// Состав (ингредиенты, полуфабрикаты и их ингредиенты) передаётся параметром
+// @composition, поэтому индексы по Product здесь не применимы — запрос
+// просматривает всё видимое меню.
+const productSearchQuery = `
+WITH q AS (
+	SELECT plainto_tsquery('russian', @query) AS query, lower(@query) AS term
+),
+docs AS (
+	SELECT
+		p.id,
+		setweight(to_tsvector('russian', coalesce(p.name, '')), 'A') ||
+		setweight(to_tsvector('russian', coalesce(p.category, '')), 'B') ||
+		setweight(to_tsvector('russian', coalesce(ing.names, '')), 'C') ||
+		setweight(to_tsvector('russian', coalesce(p.description, '')), 'D') AS document,
+		lower(p.name) AS name,
+		lower(coalesce(p.name, '') || ' ' || coalesce(p.category, '') || ' ' || coalesce(ing.names, '')) AS text
+	FROM "Product" p
+	LEFT JOIN jsonb_to_recordset(CAST(@composition AS jsonb)) AS ing(id text, names text)
+		ON ing.id = p.id
+	WHERE p."isVisible" = true AND p."deletedAt" IS NULL
+)
+SELECT
+	d.id,
+	ts_rank(d.document, q.query) +
+		GREATEST(similarity(d.name, q.term), word_similarity(q.term, d.text)) AS relevance
+FROM docs d, q
+WHERE d.document @@ q.query
+	OR word_similarity(q.term, d.text) >= @threshold
+ORDER BY relevance DESC
+LIMIT @limit`
+
+// SearchProducts ищет видимые продукты по названию, описанию, категории и ингредиентам
+func (s *SearchService) SearchProducts(query string, limit int) ([]models.ProductSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return []models.ProductSearchResult{}, nil
+	}
+
+	db := database.GetDB()
+
+	composition, err := s.compositionDocuments()
+	if err != nil {
+		return nil, err
+	}
+
+	var ranked []struct {
+		ID        string
+		Relevance float64
+	}
+	if err := db.Raw(productSearchQuery, map[string]interface{}{
+		"query":       query,
+		"composition": composition,
+		"threshold":   searchSimilarityThreshold,
+		"limit":       limit,
+	}).Scan(&ranked).Error; err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+	if len(ranked) == 0 {
+		return []models.ProductSearchResult{}, nil
+	}
+
+	ids := make([]string, 0, len(ranked))
+	for _, r := range ranked {
+		ids = append(ids, r.ID)
+	}
+
+	var products []models.Product
+	if err := db.Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	byID := make(map[string]models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	// Сохраняем порядок по релевантности
+	results := make([]models.ProductSearchResult, 0, len(ranked))
+	for _, r := range ranked {
+		product, ok := byID[r.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, models.ProductSearchResult{
+			Product:   product,
+			Relevance: math.Round(r.Relevance*1000) / 1000,
+		})
+	}
+
+	return results, nil
+}
+
+// compositionDocuments собирает состав видимых продуктов для поиска в виде JSON
+// [{"id": ..., "names": ...}] — с полуфабрикатами и их вложенными рецептурами
+func (s *SearchService) compositionDocuments() (string, error) {
+	db := database.GetDB()
+	visible := db.Model(&models.Product{}).Select("id").Where(`"isVisible" = true AND "deletedAt" IS NULL`)
+
+	var ingredients []models.ProductIngredient
+	if err := db.Where("product_id IN (?)", visible).Find(&ingredients).Error; err != nil {
+		return "", fmt.Errorf("failed to fetch product ingredients: %w", err)
+	}
+	var semiFinished []models.ProductSemiFinished
+	if err := db.Where("product_id IN (?)", visible).Find(&semiFinished).Error; err != nil {
+		return "", fmt.Errorf("failed to fetch product semi-finished: %w", err)
+	}
+
+	sfIDs := []string{}
+	for _, line := range semiFinished {
+		sfIDs = appendUnique(sfIDs, line.SemiFinishedID)
+	}
+	closure, err := loadSemiFinishedClosure(db, sfIDs)
+	if err != nil {
+		return "", err
+	}
+
+	type document struct {
+		ID    string `json:"id"`
+		Names string `json:"names"`
+	}
+	names := productCompositionNames(ingredients, semiFinished, closure)
+	docs := make([]document, 0, len(names))
+	for productID, list := range names {
+		docs = append(docs, document{ID: productID, Names: strings.Join(list, " ")})
+	}
+	data, err := json.Marshal(docs)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode composition: %w", err)
+	}
+	return string(data), nil
+}
+
+// productCompositionNames возвращает для каждого продукта названия его ингредиентов,
+// полуфабрикатов и всего, из чего они приготовлены (включая вложенные полуфабрикаты)
+func productCompositionNames(ingredients []models.ProductIngredient, semiFinished []models.ProductSemiFinished, closure map[string]models.SemiFinished) map[string][]string {
+	result := map[string][]string{}
+	add := func(productID, name string) {
+		if name = strings.TrimSpace(name); name != "" {
+			result[productID] = appendUnique(result[productID], name)
+		}
+	}
+
+	var addSemiFinished func(productID, sfID string, visited map[string]bool)
+	addSemiFinished = func(productID, sfID string, visited map[string]bool) {
+		sf, ok := closure[sfID]
+		if !ok || visited[sfID] {
+			return
+		}
+		visited[sfID] = true
+		add(productID, sf.Name)
+		for _, ing := range sf.Ingredients {
+			add(productID, ing.IngredientName)
+		}
+		for _, c := range sf.Components {
+			add(productID, c.ComponentName)
+			addSemiFinished(productID, c.ComponentID, visited)
+		}
+	}
+
+	for _, line := range ingredients {
+		add(line.ProductID, line.IngredientName)
+	}
+	for _, line := range semiFinished {
+		add(line.ProductID, line.SemiFinishedName)
+		addSemiFinished(line.ProductID, line.SemiFinishedID, map[string]bool{})
+	}
+	return result
+}
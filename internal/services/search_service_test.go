@@ -0,0 +1,59 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+)
+
+func TestProductCompositionNames(t *testing.T) {
+	closure := map[string]models.SemiFinished{
+		"sushi-rice": {
+			ID:   "sushi-rice",
+			Name: "Рис для суши",
+			Ingredients: []models.SemiFinishedIngredient{
+				{IngredientName: "Рис"},
+				{IngredientName: "Рисовый уксус"},
+			},
+			Components: []models.SemiFinishedComponent{{ComponentID: "dressing", ComponentName: "Заправка"}},
+		},
+		"dressing": {
+			ID:          "dressing",
+			Name:        "Заправка",
+			Ingredients: []models.SemiFinishedIngredient{{IngredientName: "Сахар"}, {IngredientName: "Рисовый уксус"}},
+		},
+	}
+	ingredients := []models.ProductIngredient{
+		{ProductID: "roll", IngredientName: "Лосось"},
+		{ProductID: "roll", IngredientName: "Нори"},
+		{ProductID: "soup", IngredientName: "Мисо"},
+	}
+	semiFinished := []models.ProductSemiFinished{
+		{ProductID: "roll", SemiFinishedID: "sushi-rice", SemiFinishedName: "Рис для суши"},
+		{ProductID: "bowl", SemiFinishedID: "sushi-rice", SemiFinishedName: "Рис для суши"},
+		{ProductID: "bowl", SemiFinishedID: "missing", SemiFinishedName: "Соус"},
+	}
+
+	want := map[string][]string{
+		"roll": {"Лосось", "Нори", "Рис для суши", "Рис", "Рисовый уксус", "Заправка", "Сахар"},
+		"soup": {"Мисо"},
+		"bowl": {"Рис для суши", "Рис", "Рисовый уксус", "Заправка", "Сахар", "Соус"},
+	}
+	if got := productCompositionNames(ingredients, semiFinished, closure); !reflect.DeepEqual(got, want) {
+		t.Errorf("productCompositionNames() = %v, want %v", got, want)
+	}
+}
+
+func TestProductCompositionNamesCycle(t *testing.T) {
+	closure := map[string]models.SemiFinished{
+		"a": {ID: "a", Name: "A", Components: []models.SemiFinishedComponent{{ComponentID: "b", ComponentName: "B"}}},
+		"b": {ID: "b", Name: "B", Components: []models.SemiFinishedComponent{{ComponentID: "a", ComponentName: "A"}}},
+	}
+	semiFinished := []models.ProductSemiFinished{{ProductID: "p", SemiFinishedID: "a", SemiFinishedName: "A"}}
+
+	want := map[string][]string{"p": {"A", "B"}}
+	if got := productCompositionNames(nil, semiFinished, closure); !reflect.DeepEqual(got, want) {
+		t.Errorf("productCompositionNames() = %v, want %v", got, want)
+	}
+}
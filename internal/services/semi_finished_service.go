@@ -0,0 +1,312 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+	"gorm.io/gorm"
+)
+
+// maxRecipeDepth максимальная глубина вложенности полуфабрикатов
+const maxRecipeDepth = 10
+
+// ErrRecipeCycle полуфабрикат прямо или через другие полуфабрикаты входит сам в себя
+var ErrRecipeCycle = errors.New("semi-finished recipe cycle")
+
+// SemiFinishedService - сервис вложенных рецептур полуфабрикатов
+type SemiFinishedService struct{}
+
+// NewSemiFinishedService создает новый экземпляр SemiFinishedService
+func NewSemiFinishedService() *SemiFinishedService {
+	return &SemiFinishedService{}
+}
+
+// PriceComponents проверяет вложенные полуфабрикаты (существование, единицы, отсутствие циклов)
+// и рассчитывает их стоимость по текущей себестоимости. parentID пуст для нового полуфабриката.
+func (s *SemiFinishedService) PriceComponents(parentID string, lines []models.SemiFinishedComponentInput) error {
+	db := database.GetDB()
+
+	componentIDs := make([]string, 0, len(lines))
+	for i := range lines {
+		line := &lines[i]
+		if line.ComponentID == "" {
+			return fmt.Errorf("component ID is required for all components")
+		}
+		if line.ComponentID == parentID {
+			return fmt.Errorf("%w: semi-finished cannot contain itself", ErrRecipeCycle)
+		}
+		if line.Quantity <= 0 {
+			return fmt.Errorf("component quantity must be positive")
+		}
+
+		var child models.SemiFinished
+		if err := db.First(&child, "id = ?", line.ComponentID).Error; err != nil {
+			return fmt.Errorf("semi-finished component %s not found: %w", line.ComponentID, err)
+		}
+		line.ComponentName = child.Name
+		line.Unit = units.Normalize(line.Unit)
+		total, err := semiFinishedLineCost(&child, line.Quantity, line.Unit)
+		if err != nil {
+			return fmt.Errorf("semi-finished component %s: %w", child.Name, err)
+		}
+		line.CostPerUnit = child.CostPerUnit
+		line.TotalCost = total
+		componentIDs = appendUnique(componentIDs, line.ComponentID)
+	}
+
+	if parentID == "" || len(componentIDs) == 0 {
+		return nil
+	}
+	return checkRecipeCycle(db, parentID, componentIDs)
+}
+
+// UsedIn возвращает полуфабрикаты, в рецептуру которых входит указанный
+func (s *SemiFinishedService) UsedIn(id string) ([]models.SemiFinished, error) {
+	db := database.GetDB()
+
+	var parentIDs []string
+	if err := db.Model(&models.SemiFinishedComponent{}).
+		Where("component_id = ?", id).
+		Distinct().Pluck("semi_finished_id", &parentIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch semi-finished components: %w", err)
+	}
+	if len(parentIDs) == 0 {
+		return []models.SemiFinished{}, nil
+	}
+
+	var parents []models.SemiFinished
+	if err := db.Where("id IN ?", parentIDs).Order("name ASC").Find(&parents).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch semi-finished: %w", err)
+	}
+	return parents, nil
+}
+
+// Tree возвращает полностью раскрытую рецептуру полуфабриката до сырья.
+// Стоимость считается рекурсивно по строкам рецептур, а не по сохранённой себестоимости.
+func (s *SemiFinishedService) Tree(id string) (*models.RecipeTreeNode, error) {
+	db := database.GetDB()
+
+	closure, err := loadSemiFinishedClosure(db, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	root, ok := closure[id]
+	if !ok {
+		return nil, fmt.Errorf("semi-finished not found: %w", gorm.ErrRecordNotFound)
+	}
+
+	ingredientIDs := []string{}
+	for _, sf := range closure {
+		for _, line := range sf.Ingredients {
+			ingredientIDs = appendUnique(ingredientIDs, line.IngredientID)
+		}
+	}
+	ingUnits, err := loadIngredientUnits(db, ingredientIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	node := buildRecipeTree(closure, ingUnits, &root, 1, map[string]bool{})
+	return &node, nil
+}
+
+// buildRecipeTree раскрывает полуфабрикат; scale — доля его выхода, нужная корневому полуфабрикату
+func buildRecipeTree(closure map[string]models.SemiFinished, ingUnits *ingredientUnits, sf *models.SemiFinished, scale float64, path map[string]bool) models.RecipeTreeNode {
+	node := models.RecipeTreeNode{
+		Type:     models.RecipeNodeSemiFinished,
+		ID:       sf.ID,
+		Name:     sf.Name,
+		Quantity: roundQuantity(sf.OutputQuantity * scale),
+		Unit:     sf.OutputUnit,
+		Children: []models.RecipeTreeNode{},
+	}
+	path[sf.ID] = true
+	defer delete(path, sf.ID)
+
+	cost := 0.0
+	for _, line := range sf.Ingredients {
+		lineCost := ingUnits.stockGross(semiFinishedLineQuantity(line)) * line.PricePerUnit * scale
+		node.Children = append(node.Children, models.RecipeTreeNode{
+			Type:         models.RecipeNodeIngredient,
+			ID:           line.IngredientID,
+			Name:         line.IngredientName,
+			Quantity:     roundQuantity(line.Quantity * scale),
+			Unit:         line.Unit,
+			QuantityType: line.QuantityType,
+			Cost:         roundCost(lineCost),
+		})
+		cost += lineCost
+	}
+
+	for _, line := range sf.Components {
+		child, ok := closure[line.ComponentID]
+		if !ok || child.OutputQuantity <= 0 || path[child.ID] {
+			log.Printf("[RECIPE] ⚠️ Skipping component %s of %s", line.ComponentName, sf.Name)
+			continue
+		}
+		qty, err := units.Convert(line.Quantity, line.Unit, child.OutputUnit)
+		if err != nil {
+			log.Printf("[UNITS] ⚠️ Semi-finished %s, component %s: %v", sf.Name, child.Name, err)
+			qty = units.ToBase(line.Quantity, line.Unit) / units.ToBase(1, child.OutputUnit)
+		}
+
+		childScale := qty / child.OutputQuantity * scale
+		childNode := buildRecipeTree(closure, ingUnits, &child, childScale, path)
+		childNode.Quantity = roundQuantity(line.Quantity * scale)
+		childNode.Unit = line.Unit
+		node.Children = append(node.Children, childNode)
+		cost += childNode.Cost
+	}
+
+	node.Cost = roundCost(cost)
+	if output := sf.OutputQuantity * scale; output > 0 {
+		node.CostPerUnit = roundCost(cost / output)
+	}
+	return node
+}
+
+// checkRecipeCycle проверяет, что новые вложенные полуфабрикаты не приводят к циклу
+// и не превышают допустимую глубину вложенности
+func checkRecipeCycle(db *gorm.DB, parentID string, componentIDs []string) error {
+	var edges []models.SemiFinishedComponent
+	if err := db.Select("semi_finished_id", "component_id").Find(&edges).Error; err != nil {
+		return fmt.Errorf("failed to fetch semi-finished components: %w", err)
+	}
+	graph := map[string][]string{}
+	for _, e := range edges {
+		if e.SemiFinishedID != parentID {
+			graph[e.SemiFinishedID] = append(graph[e.SemiFinishedID], e.ComponentID)
+		}
+	}
+	graph[parentID] = componentIDs
+
+	cycle, err := findRecipeCycle(graph, parentID)
+	if err != nil {
+		return err
+	}
+	if cycle != nil {
+		return fmt.Errorf("%w: %s", ErrRecipeCycle, describeRecipePath(db, cycle))
+	}
+	return nil
+}
+
+// findRecipeCycle ищет в графе вложенности путь, возвращающийся в parentID.
+// Возвращает цепочку полуфабрикатов цикла или nil; ошибку — при превышении глубины.
+func findRecipeCycle(graph map[string][]string, parentID string) ([]string, error) {
+	// Поиск в глубину от редактируемого полуфабриката: возврат в него — цикл
+	var path, cycle []string
+	var visit func(id string) error
+	visit = func(id string) error {
+		path = append(path, id)
+		defer func() { path = path[:len(path)-1] }()
+		if len(path) > maxRecipeDepth+1 {
+			return fmt.Errorf("semi-finished recipes are nested deeper than %d levels", maxRecipeDepth)
+		}
+		for _, childID := range graph[id] {
+			if childID == parentID {
+				cycle = append(append([]string{}, path...), childID)
+				return ErrRecipeCycle
+			}
+			if err := visit(childID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(parentID); err != nil && !errors.Is(err, ErrRecipeCycle) {
+		return nil, err
+	}
+	return cycle, nil
+}
+
+// describeRecipePath формирует цепочку названий полуфабрикатов вида «Соус → Майонез → Соус»
+func describeRecipePath(db *gorm.DB, ids []string) string {
+	var list []models.SemiFinished
+	db.Select("id", "name").Where("id IN ?", ids).Find(&list)
+	names := make(map[string]string, len(list))
+	for _, sf := range list {
+		names[sf.ID] = sf.Name
+	}
+
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := names[id]; ok {
+			parts = append(parts, name)
+		} else {
+			parts = append(parts, id)
+		}
+	}
+	return strings.Join(parts, " → ")
+}
+
+// semiFinishedAncestors возвращает полуфабрикаты вместе со всеми, в которые они вложены,
+// в порядке пересчёта: вложенные раньше содержащих
+func semiFinishedAncestors(db *gorm.DB, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	set := map[string]bool{}
+	children := map[string][]string{} // Полуфабрикат → вложенные из набора
+	frontier := append([]string{}, ids...)
+	for _, id := range ids {
+		set[id] = true
+	}
+	for depth := 0; len(frontier) > 0 && depth <= maxRecipeDepth; depth++ {
+		var edges []models.SemiFinishedComponent
+		if err := db.Select("semi_finished_id", "component_id").
+			Where("component_id IN ?", frontier).
+			Find(&edges).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch semi-finished components: %w", err)
+		}
+		frontier = frontier[:0]
+		for _, e := range edges {
+			children[e.SemiFinishedID] = appendUnique(children[e.SemiFinishedID], e.ComponentID)
+			if !set[e.SemiFinishedID] {
+				set[e.SemiFinishedID] = true
+				frontier = append(frontier, e.SemiFinishedID)
+			}
+		}
+	}
+
+	// Топологическая сортировка: полуфабрикат готов к пересчёту, когда пересчитаны все вложенные
+	pending := map[string]int{}
+	parents := map[string][]string{}
+	for id := range set {
+		for _, childID := range children[id] {
+			if set[childID] {
+				pending[id]++
+				parents[childID] = append(parents[childID], id)
+			}
+		}
+	}
+	ready := []string{}
+	for id := range set {
+		if pending[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	sort.Strings(ready)
+
+	order := make([]string, 0, len(set))
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+		for _, parentID := range parents[id] {
+			if pending[parentID]--; pending[parentID] == 0 {
+				ready = append(ready, parentID)
+			}
+		}
+	}
+	if len(order) < len(set) {
+		log.Printf("[RECIPE] ⚠️ Cycle in semi-finished recipes, %d item(s) not recalculated", len(set)-len(order))
+	}
+	return order, nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFindRecipeCycle(t *testing.T) {
+	// chain строит цепочку вложенности sauce → level1 → … → levelN
+	chain := func(levels int) map[string][]string {
+		graph := map[string][]string{}
+		prev := "sauce"
+		for i := 1; i <= levels; i++ {
+			id := fmt.Sprintf("level%d", i)
+			graph[prev] = []string{id}
+			prev = id
+		}
+		return graph
+	}
+
+	tests := []struct {
+		name      string
+		graph     map[string][]string
+		wantCycle string // Цепочка через «>», пусто — цикла нет
+		wantErr   bool
+	}{
+		{
+			name:  "no components",
+			graph: map[string][]string{},
+		},
+		{
+			name:  "shared component is not a cycle",
+			graph: map[string][]string{"sauce": {"mayo", "garlic"}, "mayo": {"egg"}, "garlic": {"egg"}},
+		},
+		{
+			name:      "direct self reference",
+			graph:     map[string][]string{"sauce": {"sauce"}},
+			wantCycle: "sauce>sauce",
+		},
+		{
+			name:      "cycle through nested semi-finished",
+			graph:     map[string][]string{"sauce": {"mayo"}, "mayo": {"base"}, "base": {"sauce"}},
+			wantCycle: "sauce>mayo>base>sauce",
+		},
+		{
+			name:  "cycle below the edited item is not reported here",
+			graph: map[string][]string{"sauce": {"mayo"}, "mayo": {"base"}, "base": {"mayo"}},
+			// Цикл mayo ↔ base упирается в ограничение глубины
+			wantErr: true,
+		},
+		{
+			name:  "maximum depth is allowed",
+			graph: chain(maxRecipeDepth),
+		},
+		{
+			name:    "too deep",
+			graph:   chain(maxRecipeDepth + 1),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cycle, err := findRecipeCycle(tt.graph, "sauce")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findRecipeCycle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := strings.Join(cycle, ">"); got != tt.wantCycle {
+				t.Errorf("findRecipeCycle() cycle = %q, want %q", got, tt.wantCycle)
+			}
+		})
+	}
+}
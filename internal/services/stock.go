@@ -0,0 +1,330 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// stockMovementInput параметры движения по складу
+type stockMovementInput struct {
+	Type         string   // models.MovementIn или models.MovementOut
+	Quantity     float64  // Всегда положительное, знак определяется типом
+	Price        *float64 // Цена за единицу (кг/л/шт)
+	Note         string
+	DocumentType string
+	DocumentID   string
+	UserID       *string // Кто выполнил движение
+}
+
+// lockStockItem находит складскую позицию по её ID или ID ингредиента и блокирует строку до конца транзакции
+func lockStockItem(tx *gorm.DB, id string) (*models.StockItem, error) {
+	var item models.StockItem
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Ingredient").
+		Where(`id = ? OR "ingredientId" = ?`, id, id).
+		First(&item).Error; err != nil {
+		return nil, fmt.Errorf("stock item %s not found: %w", id, err)
+	}
+	return &item, nil
+}
+
+// recordStockMovement изменяет остаток позиции и записывает движение (внутри транзакции)
+func recordStockMovement(tx *gorm.DB, item *models.StockItem, input stockMovementInput) (*models.StockMovement, error) {
+	if input.Quantity <= 0 {
+		return nil, fmt.Errorf("movement quantity must be positive")
+	}
+
+	delta := input.Quantity
+	if input.Type == models.MovementOut {
+		delta = -delta
+	}
+
+	now := time.Now()
+	if err := tx.Model(&models.StockItem{}).
+		Where("id = ?", item.ID).
+		Updates(map[string]interface{}{
+			"quantity":  gorm.Expr("quantity + ?", delta),
+			"updatedAt": now,
+		}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update stock: %w", err)
+	}
+	item.Quantity += delta
+	item.UpdatedAt = now
+
+	movement := &models.StockMovement{
+		ID:          uuid.New().String(),
+		StockItemID: item.ID,
+		Type:        input.Type,
+		Quantity:    input.Quantity,
+		PriceNetto:  input.Price,
+		CreatedAt:   now,
+		UserID:      input.UserID,
+	}
+	if input.Note != "" {
+		note := input.Note
+		movement.Note = &note
+	}
+	if input.DocumentType != "" {
+		docType, docID := input.DocumentType, input.DocumentID
+		movement.DocumentType = &docType
+		movement.DocumentID = &docID
+	}
+	if err := tx.Create(movement).Error; err != nil {
+		return nil, fmt.Errorf("failed to create stock movement: %w", err)
+	}
+
+	return movement, nil
+}
+
+// stockEpsilon погрешность сравнения количеств с плавающей точкой
+const stockEpsilon = 1e-9
+
+// consumptionPolicy правило списания партий (STOCK_CONSUMPTION_POLICY: fifo по умолчанию или fefo)
+func consumptionPolicy() string {
+	if strings.ToLower(strings.TrimSpace(os.Getenv("STOCK_CONSUMPTION_POLICY"))) == models.ConsumptionFEFO {
+		return models.ConsumptionFEFO
+	}
+	return models.ConsumptionFIFO
+}
+
+// GenerateBatchNumber генерирует номер партии в формате КРЕ-20251006-020417 (3 буквы-дата-время)
+func GenerateBatchNumber(ingredientName string, at time.Time) string {
+	// Берём первые 3 руны (символа) для поддержки UTF-8
+	runes := []rune(ingredientName)
+	if len(runes) > 3 {
+		runes = runes[:3]
+	}
+
+	// Преобразуем в заглавные и убираем пробелы
+	prefix := strings.ToUpper(strings.ReplaceAll(string(runes), " ", ""))
+
+	return fmt.Sprintf("%s-%s-%s", prefix, at.Format("20060102"), at.Format("150405"))
+}
+
+// stockBatchInput параметры новой партии
+type stockBatchInput struct {
+	Quantity     float64    // В единицах ингредиента
+	Price        float64    // Цена за единицу (кг/л/шт)
+	ReceivedAt   time.Time  // Пусто — текущее время
+	ExpiresAt    *time.Time // Пусто — дата поступления + срок годности ингредиента
+	DocumentType string
+	DocumentID   string
+}
+
+// addStockBatch заводит партию поступившего товара (внутри транзакции, вместе с движением "in")
+func addStockBatch(tx *gorm.DB, item *models.StockItem, input stockBatchInput) (*models.StockBatch, error) {
+	if input.Quantity <= 0 {
+		return nil, fmt.Errorf("batch quantity must be positive")
+	}
+	if input.ReceivedAt.IsZero() {
+		input.ReceivedAt = time.Now()
+	}
+
+	expiresAt := input.ExpiresAt
+	if expiresAt == nil && item.ExpiryDays != nil && *item.ExpiryDays > 0 {
+		at := input.ReceivedAt.AddDate(0, 0, *item.ExpiryDays)
+		expiresAt = &at
+	}
+
+	name := ""
+	if item.Ingredient != nil {
+		name = item.Ingredient.Name
+	}
+	batch := &models.StockBatch{
+		ID:                uuid.New().String(),
+		StockItemID:       item.ID,
+		IngredientID:      item.IngredientID,
+		BatchNumber:       GenerateBatchNumber(name, input.ReceivedAt),
+		ReceivedAt:        input.ReceivedAt,
+		ExpiresAt:         expiresAt,
+		InitialQuantity:   input.Quantity,
+		RemainingQuantity: input.Quantity,
+		PricePerUnit:      input.Price,
+	}
+	if input.DocumentType != "" {
+		docType, docID := input.DocumentType, input.DocumentID
+		batch.DocumentType = &docType
+		batch.DocumentID = &docID
+	}
+	if err := tx.Create(batch).Error; err != nil {
+		return nil, fmt.Errorf("failed to create stock batch: %w", err)
+	}
+
+	// В карточке ингредиента отображается номер последней партии
+	if err := tx.Model(&models.StockItem{}).Where("id = ?", item.ID).
+		Update("batchNumber", batch.BatchNumber).Error; err != nil {
+		return nil, fmt.Errorf("failed to update batch number: %w", err)
+	}
+	item.BatchNumber = &batch.BatchNumber
+
+	return batch, nil
+}
+
+// stockAllocation часть списания: из партии или из остатка без партий (Batch == nil)
+type stockAllocation struct {
+	Batch    *models.StockBatch
+	Quantity float64
+}
+
+// sortBatchesForConsumption упорядочивает партии по правилу списания: FIFO — по дате
+// поступления, FEFO — сначала с ближайшим сроком годности, партии без срока в конце
+func sortBatchesForConsumption(batches []models.StockBatch, policy string) {
+	sort.SliceStable(batches, func(i, j int) bool {
+		a, b := batches[i], batches[j]
+		if policy == models.ConsumptionFEFO {
+			switch {
+			case a.ExpiresAt != nil && b.ExpiresAt == nil:
+				return true
+			case a.ExpiresAt == nil && b.ExpiresAt != nil:
+				return false
+			case a.ExpiresAt != nil && !a.ExpiresAt.Equal(*b.ExpiresAt):
+				return a.ExpiresAt.Before(*b.ExpiresAt)
+			}
+		}
+		if !a.ReceivedAt.Equal(b.ReceivedAt) {
+			return a.ReceivedAt.Before(b.ReceivedAt)
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+}
+
+// allocateStock распределяет количество по упорядоченным партиям. Остаток без партий
+// считается самым старым и идёт первым. Возвращает части списания и нехватку.
+func allocateStock(batches []models.StockBatch, untracked, quantity float64) ([]stockAllocation, float64) {
+	allocations := []stockAllocation{}
+	left := quantity
+	if untracked > stockEpsilon {
+		qty := math.Min(left, untracked)
+		allocations = append(allocations, stockAllocation{Quantity: qty})
+		left -= qty
+	}
+	for i := range batches {
+		if left <= stockEpsilon {
+			break
+		}
+		qty := math.Min(left, batches[i].RemainingQuantity)
+		allocations = append(allocations, stockAllocation{Batch: &batches[i], Quantity: qty})
+		left -= qty
+	}
+	return allocations, math.Max(left, 0)
+}
+
+// consumeStock списывает количество (в единицах ингредиента) из партий по правилу FIFO/FEFO.
+// Остаток без партий (заведённый до учёта партий) считается самым старым и списывается первым,
+// нехватка списывается в минус по текущей цене. Пишет одно движение "out" по средневзвешенной
+// цене с разбивкой по партиям и возвращает это движение и себестоимость списания.
+// Если указан batchID, количество списывается только из этой партии и без ухода в минус.
+func consumeStock(tx *gorm.DB, item *models.StockItem, quantity float64, batchID string, input stockMovementInput) (*models.StockMovement, float64, error) {
+	if quantity <= 0 {
+		return nil, 0, fmt.Errorf("consumption quantity must be positive")
+	}
+
+	var batches []models.StockBatch
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("stock_item_id = ? AND remaining_quantity > 0", item.ID).
+		Order("id ASC").
+		Find(&batches).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch stock batches: %w", err)
+	}
+	sortBatchesForConsumption(batches, consumptionPolicy())
+
+	name, unit := item.ID, ""
+	if item.Ingredient != nil {
+		name, unit = item.Ingredient.Name, item.Ingredient.Unit
+	}
+	currentPrice := 0.0
+	if item.PricePerUnit != nil {
+		currentPrice = *item.PricePerUnit
+	}
+
+	tracked := 0.0
+	for _, batch := range batches {
+		tracked += batch.RemainingQuantity
+	}
+	untracked := math.Max(item.Quantity-tracked, 0)
+
+	if batchID != "" {
+		untracked = 0
+		selected := batches[:0]
+		for _, batch := range batches {
+			if batch.ID == batchID {
+				selected = append(selected, batch)
+			}
+		}
+		batches = selected
+		if len(batches) == 0 {
+			return nil, 0, fmt.Errorf("batch %s of %s not found or already empty", batchID, name)
+		}
+	}
+
+	consumptions := []models.StockBatchConsumption{}
+	take := func(batchID *string, qty, price float64) {
+		consumptions = append(consumptions, models.StockBatchConsumption{
+			ID:           uuid.New().String(),
+			StockItemID:  item.ID,
+			BatchID:      batchID,
+			Quantity:     qty,
+			PricePerUnit: price,
+			Cost:         units.ToBase(qty, unit) * price,
+		})
+	}
+
+	allocations, short := allocateStock(batches, untracked, quantity)
+	if short > stockEpsilon && batchID != "" {
+		return nil, 0, fmt.Errorf("batch %s of %s has only %.3f %s left",
+			batches[0].BatchNumber, name, batches[0].RemainingQuantity, unit)
+	}
+	for _, a := range allocations {
+		if a.Batch == nil {
+			take(nil, a.Quantity, currentPrice)
+			continue
+		}
+		if err := tx.Model(&models.StockBatch{}).Where("id = ?", a.Batch.ID).
+			Update("remaining_quantity", gorm.Expr("remaining_quantity - ?", a.Quantity)).Error; err != nil {
+			return nil, 0, fmt.Errorf("failed to update stock batch: %w", err)
+		}
+		take(&a.Batch.ID, a.Quantity, a.Batch.PricePerUnit)
+	}
+	if short > stockEpsilon {
+		log.Printf("[STOCK] ⚠️ Insufficient stock of %s: short by %.3f %s, going negative", name, short, unit)
+		take(nil, short, currentPrice)
+	}
+
+	cost := 0.0
+	for _, c := range consumptions {
+		cost += c.Cost
+	}
+	avgPrice := currentPrice
+	if base := units.ToBase(quantity, unit); base > 0 {
+		avgPrice = roundCost(cost / base)
+	}
+
+	input.Type = models.MovementOut
+	input.Quantity = quantity
+	input.Price = &avgPrice
+	movement, err := recordStockMovement(tx, item, input)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range consumptions {
+		consumptions[i].MovementID = movement.ID
+		consumptions[i].Cost = roundCost(consumptions[i].Cost)
+	}
+	if err := tx.Create(&consumptions).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to record batch consumption: %w", err)
+	}
+
+	return movement, roundCost(cost), nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+)
+
+// StockAlertService - сервис контроля минимальных остатков
+type StockAlertService struct {
+	mu       sync.Mutex
+	notified map[string]bool // Позиции, о которых уже уведомили (до восстановления остатка)
+}
+
+// NewStockAlertService создает новый экземпляр StockAlertService
+func NewStockAlertService() *StockAlertService {
+	return &StockAlertService{notified: map[string]bool{}}
+}
+
+// GetLowStock возвращает ингредиенты, остаток которых не выше точки заказа, с рекомендуемым объёмом заказа
+func (s *StockAlertService) GetLowStock() ([]models.LowStockItem, error) {
+	var items []models.StockItem
+	if err := database.GetDB().
+		Preload("Ingredient").
+		Where(`"minStock" IS NOT NULL AND "minStock" > 0 AND quantity <= "minStock"`).
+		Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch low stock: %w", err)
+	}
+
+	result := make([]models.LowStockItem, 0, len(items))
+	for _, item := range items {
+		low := models.LowStockItem{
+			StockItemID:     item.ID,
+			IngredientID:    item.IngredientID,
+			Quantity:        item.Quantity,
+			MinStock:        *item.MinStock,
+			ReorderQuantity: item.ReorderQuantity,
+			SuggestedOrder:  suggestedReorder(item),
+			Supplier:        item.Supplier,
+			PricePerUnit:    item.PricePerUnit,
+		}
+		if item.Ingredient != nil {
+			low.IngredientName = item.Ingredient.Name
+			low.Unit = item.Ingredient.Unit
+		}
+		if item.PricePerUnit != nil {
+			low.EstimatedCost = roundCost(units.ToBase(low.SuggestedOrder, low.Unit) * *item.PricePerUnit)
+		}
+		result = append(result, low)
+	}
+
+	// Сначала самые дефицитные позиции
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Quantity/result[i].MinStock < result[j].Quantity/result[j].MinStock
+	})
+	return result, nil
+}
+
+// Check находит позиции с низким остатком и возвращает те, о которых ещё не уведомляли.
+// Позиция снова попадает в уведомления после того, как её остаток поднялся выше точки заказа.
+func (s *StockAlertService) Check() ([]models.LowStockItem, error) {
+	items, err := s.GetLowStock()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	low := make(map[string]bool, len(items))
+	fresh := []models.LowStockItem{}
+	for _, item := range items {
+		low[item.StockItemID] = true
+		if !s.notified[item.StockItemID] {
+			fresh = append(fresh, item)
+		}
+	}
+	s.notified = low
+
+	return fresh, nil
+}
+
+// StartChecker запускает фоновую проверку остатков; notify получает позиции, впервые опустившиеся до минимума
+func (s *StockAlertService) StartChecker(interval time.Duration, notify func([]models.LowStockItem)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			fresh, err := s.Check()
+			if err != nil {
+				log.Printf("[STOCK] ❌ Low stock check error: %v", err)
+			} else if len(fresh) > 0 {
+				log.Printf("[STOCK] 📉 %d ingredient(s) reached reorder point", len(fresh))
+				notify(fresh)
+			}
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("[STOCK] ⏰ Low stock checker started (interval: %s)", interval)
+}
+
+// suggestedReorder рекомендуемый объём заказа: объём заказа позиции (не меньше нехватки до минимума)
+// или пополнение до двух минимумов, если объём заказа не задан
+func suggestedReorder(item models.StockItem) float64 {
+	minStock := *item.MinStock
+	shortage := minStock - item.Quantity
+	if item.ReorderQuantity != nil && *item.ReorderQuantity > 0 {
+		return roundQuantity(math.Max(*item.ReorderQuantity, shortage))
+	}
+	return roundQuantity(minStock + shortage)
+}
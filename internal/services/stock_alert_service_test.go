@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+)
+
+func TestSuggestedReorder(t *testing.T) {
+	ptr := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name     string
+		quantity float64
+		minStock float64
+		reorder  *float64
+		want     float64
+	}{
+		{name: "refill to twice the minimum", quantity: 2, minStock: 5, want: 8},
+		{name: "zero reorder quantity means twice the minimum", quantity: 2, minStock: 5, reorder: ptr(0), want: 8},
+		{name: "fixed reorder quantity", quantity: 4, minStock: 5, reorder: ptr(10), want: 10},
+		{name: "reorder quantity below the shortage", quantity: 1, minStock: 20, reorder: ptr(10), want: 19},
+		{name: "negative stock is covered", quantity: -3, minStock: 5, want: 13},
+		{name: "fractional quantities are rounded", quantity: 0.1234, minStock: 0.5, want: 0.877},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := models.StockItem{Quantity: tt.quantity, MinStock: &tt.minStock, ReorderQuantity: tt.reorder}
+			if got := suggestedReorder(item); got != tt.want {
+				t.Errorf("suggestedReorder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// stockJournalSQL движения с остатком позиции после каждого движения.
+// Остаток восстанавливается от текущего количества назад: из него вычитаются движения
+// позиции после периода (later) и более поздние движения внутри периода (окно).
+// Поэтому окно считается только по движениям выбранных позиций за выбранный период,
+// а не по всему журналу. Условия выборки движений подставляются вместо %s.
+const stockJournalSQL = `
+	SELECT
+		m.id,
+		m."stockItemId" AS stock_item_id,
+		si."ingredientId" AS ingredient_id,
+		COALESCE(i.name, '') AS ingredient_name,
+		COALESCE(i.unit, '') AS unit,
+		m.type,
+		m.quantity,
+		CASE WHEN m.type = 'out' THEN -m.quantity ELSE m.quantity END AS delta,
+		si.quantity - COALESCE(later.delta, 0) - COALESCE(SUM(CASE WHEN m.type = 'out' THEN -m.quantity ELSE m.quantity END) OVER (
+			PARTITION BY m."stockItemId"
+			ORDER BY m."createdAt" DESC, m.id DESC
+			ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+		), 0) AS balance_after,
+		m."priceBrutto" AS price_brutto,
+		m."priceNetto" AS price_netto,
+		m.note,
+		m."documentType" AS document_type,
+		m."documentId" AS document_id,
+		m."userId" AS user_id,
+		u.name AS user_name,
+		m."createdAt" AS created_at
+	FROM "StockMovement" m
+	JOIN "StockItem" si ON si.id = m."stockItemId"
+	LEFT JOIN "Ingredient" i ON i.id = si."ingredientId"
+	LEFT JOIN "User" u ON u.id = m."userId"
+	LEFT JOIN (
+		SELECT "stockItemId", SUM(CASE WHEN type = 'out' THEN -quantity ELSE quantity END) AS delta
+		FROM "StockMovement"
+		WHERE "createdAt" >= ?
+		GROUP BY "stockItemId"
+	) later ON later."stockItemId" = m."stockItemId"
+	WHERE %s`
+
+// stockJournalCSVHeader колонки CSV-выгрузки журнала
+var stockJournalCSVHeader = []string{
+	"date", "ingredient", "unit", "type", "quantity", "delta", "balance_after",
+	"price", "document_type", "document_id", "user", "note",
+}
+
+// StockJournalService - сервис журнала движений склада
+type StockJournalService struct{}
+
+// NewStockJournalService создает новый экземпляр StockJournalService
+func NewStockJournalService() *StockJournalService {
+	return &StockJournalService{}
+}
+
+// GetPage возвращает страницу журнала движений (новые первыми)
+func (s *StockJournalService) GetPage(filter models.StockJournalFilter, page, pageSize int) (*models.StockJournalPage, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	// Для количества строк остаток не нужен — считаем по движениям без оконной функции
+	var total int64
+	if err := s.countQuery(filter).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count stock movements: %w", err)
+	}
+
+	entries := []models.StockJournalEntry{}
+	if err := s.query(filter).
+		Order("created_at DESC, id DESC").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Scan(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch stock movements: %w", err)
+	}
+
+	return &models.StockJournalPage{
+		Entries:    entries,
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: int((total + int64(pageSize) - 1) / int64(pageSize)),
+	}, nil
+}
+
+// WriteCSV выгружает весь журнал по фильтрам в CSV
+func (s *StockJournalService) WriteCSV(w io.Writer, filter models.StockJournalFilter) error {
+	rows, err := s.query(filter).Order("created_at DESC, id DESC").Rows()
+	if err != nil {
+		return fmt.Errorf("failed to fetch stock movements: %w", err)
+	}
+	defer rows.Close()
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(stockJournalCSVHeader); err != nil {
+		return err
+	}
+
+	db := database.GetDB()
+	for rows.Next() {
+		var entry models.StockJournalEntry
+		if err := db.ScanRows(rows, &entry); err != nil {
+			return fmt.Errorf("failed to read stock movement: %w", err)
+		}
+		record := []string{
+			entry.CreatedAt.Format(time.RFC3339),
+			entry.IngredientName,
+			entry.Unit,
+			entry.Type,
+			formatQuantity(entry.Quantity),
+			formatQuantity(entry.Delta),
+			formatQuantity(entry.BalanceAfter),
+			formatOptionalFloat(entry.PriceNetto),
+			derefString(entry.DocumentType),
+			derefString(entry.DocumentID),
+			derefString(entry.UserName),
+			derefString(entry.Note),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// query собирает запрос журнала: остаток считается по движениям выбранных позиций
+// за период, остальные фильтры накладываются поверх (им нужен остаток по всем движениям)
+func (s *StockJournalService) query(filter models.StockJournalFilter) *gorm.DB {
+	db := database.GetDB()
+
+	where, args := journalScope(filter)
+	// Движения после периода нужны только для восстановления остатка;
+	// без конца периода их нет (сравнение с NULL не выбирает строк)
+	var after interface{}
+	if filter.To != nil {
+		after = *filter.To
+	}
+	source := db.Raw(fmt.Sprintf(stockJournalSQL, where), append([]interface{}{after}, args...)...)
+
+	query := db.Table("(?) AS journal", source)
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.DocumentType != "" {
+		query = query.Where("document_type = ?", filter.DocumentType)
+	}
+	if filter.DocumentID != "" {
+		query = query.Where("document_id = ?", filter.DocumentID)
+	}
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	return query
+}
+
+// countQuery считает движения по тем же фильтрам без расчёта остатка
+func (s *StockJournalService) countQuery(filter models.StockJournalFilter) *gorm.DB {
+	where, args := journalScope(filter)
+	query := database.GetDB().
+		Table(`"StockMovement" m`).
+		Joins(`JOIN "StockItem" si ON si.id = m."stockItemId"`).
+		Where(where, args...)
+	if filter.Type != "" {
+		query = query.Where("m.type = ?", filter.Type)
+	}
+	if filter.DocumentType != "" {
+		query = query.Where(`m."documentType" = ?`, filter.DocumentType)
+	}
+	if filter.DocumentID != "" {
+		query = query.Where(`m."documentId" = ?`, filter.DocumentID)
+	}
+	if filter.UserID != "" {
+		query = query.Where(`m."userId" = ?`, filter.UserID)
+	}
+	return query
+}
+
+// journalScope условия по позиции и периоду — они сужают окно расчёта остатка
+func journalScope(filter models.StockJournalFilter) (string, []interface{}) {
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+	if filter.IngredientID != "" {
+		conditions = append(conditions, `(si.id = ? OR si."ingredientId" = ?)`)
+		args = append(args, filter.IngredientID, filter.IngredientID)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, `m."createdAt" >= ?`)
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, `m."createdAt" < ?`)
+		args = append(args, *filter.To)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// formatQuantity форматирует количество для CSV
+func formatQuantity(value float64) string {
+	return strconv.FormatFloat(roundQuantity(value), 'f', -1, 64)
+}
+
+// formatOptionalFloat форматирует необязательную цену для CSV
+func formatOptionalFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', 2, 64)
+}
+
+// derefString возвращает значение строки или пустую строку
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
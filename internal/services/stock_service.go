@@ -0,0 +1,375 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StockService - сервис партий склада и списания ингредиентов по заказам
+type StockService struct{}
+
+// NewStockService создает новый экземпляр StockService
+func NewStockService() *StockService {
+	return &StockService{}
+}
+
+// StockNeeds расход на позицию заказа: сырьё рецептуры и полуфабрикаты
+type StockNeeds struct {
+	Ingredients  map[string]float64        // Брутто в базовых единицах склада по ID ингредиента
+	SemiFinished map[string]float64        // В единице выхода по ID полуфабриката
+	recipes      *semiFinishedRequirements // Рецептуры полуфабрикатов — на случай их нехватки
+}
+
+// Requirements возвращает расход ингредиентов и полуфабрикатов на одну порцию продуктов
+func (s *StockService) Requirements(productIDs []string) (*PortionRequirements, error) {
+	return loadPortionRequirements(productIDs)
+}
+
+// OrderItemNeeds рассчитывает расход на позицию заказа.
+// Для сетов учитывается фактический состав с выбором клиента (components — на один сет).
+func (s *StockService) OrderItemNeeds(requirements *PortionRequirements, productID string, components []models.OrderItemComponent, quantity int) StockNeeds {
+	needs := StockNeeds{
+		Ingredients:  map[string]float64{},
+		SemiFinished: map[string]float64{},
+		recipes:      requirements.recipes,
+	}
+	add := func(productID string, multiplier float64) {
+		for ingredientID, qty := range requirements.ingredients[productID] {
+			needs.Ingredients[ingredientID] += qty * multiplier
+		}
+		for sfID, qty := range requirements.semiFinished[productID] {
+			needs.SemiFinished[sfID] += qty * multiplier
+		}
+	}
+
+	if len(components) == 0 {
+		add(productID, float64(quantity))
+		return needs
+	}
+	for _, component := range components {
+		add(component.ProductID, float64(component.Quantity*quantity))
+	}
+	return needs
+}
+
+// Consume списывает позицию внутри транзакции: полуфабрикаты — с их остатка (партии выпуска),
+// а сырьё рецептуры и нехватку полуфабрикатов — с ингредиентов. Возвращает себестоимость
+// по ценам списанных партий.
+func (s *StockService) Consume(tx *gorm.DB, needs StockNeeds, note, documentType, documentID string, userID *string) (float64, error) {
+	return consumeNeeds(tx, needs, stockMovementInput{
+		Note:         note,
+		DocumentType: documentType,
+		DocumentID:   documentID,
+		UserID:       userID,
+	})
+}
+
+// consumeNeeds списывает полуфабрикаты с их остатка, а нехватку раскрывает по рецептуре:
+// вложенные полуфабрикаты снова списываются с остатка, сырьё — с ингредиентов
+func consumeNeeds(tx *gorm.DB, needs StockNeeds, input stockMovementInput) (float64, error) {
+	ingredients := make(map[string]float64, len(needs.Ingredients))
+	for id, qty := range needs.Ingredients {
+		ingredients[id] = qty
+	}
+	recipes := needs.recipes
+	if recipes == nil {
+		recipes = newSemiFinishedRequirements()
+	}
+
+	total := 0.0
+	var consume func(sfIDs map[string]float64, depth int) error
+	consume = func(sfIDs map[string]float64, depth int) error {
+		if depth > maxRecipeDepth {
+			return fmt.Errorf("semi-finished recipes are nested too deeply")
+		}
+		// Полуфабрикаты блокируются раньше ингредиентов — тот же порядок, что и при производстве
+		ids := make([]string, 0, len(sfIDs))
+		for id, qty := range sfIDs {
+			if qty > stockEpsilon {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+
+		for _, sfID := range ids {
+			cost, shortage, err := consumeSemiFinished(tx, sfID, sfIDs[sfID], input)
+			if err != nil {
+				return err
+			}
+			total += cost
+			if shortage <= stockEpsilon {
+				continue
+			}
+
+			// Не хватило готового полуфабриката — списываем его состав, как при приготовлении на месте
+			for ingredientID, perUnit := range recipes.ingredients[sfID] {
+				ingredients[ingredientID] += perUnit * shortage
+			}
+			nested := map[string]float64{}
+			for childID, perUnit := range recipes.components[sfID] {
+				nested[childID] += perUnit * shortage
+			}
+			if err := consume(nested, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := consume(needs.SemiFinished, 0); err != nil {
+		return 0, err
+	}
+
+	cost, err := consumeIngredients(tx, ingredients, input)
+	if err != nil {
+		return 0, err
+	}
+
+	return roundCost(total + cost), nil
+}
+
+// consumeIngredients списывает ингредиенты (needs — в базовых единицах по ID ингредиента)
+// и возвращает себестоимость по ценам списанных партий
+func consumeIngredients(tx *gorm.DB, needs map[string]float64, input stockMovementInput) (float64, error) {
+	// Фиксированный порядок блокировок защищает от взаимных блокировок параллельных заказов
+	ingredientIDs := make([]string, 0, len(needs))
+	for id, qty := range needs {
+		if qty > stockEpsilon {
+			ingredientIDs = append(ingredientIDs, id)
+		}
+	}
+	sort.Strings(ingredientIDs)
+
+	total := 0.0
+	for _, ingredientID := range ingredientIDs {
+		item, err := lockStockItem(tx, ingredientID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("[STOCK] ⚠️ No stock record for ingredient %s, skipping consumption", ingredientID)
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		unit := ""
+		if item.Ingredient != nil {
+			unit = item.Ingredient.Unit
+		}
+		_, cost, err := consumeStock(tx, item, units.FromBase(needs[ingredientID], unit), "", input)
+		if err != nil {
+			return 0, err
+		}
+		total += cost
+	}
+
+	return total, nil
+}
+
+// ChangeOrderStatus меняет статус заказа. При отмене заказа, который ещё не доставлен,
+// ингредиенты и полуфабрикаты возвращаются на склад в ту же транзакцию — в те же
+// партии, из которых были списаны. Возвращает статус до изменения и число возвращённых позиций.
+func (s *StockService) ChangeOrderStatus(orderID, status string, userID *string) (string, int, error) {
+	var previous string
+	returned := 0
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Блокировка заказа исключает двойной возврат при параллельной отмене
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", orderID).Error; err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+		previous = order.Status
+
+		if err := tx.Table("Order").Where("id = ?", orderID).Updates(map[string]interface{}{
+			"status":     status,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update order status: %w", err)
+		}
+
+		// Доставленный заказ съеден — при его отмене (возврате денег) склад не меняется
+		if status != "cancelled" || previous == "cancelled" || previous == "delivered" {
+			return nil
+		}
+		var err error
+		returned, err = returnOrderStock(tx, orderID, userID)
+		return err
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	if returned > 0 {
+		log.Printf("[STOCK] ↩️ Returned %d position(s) to stock for cancelled order %s", returned, orderID)
+	}
+	return previous, returned, nil
+}
+
+// returnOrderStock возвращает на склад списанное по заказу (внутри транзакции,
+// заказ должен быть заблокирован). Повторный вызов для того же заказа ничего не делает.
+func returnOrderStock(tx *gorm.DB, orderID string, userID *string) (int, error) {
+	var done int64
+	if err := tx.Model(&models.StockMovement{}).
+		Where(`"documentType" = ? AND "documentId" = ?`, models.DocumentOrderReturn, orderID).
+		Count(&done).Error; err != nil {
+		return 0, fmt.Errorf("failed to check stock return: %w", err)
+	}
+	if done == 0 {
+		if err := tx.Model(&models.SemiFinishedMovement{}).
+			Where("document_type = ? AND document_id = ?", models.DocumentOrderReturn, orderID).
+			Count(&done).Error; err != nil {
+			return 0, fmt.Errorf("failed to check semi-finished return: %w", err)
+		}
+	}
+	if done > 0 {
+		return 0, nil
+	}
+
+	note := fmt.Sprintf("Возврат по отмене заказа %s", orderID)
+
+	// Полуфабрикаты блокируются раньше ингредиентов — тот же порядок, что и при списании
+	returned, err := returnSemiFinished(tx, models.DocumentOrder, orderID, stockMovementInput{
+		Note:         note,
+		DocumentType: models.DocumentOrderReturn,
+		DocumentID:   orderID,
+		UserID:       userID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var movements []models.StockMovement
+	if err := tx.Where(`"documentType" = ? AND "documentId" = ? AND type = ?`,
+		models.DocumentOrder, orderID, models.MovementOut).
+		Order(`"stockItemId" ASC`).
+		Find(&movements).Error; err != nil {
+		return 0, fmt.Errorf("failed to fetch order movements: %w", err)
+	}
+
+	for _, movement := range movements {
+		item, err := lockStockItem(tx, movement.StockItemID)
+		if err != nil {
+			return 0, err
+		}
+
+		var consumptions []models.StockBatchConsumption
+		if err := tx.Where("movement_id = ? AND batch_id IS NOT NULL", movement.ID).
+			Find(&consumptions).Error; err != nil {
+			return 0, fmt.Errorf("failed to fetch batch consumption: %w", err)
+		}
+		for _, c := range consumptions {
+			if err := tx.Model(&models.StockBatch{}).Where("id = ?", *c.BatchID).
+				Update("remaining_quantity", gorm.Expr("remaining_quantity + ?", c.Quantity)).Error; err != nil {
+				return 0, fmt.Errorf("failed to return stock batch: %w", err)
+			}
+		}
+
+		if _, err := recordStockMovement(tx, item, stockMovementInput{
+			Type:         models.MovementIn,
+			Quantity:     movement.Quantity,
+			Price:        movement.PriceNetto,
+			Note:         note,
+			DocumentType: models.DocumentOrderReturn,
+			DocumentID:   orderID,
+			UserID:       userID,
+		}); err != nil {
+			return 0, err
+		}
+		returned++
+	}
+	return returned, nil
+}
+
+// AddOpeningBatch заводит партию для начального остатка нового ингредиента
+// (в той же транзакции, что и складская запись)
+func (s *StockService) AddOpeningBatch(tx *gorm.DB, item *models.StockItem) error {
+	if item.Quantity <= 0 {
+		return nil
+	}
+	price := 0.0
+	if item.PricePerUnit != nil {
+		price = *item.PricePerUnit
+	}
+	_, err := addStockBatch(tx, item, stockBatchInput{Quantity: item.Quantity, Price: price})
+	return err
+}
+
+// GetBatches возвращает партии складской позиции (по ID позиции или ингредиента), новые первыми
+func (s *StockService) GetBatches(id string, includeEmpty bool) ([]models.StockBatch, error) {
+	db := database.GetDB()
+
+	var item models.StockItem
+	if err := db.Preload("Ingredient").
+		Where(`id = ? OR "ingredientId" = ?`, id, id).
+		First(&item).Error; err != nil {
+		return nil, fmt.Errorf("stock item not found: %w", err)
+	}
+
+	query := db.Where("stock_item_id = ?", item.ID)
+	if !includeEmpty {
+		query = query.Where("remaining_quantity > 0")
+	}
+	var batches []models.StockBatch
+	if err := query.Order("received_at DESC").Find(&batches).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch stock batches: %w", err)
+	}
+
+	now := time.Now()
+	for i := range batches {
+		fillBatchInfo(&batches[i], item.Ingredient, now)
+	}
+	return batches, nil
+}
+
+// GetExpiring возвращает непустые партии, срок годности которых истекает в ближайшие days дней
+// (включая уже просроченные), в порядке истечения срока
+func (s *StockService) GetExpiring(days int, now time.Time) ([]models.StockBatch, error) {
+	db := database.GetDB()
+
+	var batches []models.StockBatch
+	if err := db.Where("remaining_quantity > 0 AND expires_at IS NOT NULL AND expires_at <= ?", now.AddDate(0, 0, days)).
+		Order("expires_at ASC").
+		Find(&batches).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch expiring batches: %w", err)
+	}
+
+	ingredientIDs := []string{}
+	for _, batch := range batches {
+		ingredientIDs = appendUnique(ingredientIDs, batch.IngredientID)
+	}
+	ingredients := map[string]*models.Ingredient{}
+	if len(ingredientIDs) > 0 {
+		var list []models.Ingredient
+		if err := db.Where("id IN ?", ingredientIDs).Find(&list).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch ingredients: %w", err)
+		}
+		for i := range list {
+			ingredients[list[i].ID] = &list[i]
+		}
+	}
+
+	for i := range batches {
+		fillBatchInfo(&batches[i], ingredients[batches[i].IngredientID], now)
+	}
+	return batches, nil
+}
+
+// fillBatchInfo заполняет название, единицу и число дней до истечения срока партии
+func fillBatchInfo(batch *models.StockBatch, ingredient *models.Ingredient, now time.Time) {
+	if ingredient != nil {
+		batch.IngredientName = ingredient.Name
+		batch.Unit = ingredient.Unit
+	}
+	if batch.ExpiresAt != nil {
+		days := int(math.Floor(batch.ExpiresAt.Sub(now).Hours() / 24))
+		batch.DaysLeft = &days
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+)
+
+func TestSortBatchesForConsumption(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2026, time.October, d, 9, 0, 0, 0, time.UTC)
+	}
+	expires := func(d int) *time.Time {
+		at := day(d)
+		return &at
+	}
+
+	// Поступили по порядку A, B, C; срок годности у B ближе всех, у C не указан
+	batches := func() []models.StockBatch {
+		return []models.StockBatch{
+			{ID: "C", ReceivedAt: day(3)},
+			{ID: "A", ReceivedAt: day(1), ExpiresAt: expires(20)},
+			{ID: "B", ReceivedAt: day(2), ExpiresAt: expires(10)},
+			{ID: "D", ReceivedAt: day(2), ExpiresAt: expires(10), CreatedAt: day(2).Add(time.Hour)},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		policy string
+		want   []string
+	}{
+		{name: "fifo by received date", policy: models.ConsumptionFIFO, want: []string{"A", "B", "D", "C"}},
+		{name: "fefo by expiry, no expiry last", policy: models.ConsumptionFEFO, want: []string{"B", "D", "A", "C"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := batches()
+			sortBatchesForConsumption(list, tt.policy)
+			for i, batch := range list {
+				if batch.ID != tt.want[i] {
+					t.Fatalf("order = %v, want %v", batchIDs(list), tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAllocateStock(t *testing.T) {
+	batches := func() []models.StockBatch {
+		return []models.StockBatch{
+			{ID: "A", RemainingQuantity: 2},
+			{ID: "B", RemainingQuantity: 3},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		untracked float64
+		quantity  float64
+		want      []string // "batch:qty", "-" — остаток без партий
+		wantShort float64
+	}{
+		{name: "first batch covers", quantity: 1.5, want: []string{"A:1.5"}},
+		{name: "spills into next batch", quantity: 4, want: []string{"A:2", "B:2"}},
+		{name: "untracked stock goes first", untracked: 1, quantity: 2, want: []string{"-:1", "A:1"}},
+		{name: "shortage goes negative", quantity: 6.5, want: []string{"A:2", "B:3"}, wantShort: 1.5},
+		{name: "exact amount leaves no shortage", untracked: 0.5, quantity: 5.5, want: []string{"-:0.5", "A:2", "B:3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocations, short := allocateStock(batches(), tt.untracked, tt.quantity)
+
+			got := make([]string, 0, len(allocations))
+			for _, a := range allocations {
+				id := "-"
+				if a.Batch != nil {
+					id = a.Batch.ID
+				}
+				got = append(got, id+":"+formatQty(a.Quantity))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("allocations = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("allocations = %v, want %v", got, tt.want)
+				}
+			}
+			if math.Abs(short-tt.wantShort) > stockEpsilon {
+				t.Errorf("short = %v, want %v", short, tt.wantShort)
+			}
+		})
+	}
+}
+
+func batchIDs(batches []models.StockBatch) []string {
+	ids := make([]string, 0, len(batches))
+	for _, b := range batches {
+		ids = append(ids, b.ID)
+	}
+	return ids
+}
+
+func formatQty(qty float64) string {
+	return strconv.FormatFloat(qty, 'f', -1, 64)
+}
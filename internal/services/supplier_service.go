@@ -0,0 +1,213 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrSupplierInUse поставщика с заказами нельзя удалить, только деактивировать
+var ErrSupplierInUse = errors.New("supplier has purchase orders, deactivate it instead")
+
+// SupplierService - сервис справочника поставщиков и их прайс-листов
+type SupplierService struct{}
+
+// NewSupplierService создает новый экземпляр SupplierService
+func NewSupplierService() *SupplierService {
+	return &SupplierService{}
+}
+
+// GetAll возвращает поставщиков (includeInactive — включая неактивных)
+func (s *SupplierService) GetAll(includeInactive bool) ([]models.Supplier, error) {
+	query := database.GetDB().Order("name ASC")
+	if !includeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var suppliers []models.Supplier
+	if err := query.Find(&suppliers).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch suppliers: %w", err)
+	}
+	return suppliers, nil
+}
+
+// GetByID возвращает поставщика с прайс-листом
+func (s *SupplierService) GetByID(id string) (*models.Supplier, error) {
+	var supplier models.Supplier
+	if err := database.GetDB().
+		Preload("Prices", func(db *gorm.DB) *gorm.DB { return db.Order("ingredient_name ASC") }).
+		First(&supplier, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("supplier not found: %w", err)
+	}
+	return &supplier, nil
+}
+
+// Create создает поставщика
+func (s *SupplierService) Create(req models.SupplierRequest) (*models.Supplier, error) {
+	supplier := models.Supplier{ID: uuid.New().String(), IsActive: true}
+	if err := s.fill(&supplier, req); err != nil {
+		return nil, err
+	}
+	if err := database.GetDB().Create(&supplier).Error; err != nil {
+		return nil, fmt.Errorf("failed to create supplier: %w", err)
+	}
+	return &supplier, nil
+}
+
+// Update изменяет карточку поставщика
+func (s *SupplierService) Update(id string, req models.SupplierRequest) (*models.Supplier, error) {
+	db := database.GetDB()
+
+	var supplier models.Supplier
+	if err := db.First(&supplier, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("supplier not found: %w", err)
+	}
+	if err := s.fill(&supplier, req); err != nil {
+		return nil, err
+	}
+	if err := db.Save(&supplier).Error; err != nil {
+		return nil, fmt.Errorf("failed to update supplier: %w", err)
+	}
+	return &supplier, nil
+}
+
+// Delete удаляет поставщика без заказов вместе с его прайс-листом
+func (s *SupplierService) Delete(id string) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var orders int64
+		if err := tx.Model(&models.PurchaseOrder{}).Where("supplier_id = ?", id).Count(&orders).Error; err != nil {
+			return fmt.Errorf("failed to check purchase orders: %w", err)
+		}
+		if orders > 0 {
+			return ErrSupplierInUse
+		}
+		if err := tx.Where("supplier_id = ?", id).Delete(&models.SupplierPrice{}).Error; err != nil {
+			return fmt.Errorf("failed to delete price list: %w", err)
+		}
+		result := tx.Delete(&models.Supplier{}, "id = ?", id)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete supplier: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("supplier not found")
+		}
+		return nil
+	})
+}
+
+// SetPrice добавляет или обновляет цену ингредиента в прайс-листе поставщика.
+// Основной поставщик у ингредиента может быть только один.
+func (s *SupplierService) SetPrice(supplierID string, req models.SupplierPriceRequest) (*models.SupplierPrice, error) {
+	if req.PricePerUnit < 0 {
+		return nil, fmt.Errorf("price must be positive")
+	}
+	if req.PackSize != nil && *req.PackSize <= 0 {
+		req.PackSize = nil
+	}
+
+	var price models.SupplierPrice
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.Supplier{}, "id = ?", supplierID).Error; err != nil {
+			return fmt.Errorf("supplier not found: %w", err)
+		}
+
+		var item models.StockItem
+		if err := tx.Preload("Ingredient").
+			Where(`id = ? OR "ingredientId" = ?`, req.IngredientID, req.IngredientID).
+			First(&item).Error; err != nil {
+			return fmt.Errorf("ingredient %s not found", req.IngredientID)
+		}
+
+		if err := tx.Where("supplier_id = ? AND stock_item_id = ?", supplierID, item.ID).First(&price).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("failed to fetch price: %w", err)
+			}
+			price = models.SupplierPrice{ID: uuid.New().String(), SupplierID: supplierID, StockItemID: item.ID}
+		}
+		price.IngredientID = item.IngredientID
+		if item.Ingredient != nil {
+			price.IngredientName = item.Ingredient.Name
+			price.Unit = item.Ingredient.Unit
+		}
+		price.PricePerUnit = roundCost(req.PricePerUnit)
+		price.PackSize = req.PackSize
+		price.SupplierSKU = optionalString(req.SupplierSKU)
+		price.IsPreferred = req.IsPreferred
+
+		if price.IsPreferred {
+			if err := tx.Model(&models.SupplierPrice{}).
+				Where("stock_item_id = ? AND id <> ?", item.ID, price.ID).
+				Update("is_preferred", false).Error; err != nil {
+				return fmt.Errorf("failed to reset preferred supplier: %w", err)
+			}
+		}
+		return tx.Save(&price).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
+
+// DeletePrice удаляет строку прайс-листа
+func (s *SupplierService) DeletePrice(supplierID, priceID string) error {
+	result := database.GetDB().Where("id = ? AND supplier_id = ?", priceID, supplierID).Delete(&models.SupplierPrice{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete price: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("price not found")
+	}
+	return nil
+}
+
+// fill валидирует запрос и заполняет карточку поставщика
+func (s *SupplierService) fill(supplier *models.Supplier, req models.SupplierRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("supplier name is required")
+	}
+	if req.LeadTimeDays < 0 {
+		return fmt.Errorf("lead time must not be negative")
+	}
+	if req.MinOrderAmount < 0 {
+		return fmt.Errorf("minimum order amount must not be negative")
+	}
+
+	days := models.StringList{}
+	for _, day := range req.DeliveryDays {
+		day = strings.ToLower(strings.TrimSpace(day))
+		valid := false
+		for _, known := range models.WeekDays {
+			if day == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unknown delivery day: %s", day)
+		}
+		if !days.Contains(day) {
+			days = append(days, day)
+		}
+	}
+
+	supplier.Name = name
+	supplier.ContactName = optionalString(req.ContactName)
+	supplier.Phone = optionalString(req.Phone)
+	supplier.Email = optionalString(req.Email)
+	supplier.Address = optionalString(req.Address)
+	supplier.LeadTimeDays = req.LeadTimeDays
+	supplier.DeliveryDays = days
+	supplier.MinOrderAmount = roundCost(req.MinOrderAmount)
+	supplier.Note = optionalString(req.Note)
+	if req.IsActive != nil {
+		supplier.IsActive = *req.IsActive
+	}
+	return nil
+}
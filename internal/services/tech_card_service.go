@@ -0,0 +1,487 @@
+package services
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+	"gorm.io/gorm"
+)
+
+// TechCardService - сервис технологических карт продуктов и полуфабрикатов
+type TechCardService struct {
+	nutritionService *NutritionService
+}
+
+// NewTechCardService создает новый экземпляр TechCardService
+func NewTechCardService() *TechCardService {
+	return &TechCardService{
+		nutritionService: NewNutritionService(),
+	}
+}
+
+// ForProduct технологическая карта продукта
+func (s *TechCardService) ForProduct(id string) (*models.TechCard, error) {
+	db := database.GetDB()
+
+	var product models.Product
+	if err := db.Preload("Ingredients").Preload("SemiFinished").First(&product, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("product not found: %w", err)
+	}
+	if product.Type == models.ProductTypeBundle {
+		return nil, fmt.Errorf("bundle has no recipe of its own, use tech cards of its products")
+	}
+
+	src, err := s.load(db, []models.Product{product}, nil)
+	if err != nil {
+		return nil, err
+	}
+	card := src.productCard(&product)
+	return &card, nil
+}
+
+// ForSemiFinished технологическая карта полуфабриката
+func (s *TechCardService) ForSemiFinished(id string) (*models.TechCard, error) {
+	src, err := s.load(database.GetDB(), nil, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	sf, ok := src.closure[id]
+	if !ok {
+		return nil, fmt.Errorf("semi-finished not found: %w", gorm.ErrRecordNotFound)
+	}
+	card := src.semiFinishedCard(&sf)
+	return &card, nil
+}
+
+// ForMenu технологические карты всех видимых продуктов меню (кроме сетов) и всех
+// полуфабрикатов, входящих в их рецептуры. category ограничивает продукты одной категорией.
+func (s *TechCardService) ForMenu(category string) ([]models.TechCard, error) {
+	db := database.GetDB()
+
+	query := db.Preload("Ingredients").Preload("SemiFinished").
+		Where(`"isVisible" = ? AND (type = ? OR type IS NULL)`, true, models.ProductTypeSingle)
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	var products []models.Product
+	if err := query.Order("category ASC, name ASC").Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+
+	src, err := s.load(db, products, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cards := make([]models.TechCard, 0, len(products)+len(src.closure))
+	for i := range products {
+		cards = append(cards, src.productCard(&products[i]))
+	}
+	semiFinished := make([]models.SemiFinished, 0, len(src.closure))
+	for _, sf := range src.closure {
+		semiFinished = append(semiFinished, sf)
+	}
+	sort.Slice(semiFinished, func(i, j int) bool { return semiFinished[i].Name < semiFinished[j].Name })
+	for i := range semiFinished {
+		cards = append(cards, src.semiFinishedCard(&semiFinished[i]))
+	}
+
+	log.Printf("[TECH-CARD] 📄 Generated %d tech card(s): %d product(s), %d semi-finished", len(cards), len(products), len(semiFinished))
+	return cards, nil
+}
+
+// techCardSource данные для построения технологических карт: полуфабрикаты со всеми
+// вложенными, ингредиенты, единицы и действующие версии рецептур
+type techCardSource struct {
+	closure     map[string]models.SemiFinished
+	ingredients map[string]models.Ingredient
+	ingUnits    *ingredientUnits
+	nutrition   map[string]*models.ProductNutrition
+	versions    map[string]int // "тип:ID" → номер версии
+	sfAllergens map[string]allergenSet
+	generatedAt time.Time
+}
+
+// allergenSet аллергены состава; known = false, если у части ингредиентов они не указаны
+type allergenSet struct {
+	codes map[string]bool
+	known bool
+}
+
+// load загружает всё, что нужно для карт указанных продуктов и полуфабрикатов
+func (s *TechCardService) load(db *gorm.DB, products []models.Product, sfIDs []string) (*techCardSource, error) {
+	productIDs := make([]string, 0, len(products))
+	for _, p := range products {
+		productIDs = append(productIDs, p.ID)
+		for _, line := range p.SemiFinished {
+			sfIDs = appendUnique(sfIDs, line.SemiFinishedID)
+		}
+	}
+
+	closure, err := loadSemiFinishedClosure(db, sfIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	ingredientIDs := []string{}
+	for _, p := range products {
+		for _, line := range p.Ingredients {
+			ingredientIDs = appendUnique(ingredientIDs, line.IngredientID)
+		}
+	}
+	for _, sf := range closure {
+		for _, line := range sf.Ingredients {
+			ingredientIDs = appendUnique(ingredientIDs, line.IngredientID)
+		}
+	}
+
+	src := &techCardSource{
+		closure:     closure,
+		ingredients: map[string]models.Ingredient{},
+		nutrition:   map[string]*models.ProductNutrition{},
+		versions:    map[string]int{},
+		sfAllergens: map[string]allergenSet{},
+		generatedAt: time.Now(),
+	}
+	if len(ingredientIDs) > 0 {
+		var list []models.Ingredient
+		if err := db.Where("id IN ?", ingredientIDs).Find(&list).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch ingredients: %w", err)
+		}
+		for _, ing := range list {
+			src.ingredients[ing.ID] = ing
+		}
+	}
+	if src.ingUnits, err = loadIngredientUnits(db, ingredientIDs); err != nil {
+		return nil, err
+	}
+	if len(productIDs) > 0 {
+		if src.nutrition, err = s.nutritionService.ForProducts(productIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	entityIDs := append([]string{}, productIDs...)
+	for id := range closure {
+		entityIDs = append(entityIDs, id)
+	}
+	if len(entityIDs) > 0 {
+		var rows []struct {
+			EntityType string
+			EntityID   string
+			Version    int
+		}
+		if err := db.Model(&models.RecipeVersion{}).
+			Select("entity_type, entity_id, MAX(version) AS version").
+			Where("entity_id IN ?", entityIDs).
+			Group("entity_type, entity_id").
+			Scan(&rows).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch recipe versions: %w", err)
+		}
+		for _, row := range rows {
+			src.versions[row.EntityType+":"+row.EntityID] = row.Version
+		}
+	}
+	return src, nil
+}
+
+// productCard карта продукта: выход — сумма нетто строк в граммах
+func (src *techCardSource) productCard(p *models.Product) models.TechCard {
+	card := models.TechCard{
+		EntityType:          models.CostEntityProduct,
+		ID:                  p.ID,
+		Name:                p.Name,
+		Category:            p.Category,
+		Description:         p.Description,
+		Lines:               make([]models.TechCardLine, 0, len(p.Ingredients)+len(p.SemiFinished)),
+		YieldUnit:           units.Gram,
+		YieldComplete:       true,
+		DeclaredWeight:      p.Weight,
+		CookingInstructions: p.CookingInstructions,
+		StorageConditions:   p.StorageConditions,
+		Nutrition:           src.nutrition[p.ID],
+		GeneratedAt:         src.generatedAt,
+	}
+	allergens := allergenSet{codes: map[string]bool{}, known: true}
+
+	for _, line := range p.Ingredients {
+		techLine, set := src.ingredientLine(productLineQuantity(line), line.IngredientName)
+		card.Lines = append(card.Lines, techLine)
+		allergens.merge(set)
+		card.YieldComplete = addTechCardYield(&card, src.ingUnits.converter(line.IngredientID), techLine) && card.YieldComplete
+	}
+	for _, line := range p.SemiFinished {
+		techLine, set := src.semiFinishedLine(line.SemiFinishedID, line.SemiFinishedName, line.Quantity, line.Unit)
+		card.Lines = append(card.Lines, techLine)
+		allergens.merge(set)
+		card.YieldComplete = addTechCardYield(&card, nil, techLine) && card.YieldComplete
+	}
+
+	card.YieldQuantity = roundQuantity(card.YieldQuantity)
+	card.Allergens, card.AllergensComplete = allergens.list(), allergens.known
+	if v, ok := src.versions[models.CostEntityProduct+":"+p.ID]; ok {
+		card.RecipeVersion = &v
+	}
+	return card
+}
+
+// semiFinishedCard карта полуфабриката: выход — заявленный в рецептуре
+func (src *techCardSource) semiFinishedCard(sf *models.SemiFinished) models.TechCard {
+	card := models.TechCard{
+		EntityType:          models.CostEntitySemiFinished,
+		ID:                  sf.ID,
+		Name:                sf.Name,
+		Category:            sf.Category,
+		Description:         sf.Description,
+		Lines:               make([]models.TechCardLine, 0, len(sf.Ingredients)+len(sf.Components)),
+		YieldQuantity:       sf.OutputQuantity,
+		YieldUnit:           sf.OutputUnit,
+		YieldComplete:       true,
+		CookingInstructions: sf.CookingInstructions,
+		StorageConditions:   sf.StorageConditions,
+		ShelfLifeHours:      sf.ShelfLifeHours,
+		GeneratedAt:         src.generatedAt,
+	}
+	for _, line := range sf.Ingredients {
+		techLine, _ := src.ingredientLine(semiFinishedLineQuantity(line), line.IngredientName)
+		card.Lines = append(card.Lines, techLine)
+	}
+	for _, line := range sf.Components {
+		techLine, _ := src.semiFinishedLine(line.ComponentID, line.ComponentName, line.Quantity, line.Unit)
+		card.Lines = append(card.Lines, techLine)
+	}
+
+	allergens := src.semiFinishedAllergens(sf.ID, map[string]bool{})
+	card.Allergens, card.AllergensComplete = allergens.list(), allergens.known
+	if v, ok := src.versions[models.CostEntitySemiFinished+":"+sf.ID]; ok {
+		card.RecipeVersion = &v
+	}
+	return card
+}
+
+// ingredientLine строка сырья: брутто и нетто с учётом отходов и тепловой обработки
+func (src *techCardSource) ingredientLine(line recipeQuantity, name string) (models.TechCardLine, allergenSet) {
+	b := src.ingUnits.breakdown(line, name, 0)
+	set := src.ingredientAllergens(line.IngredientID)
+	return models.TechCardLine{
+		Kind:               models.RecipeNodeIngredient,
+		ID:                 line.IngredientID,
+		Name:               name,
+		Unit:               line.Unit,
+		GrossQuantity:      b.GrossQuantity,
+		NetQuantity:        b.NetQuantity,
+		TrimLossPercent:    b.TrimLossPercent,
+		CookingLossPercent: b.CookingLossPercent,
+		Allergens:          set.list(),
+	}, set
+}
+
+// semiFinishedLine строка полуфабриката: закладывается готовым, брутто равно нетто
+func (src *techCardSource) semiFinishedLine(id, name string, quantity float64, unit string) (models.TechCardLine, allergenSet) {
+	set := src.semiFinishedAllergens(id, map[string]bool{})
+	return models.TechCardLine{
+		Kind:          models.RecipeNodeSemiFinished,
+		ID:            id,
+		Name:          name,
+		Unit:          unit,
+		GrossQuantity: roundQuantity(quantity),
+		NetQuantity:   roundQuantity(quantity),
+		Allergens:     set.list(),
+	}, set
+}
+
+// addTechCardYield добавляет нетто строки к выходу в граммах (жидкости — 1 мл = 1 г)
+func addTechCardYield(card *models.TechCard, conv *units.Converter, line models.TechCardLine) bool {
+	if grams, err := conv.Convert(line.NetQuantity, line.Unit, units.Gram); err == nil {
+		card.YieldQuantity += grams
+		return true
+	}
+	if ml, err := units.Convert(line.NetQuantity, line.Unit, units.Milliliter); err == nil {
+		card.YieldQuantity += ml
+		return true
+	}
+	return false
+}
+
+// ingredientAllergens аллергены ингредиента. NULL в БД (nil) — аллергены не указаны,
+// пустой список — ингредиент их не содержит
+func (src *techCardSource) ingredientAllergens(id string) allergenSet {
+	set := allergenSet{codes: map[string]bool{}}
+	ing, ok := src.ingredients[id]
+	if !ok {
+		return set
+	}
+	for _, code := range ing.Allergens {
+		set.codes[code] = true
+	}
+	set.known = ing.Allergens != nil
+	return set
+}
+
+// semiFinishedAllergens аллергены полуфабриката с учётом вложенных (с мемоизацией)
+func (src *techCardSource) semiFinishedAllergens(id string, path map[string]bool) allergenSet {
+	if set, ok := src.sfAllergens[id]; ok {
+		return set
+	}
+	set := allergenSet{codes: map[string]bool{}, known: true}
+	sf, ok := src.closure[id]
+	if !ok || path[id] {
+		set.known = false
+		return set
+	}
+	path[id] = true
+	defer delete(path, id)
+
+	for _, line := range sf.Ingredients {
+		set.merge(src.ingredientAllergens(line.IngredientID))
+	}
+	for _, line := range sf.Components {
+		set.merge(src.semiFinishedAllergens(line.ComponentID, path))
+	}
+	src.sfAllergens[id] = set
+	return set
+}
+
+// merge объединяет аллергены
+func (a *allergenSet) merge(other allergenSet) {
+	for code := range other.codes {
+		a.codes[code] = true
+	}
+	a.known = a.known && other.known
+}
+
+// list аллергены по алфавиту
+func (a allergenSet) list() []string {
+	result := make([]string, 0, len(a.codes))
+	for code := range a.codes {
+		result = append(result, code)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// allergenNames названия аллергенов для печатной карты
+var allergenNames = map[string]string{
+	models.AllergenGluten:      "глютен",
+	models.AllergenCrustaceans: "ракообразные",
+	models.AllergenEggs:        "яйца",
+	models.AllergenFish:        "рыба",
+	models.AllergenPeanuts:     "арахис",
+	models.AllergenSoy:         "соя",
+	models.AllergenMilk:        "молоко",
+	models.AllergenNuts:        "орехи",
+	models.AllergenCelery:      "сельдерей",
+	models.AllergenMustard:     "горчица",
+	models.AllergenSesame:      "кунжут",
+	models.AllergenSulphites:   "сульфиты",
+	models.AllergenLupin:       "люпин",
+	models.AllergenMolluscs:    "моллюски",
+}
+
+// techCardTemplate печатная форма: одна карта на страницу A4, PDF — через печать из браузера
+var techCardTemplate = template.Must(template.New("tech-cards").Funcs(template.FuncMap{
+	"qty": func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) },
+	"inc": func(i int) int { return i + 1 },
+	"allergens": func(codes []string) string {
+		names := make([]string, 0, len(codes))
+		for _, code := range codes {
+			if name, ok := allergenNames[code]; ok {
+				names = append(names, name)
+			} else {
+				names = append(names, code)
+			}
+		}
+		return strings.Join(names, ", ")
+	},
+	"kind": func(entityType string) string {
+		if entityType == models.CostEntitySemiFinished {
+			return "полуфабрикат"
+		}
+		return "блюдо"
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Технологические карты</title>
+<style>
+  @page { size: A4; margin: 15mm; }
+  body { font-family: "Times New Roman", serif; font-size: 12pt; color: #000; }
+  .card { page-break-after: always; }
+  .card:last-child { page-break-after: auto; }
+  h1 { font-size: 16pt; text-align: center; margin: 0 0 4pt; }
+  h2 { font-size: 14pt; text-align: center; margin: 0 0 12pt; }
+  h3 { font-size: 12pt; margin: 12pt 0 4pt; }
+  table { width: 100%; border-collapse: collapse; }
+  th, td { border: 1px solid #000; padding: 3pt 5pt; }
+  td.num { text-align: right; white-space: nowrap; }
+  .meta { margin: 0 0 8pt; }
+  .pre { white-space: pre-wrap; }
+  .warn { font-style: italic; }
+</style>
+</head>
+<body>
+{{range .}}
+<div class="card">
+  <h1>Технологическая карта</h1>
+  <h2>{{.Name}}</h2>
+  <p class="meta">
+    Вид: {{kind .EntityType}}{{if .Category}}; категория: {{.Category}}{{end}}{{if .RecipeVersion}}; версия рецептуры: {{.RecipeVersion}}{{end}}<br>
+    Дата составления: {{.GeneratedAt.Format "02.01.2006"}}
+  </p>
+  {{if .Description}}<p>{{.Description}}</p>{{end}}
+
+  <h3>1. Рецептура</h3>
+  <table>
+    <tr><th>№</th><th>Наименование сырья и полуфабрикатов</th><th>Ед.</th><th>Брутто</th><th>Нетто</th><th>Отходы, %</th><th>Потери при тепловой обработке, %</th></tr>
+    {{range $i, $l := .Lines}}
+    <tr>
+      <td class="num">{{$i | inc}}</td>
+      <td>{{$l.Name}}{{if eq $l.Kind "semi_finished"}} (п/ф){{end}}</td>
+      <td>{{$l.Unit}}</td>
+      <td class="num">{{qty $l.GrossQuantity}}</td>
+      <td class="num">{{qty $l.NetQuantity}}</td>
+      <td class="num">{{if $l.TrimLossPercent}}{{qty $l.TrimLossPercent}}{{end}}</td>
+      <td class="num">{{if $l.CookingLossPercent}}{{qty $l.CookingLossPercent}}{{end}}</td>
+    </tr>
+    {{end}}
+    <tr><td></td><td><b>Выход</b></td><td>{{.YieldUnit}}</td><td></td><td class="num"><b>{{qty .YieldQuantity}}</b></td><td></td><td></td></tr>
+  </table>
+  {{if not .YieldComplete}}<p class="warn">Выход рассчитан не полностью: часть строк не переводится в граммы.</p>{{end}}
+  {{if .DeclaredWeight}}<p>Вес порции по меню: {{.DeclaredWeight}}</p>{{end}}
+
+  <h3>2. Технология приготовления</h3>
+  {{if .CookingInstructions}}<p class="pre">{{.CookingInstructions}}</p>{{else}}<p class="warn">Не указана.</p>{{end}}
+
+  <h3>3. Условия и сроки хранения</h3>
+  {{if .StorageConditions}}<p class="pre">{{.StorageConditions}}</p>{{end}}
+  {{if .ShelfLifeHours}}<p>Срок годности: {{.ShelfLifeHours}} ч</p>{{end}}
+  {{if not (or .StorageConditions .ShelfLifeHours)}}<p class="warn">Не указаны.</p>{{end}}
+
+  <h3>4. Аллергены</h3>
+  <p>{{if .Allergens}}{{allergens .Allergens}}{{else if .AllergensComplete}}Не содержит{{else}}Нет данных{{end}}</p>
+  {{if not .AllergensComplete}}<p class="warn">Аллергены указаны не для всех ингредиентов.</p>{{end}}
+
+  {{with .Nutrition}}
+  <h3>5. Пищевая ценность на порцию</h3>
+  <p>Белки {{qty .Total.Protein}} г, жиры {{qty .Total.Fat}} г, углеводы {{qty .Total.Carbs}} г, энергетическая ценность {{qty .Total.Kcal}} ккал{{if not .Complete}} (рассчитана не полностью){{end}}</p>
+  {{end}}
+</div>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML печатная форма технологических карт
+func (s *TechCardService) WriteHTML(w io.Writer, cards []models.TechCard) error {
+	return techCardTemplate.Execute(w, cards)
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+)
+
+func TestTechCardAllergens(t *testing.T) {
+	const incompleteWarning = "Аллергены указаны не для всех ингредиентов."
+
+	src := &techCardSource{
+		closure: map[string]models.SemiFinished{
+			"dressing": {
+				ID: "dressing", Name: "Заправка", OutputQuantity: 100, OutputUnit: "g",
+				Ingredients: []models.SemiFinishedIngredient{{IngredientID: "oil", IngredientName: "Масло", Quantity: 100, Unit: "g"}},
+			},
+		},
+		ingredients: map[string]models.Ingredient{
+			"flour": {ID: "flour", Name: "Мука", Allergens: models.StringList{models.AllergenGluten}},
+			"salt":  {ID: "salt", Name: "Соль", Allergens: models.StringList{}},
+			"oil":   {ID: "oil", Name: "Масло"}, // Аллергены не указаны
+		},
+		ingUnits:    emptyIngredientUnits(),
+		nutrition:   map[string]*models.ProductNutrition{},
+		versions:    map[string]int{},
+		sfAllergens: map[string]allergenSet{},
+		generatedAt: time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC),
+	}
+	line := func(id string) models.ProductIngredient {
+		return models.ProductIngredient{IngredientID: id, IngredientName: id, Quantity: 10, Unit: "g"}
+	}
+
+	tests := []struct {
+		name         string
+		product      models.Product
+		wantList     string
+		wantComplete bool
+		wantText     string
+	}{
+		{
+			name:         "all ingredients specified",
+			product:      models.Product{ID: "bread", Name: "Хлеб", Ingredients: []models.ProductIngredient{line("flour"), line("salt")}},
+			wantList:     models.AllergenGluten,
+			wantComplete: true,
+			wantText:     "глютен",
+		},
+		{
+			name:         "no allergens",
+			product:      models.Product{ID: "brine", Name: "Рассол", Ingredients: []models.ProductIngredient{line("salt")}},
+			wantComplete: true,
+			wantText:     "Не содержит",
+		},
+		{
+			name:     "ingredient without allergen data",
+			product:  models.Product{ID: "fries", Name: "Картофель", Ingredients: []models.ProductIngredient{line("salt"), line("oil")}},
+			wantText: "Нет данных",
+		},
+		{
+			name: "unknown allergens in a nested semi-finished",
+			product: models.Product{
+				ID: "salad", Name: "Салат",
+				Ingredients:  []models.ProductIngredient{line("flour")},
+				SemiFinished: []models.ProductSemiFinished{{SemiFinishedID: "dressing", SemiFinishedName: "Заправка", Quantity: 20, Unit: "g"}},
+			},
+			wantList: models.AllergenGluten,
+			wantText: "глютен",
+		},
+		{
+			name:     "ingredient missing from the catalogue",
+			product:  models.Product{ID: "soup", Name: "Суп", Ingredients: []models.ProductIngredient{line("water")}},
+			wantText: "Нет данных",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := src.productCard(&tt.product)
+			if got := strings.Join(card.Allergens, ","); got != tt.wantList {
+				t.Errorf("Allergens = %q, want %q", got, tt.wantList)
+			}
+			if card.AllergensComplete != tt.wantComplete {
+				t.Errorf("AllergensComplete = %v, want %v", card.AllergensComplete, tt.wantComplete)
+			}
+
+			var buf bytes.Buffer
+			if err := (&TechCardService{}).WriteHTML(&buf, []models.TechCard{card}); err != nil {
+				t.Fatalf("WriteHTML() error = %v", err)
+			}
+			html := buf.String()
+			if !strings.Contains(html, tt.wantText) {
+				t.Errorf("HTML does not contain %q", tt.wantText)
+			}
+			if shown := strings.Contains(html, incompleteWarning); shown == tt.wantComplete {
+				t.Errorf("incomplete warning shown = %v, want %v", shown, !tt.wantComplete)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/units"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrConversionInUse без коэффициента сохранённые рецептуры ингредиента не пересчитать
+var ErrConversionInUse = errors.New("unit conversion is used by saved recipes")
+
+// UnitService - сервис единиц измерения и коэффициентов перевода ингредиентов
+type UnitService struct {
+	costService *CostService
+}
+
+// NewUnitService создает новый экземпляр UnitService
+func NewUnitService() *UnitService {
+	return &UnitService{
+		costService: NewCostService(),
+	}
+}
+
+// GetUnits возвращает справочник стандартных единиц
+func (s *UnitService) GetUnits() []models.UnitInfo {
+	list := make([]models.UnitInfo, 0, len(units.All))
+	for _, code := range units.All {
+		list = append(list, models.UnitInfo{
+			Code:      code,
+			Dimension: units.Dimension(code),
+			BaseUnit:  units.BaseUnit(code),
+		})
+	}
+	return list
+}
+
+// GetConversions возвращает коэффициенты перевода ингредиента
+func (s *UnitService) GetConversions(ingredientID string) ([]models.IngredientUnitConversion, error) {
+	var conversions []models.IngredientUnitConversion
+	if err := database.GetDB().
+		Where("ingredient_id = ?", ingredientID).
+		Order("created_at ASC").
+		Find(&conversions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch unit conversions: %w", err)
+	}
+	return conversions, nil
+}
+
+// SetConversion задаёт коэффициент перевода ингредиента. Коэффициент между теми же
+// измерениями (в любом направлении) заменяется, чтобы граф оставался однозначным.
+// Себестоимость рецептур с ингредиентом пересчитывается в той же транзакции.
+func (s *UnitService) SetConversion(ingredientID string, req models.UnitConversionRequest) (*models.IngredientUnitConversion, *models.CostRecalculationResult, error) {
+	db := database.GetDB()
+
+	var ingredient models.Ingredient
+	if err := db.First(&ingredient, "id = ?", ingredientID).Error; err != nil {
+		return nil, nil, fmt.Errorf("ingredient not found: %w", err)
+	}
+
+	conversion := models.IngredientUnitConversion{
+		ID:           uuid.New().String(),
+		IngredientID: ingredientID,
+		FromUnit:     units.Normalize(req.FromUnit),
+		ToUnit:       units.Normalize(req.ToUnit),
+		Factor:       req.Factor,
+	}
+	if err := units.ValidateConversion(units.Conversion{
+		From: conversion.FromUnit, To: conversion.ToUnit, Factor: conversion.Factor,
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	var costs *models.CostRecalculationResult
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.IngredientUnitConversion
+		if err := tx.Where("ingredient_id = ?", ingredientID).Find(&existing).Error; err != nil {
+			return fmt.Errorf("failed to fetch unit conversions: %w", err)
+		}
+		fromDim, toDim := units.Dimension(conversion.FromUnit), units.Dimension(conversion.ToUnit)
+		for _, c := range existing {
+			a, b := units.Dimension(c.FromUnit), units.Dimension(c.ToUnit)
+			if (a == fromDim && b == toDim) || (a == toDim && b == fromDim) {
+				if err := tx.Delete(&c).Error; err != nil {
+					return fmt.Errorf("failed to replace unit conversion: %w", err)
+				}
+			}
+		}
+		if err := tx.Create(&conversion).Error; err != nil {
+			return fmt.Errorf("failed to save unit conversion: %w", err)
+		}
+
+		// Заменённый коэффициент мог быть нужен сохранённым рецептурам
+		if err := checkRecipeUnits(tx, ingredientID); err != nil {
+			return err
+		}
+		var err error
+		costs, err = s.costService.RecalculateIngredientUnits(tx, ingredientID, "unit conversion changed")
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Printf("[UNITS] ✅ %s: 1 %s = %g %s", ingredient.Name, conversion.FromUnit, conversion.Factor, conversion.ToUnit)
+	return &conversion, costs, nil
+}
+
+// DeleteConversion удаляет коэффициент перевода ингредиента. Коэффициент, без которого
+// сохранённые рецептуры не переводятся в единицу склада, удалить нельзя.
+func (s *UnitService) DeleteConversion(ingredientID, id string) (*models.CostRecalculationResult, error) {
+	var costs *models.CostRecalculationResult
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.IngredientUnitConversion{}, "id = ? AND ingredient_id = ?", id, ingredientID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete unit conversion: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("unit conversion not found")
+		}
+		if err := checkRecipeUnits(tx, ingredientID); err != nil {
+			return err
+		}
+		var err error
+		costs, err = s.costService.RecalculateIngredientUnits(tx, ingredientID, "unit conversion deleted")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return costs, nil
+}
+
+// checkRecipeUnits проверяет, что все сохранённые строки рецептур с ингредиентом
+// переводятся в его единицу склада по текущим коэффициентам (внутри транзакции)
+func checkRecipeUnits(tx *gorm.DB, ingredientID string) error {
+	ingUnits, err := loadIngredientUnits(tx, []string{ingredientID})
+	if err != nil {
+		return err
+	}
+
+	var sfLines []models.SemiFinishedIngredient
+	if err := tx.Where("ingredient_id = ?", ingredientID).Find(&sfLines).Error; err != nil {
+		return fmt.Errorf("failed to fetch semi-finished lines: %w", err)
+	}
+	for _, line := range sfLines {
+		if _, err := ingUnits.toStockGross(semiFinishedLineQuantity(line)); err != nil {
+			var sf models.SemiFinished
+			tx.Select("name").First(&sf, "id = ?", line.SemiFinishedID)
+			return fmt.Errorf("%w: semi-finished %s (%g %s)", ErrConversionInUse, sf.Name, line.Quantity, line.Unit)
+		}
+	}
+
+	var productLines []models.ProductIngredient
+	if err := tx.Where("ingredient_id = ?", ingredientID).Find(&productLines).Error; err != nil {
+		return fmt.Errorf("failed to fetch product lines: %w", err)
+	}
+	for _, line := range productLines {
+		if _, err := ingUnits.toStockGross(productLineQuantity(line)); err != nil {
+			var product models.Product
+			tx.Unscoped().Select("name").First(&product, "id = ?", line.ProductID)
+			return fmt.Errorf("%w: product %s (%g %s)", ErrConversionInUse, product.Name, line.Quantity, line.Unit)
+		}
+	}
+	return nil
+}
+
+// Convert переводит количество ингредиента между единицами с учётом его коэффициентов
+func (s *UnitService) Convert(ingredientID string, quantity float64, from, to string) (*models.UnitConversionResult, error) {
+	ingUnits, err := loadIngredientUnits(database.GetDB(), []string{ingredientID})
+	if err != nil {
+		return nil, err
+	}
+	converted, err := ingUnits.converter(ingredientID).Convert(quantity, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &models.UnitConversionResult{
+		IngredientID: ingredientID,
+		Quantity:     quantity,
+		FromUnit:     units.Normalize(from),
+		Result:       roundQuantity(converted),
+		ToUnit:       units.Normalize(to),
+	}, nil
+}
+
+// PriceSemiFinishedLines проверяет единицы строк рецептуры полуфабриката и рассчитывает
+// их стоимость по количеству брутто
+func (s *UnitService) PriceSemiFinishedLines(lines []models.SemiFinishedIngredientInput) error {
+	ids := make([]string, 0, len(lines))
+	for _, line := range lines {
+		ids = appendUnique(ids, line.IngredientID)
+	}
+	ingUnits, err := loadIngredientUnits(database.GetDB(), ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range lines {
+		line := &lines[i]
+		line.Unit = units.Normalize(line.Unit)
+		if line.QuantityType, err = normalizeRecipeQuantity(line.QuantityType, line.CookingLoss); err != nil {
+			return fmt.Errorf("ingredient %s: %w", line.IngredientName, err)
+		}
+		qty, err := ingUnits.toStockGross(recipeQuantity{
+			IngredientID: line.IngredientID,
+			Quantity:     line.Quantity,
+			Unit:         line.Unit,
+			QuantityType: line.QuantityType,
+			CookingLoss:  line.CookingLoss,
+		})
+		if err != nil {
+			return fmt.Errorf("ingredient %s: %w", line.IngredientName, err)
+		}
+		line.TotalPrice = roundCost(qty * line.PricePerUnit)
+	}
+	return nil
+}
+
+// PriceProductLines проверяет единицы строк рецептуры продукта и рассчитывает
+// их стоимость по количеству брутто
+func (s *UnitService) PriceProductLines(ingredients []models.ProductIngredientInput, semiFinished []models.ProductSemiFinishedInput) error {
+	db := database.GetDB()
+
+	ids := make([]string, 0, len(ingredients))
+	for _, line := range ingredients {
+		ids = appendUnique(ids, line.IngredientID)
+	}
+	ingUnits, err := loadIngredientUnits(db, ids)
+	if err != nil {
+		return err
+	}
+	for i := range ingredients {
+		line := &ingredients[i]
+		line.Unit = units.Normalize(line.Unit)
+		if line.QuantityType, err = normalizeRecipeQuantity(line.QuantityType, line.CookingLoss); err != nil {
+			return fmt.Errorf("ingredient %s: %w", line.IngredientName, err)
+		}
+		qty, err := ingUnits.toStockGross(recipeQuantity{
+			IngredientID: line.IngredientID,
+			Quantity:     line.Quantity,
+			Unit:         line.Unit,
+			QuantityType: line.QuantityType,
+			CookingLoss:  line.CookingLoss,
+		})
+		if err != nil {
+			return fmt.Errorf("ingredient %s: %w", line.IngredientName, err)
+		}
+		line.TotalPrice = roundCost(qty * line.PricePerUnit)
+	}
+
+	for i := range semiFinished {
+		line := &semiFinished[i]
+		var sf models.SemiFinished
+		if err := db.First(&sf, "id = ?", line.SemiFinishedID).Error; err != nil {
+			return fmt.Errorf("semi-finished %s not found: %w", line.SemiFinishedName, err)
+		}
+		line.Unit = units.Normalize(line.Unit)
+		total, err := semiFinishedLineCost(&sf, line.Quantity, line.Unit)
+		if err != nil {
+			return fmt.Errorf("semi-finished %s: %w", line.SemiFinishedName, err)
+		}
+		line.CostPerUnit = sf.CostPerUnit
+		line.TotalCost = total
+	}
+	return nil
+}
+
+// semiFinishedLineCost стоимость количества полуфабриката: себестоимость хранится
+// за единицу выхода, количество строки переводится в неё
+func semiFinishedLineCost(sf *models.SemiFinished, quantity float64, unit string) (float64, error) {
+	qty, err := units.Convert(quantity, unit, sf.OutputUnit)
+	if err != nil {
+		return 0, err
+	}
+	return roundCost(qty * sf.CostPerUnit), nil
+}
+
+// ingredientUnits единицы учёта ингредиентов, их графы перевода и нормы отходов
+type ingredientUnits struct {
+	stockUnits map[string]string
+	converters map[string]*units.Converter
+	trimLoss   map[string]float64 // Доля потерь при холодной обработке (0..1)
+}
+
+// loadIngredientUnits загружает единицы учёта, коэффициенты перевода и нормы отходов ингредиентов
+func loadIngredientUnits(db *gorm.DB, ingredientIDs []string) (*ingredientUnits, error) {
+	result := &ingredientUnits{
+		stockUnits: map[string]string{},
+		converters: map[string]*units.Converter{},
+		trimLoss:   map[string]float64{},
+	}
+	if len(ingredientIDs) == 0 {
+		return result, nil
+	}
+
+	var ingredients []models.Ingredient
+	if err := db.Where("id IN ?", ingredientIDs).Find(&ingredients).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch ingredients: %w", err)
+	}
+	for _, ing := range ingredients {
+		result.stockUnits[ing.ID] = units.Normalize(ing.Unit)
+	}
+
+	var conversions []models.IngredientUnitConversion
+	if err := db.Where("ingredient_id IN ?", ingredientIDs).Order("created_at ASC").Find(&conversions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch unit conversions: %w", err)
+	}
+	for _, c := range conversions {
+		converter := result.converters[c.IngredientID]
+		if converter == nil {
+			converter, _ = units.NewConverter()
+			result.converters[c.IngredientID] = converter
+		}
+		if err := converter.Add(units.Conversion{From: c.FromUnit, To: c.ToUnit, Factor: c.Factor}); err != nil {
+			log.Printf("[UNITS] ⚠️ Skipping invalid conversion %s for ingredient %s: %v", c.ID, c.IngredientID, err)
+		}
+	}
+
+	var stockItems []models.StockItem
+	if err := db.Where(`"ingredientId" IN ?`, ingredientIDs).Find(&stockItems).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch stock items: %w", err)
+	}
+	for _, item := range stockItems {
+		if loss := TrimLossOf(&item); loss > 0 {
+			result.trimLoss[item.IngredientID] = loss
+		}
+	}
+	return result, nil
+}
+
+// converter граф перевода ингредиента (nil — только стандартные переводы)
+func (u *ingredientUnits) converter(ingredientID string) *units.Converter {
+	return u.converters[ingredientID]
+}
+
+// toStockBase переводит количество строки рецептуры в базовую единицу складского учёта
+// ингредиента (кг/л/шт), за которую хранится цена. Для неизвестного ингредиента —
+// стандартный перевод внутри измерения.
+func (u *ingredientUnits) toStockBase(ingredientID string, quantity float64, unit string) (float64, error) {
+	stockUnit, ok := u.stockUnits[ingredientID]
+	if !ok || !units.IsKnown(stockUnit) {
+		return units.ToBase(quantity, unit), nil
+	}
+	qty, err := u.converter(ingredientID).Convert(quantity, unit, units.BaseUnit(stockUnit))
+	if err != nil {
+		return 0, fmt.Errorf("%w (stock unit %s, add a conversion factor)", err, stockUnit)
+	}
+	return qty, nil
+}
+
+// stockBase как toStockBase, но для пересчётов по уже сохранённым рецептурам:
+// непереводимая строка считается стандартным переводом с предупреждением в лог
+func (u *ingredientUnits) stockBase(ingredientID string, quantity float64, unit string) float64 {
+	qty, err := u.toStockBase(ingredientID, quantity, unit)
+	if err != nil {
+		log.Printf("[UNITS] ⚠️ Ingredient %s: %v", ingredientID, err)
+		return units.ToBase(quantity, unit)
+	}
+	return qty
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/database"
+	"github.com/dmitrijfomin/menu-fodifood/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Превышение нормы отходов, после которого потери считаются аномальными:
+// в полтора раза выше нормы и не меньше чем на 1 процентный пункт
+const (
+	wasteAbnormalFactor = 1.5
+	wasteAbnormalMinGap = 1.0
+)
+
+// WriteOffService - сервис списаний (порча, потери, питание персонала)
+type WriteOffService struct{}
+
+// NewWriteOffService создает новый экземпляр WriteOffService
+func NewWriteOffService() *WriteOffService {
+	return &WriteOffService{}
+}
+
+// Create списывает ингредиент со склада: движение "out" из партий и запись о списании
+func (s *WriteOffService) Create(req models.WriteOffRequest, createdBy *string) (*models.WriteOff, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if _, ok := models.WriteOffReasons[reason]; !ok {
+		return nil, fmt.Errorf("invalid write-off reason: %s", req.Reason)
+	}
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+
+	writeOff := models.WriteOff{
+		ID:        uuid.New().String(),
+		Quantity:  req.Quantity,
+		Reason:    reason,
+		Comment:   optionalString(req.Comment),
+		PhotoURL:  optionalString(req.PhotoURL),
+		BatchID:   optionalString(req.BatchID),
+		CreatedBy: createdBy,
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		item, err := lockStockItem(tx, req.IngredientID)
+		if err != nil {
+			return err
+		}
+		if item.Quantity < req.Quantity-stockEpsilon {
+			return fmt.Errorf("cannot write off %.3f: only %.3f in stock", req.Quantity, item.Quantity)
+		}
+
+		writeOff.StockItemID = item.ID
+		writeOff.IngredientID = item.IngredientID
+		if item.Ingredient != nil {
+			writeOff.IngredientName = item.Ingredient.Name
+			writeOff.Unit = item.Ingredient.Unit
+		}
+
+		note := fmt.Sprintf("Списание: %s", models.WriteOffReasons[reason])
+		if writeOff.Comment != nil {
+			note += " — " + *writeOff.Comment
+		}
+		movement, cost, err := consumeStock(tx, item, req.Quantity, strings.TrimSpace(req.BatchID), stockMovementInput{
+			Note:         note,
+			DocumentType: models.DocumentWriteOff,
+			DocumentID:   writeOff.ID,
+			UserID:       createdBy,
+		})
+		if err != nil {
+			return err
+		}
+		writeOff.Cost = cost
+		writeOff.MovementID = movement.ID
+
+		if err := tx.Create(&writeOff).Error; err != nil {
+			return fmt.Errorf("failed to create write-off: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[WASTE] 🗑️ Written off %.3f %s of %s (%s), cost %.2f",
+		writeOff.Quantity, writeOff.Unit, writeOff.IngredientName, writeOff.Reason, writeOff.Cost)
+	return &writeOff, nil
+}
+
+// GetAll возвращает списания за период с фильтром по причине и ингредиенту
+func (s *WriteOffService) GetAll(from, to time.Time, reason, ingredientID string, limit int) ([]models.WriteOff, error) {
+	query := database.GetDB().
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Order("created_at DESC").
+		Limit(limit)
+	if reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+	if ingredientID != "" {
+		query = query.Where("ingredient_id = ? OR stock_item_id = ?", ingredientID, ingredientID)
+	}
+
+	var writeOffs []models.WriteOff
+	if err := query.Find(&writeOffs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch write-offs: %w", err)
+	}
+	return writeOffs, nil
+}
+
+// WasteReport строит отчёт о списаниях за период по причинам и ингредиентам.
+// Доля потерь в общем расходе ингредиента сравнивается с нормой отходов (WastePercentage).
+func (s *WriteOffService) WasteReport(from, to time.Time) (*models.WasteReport, error) {
+	db := database.GetDB()
+
+	var writeOffs []models.WriteOff
+	if err := db.Where("created_at >= ? AND created_at < ?", from, to).Find(&writeOffs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch write-offs: %w", err)
+	}
+
+	report := &models.WasteReport{
+		From:        from,
+		To:          to,
+		ByReason:    []models.WasteReasonTotal{},
+		Ingredients: []models.WasteIngredientTotal{},
+	}
+	if len(writeOffs) == 0 {
+		return report, nil
+	}
+
+	reasons := map[string]*models.WasteReasonTotal{}
+	ingredients := map[string]*models.WasteIngredientTotal{}
+	stockItemIDs := []string{}
+	for _, w := range writeOffs {
+		report.TotalCost += w.Cost
+
+		rt, ok := reasons[w.Reason]
+		if !ok {
+			rt = &models.WasteReasonTotal{Reason: w.Reason, Label: models.WriteOffReasons[w.Reason]}
+			reasons[w.Reason] = rt
+		}
+		rt.Count++
+		rt.Cost += w.Cost
+
+		it, ok := ingredients[w.StockItemID]
+		if !ok {
+			it = &models.WasteIngredientTotal{
+				IngredientID:   w.IngredientID,
+				IngredientName: w.IngredientName,
+				Unit:           w.Unit,
+				ByReason:       map[string]float64{},
+			}
+			ingredients[w.StockItemID] = it
+			stockItemIDs = append(stockItemIDs, w.StockItemID)
+		}
+		it.Quantity += w.Quantity
+		it.Cost += w.Cost
+		it.ByReason[w.Reason] += w.Quantity
+		if models.IsLossReason(w.Reason) {
+			it.LossQuantity += w.Quantity
+		}
+	}
+
+	// Весь расход ингредиентов за период (заказы, списания, недостачи)
+	type consumedRow struct {
+		StockItemID string
+		Quantity    float64
+	}
+	var consumed []consumedRow
+	if err := db.Model(&models.StockMovement{}).
+		Select(`"stockItemId" AS stock_item_id, SUM(quantity) AS quantity`).
+		Where(`"stockItemId" IN ? AND type = ? AND "createdAt" >= ? AND "createdAt" < ?`,
+			stockItemIDs, models.MovementOut, from, to).
+		Group(`"stockItemId"`).
+		Scan(&consumed).Error; err != nil {
+		return nil, fmt.Errorf("failed to calculate consumption: %w", err)
+	}
+	for _, row := range consumed {
+		ingredients[row.StockItemID].ConsumedQuantity = row.Quantity
+	}
+
+	var items []models.StockItem
+	if err := db.Where("id IN ?", stockItemIDs).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch stock items: %w", err)
+	}
+	for _, item := range items {
+		ingredients[item.ID].ExpectedPercent = item.WastePercentage
+	}
+
+	for _, id := range stockItemIDs {
+		it := ingredients[id]
+		it.Quantity = roundQuantity(it.Quantity)
+		it.LossQuantity = roundQuantity(it.LossQuantity)
+		it.ConsumedQuantity = roundQuantity(it.ConsumedQuantity)
+		it.Cost = roundCost(it.Cost)
+		if it.ConsumedQuantity > 0 {
+			it.WastePercent = roundCost(it.LossQuantity / it.ConsumedQuantity * 100)
+		}
+
+		expected := 0.0
+		if it.ExpectedPercent != nil {
+			expected = *it.ExpectedPercent
+		}
+		it.IsAbnormal = it.WastePercent > expected*wasteAbnormalFactor && it.WastePercent-expected >= wasteAbnormalMinGap
+
+		report.Ingredients = append(report.Ingredients, *it)
+	}
+	for _, rt := range reasons {
+		rt.Cost = roundCost(rt.Cost)
+		report.ByReason = append(report.ByReason, *rt)
+	}
+	report.TotalCost = roundCost(report.TotalCost)
+
+	sort.Slice(report.ByReason, func(i, j int) bool { return report.ByReason[i].Cost > report.ByReason[j].Cost })
+	sort.Slice(report.Ingredients, func(i, j int) bool { return report.Ingredients[i].Cost > report.Ingredients[j].Cost })
+
+	return report, nil
+}
@@ -0,0 +1,145 @@
+package units
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnknownUnit единица измерения не распознана
+	ErrUnknownUnit = errors.New("unknown unit")
+	// ErrNotConvertible нет пути перевода между единицами
+	ErrNotConvertible = errors.New("units are not convertible")
+)
+
+// Conversion коэффициент перевода между измерениями: 1 From = Factor To
+// (например, 1 pcs авокадо = 180 g, 1 l масла = 0.92 kg)
+type Conversion struct {
+	From   string
+	To     string
+	Factor float64
+}
+
+// Converter граф перевода единиц. Внутри измерения перевод стандартный (г ↔ кг),
+// между измерениями — по рёбрам из коэффициентов ингредиента; путь может идти
+// через несколько измерений (шт → кг → л).
+type Converter struct {
+	edges map[string]map[string]float64 // Базовая единица → базовая единица → множитель
+}
+
+// NewConverter создаёт граф по коэффициентам ингредиента
+func NewConverter(conversions ...Conversion) (*Converter, error) {
+	c := &Converter{edges: map[string]map[string]float64{}}
+	for _, conversion := range conversions {
+		if err := c.Add(conversion); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Add добавляет ребро перевода между измерениями
+func (c *Converter) Add(conversion Conversion) error {
+	from, to, err := resolveConversion(conversion)
+	if err != nil {
+		return err
+	}
+
+	// 1 base(from) = ratio base(to)
+	ratio := conversion.Factor * to.scale / from.scale
+	c.link(from.base, to.base, ratio)
+	c.link(to.base, from.base, 1/ratio)
+	return nil
+}
+
+// Convert переводит количество из одной единицы в другую
+func (c *Converter) Convert(value float64, from, to string) (float64, error) {
+	fromDef, ok := definitions[Normalize(from)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownUnit, from)
+	}
+	toDef, ok := definitions[Normalize(to)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownUnit, to)
+	}
+
+	ratio, ok := c.ratio(fromDef.base, toDef.base)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s → %s", ErrNotConvertible, Normalize(from), Normalize(to))
+	}
+	return value * fromDef.scale * ratio / toDef.scale, nil
+}
+
+// CanConvert проверяет, что между единицами есть путь перевода
+func (c *Converter) CanConvert(from, to string) bool {
+	_, err := c.Convert(1, from, to)
+	return err == nil
+}
+
+// link добавляет направленное ребро графа
+func (c *Converter) link(from, to string, ratio float64) {
+	if c.edges[from] == nil {
+		c.edges[from] = map[string]float64{}
+	}
+	c.edges[from][to] = ratio
+}
+
+// ratio ищет путь между базовыми единицами обходом в ширину и перемножает коэффициенты
+func (c *Converter) ratio(from, to string) (float64, bool) {
+	if from == to {
+		return 1, true
+	}
+	if c == nil {
+		return 0, false
+	}
+
+	ratios := map[string]float64{from: 1}
+	queue := []string{from}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for next, r := range c.edges[node] {
+			if _, seen := ratios[next]; seen {
+				continue
+			}
+			ratios[next] = ratios[node] * r
+			if next == to {
+				return ratios[next], true
+			}
+			queue = append(queue, next)
+		}
+	}
+	return 0, false
+}
+
+// Convert переводит количество внутри одного измерения (г ↔ кг, мл ↔ л)
+func Convert(value float64, from, to string) (float64, error) {
+	var c *Converter
+	return c.Convert(value, from, to)
+}
+
+// ValidateConversion проверяет коэффициент: обе единицы известны, относятся
+// к разным измерениям, коэффициент положительный
+func ValidateConversion(conversion Conversion) error {
+	_, _, err := resolveConversion(conversion)
+	return err
+}
+
+// resolveConversion проверяет коэффициент и возвращает описания его единиц
+func resolveConversion(conversion Conversion) (from, to definition, err error) {
+	from, ok := definitions[Normalize(conversion.From)]
+	if !ok {
+		return from, to, fmt.Errorf("%w: %s", ErrUnknownUnit, conversion.From)
+	}
+	to, ok = definitions[Normalize(conversion.To)]
+	if !ok {
+		return from, to, fmt.Errorf("%w: %s", ErrUnknownUnit, conversion.To)
+	}
+	if from.dimension == to.dimension {
+		return from, to, fmt.Errorf("%s and %s are converted by the standard ratio", Normalize(conversion.From), Normalize(conversion.To))
+	}
+	if conversion.Factor <= 0 {
+		return from, to, fmt.Errorf("conversion factor must be positive")
+	}
+	return from, to, nil
+}
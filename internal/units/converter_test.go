@@ -0,0 +1,90 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestConverterConvert(t *testing.T) {
+	// Авокадо: 1 шт = 180 г; масло: 1 л = 0.92 кг
+	avocado, err := NewConverter(Conversion{From: Piece, To: Gram, Factor: 180})
+	if err != nil {
+		t.Fatalf("NewConverter() error = %v", err)
+	}
+	oil, err := NewConverter(Conversion{From: Liter, To: Kilogram, Factor: 0.92})
+	if err != nil {
+		t.Fatalf("NewConverter() error = %v", err)
+	}
+	// Цепочка через три измерения: шт → кг → л
+	chain, err := NewConverter(
+		Conversion{From: Piece, To: Gram, Factor: 500},
+		Conversion{From: Liter, To: Kilogram, Factor: 1.25},
+	)
+	if err != nil {
+		t.Fatalf("NewConverter() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		converter *Converter
+		value     float64
+		from, to  string
+		want      float64
+		wantErr   error
+	}{
+		{name: "standard ratio without converter", value: 1500, from: Gram, to: Kilogram, want: 1.5},
+		{name: "aliases are normalized", value: 2, from: "Литр", to: "мл", want: 2000},
+		{name: "same unit", value: 3, from: Piece, to: Piece, want: 3},
+		{name: "pieces to grams", converter: avocado, value: 2, from: Piece, to: Gram, want: 360},
+		{name: "grams to pieces", converter: avocado, value: 90, from: Gram, to: Piece, want: 0.5},
+		{name: "pieces to kilograms", converter: avocado, value: 10, from: Piece, to: Kilogram, want: 1.8},
+		{name: "milliliters to grams", converter: oil, value: 500, from: Milliliter, to: Gram, want: 460},
+		{name: "path through several dimensions", converter: chain, value: 1, from: Piece, to: Milliliter, want: 400},
+		{name: "no conversion between dimensions", value: 1, from: Piece, to: Gram, wantErr: ErrNotConvertible},
+		{name: "converter without the edge", converter: oil, value: 1, from: Piece, to: Kilogram, wantErr: ErrNotConvertible},
+		{name: "unknown source unit", value: 1, from: "cup", to: Gram, wantErr: ErrUnknownUnit},
+		{name: "unknown target unit", converter: avocado, value: 1, from: Piece, to: "oz", wantErr: ErrUnknownUnit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.converter.Convert(tt.value, tt.from, tt.to)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Convert() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Convert() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConversion(t *testing.T) {
+	tests := []struct {
+		name       string
+		conversion Conversion
+		wantErr    bool
+	}{
+		{name: "between dimensions", conversion: Conversion{From: Piece, To: Gram, Factor: 180}},
+		{name: "same dimension", conversion: Conversion{From: Gram, To: Kilogram, Factor: 0.001}, wantErr: true},
+		{name: "zero factor", conversion: Conversion{From: Piece, To: Gram, Factor: 0}, wantErr: true},
+		{name: "negative factor", conversion: Conversion{From: Liter, To: Kilogram, Factor: -1}, wantErr: true},
+		{name: "unknown unit", conversion: Conversion{From: "cup", To: Gram, Factor: 240}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConversion(tt.conversion)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConversion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
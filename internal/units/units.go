@@ -0,0 +1,153 @@
+// Package units — единицы измерения: нормализация, перевод внутри измерения
+// и граф перевода между измерениями по коэффициентам конкретного ингредиента.
+package units
+
+import "strings"
+
+// Стандартные единицы измерения
+const (
+	Gram       = "g"
+	Kilogram   = "kg"
+	Milliliter = "ml"
+	Liter      = "l"
+	Piece      = "pcs"
+)
+
+// Измерения (физические величины)
+const (
+	DimensionMass   = "mass"
+	DimensionVolume = "volume"
+	DimensionCount  = "count"
+)
+
+// definition описание единицы: измерение, базовая единица и множитель к ней
+type definition struct {
+	dimension string
+	base      string
+	scale     float64 // Сколько базовых единиц в одной единице
+}
+
+var definitions = map[string]definition{
+	Gram:       {dimension: DimensionMass, base: Kilogram, scale: 0.001},
+	Kilogram:   {dimension: DimensionMass, base: Kilogram, scale: 1},
+	Milliliter: {dimension: DimensionVolume, base: Liter, scale: 0.001},
+	Liter:      {dimension: DimensionVolume, base: Liter, scale: 1},
+	Piece:      {dimension: DimensionCount, base: Piece, scale: 1},
+}
+
+// All стандартные единицы в порядке отображения
+var All = []string{Gram, Kilogram, Milliliter, Liter, Piece}
+
+// aliases написания единиц, встречающиеся во входных данных
+var aliases = map[string]string{
+	"г": Gram, "гр": Gram, "грамм": Gram, "граммов": Gram, "gram": Gram, "grams": Gram, "gr": Gram,
+	"кг": Kilogram, "килограмм": Kilogram, "kilogram": Kilogram, "kilograms": Kilogram, "kgs": Kilogram,
+	"мл": Milliliter, "миллилитр": Milliliter, "milliliter": Milliliter, "millilitre": Milliliter,
+	"л": Liter, "литр": Liter, "литров": Liter, "liter": Liter, "litre": Liter, "liters": Liter,
+	"шт": Piece, "штук": Piece, "штука": Piece, "pc": Piece, "piece": Piece, "pieces": Piece,
+}
+
+// Normalize приводит единицу измерения к стандартному виду ("гр." → "g", "Литр" → "l").
+// Нераспознанная единица возвращается без изменений (в нижнем регистре).
+func Normalize(unit string) string {
+	u := strings.ToLower(strings.TrimSpace(unit))
+	u = strings.TrimSuffix(u, ".")
+	if _, ok := definitions[u]; ok {
+		return u
+	}
+	if canonical, ok := aliases[u]; ok {
+		return canonical
+	}
+
+	// Свободное написание: "килограммы", "гр. (вес)" и т.п.
+	switch {
+	case strings.Contains(u, "кг") || strings.Contains(u, "килограмм") || strings.Contains(u, "kilogram"):
+		return Kilogram
+	case strings.Contains(u, "мл") || strings.Contains(u, "миллилитр") || strings.Contains(u, "millilit"):
+		return Milliliter
+	case strings.Contains(u, "гр") || strings.Contains(u, "gram"):
+		return Gram
+	case strings.Contains(u, "литр") || strings.Contains(u, "liter") || strings.Contains(u, "litre"):
+		return Liter
+	case strings.Contains(u, "шт") || strings.Contains(u, "pcs"):
+		return Piece
+	}
+	return u
+}
+
+// IsKnown проверяет, что единица распознаётся
+func IsKnown(unit string) bool {
+	_, ok := definitions[Normalize(unit)]
+	return ok
+}
+
+// Dimension возвращает измерение единицы ("mass", "volume", "count") или пустую строку
+func Dimension(unit string) string {
+	return definitions[Normalize(unit)].dimension
+}
+
+// BaseUnit возвращает базовую единицу измерения (g → kg, ml → l).
+// Для нераспознанной единицы возвращается она сама.
+func BaseUnit(unit string) string {
+	if def, ok := definitions[Normalize(unit)]; ok {
+		return def.base
+	}
+	return unit
+}
+
+// ToBase переводит количество в базовую единицу своего измерения (г → кг, мл → л).
+// Цены и себестоимость хранятся за базовую единицу.
+func ToBase(value float64, unit string) float64 {
+	if def, ok := definitions[Normalize(unit)]; ok {
+		return value * def.scale
+	}
+	return value
+}
+
+// FromBase переводит количество из базовой единицы в указанную (кг → г, л → мл)
+func FromBase(value float64, unit string) float64 {
+	if def, ok := definitions[Normalize(unit)]; ok {
+		return value / def.scale
+	}
+	return value
+}
+
+// defaultUnits единицы по умолчанию для типовых ингредиентов (по фрагменту названия)
+var defaultUnits = []struct {
+	fragment string
+	unit     string
+}{
+	{"мук", Kilogram},
+	{"сахар", Kilogram},
+	{"рис", Kilogram},
+	{"круп", Kilogram},
+	{"соль", Kilogram},
+	{"вода", Liter},
+	{"масло", Milliliter},
+	{"молок", Liter},
+	{"яйц", Piece},
+	{"лосос", Kilogram},
+	{"сёмг", Kilogram},
+	{"тунец", Kilogram},
+	{"креве", Kilogram},
+	{"угор", Kilogram},
+	{"сыр", Kilogram},
+	{"соус", Milliliter},
+	{"уксус", Milliliter},
+	{"нори", Piece},
+	{"васаби", Kilogram},
+	{"имбир", Kilogram},
+	{"авока", Piece},
+	{"огуре", Piece},
+}
+
+// DetectDefault возвращает единицу по умолчанию по названию ингредиента или пустую строку
+func DetectDefault(name string) string {
+	nameLower := strings.ToLower(name)
+	for _, d := range defaultUnits {
+		if strings.Contains(nameLower, d.fragment) {
+			return d.unit
+		}
+	}
+	return ""
+}
@@ -0,0 +1,14 @@
+-- Migration: Full-text and fuzzy product search
+-- Date: 2026-10-18
+
+-- Триграммы для поиска с опечатками ("филадельфя")
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Полнотекстовый индекс по названию, категории и описанию (русская морфология)
+CREATE INDEX IF NOT EXISTS idx_product_search_fts ON "Product" USING GIN (
+    to_tsvector('russian', coalesce(name, '') || ' ' || coalesce(category, '') || ' ' || coalesce(description, ''))
+);
+
+-- Триграммные индексы для нечёткого поиска
+CREATE INDEX IF NOT EXISTS idx_product_name_trgm ON "Product" USING GIN (lower(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_product_ingredients_name_trgm ON product_ingredients USING GIN (lower(ingredient_name) gin_trgm_ops);
@@ -0,0 +1,13 @@
+-- Migration: Link stock movements to source documents (goods receipts, write-offs, ...)
+-- Date: 2026-10-18
+
+ALTER TABLE "StockMovement"
+ADD COLUMN IF NOT EXISTS "documentType" TEXT;
+
+ALTER TABLE "StockMovement"
+ADD COLUMN IF NOT EXISTS "documentId" TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_stock_movement_document ON "StockMovement" ("documentType", "documentId");
+
+COMMENT ON COLUMN "StockMovement"."documentType" IS 'Тип документа-основания: goods_receipt, ...';
+COMMENT ON COLUMN "StockMovement"."documentId" IS 'ID документа-основания';
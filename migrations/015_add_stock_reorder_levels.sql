@@ -0,0 +1,11 @@
+-- Migration: Minimum stock and reorder quantity for low-stock alerts
+-- Date: 2026-10-18
+
+ALTER TABLE "StockItem"
+ADD COLUMN IF NOT EXISTS "minStock" DOUBLE PRECISION;
+
+ALTER TABLE "StockItem"
+ADD COLUMN IF NOT EXISTS "reorderQuantity" DOUBLE PRECISION;
+
+COMMENT ON COLUMN "StockItem"."minStock" IS 'Минимальный остаток (точка заказа) в единицах ингредиента';
+COMMENT ON COLUMN "StockItem"."reorderQuantity" IS 'Рекомендуемый объём заказа в единицах ингредиента';
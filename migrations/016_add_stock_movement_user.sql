@@ -0,0 +1,11 @@
+-- Migration: Track who made a stock movement and index the movement journal
+-- Date: 2026-10-18
+
+ALTER TABLE "StockMovement"
+ADD COLUMN IF NOT EXISTS "userId" TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_stock_movement_user ON "StockMovement" ("userId");
+CREATE INDEX IF NOT EXISTS idx_stock_movement_item_created ON "StockMovement" ("stockItemId", "createdAt" DESC);
+CREATE INDEX IF NOT EXISTS idx_stock_movement_created ON "StockMovement" ("createdAt" DESC);
+
+COMMENT ON COLUMN "StockMovement"."userId" IS 'Пользователь, выполнивший движение (админ, покупатель для заказов)';
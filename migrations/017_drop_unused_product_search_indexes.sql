@@ -0,0 +1,10 @@
+-- Migration: Drop product search indexes that the search query cannot use
+-- Date: 2026-10-18
+
+-- Документ поиска собирается с весами (setweight) и с названиями ингредиентов из
+-- product_ingredients, а нечёткое совпадение считается word_similarity по склеенному
+-- тексту — ни одно из этих выражений не совпадает с индексами из 013, поэтому
+-- они только замедляли запись. Меню небольшое, поиск идёт последовательным просмотром.
+DROP INDEX IF EXISTS idx_product_search_fts;
+DROP INDEX IF EXISTS idx_product_name_trgm;
+DROP INDEX IF EXISTS idx_product_ingredients_name_trgm;
@@ -0,0 +1,9 @@
+-- Migration: One active goods receipt per supplier invoice
+-- Date: 2026-10-18
+
+-- Проверка дубликата в сервисе не защищает от двух одновременных запросов,
+-- поэтому уникальность накладной поставщика гарантируется индексом.
+-- Сторнированные накладные не учитываются: исправленную можно оприходовать заново.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_goods_receipt_invoice
+    ON goods_receipts (LOWER(supplier), invoice_number)
+    WHERE status <> 'reversed';
@@ -0,0 +1,8 @@
+-- Migration: Only one open inventory count at a time
+-- Date: 2026-10-18
+
+-- Проверка "нет открытой инвентаризации" и создание новой выполняются в сервисе
+-- под advisory-блокировкой; индекс гарантирует то же правило на уровне БД.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_inventory_count_open
+    ON inventory_counts ((status))
+    WHERE status = 'open';